@@ -0,0 +1,144 @@
+package main
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/joho/godotenv"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/go-chi/cors"
+
+	"github.com/gavinadlan/tripnest/backend/api-gateway/internal/config"
+	"github.com/gavinadlan/tripnest/backend/api-gateway/internal/proxy"
+	"github.com/gavinadlan/tripnest/backend/api-gateway/internal/ratelimit"
+	"github.com/gavinadlan/tripnest/backend/common/auth"
+)
+
+func main() {
+	godotenv.Load()
+	cfg := config.Load()
+
+	opts := proxy.Options{
+		HealthPath:      "/health",
+		HealthTimeout:   cfg.HealthCheckTimeout,
+		ResponseTimeout: cfg.UpstreamTimeout,
+	}
+	users, err := proxy.NewUpstream("user-service", cfg.UserServiceURLs, opts)
+	if err != nil {
+		log.Fatalf("Failed to configure upstream: %v", err)
+	}
+	bookings, err := proxy.NewUpstream("booking-service", cfg.BookingServiceURLs, opts)
+	if err != nil {
+		log.Fatalf("Failed to configure upstream: %v", err)
+	}
+	search, err := proxy.NewUpstream("search-service", cfg.SearchServiceURLs, opts)
+	if err != nil {
+		log.Fatalf("Failed to configure upstream: %v", err)
+	}
+	notifications, err := proxy.NewUpstream("notification-service", cfg.NotificationServiceURLs, opts)
+	if err != nil {
+		log.Fatalf("Failed to configure upstream: %v", err)
+	}
+	recommendations, err := proxy.NewUpstream("recommendation-service", cfg.RecommendationServiceURLs, opts)
+	if err != nil {
+		log.Fatalf("Failed to configure upstream: %v", err)
+	}
+
+	var limiter ratelimit.Limiter
+	switch cfg.RateLimitBackend {
+	case "memory":
+		limiter = ratelimit.NewMemoryLimiter()
+	case "redis":
+		redisLimiter := ratelimit.NewRedisLimiter(cfg.RedisAddr)
+		defer redisLimiter.Close()
+		limiter = redisLimiter
+	default:
+		log.Fatalf("Unknown RATE_LIMIT_BACKEND %q", cfg.RateLimitBackend)
+	}
+
+	rules, err := ratelimit.ParseRouteRules(cfg.RateLimitRoutes)
+	if err != nil {
+		log.Fatalf("Failed to parse RATE_LIMIT_ROUTES: %v", err)
+	}
+	perUser, err := ratelimit.ParseLimit(cfg.RateLimitPerUser)
+	if err != nil {
+		log.Fatalf("Failed to parse RATE_LIMIT_PER_USER: %v", err)
+	}
+	perIP, err := ratelimit.ParseLimit(cfg.RateLimitPerIP)
+	if err != nil {
+		log.Fatalf("Failed to parse RATE_LIMIT_PER_IP: %v", err)
+	}
+	// Most specific first, so a route limit is reported over the broad ones
+	rules = append(rules, ratelimit.PerUser(perUser), ratelimit.PerIP(perIP))
+
+	verifier := auth.NewJWKSVerifier(cfg.JWKSURL, cfg.JWTIssuer, cfg.JWKSRefreshTime)
+	gw := proxy.NewGateway(verifier, ratelimit.Middleware(limiter, rules...),
+		proxy.Route{Prefix: "/api/users", StripPrefix: "/api/users", Upstream: users},
+		proxy.Route{Prefix: "/api/bookings", StripPrefix: "/api", Upstream: bookings, RequireAuth: true},
+		proxy.Route{Prefix: "/api/search", StripPrefix: "/api", Upstream: search},
+		proxy.Route{Prefix: "/api/listings", StripPrefix: "/api", Upstream: search},
+		proxy.Route{Prefix: "/api/notifications", StripPrefix: "/api", Upstream: notifications, RequireAuth: true},
+		proxy.Route{Prefix: "/api/recommendations", StripPrefix: "/api", Upstream: recommendations},
+	)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	for _, u := range []*proxy.Upstream{users, bookings, search, notifications, recommendations} {
+		go u.RunHealthChecks(ctx, cfg.HealthCheckInterval)
+	}
+
+	r := chi.NewRouter()
+
+	// CORS is owned by the gateway; upstream services no longer set it
+	r.Use(cors.Handler(cors.Options{
+		AllowedOrigins:   cfg.CORSAllowedOrigins,
+		AllowedMethods:   []string{"GET", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"},
+		AllowedHeaders:   []string{"Accept", "Authorization", "Content-Type", "If-Match", "X-CSRF-Token"},
+		ExposedHeaders:   []string{"ETag", "Link", "Retry-After", "RateLimit-Limit", "RateLimit-Remaining", "RateLimit-Reset", "RateLimit-Policy"},
+		AllowCredentials: true,
+		MaxAge:           300,
+	}))
+
+	r.Use(middleware.RequestID)
+	if cfg.TrustProxyHeaders {
+		r.Use(middleware.RealIP)
+	}
+	r.Use(middleware.Logger)
+	r.Use(middleware.Recoverer)
+
+	gw.RegisterRoutes(r)
+
+	server := &http.Server{
+		Addr:    ":" + cfg.Port,
+		Handler: r,
+	}
+
+	go func() {
+		log.Printf("API Gateway starting on port %s", cfg.Port)
+		if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			log.Fatalf("Server error: %v", err)
+		}
+	}()
+
+	quit := make(chan os.Signal, 1)
+	signal.Notify(quit, os.Interrupt, syscall.SIGTERM)
+	<-quit
+	log.Println("Shutting down server...")
+	cancel()
+
+	shutdownCtx, shutdownCancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer shutdownCancel()
+
+	if err := server.Shutdown(shutdownCtx); err != nil {
+		log.Fatalf("Server forced to shutdown: %v", err)
+	}
+	log.Println("Server exiting")
+}
@@ -1,3 +1,19 @@
 module github.com/gavinadlan/tripnest/backend/api-gateway
 
 go 1.23.4
+
+require (
+	github.com/gavinadlan/tripnest/backend/common v0.0.0
+	github.com/go-chi/chi/v5 v5.2.5
+	github.com/go-chi/cors v1.2.2
+	github.com/go-redis/redis/v8 v8.11.5
+	github.com/joho/godotenv v1.5.1
+)
+
+require (
+	github.com/cespare/xxhash/v2 v2.2.0 // indirect
+	github.com/dgryski/go-rendezvous v0.0.0-20200823014737-9f7001d12a5f // indirect
+	github.com/golang-jwt/jwt/v5 v5.3.1 // indirect
+)
+
+replace github.com/gavinadlan/tripnest/backend/common => ../common
@@ -0,0 +1,82 @@
+package config
+
+import (
+	"strings"
+	"time"
+
+	"github.com/gavinadlan/tripnest/backend/common/env"
+)
+
+type Config struct {
+	Port string
+
+	// Upstream base URLs; a comma-separated list load-balances over replicas
+	UserServiceURLs           []string
+	BookingServiceURLs        []string
+	SearchServiceURLs         []string
+	NotificationServiceURLs   []string
+	RecommendationServiceURLs []string
+
+	HealthCheckInterval time.Duration
+	HealthCheckTimeout  time.Duration
+	UpstreamTimeout     time.Duration
+
+	JWTIssuer       string
+	JWKSURL         string
+	JWKSRefreshTime time.Duration
+
+	CORSAllowedOrigins []string
+	// TrustProxyHeaders takes the client address from X-Forwarded-For /
+	// X-Real-IP. Only enable it behind a load balancer that overwrites them,
+	// otherwise clients can pick their own IP for rate limiting.
+	TrustProxyHeaders bool
+
+	// RateLimitBackend is "memory" (per instance) or "redis" (shared)
+	RateLimitBackend string
+	RedisAddr        string
+	// Limits are "<requests>/<period>"; "0" disables a rule
+	RateLimitPerIP   string
+	RateLimitPerUser string
+	// RateLimitRoutes lists "<METHOD> <path>=<limit>" entries
+	RateLimitRoutes string
+}
+
+func Load() *Config {
+	return &Config{
+		Port: env.GetString("PORT", "8000"),
+
+		UserServiceURLs:           splitList(env.GetString("USER_SERVICE_URLS", "http://localhost:8080")),
+		BookingServiceURLs:        splitList(env.GetString("BOOKING_SERVICE_URLS", "http://localhost:8081")),
+		SearchServiceURLs:         splitList(env.GetString("SEARCH_SERVICE_URLS", "http://localhost:8083")),
+		NotificationServiceURLs:   splitList(env.GetString("NOTIFICATION_SERVICE_URLS", "http://localhost:8084")),
+		RecommendationServiceURLs: splitList(env.GetString("RECOMMENDATION_SERVICE_URLS", "http://localhost:8085")),
+
+		HealthCheckInterval: env.GetDuration("HEALTH_CHECK_INTERVAL", 10*time.Second),
+		HealthCheckTimeout:  env.GetDuration("HEALTH_CHECK_TIMEOUT", 2*time.Second),
+		UpstreamTimeout:     env.GetDuration("UPSTREAM_TIMEOUT", 30*time.Second),
+
+		JWTIssuer:       env.GetString("JWT_ISSUER", "user-service"),
+		JWKSURL:         env.GetString("JWKS_URL", "http://localhost:8080/.well-known/jwks.json"),
+		JWKSRefreshTime: env.GetDuration("JWKS_REFRESH_INTERVAL", 10*time.Minute),
+
+		CORSAllowedOrigins: splitList(env.GetString("CORS_ALLOWED_ORIGINS", "http://localhost:3000")),
+		TrustProxyHeaders:  env.GetBool("TRUST_PROXY_HEADERS", false),
+
+		RateLimitBackend: env.GetString("RATE_LIMIT_BACKEND", "memory"),
+		RedisAddr:        env.GetString("REDIS_ADDR", "localhost:6379"),
+		RateLimitPerIP:   env.GetString("RATE_LIMIT_PER_IP", "300/1m"),
+		RateLimitPerUser: env.GetString("RATE_LIMIT_PER_USER", "600/1m"),
+		RateLimitRoutes: env.GetString("RATE_LIMIT_ROUTES",
+			"POST /api/users/login=5/1m,POST /api/users/register=5/10m,POST /api/bookings=10/1m"),
+	}
+}
+
+func splitList(s string) []string {
+	var out []string
+	for _, item := range strings.Split(s, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			out = append(out, item)
+		}
+	}
+	return out
+}
@@ -0,0 +1,106 @@
+package proxy
+
+import (
+	"errors"
+	"net/http"
+	"path"
+	"strings"
+
+	"github.com/gavinadlan/tripnest/backend/common/auth"
+	"github.com/gavinadlan/tripnest/backend/common/utils"
+	"github.com/go-chi/chi/v5"
+)
+
+// Route sends requests under Prefix to Upstream after removing StripPrefix
+// from the path, e.g. Prefix and StripPrefix "/api/users" forward
+// /api/users/login as /login.
+type Route struct {
+	Prefix      string
+	StripPrefix string
+	Upstream    *Upstream
+	// RequireAuth rejects anonymous requests at the gateway
+	RequireAuth bool
+}
+
+type Gateway struct {
+	verifier  auth.Verifier
+	rateLimit func(http.Handler) http.Handler
+	routes    []Route
+}
+
+// NewGateway proxies routes, applying rateLimit once the caller is known.
+func NewGateway(verifier auth.Verifier, rateLimit func(http.Handler) http.Handler, routes ...Route) *Gateway {
+	return &Gateway{verifier: verifier, rateLimit: rateLimit, routes: routes}
+}
+
+// HealthResponse reports the gateway's view of its upstreams.
+type HealthResponse struct {
+	Status    string                    `json:"status"`
+	Upstreams map[string][]TargetStatus `json:"upstreams"`
+}
+
+func (g *Gateway) RegisterRoutes(r chi.Router) {
+	r.Get("/health", g.Health)
+
+	r.Group(func(r chi.Router) {
+		// Tokens are verified once here; upstreams get the principal headers
+		r.Use(auth.Identify(g.verifier))
+		r.Use(g.rateLimit)
+
+		for _, route := range g.routes {
+			h := stripPrefix(route.StripPrefix, blockInternal(route.Upstream))
+			if route.RequireAuth {
+				h = requireAuth(h)
+			}
+			r.Handle(route.Prefix, h)
+			r.Handle(route.Prefix+"/*", h)
+		}
+	})
+}
+
+func (g *Gateway) Health(w http.ResponseWriter, r *http.Request) {
+	resp := HealthResponse{Status: "ok", Upstreams: make(map[string][]TargetStatus)}
+	for _, route := range g.routes {
+		resp.Upstreams[route.Upstream.Name] = route.Upstream.Status()
+		if !route.Upstream.Healthy() {
+			resp.Status = "degraded"
+		}
+	}
+	utils.WriteJSON(w, http.StatusOK, resp)
+}
+
+func stripPrefix(prefix string, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path := strings.TrimPrefix(r.URL.Path, prefix)
+		if !strings.HasPrefix(path, "/") {
+			path = "/" + path
+		}
+
+		r2 := r.Clone(r.Context())
+		r2.URL.Path = path
+		r2.URL.RawPath = ""
+		next.ServeHTTP(w, r2)
+	})
+}
+
+// blockInternal hides the service-to-service endpoints (/internal/...) of
+// an upstream from clients. It runs on the forwarded path.
+func blockInternal(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if p := path.Clean(r.URL.Path); p == "/internal" || strings.HasPrefix(p, "/internal/") {
+			utils.WriteError(w, http.StatusNotFound, errors.New("not found"))
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func requireAuth(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, ok := auth.PrincipalFromContext(r.Context()); !ok {
+			utils.WriteError(w, http.StatusUnauthorized, errors.New("authentication required"))
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,105 @@
+package proxy
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gavinadlan/tripnest/backend/common/auth"
+	"github.com/go-chi/chi/v5"
+)
+
+type fakeVerifier map[string]*auth.Claims
+
+func (v fakeVerifier) Verify(token string) (*auth.Claims, error) {
+	if claims, ok := v[token]; ok {
+		return claims, nil
+	}
+	return nil, auth.ErrInvalidToken
+}
+
+// newTestGateway routes /api/users (prefix stripped entirely) and
+// /api/search and /api/bookings (prefix /api stripped) to one echo upstream,
+// which answers with the forwarded path and caller.
+func newTestGateway(t *testing.T) http.Handler {
+	t.Helper()
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, "%s user=%s", r.URL.Path, r.Header.Get(auth.HeaderUserID))
+	}))
+	t.Cleanup(upstream.Close)
+
+	u, err := NewUpstream("echo", []string{upstream.URL}, Options{HealthPath: "/health", ResponseTimeout: time.Second})
+	if err != nil {
+		t.Fatal(err)
+	}
+	verifier := fakeVerifier{"valid": {UserID: "u1", Role: "user"}}
+	noLimit := func(next http.Handler) http.Handler { return next }
+	gw := NewGateway(verifier, noLimit,
+		Route{Prefix: "/api/users", StripPrefix: "/api/users", Upstream: u},
+		Route{Prefix: "/api/search", StripPrefix: "/api", Upstream: u},
+		Route{Prefix: "/api/bookings", StripPrefix: "/api", Upstream: u, RequireAuth: true},
+	)
+
+	r := chi.NewRouter()
+	gw.RegisterRoutes(r)
+	return r
+}
+
+func serve(h http.Handler, target, token string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodGet, target, nil)
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestGatewayHidesInternalEndpoints(t *testing.T) {
+	h := newTestGateway(t)
+
+	for _, target := range []string{
+		"/api/users/internal/users/42",
+		"/api/users/internal",
+		"/api/users/%69nternal/users/42",
+		"/api/users/me/../internal/users/42",
+		"/api/users//internal/users/42",
+	} {
+		if rec := serve(h, target, "valid"); rec.Code != http.StatusNotFound {
+			t.Errorf("GET %s: status %d (%s), want 404", target, rec.Code, rec.Body)
+		}
+	}
+
+	if rec := serve(h, "/api/users/login", ""); rec.Code != http.StatusOK || rec.Body.String() != "/login user=" {
+		t.Errorf("GET /api/users/login: %d %q", rec.Code, rec.Body)
+	}
+}
+
+func TestGatewayTreatsInvalidTokenAsAnonymous(t *testing.T) {
+	h := newTestGateway(t)
+
+	cases := []struct {
+		target, token string
+		status        int
+		body          string
+	}{
+		{"/api/search", "valid", http.StatusOK, "/search user=u1"},
+		{"/api/search", "expired", http.StatusOK, "/search user="},
+		{"/api/search", "", http.StatusOK, "/search user="},
+		{"/api/bookings", "valid", http.StatusOK, "/bookings user=u1"},
+		{"/api/bookings", "expired", http.StatusUnauthorized, ""},
+		{"/api/bookings", "", http.StatusUnauthorized, ""},
+	}
+	for _, tc := range cases {
+		rec := serve(h, tc.target, tc.token)
+		if rec.Code != tc.status {
+			t.Errorf("GET %s with token %q: status %d, want %d", tc.target, tc.token, rec.Code, tc.status)
+			continue
+		}
+		if tc.body != "" && rec.Body.String() != tc.body {
+			t.Errorf("GET %s with token %q: body %q, want %q", tc.target, tc.token, rec.Body, tc.body)
+		}
+	}
+}
@@ -0,0 +1,181 @@
+package proxy
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+	"sync/atomic"
+	"time"
+
+	"github.com/gavinadlan/tripnest/backend/common/auth"
+	"github.com/gavinadlan/tripnest/backend/common/utils"
+	"github.com/go-chi/chi/v5/middleware"
+)
+
+var ErrNoHealthyTarget = errors.New("upstream unavailable")
+
+// Upstream is a named service reachable at one or more target URLs.
+// Requests are spread round-robin over the targets that passed their last
+// health check.
+type Upstream struct {
+	Name      string
+	opts      Options
+	targets   []*target
+	next      atomic.Uint64
+	client    *http.Client
+	transport http.RoundTripper
+}
+
+type Options struct {
+	// HealthPath is probed with GET on every target; any 2xx is healthy.
+	HealthPath    string
+	HealthTimeout time.Duration
+	// ResponseTimeout bounds the wait for an upstream's response headers.
+	ResponseTimeout time.Duration
+}
+
+type target struct {
+	url     *url.URL
+	proxy   *httputil.ReverseProxy
+	healthy atomic.Bool
+}
+
+// TargetStatus reports the health of a single upstream target.
+type TargetStatus struct {
+	URL     string `json:"url"`
+	Healthy bool   `json:"healthy"`
+}
+
+// NewUpstream builds an upstream over rawURLs. Targets start healthy so
+// traffic flows before the first health check.
+func NewUpstream(name string, rawURLs []string, opts Options) (*Upstream, error) {
+	if len(rawURLs) == 0 {
+		return nil, fmt.Errorf("upstream %s has no target URLs", name)
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.ResponseHeaderTimeout = opts.ResponseTimeout
+
+	u := &Upstream{
+		Name:      name,
+		opts:      opts,
+		client:    &http.Client{Timeout: opts.HealthTimeout},
+		transport: transport,
+	}
+	for _, raw := range rawURLs {
+		parsed, err := url.Parse(raw)
+		if err != nil || parsed.Scheme == "" || parsed.Host == "" {
+			return nil, fmt.Errorf("invalid URL %q for upstream %s", raw, name)
+		}
+		t := &target{url: parsed}
+		t.healthy.Store(true)
+		t.proxy = u.newReverseProxy(t)
+		u.targets = append(u.targets, t)
+	}
+	return u, nil
+}
+
+func (u *Upstream) newReverseProxy(t *target) *httputil.ReverseProxy {
+	return &httputil.ReverseProxy{
+		Transport: u.transport,
+		Rewrite: func(pr *httputil.ProxyRequest) {
+			pr.SetURL(t.url)
+			pr.SetXForwarded()
+
+			// Only the gateway may assert who the caller is
+			auth.ClearPrincipalHeaders(pr.Out.Header)
+			if p, ok := auth.PrincipalFromContext(pr.In.Context()); ok {
+				auth.SetPrincipalHeaders(pr.Out.Header, p)
+			}
+			if id := middleware.GetReqID(pr.In.Context()); id != "" {
+				pr.Out.Header.Set(middleware.RequestIDHeader, id)
+			}
+		},
+		ErrorHandler: func(w http.ResponseWriter, r *http.Request, err error) {
+			log.Printf("Proxy error for %s (%s): %v", u.Name, t.url, err)
+			if errors.Is(err, context.Canceled) {
+				return
+			}
+			// Take the target out of rotation until the next health check
+			t.healthy.Store(false)
+			utils.WriteError(w, http.StatusBadGateway, fmt.Errorf("%s is unreachable", u.Name))
+		},
+	}
+}
+
+// ServeHTTP forwards r to the next healthy target.
+func (u *Upstream) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	t := u.pick()
+	if t == nil {
+		utils.WriteError(w, http.StatusServiceUnavailable, ErrNoHealthyTarget)
+		return
+	}
+	t.proxy.ServeHTTP(w, r)
+}
+
+func (u *Upstream) pick() *target {
+	n := len(u.targets)
+	start := int(u.next.Add(1) % uint64(n))
+	for i := 0; i < n; i++ {
+		t := u.targets[(start+i)%n]
+		if t.healthy.Load() {
+			return t
+		}
+	}
+	return nil
+}
+
+// Status returns the health of every target.
+func (u *Upstream) Status() []TargetStatus {
+	status := make([]TargetStatus, len(u.targets))
+	for i, t := range u.targets {
+		status[i] = TargetStatus{URL: t.url.String(), Healthy: t.healthy.Load()}
+	}
+	return status
+}
+
+// Healthy reports whether at least one target can take traffic.
+func (u *Upstream) Healthy() bool {
+	return u.pick() != nil
+}
+
+// RunHealthChecks probes every target each interval until ctx is done.
+func (u *Upstream) RunHealthChecks(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		u.checkAll(ctx)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (u *Upstream) checkAll(ctx context.Context) {
+	for _, t := range u.targets {
+		healthy := u.check(ctx, t)
+		if was := t.healthy.Swap(healthy); was != healthy {
+			log.Printf("Upstream %s target %s healthy=%t", u.Name, t.url, healthy)
+		}
+	}
+}
+
+func (u *Upstream) check(ctx context.Context, t *target) bool {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, t.url.JoinPath(u.opts.HealthPath).String(), nil)
+	if err != nil {
+		return false
+	}
+	resp, err := u.client.Do(req)
+	if err != nil {
+		return false
+	}
+	resp.Body.Close()
+	return resp.StatusCode >= 200 && resp.StatusCode < 300
+}
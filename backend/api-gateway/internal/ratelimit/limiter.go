@@ -0,0 +1,96 @@
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Limit is a token bucket holding up to Burst tokens that refills completely
+// over Period, e.g. 5/1m allows a burst of 5 and then one request every 12s.
+type Limit struct {
+	Burst  int
+	Period time.Duration
+}
+
+// ParseLimit parses "<burst>/<period>", e.g. "100/1m". An empty string or
+// "0" returns a zero Limit, which disables the rule.
+func ParseLimit(s string) (Limit, error) {
+	s = strings.TrimSpace(s)
+	if s == "" || s == "0" {
+		return Limit{}, nil
+	}
+	burst, period, ok := strings.Cut(s, "/")
+	if !ok {
+		return Limit{}, fmt.Errorf("invalid rate limit %q: want <requests>/<period>", s)
+	}
+	n, err := strconv.Atoi(burst)
+	if err != nil || n <= 0 {
+		return Limit{}, fmt.Errorf("invalid rate limit %q: bad request count", s)
+	}
+	d, err := time.ParseDuration(period)
+	if err != nil || d <= 0 {
+		return Limit{}, fmt.Errorf("invalid rate limit %q: bad period", s)
+	}
+	return Limit{Burst: n, Period: d}, nil
+}
+
+func (l Limit) IsZero() bool {
+	return l.Burst == 0
+}
+
+// perSecond is the refill rate in tokens per second.
+func (l Limit) perSecond() float64 {
+	return float64(l.Burst) / l.Period.Seconds()
+}
+
+// Result is the outcome of taking a token from a bucket.
+type Result struct {
+	Allowed   bool
+	Limit     Limit
+	Remaining int
+	// RetryAfter is how long until a token is available when not Allowed
+	RetryAfter time.Duration
+	// ResetAfter is how long until the bucket is full again
+	ResetAfter time.Duration
+}
+
+// Bucket names a token bucket and the limit it enforces.
+type Bucket struct {
+	Key   string
+	Limit Limit
+}
+
+// Limiter takes one token from each of the buckets, creating missing ones
+// full. Tokens are only taken if every bucket has one, so a request denied
+// by one limit does not use up the others. Results are in bucket order;
+// a bucket's result is Allowed if it had a token, whether or not one was
+// taken.
+type Limiter interface {
+	Allow(ctx context.Context, buckets ...Bucket) ([]Result, error)
+}
+
+// result derives the caller-facing numbers from the tokens left in a bucket.
+func result(limit Limit, allowed bool, tokens float64) Result {
+	rate := limit.perSecond()
+	res := Result{
+		Allowed:    allowed,
+		Limit:      limit,
+		Remaining:  int(math.Floor(tokens)),
+		ResetAfter: seconds((float64(limit.Burst) - tokens) / rate),
+	}
+	if !allowed {
+		res.RetryAfter = seconds((1 - tokens) / rate)
+	}
+	return res
+}
+
+func seconds(s float64) time.Duration {
+	if s <= 0 {
+		return 0
+	}
+	return time.Duration(s * float64(time.Second))
+}
@@ -0,0 +1,90 @@
+package ratelimit
+
+import (
+	"context"
+	"math"
+	"sync"
+	"time"
+)
+
+// MemoryLimiter keeps buckets in process memory, so limits are per gateway
+// instance. Buckets that have refilled completely are dropped periodically.
+type MemoryLimiter struct {
+	mu        sync.Mutex
+	buckets   map[string]*bucket
+	lastSweep time.Time
+	now       func() time.Time
+}
+
+type bucket struct {
+	tokens float64
+	last   time.Time
+	// full is when the bucket will have refilled, after which it can be
+	// forgotten without changing any decision
+	full time.Time
+}
+
+const sweepInterval = time.Minute
+
+func NewMemoryLimiter() *MemoryLimiter {
+	return &MemoryLimiter{
+		buckets:   make(map[string]*bucket),
+		lastSweep: time.Now(),
+		now:       time.Now,
+	}
+}
+
+func (m *MemoryLimiter) Allow(ctx context.Context, buckets ...Bucket) ([]Result, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := m.now()
+	if now.Sub(m.lastSweep) > sweepInterval {
+		m.sweep(now)
+	}
+
+	// Refill every bucket before deciding, then take from all or none
+	refilled := make([]*bucket, len(buckets))
+	allowed := true
+	for i, bk := range buckets {
+		b := m.refill(bk, now)
+		refilled[i] = b
+		if b.tokens < 1 {
+			allowed = false
+		}
+	}
+
+	results := make([]Result, len(buckets))
+	for i, b := range refilled {
+		limit := buckets[i].Limit
+		if allowed {
+			b.tokens--
+		}
+		results[i] = result(limit, allowed || b.tokens >= 1, b.tokens)
+		b.full = now.Add(results[i].ResetAfter)
+	}
+	return results, nil
+}
+
+// refill returns the bucket named bk.Key topped up to now, creating it full.
+func (m *MemoryLimiter) refill(bk Bucket, now time.Time) *bucket {
+	burst := float64(bk.Limit.Burst)
+	b, ok := m.buckets[bk.Key]
+	if !ok {
+		b = &bucket{tokens: burst, last: now}
+		m.buckets[bk.Key] = b
+	}
+	elapsed := now.Sub(b.last).Seconds()
+	b.tokens = math.Min(burst, b.tokens+elapsed*bk.Limit.perSecond())
+	b.last = now
+	return b
+}
+
+func (m *MemoryLimiter) sweep(now time.Time) {
+	for key, b := range m.buckets {
+		if !now.Before(b.full) {
+			delete(m.buckets, key)
+		}
+	}
+	m.lastSweep = now
+}
@@ -0,0 +1,133 @@
+package ratelimit
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// newTestLimiter returns a memory limiter on a clock advanced by hand.
+func newTestLimiter() (*MemoryLimiter, *time.Time) {
+	now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	m := NewMemoryLimiter()
+	m.lastSweep = now
+	m.now = func() time.Time { return now }
+	return m, &now
+}
+
+func allow(t *testing.T, m *MemoryLimiter, buckets ...Bucket) []Result {
+	t.Helper()
+	results, err := m.Allow(context.Background(), buckets...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != len(buckets) {
+		t.Fatalf("%d results for %d buckets", len(results), len(buckets))
+	}
+	return results
+}
+
+func TestMemoryLimiterBurstAndRefill(t *testing.T) {
+	m, now := newTestLimiter()
+	b := Bucket{Key: "k", Limit: Limit{Burst: 3, Period: 30 * time.Second}}
+
+	for i := 2; i >= 0; i-- {
+		res := allow(t, m, b)[0]
+		if !res.Allowed || res.Remaining != i {
+			t.Fatalf("request %d: %+v", 3-i, res)
+		}
+	}
+	res := allow(t, m, b)[0]
+	if res.Allowed || res.RetryAfter != 10*time.Second || res.ResetAfter != 30*time.Second {
+		t.Fatalf("exhausted bucket gave %+v", res)
+	}
+
+	// One token refills every 10s
+	*now = now.Add(10 * time.Second)
+	if res := allow(t, m, b)[0]; !res.Allowed || res.Remaining != 0 {
+		t.Fatalf("after refilling one token: %+v", res)
+	}
+	*now = now.Add(time.Hour)
+	if res := allow(t, m, b)[0]; res.Remaining != 2 {
+		t.Fatalf("bucket refilled past its burst: %+v", res)
+	}
+}
+
+func TestMemoryLimiterTakesFromAllOrNone(t *testing.T) {
+	m, _ := newTestLimiter()
+	wide := Bucket{Key: "user:u1", Limit: Limit{Burst: 10, Period: time.Minute}}
+	narrow := Bucket{Key: "route:u1", Limit: Limit{Burst: 1, Period: time.Minute}}
+
+	if results := allow(t, m, wide, narrow); !results[0].Allowed || !results[1].Allowed {
+		t.Fatalf("first request denied: %+v", results)
+	}
+	for i := 0; i < 5; i++ {
+		results := allow(t, m, wide, narrow)
+		if !results[0].Allowed || results[1].Allowed {
+			t.Fatalf("retry %d: %+v, want only the narrow bucket exhausted", i, results)
+		}
+		if results[0].Remaining != 9 {
+			t.Fatalf("denied retry %d took a token from the wide bucket: %d left", i, results[0].Remaining)
+		}
+	}
+	if res := allow(t, m, wide)[0]; !res.Allowed || res.Remaining != 8 {
+		t.Fatalf("wide bucket alone: %+v", res)
+	}
+}
+
+func TestMemoryLimiterSweepsFullBuckets(t *testing.T) {
+	m, now := newTestLimiter()
+	allow(t, m, Bucket{Key: "short", Limit: Limit{Burst: 1, Period: time.Second}})
+	allow(t, m, Bucket{Key: "long", Limit: Limit{Burst: 1, Period: time.Hour}})
+
+	*now = now.Add(2 * sweepInterval)
+	allow(t, m)
+	if _, ok := m.buckets["short"]; ok {
+		t.Fatal("refilled bucket was not swept")
+	}
+	if _, ok := m.buckets["long"]; !ok {
+		t.Fatal("bucket still refilling was swept")
+	}
+}
+
+func TestMiddlewareDeniedRequestKeepsOtherRulesTokens(t *testing.T) {
+	m, _ := newTestLimiter()
+	login := PerRoute(http.MethodPost, "/login", Limit{Burst: 1, Period: time.Minute})
+	perIP := PerIP(Limit{Burst: 3, Period: time.Minute})
+	h := Middleware(m, login, perIP)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	serve := func(method, path string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, httptest.NewRequest(method, path, nil))
+		return rec
+	}
+
+	if rec := serve(http.MethodPost, "/login"); rec.Code != http.StatusOK {
+		t.Fatalf("first login: %d", rec.Code)
+	}
+	for i := 0; i < 3; i++ {
+		rec := serve(http.MethodPost, "/login")
+		if rec.Code != http.StatusTooManyRequests {
+			t.Fatalf("login retry %d: %d", i, rec.Code)
+		}
+		if got := rec.Header().Get("RateLimit-Limit"); got != "1" {
+			t.Fatalf("429 described the rule with limit %s, want the login rule", got)
+		}
+		if got := rec.Header().Get("Retry-After"); got != "60" {
+			t.Fatalf("Retry-After %s", got)
+		}
+	}
+
+	// Denied logins used none of the per-IP allowance
+	for i := 0; i < 2; i++ {
+		if rec := serve(http.MethodGet, "/search"); rec.Code != http.StatusOK {
+			t.Fatalf("search %d after denied logins: %d", i, rec.Code)
+		}
+	}
+	rec := serve(http.MethodGet, "/search")
+	if rec.Code != http.StatusTooManyRequests || rec.Header().Get("RateLimit-Limit") != "3" {
+		t.Fatalf("fourth request from the address: %d with limit %s", rec.Code, rec.Header().Get("RateLimit-Limit"))
+	}
+}
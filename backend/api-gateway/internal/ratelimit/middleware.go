@@ -0,0 +1,169 @@
+package ratelimit
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gavinadlan/tripnest/backend/common/auth"
+	"github.com/gavinadlan/tripnest/backend/common/utils"
+)
+
+var ErrRateLimited = errors.New("rate limit exceeded")
+
+// Rule applies Limit to the bucket returned by Key. Key returns false when
+// the rule does not apply to the request.
+type Rule struct {
+	Name  string
+	Limit Limit
+	Key   func(r *http.Request) (string, bool)
+}
+
+// PerIP limits every request by client address.
+func PerIP(limit Limit) Rule {
+	return Rule{Name: "ip", Limit: limit, Key: func(r *http.Request) (string, bool) {
+		return clientIP(r), true
+	}}
+}
+
+// PerUser limits authenticated requests by the user ID from the token.
+func PerUser(limit Limit) Rule {
+	return Rule{Name: "user", Limit: limit, Key: func(r *http.Request) (string, bool) {
+		p, ok := auth.PrincipalFromContext(r.Context())
+		return p.UserID, ok
+	}}
+}
+
+// PerRoute limits requests to one method and path, per user when the
+// request is authenticated and per client address otherwise.
+func PerRoute(method, path string, limit Limit) Rule {
+	return Rule{Name: "route:" + method + " " + path, Limit: limit, Key: func(r *http.Request) (string, bool) {
+		if r.Method != method || r.URL.Path != path {
+			return "", false
+		}
+		if p, ok := auth.PrincipalFromContext(r.Context()); ok {
+			return "user:" + p.UserID, true
+		}
+		return "ip:" + clientIP(r), true
+	}}
+}
+
+// ParseRouteRules parses comma-separated "<METHOD> <path>=<limit>" entries,
+// e.g. "POST /api/users/login=5/1m,POST /api/bookings=10/1m".
+func ParseRouteRules(s string) ([]Rule, error) {
+	var rules []Rule
+	for _, entry := range strings.Split(s, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		route, rawLimit, ok := strings.Cut(entry, "=")
+		method, path, ok2 := strings.Cut(strings.TrimSpace(route), " ")
+		if !ok || !ok2 {
+			return nil, fmt.Errorf("invalid route rate limit %q: want <METHOD> <path>=<limit>", entry)
+		}
+		limit, err := ParseLimit(rawLimit)
+		if err != nil {
+			return nil, err
+		}
+		if limit.IsZero() {
+			continue
+		}
+		rules = append(rules, PerRoute(strings.ToUpper(method), strings.TrimSpace(path), limit))
+	}
+	return rules, nil
+}
+
+// Middleware enforces all applicable rules together: a request takes a
+// token from each of their buckets, or from none when any of them is
+// exhausted, and is then answered 429 with Retry-After. The RateLimit-*
+// headers describe the tightest applicable rule. If the limiter fails the
+// request is let through rather than taking the API down with it.
+func Middleware(limiter Limiter, rules ...Rule) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			var buckets []Bucket
+			for _, rule := range rules {
+				if rule.Limit.IsZero() {
+					continue
+				}
+				key, ok := rule.Key(r)
+				if !ok {
+					continue
+				}
+				buckets = append(buckets, Bucket{Key: rule.Name + ":" + key, Limit: rule.Limit})
+			}
+
+			var results []Result
+			if len(buckets) > 0 {
+				var err error
+				results, err = limiter.Allow(r.Context(), buckets...)
+				if err != nil {
+					log.Printf("Rate limiter error: %v", err)
+					results = nil
+				}
+			}
+
+			var tightest *Result
+			for i := range results {
+				res := &results[i]
+				if tightest == nil || tighter(res, tightest) {
+					tightest = res
+				}
+			}
+
+			if tightest == nil {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			setHeaders(w.Header(), tightest)
+			if !tightest.Allowed {
+				w.Header().Set("Retry-After", strconv.Itoa(ceilSeconds(tightest.RetryAfter)))
+				utils.WriteError(w, http.StatusTooManyRequests, ErrRateLimited)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// tighter reports whether a should be described instead of b: an exhausted
+// bucket over one with tokens, among exhausted ones the one that refills
+// last (the request is refused until then) and otherwise the one with fewer
+// tokens left.
+func tighter(a, b *Result) bool {
+	if a.Allowed != b.Allowed {
+		return !a.Allowed
+	}
+	if !a.Allowed {
+		return a.RetryAfter > b.RetryAfter
+	}
+	return a.Remaining < b.Remaining
+}
+
+// setHeaders writes the IETF draft RateLimit header fields.
+func setHeaders(h http.Header, res *Result) {
+	h.Set("RateLimit-Limit", strconv.Itoa(res.Limit.Burst))
+	h.Set("RateLimit-Remaining", strconv.Itoa(res.Remaining))
+	h.Set("RateLimit-Reset", strconv.Itoa(ceilSeconds(res.ResetAfter)))
+	h.Set("RateLimit-Policy", fmt.Sprintf("%d;w=%d", res.Limit.Burst, ceilSeconds(res.Limit.Period)))
+}
+
+func ceilSeconds(d time.Duration) int {
+	return int(math.Ceil(d.Seconds()))
+}
+
+func clientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
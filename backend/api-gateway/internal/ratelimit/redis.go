@@ -0,0 +1,94 @@
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// tokenBucketScript refills the buckets and takes one token from each of
+// them atomically, or from none if any is empty, using the Redis clock so
+// gateway instances with skewed clocks share one view. ARGV holds a burst
+// and a period in microseconds per key. Returns {allowed, tokens left per
+// key}; tokens are strings because Redis truncates Lua numbers to integers.
+var tokenBucketScript = redis.NewScript(`
+local t = redis.call('TIME')
+local now = tonumber(t[1]) * 1000000 + tonumber(t[2])
+
+local tokens = {}
+local allowed = 1
+for i, key in ipairs(KEYS) do
+	local burst = tonumber(ARGV[2 * i - 1])
+	local period_us = tonumber(ARGV[2 * i])
+	local state = redis.call('HMGET', key, 'tokens', 'ts')
+	local left = tonumber(state[1]) or burst
+	local ts = tonumber(state[2]) or now
+	tokens[i] = math.min(burst, left + math.max(0, now - ts) * burst / period_us)
+	if tokens[i] < 1 then
+		allowed = 0
+	end
+end
+
+local reply = {allowed}
+for i, key in ipairs(KEYS) do
+	if allowed == 1 then
+		tokens[i] = tokens[i] - 1
+	end
+	redis.call('HSET', key, 'tokens', string.format('%.6f', tokens[i]), 'ts', string.format('%.0f', now))
+	redis.call('PEXPIRE', key, math.ceil(tonumber(ARGV[2 * i]) / 1000))
+	reply[i + 1] = string.format('%.6f', tokens[i])
+end
+return reply
+`)
+
+// RedisLimiter keeps buckets in Redis so every gateway instance enforces
+// the same limits.
+type RedisLimiter struct {
+	rdb    *redis.Client
+	prefix string
+}
+
+func NewRedisLimiter(redisAddr string) *RedisLimiter {
+	rdb := redis.NewClient(&redis.Options{
+		Addr: redisAddr,
+	})
+	return &RedisLimiter{rdb: rdb, prefix: "ratelimit:"}
+}
+
+func (l *RedisLimiter) Allow(ctx context.Context, buckets ...Bucket) ([]Result, error) {
+	if len(buckets) == 0 {
+		return nil, nil
+	}
+	keys := make([]string, len(buckets))
+	args := make([]interface{}, 0, 2*len(buckets))
+	for i, bk := range buckets {
+		keys[i] = l.prefix + bk.Key
+		args = append(args, bk.Limit.Burst, bk.Limit.Period.Microseconds())
+	}
+
+	reply, err := tokenBucketScript.Run(ctx, l.rdb, keys, args...).Slice()
+	if err != nil {
+		return nil, fmt.Errorf("failed to run rate limit script: %w", err)
+	}
+	if len(reply) != len(buckets)+1 {
+		return nil, fmt.Errorf("unexpected rate limit reply: %v", reply)
+	}
+
+	allowed, _ := reply[0].(int64)
+	results := make([]Result, len(buckets))
+	for i, bk := range buckets {
+		left, _ := reply[i+1].(string)
+		tokens, err := strconv.ParseFloat(left, 64)
+		if err != nil {
+			return nil, fmt.Errorf("unexpected rate limit tokens %q: %w", left, err)
+		}
+		results[i] = result(bk.Limit, allowed == 1 || tokens >= 1, tokens)
+	}
+	return results, nil
+}
+
+func (l *RedisLimiter) Close() error {
+	return l.rdb.Close()
+}
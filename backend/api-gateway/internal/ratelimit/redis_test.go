@@ -0,0 +1,106 @@
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"testing"
+	"time"
+)
+
+// newRedisLimiter connects to the Redis at RATELIMIT_TEST_REDIS_ADDR, e.g.
+// localhost:6379, skipping the test when it is unset. Keys get a prefix of
+// their own and are deleted afterwards.
+func newRedisLimiter(t *testing.T) *RedisLimiter {
+	t.Helper()
+	addr := os.Getenv("RATELIMIT_TEST_REDIS_ADDR")
+	if addr == "" {
+		t.Skip("RATELIMIT_TEST_REDIS_ADDR not set")
+	}
+	l := NewRedisLimiter(addr)
+	l.prefix = fmt.Sprintf("ratelimit-test:%s:%d:", t.Name(), time.Now().UnixNano())
+
+	ctx := context.Background()
+	if err := l.rdb.Ping(ctx).Err(); err != nil {
+		t.Fatalf("redis at %s: %v", addr, err)
+	}
+	t.Cleanup(func() {
+		keys, _ := l.rdb.Keys(ctx, l.prefix+"*").Result()
+		if len(keys) > 0 {
+			l.rdb.Del(ctx, keys...)
+		}
+		l.Close()
+	})
+	return l
+}
+
+func allowRedis(t *testing.T, l *RedisLimiter, buckets ...Bucket) []Result {
+	t.Helper()
+	results, err := l.Allow(context.Background(), buckets...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != len(buckets) {
+		t.Fatalf("%d results for %d buckets", len(results), len(buckets))
+	}
+	return results
+}
+
+func TestRedisLimiterTakesFromAllOrNone(t *testing.T) {
+	l := newRedisLimiter(t)
+	wide := Bucket{Key: "user:u1", Limit: Limit{Burst: 10, Period: time.Minute}}
+	narrow := Bucket{Key: "route:u1", Limit: Limit{Burst: 1, Period: time.Minute}}
+
+	if results := allowRedis(t, l, wide, narrow); !results[0].Allowed || !results[1].Allowed {
+		t.Fatalf("first request denied: %+v", results)
+	}
+	for i := 0; i < 5; i++ {
+		results := allowRedis(t, l, wide, narrow)
+		if !results[0].Allowed || results[1].Allowed {
+			t.Fatalf("retry %d: %+v, want only the narrow bucket exhausted", i, results)
+		}
+		if results[0].Remaining != 9 {
+			t.Fatalf("denied retry %d took a token from the wide bucket: %d left", i, results[0].Remaining)
+		}
+		if results[1].RetryAfter <= 0 || results[1].RetryAfter > time.Minute {
+			t.Fatalf("retry %d: RetryAfter %s", i, results[1].RetryAfter)
+		}
+	}
+	if res := allowRedis(t, l, wide)[0]; !res.Allowed || res.Remaining != 8 {
+		t.Fatalf("wide bucket alone: %+v", res)
+	}
+}
+
+func TestRedisLimiterRefillsOverPeriod(t *testing.T) {
+	l := newRedisLimiter(t)
+	// One token every 200ms
+	b := Bucket{Key: "k", Limit: Limit{Burst: 2, Period: 400 * time.Millisecond}}
+
+	for i := 1; i >= 0; i-- {
+		if res := allowRedis(t, l, b)[0]; !res.Allowed || res.Remaining != i {
+			t.Fatalf("burst request: %+v", res)
+		}
+	}
+	if res := allowRedis(t, l, b)[0]; res.Allowed {
+		t.Fatalf("exhausted bucket gave %+v", res)
+	}
+
+	time.Sleep(250 * time.Millisecond)
+	if res := allowRedis(t, l, b)[0]; !res.Allowed || res.Remaining != 0 {
+		t.Fatalf("after refilling one token: %+v", res)
+	}
+	if res := allowRedis(t, l, b)[0]; res.Allowed {
+		t.Fatalf("refilled more than one token: %+v", res)
+	}
+
+	time.Sleep(time.Second)
+	if res := allowRedis(t, l, b)[0]; !res.Allowed || res.Remaining != 1 {
+		t.Fatalf("bucket refilled past its burst: %+v", res)
+	}
+
+	// Idle buckets expire once they would be full again
+	ttl, err := l.rdb.PTTL(context.Background(), l.prefix+b.Key).Result()
+	if err != nil || ttl <= 0 || ttl > b.Limit.Period {
+		t.Fatalf("bucket TTL %s, %v", ttl, err)
+	}
+}
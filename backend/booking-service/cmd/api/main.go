@@ -12,15 +12,19 @@ import (
 
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/chi/v5/middleware"
-	"github.com/go-chi/cors"
 	"github.com/joho/godotenv"
 
+	"github.com/gavinadlan/tripnest/backend/common/auth"
+
 	"github.com/gavinadlan/tripnest/backend/booking-service/internal/config"
 	"github.com/gavinadlan/tripnest/backend/booking-service/internal/db"
 	"github.com/gavinadlan/tripnest/backend/booking-service/internal/events"
 	"github.com/gavinadlan/tripnest/backend/booking-service/internal/handler"
+	"github.com/gavinadlan/tripnest/backend/booking-service/internal/inventory"
 	"github.com/gavinadlan/tripnest/backend/booking-service/internal/model"
+	"github.com/gavinadlan/tripnest/backend/booking-service/internal/outbox"
 	"github.com/gavinadlan/tripnest/backend/booking-service/internal/repository"
+	"github.com/gavinadlan/tripnest/backend/booking-service/internal/scheduler"
 	"github.com/gavinadlan/tripnest/backend/booking-service/internal/service"
 )
 
@@ -40,7 +44,13 @@ func main() {
 	producer := events.NewKafkaProducer(cfg.KafkaBrokers)
 	defer producer.Close()
 
-	svc := service.NewBookingService(repo, producer)
+	policy, err := model.ParseCancellationPolicy(cfg.RefundTiers, cfg.DefaultRefundPercent)
+	if err != nil {
+		log.Fatalf("Invalid cancellation policy: %v", err)
+	}
+
+	inv := inventory.NewHTTPInventory(cfg.SearchServiceURL, cfg.ServiceToken, cfg.InventoryTimeout)
+	svc := service.NewBookingService(repo, inv, policy)
 
 	// Payment Success Consumer
 	paymentSuccessConsumer := events.NewConsumer(cfg.KafkaBrokers, "payment.success", "booking-service-group")
@@ -50,19 +60,20 @@ func main() {
 	paymentFailedConsumer := events.NewConsumer(cfg.KafkaBrokers, "payment.failed", "booking-service-group")
 	defer paymentFailedConsumer.Close()
 
-	h := handler.NewHandler(svc)
+	// Refund Consumers
+	refundedConsumer := events.NewConsumer(cfg.KafkaBrokers, "payment.refunded", "booking-service-group")
+	defer refundedConsumer.Close()
 
-	r := chi.NewRouter()
+	refundFailedConsumer := events.NewConsumer(cfg.KafkaBrokers, "payment.refund_failed", "booking-service-group")
+	defer refundFailedConsumer.Close()
 
-	// CORS Setup
-	r.Use(cors.Handler(cors.Options{
-		AllowedOrigins:   []string{"http://localhost:3000"}, // Frontend URL
-		AllowedMethods:   []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"},
-		AllowedHeaders:   []string{"Accept", "Authorization", "Content-Type", "X-CSRF-Token"},
-		ExposedHeaders:   []string{"Link"},
-		AllowCredentials: true,
-		MaxAge:           300,
-	}))
+	authenticate := auth.Authenticate(auth.NewJWKSVerifier(cfg.JWKSURL, cfg.JWTIssuer, cfg.JWKSRefreshTime))
+	if cfg.TrustGatewayHeaders {
+		authenticate = auth.TrustGatewayHeaders()
+	}
+	h := handler.NewHandler(svc, authenticate)
+
+	r := chi.NewRouter()
 
 	r.Use(middleware.Logger)
 	r.Use(middleware.Recoverer)
@@ -78,6 +89,12 @@ func main() {
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
 
+	relay := outbox.NewRelay(repo, producer, cfg.OutboxPollInterval, cfg.OutboxBatchSize)
+	go relay.Run(ctx)
+
+	sweeper := scheduler.NewTimeoutSweeper(svc, cfg.PendingBookingTimeout, cfg.TimeoutSweepInterval, cfg.TimeoutSweepBatchSize)
+	go sweeper.Run(ctx)
+
 	go func() {
 		log.Println("Listening for payment.success events...")
 		for {
@@ -93,7 +110,7 @@ func main() {
 				continue
 			}
 
-			if err := svc.ConfirmBooking(ctx, event.BookingID); err != nil {
+			if err := svc.ConfirmBooking(ctx, event.BookingID, events.MessageID(msg)); err != nil {
 				log.Printf("Failed to confirm booking %s: %v", event.BookingID, err)
 			}
 		}
@@ -114,12 +131,54 @@ func main() {
 				continue
 			}
 
-			if err := svc.CancelBooking(ctx, event.BookingID); err != nil {
+			if err := svc.CancelBooking(ctx, event.BookingID, events.MessageID(msg)); err != nil {
 				log.Printf("Failed to cancel booking %s: %v", event.BookingID, err)
 			}
 		}
 	}()
 
+	go func() {
+		log.Println("Listening for payment.refunded events...")
+		for {
+			msg, err := refundedConsumer.ReadMessage(ctx)
+			if err != nil {
+				log.Printf("Payment Refunded Consumer error: %v", err)
+				break
+			}
+
+			var event model.RefundProcessedEvent
+			if err := json.Unmarshal(msg.Value, &event); err != nil {
+				log.Printf("Failed to unmarshal payment refunded event: %v", err)
+				continue
+			}
+
+			if err := svc.CompleteRefund(ctx, event.BookingID, events.MessageID(msg)); err != nil {
+				log.Printf("Failed to complete refund for booking %s: %v", event.BookingID, err)
+			}
+		}
+	}()
+
+	go func() {
+		log.Println("Listening for payment.refund_failed events...")
+		for {
+			msg, err := refundFailedConsumer.ReadMessage(ctx)
+			if err != nil {
+				log.Printf("Payment Refund Failed Consumer error: %v", err)
+				break
+			}
+
+			var event model.RefundProcessedEvent
+			if err := json.Unmarshal(msg.Value, &event); err != nil {
+				log.Printf("Failed to unmarshal payment refund failed event: %v", err)
+				continue
+			}
+
+			if err := svc.FailRefund(ctx, event.BookingID, events.MessageID(msg)); err != nil {
+				log.Printf("Failed to restore booking %s after refund failure: %v", event.BookingID, err)
+			}
+		}
+	}()
+
 	go func() {
 		log.Printf("Booking Service starting on port %s", cfg.Port)
 		if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
@@ -10,12 +10,10 @@ require (
 	github.com/joho/godotenv v1.5.1
 )
 
-require (
-	github.com/go-chi/cors v1.2.2
-	github.com/segmentio/kafka-go v0.4.48
-)
+require github.com/segmentio/kafka-go v0.4.48
 
 require (
+	github.com/golang-jwt/jwt/v5 v5.3.1 // indirect
 	github.com/hashicorp/errwrap v1.1.0 // indirect
 	github.com/hashicorp/go-multierror v1.1.1 // indirect
 	github.com/jackc/pgpassfile v1.0.0 // indirect
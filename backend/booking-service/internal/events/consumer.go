@@ -2,6 +2,7 @@ package events
 
 import (
 	"context"
+	"fmt"
 
 	"github.com/segmentio/kafka-go"
 )
@@ -29,3 +30,9 @@ func (c *Consumer) ReadMessage(ctx context.Context) (kafka.Message, error) {
 func (c *Consumer) Close() error {
 	return c.reader.Close()
 }
+
+// MessageID identifies a Kafka message by its position in the log, which is
+// stable across redeliveries of the same message.
+func MessageID(msg kafka.Message) string {
+	return fmt.Sprintf("%s/%d/%d", msg.Topic, msg.Partition, msg.Offset)
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"encoding/json"
 	"log"
+	"time"
 
 	"github.com/segmentio/kafka-go"
 )
@@ -21,8 +22,12 @@ func NewKafkaProducer(brokers []string) *KafkaProducer {
 	log.Printf("Connecting to Kafka brokers: %v", brokers)
 	return &KafkaProducer{
 		writer: &kafka.Writer{
-			Addr:     kafka.TCP(brokers...),
-			Balancer: &kafka.LeastBytes{},
+			Addr: kafka.TCP(brokers...),
+			// Hash by key so every event for a booking lands on the same
+			// partition and is consumed in the order it was written.
+			Balancer:     &kafka.Hash{},
+			BatchTimeout: 10 * time.Millisecond,
+			RequiredAcks: kafka.RequireAll,
 		},
 	}
 }
@@ -2,27 +2,44 @@ package handler
 
 import (
 	"errors"
+	"fmt"
+	"io"
 	"log"
 	"net/http"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/gavinadlan/tripnest/backend/booking-service/internal/model"
 	"github.com/gavinadlan/tripnest/backend/booking-service/internal/service"
+	"github.com/gavinadlan/tripnest/backend/common/auth"
 	"github.com/gavinadlan/tripnest/backend/common/utils"
 	"github.com/go-chi/chi/v5"
 )
 
 type Handler struct {
-	svc service.BookingService
+	svc          service.BookingService
+	authenticate func(http.Handler) http.Handler
 }
 
-func NewHandler(svc service.BookingService) *Handler {
-	return &Handler{svc: svc}
+// NewHandler serves bookings, identifying callers with authenticate
+// (auth.Authenticate or, behind the api-gateway, auth.TrustGatewayHeaders).
+func NewHandler(svc service.BookingService, authenticate func(http.Handler) http.Handler) *Handler {
+	return &Handler{svc: svc, authenticate: authenticate}
 }
 
 func (h *Handler) RegisterRoutes(r chi.Router) {
-	r.Post("/bookings", h.CreateBooking)
-	r.Get("/bookings/{id}", h.GetBooking)
 	r.Get("/health", h.Health)
+
+	r.Group(func(r chi.Router) {
+		r.Use(h.authenticate)
+
+		r.Get("/bookings", h.ListBookings)
+		r.Post("/bookings", h.CreateBooking)
+		r.Get("/bookings/{id}", h.GetBooking)
+		r.Get("/bookings/{id}/history", h.GetBookingHistory)
+		r.Post("/bookings/{id}/cancel", h.CancelBooking)
+	})
 }
 
 func (h *Handler) CreateBooking(w http.ResponseWriter, r *http.Request) {
@@ -32,6 +49,10 @@ func (h *Handler) CreateBooking(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// The booking always belongs to the caller, whatever the body says
+	principal, _ := auth.PrincipalFromContext(r.Context())
+	req.UserID = principal.UserID
+
 	// Validate (simple)
 	if req.UserID == "" || req.ResourceID == "" || req.TotalAmount <= 0 {
 		utils.WriteError(w, http.StatusBadRequest, errors.New("invalid booking request: missing required fields"))
@@ -39,7 +60,18 @@ func (h *Handler) CreateBooking(w http.ResponseWriter, r *http.Request) {
 	}
 
 	booking, err := h.svc.CreateBooking(r.Context(), &req)
-	if err != nil {
+	switch {
+	case errors.Is(err, model.ErrSoldOut):
+		utils.WriteErrorCode(w, http.StatusConflict, "SOLD_OUT", err)
+		return
+	case errors.Is(err, model.ErrListingNotFound):
+		utils.WriteErrorCode(w, http.StatusUnprocessableEntity, "LISTING_NOT_FOUND", err)
+		return
+	case errors.Is(err, model.ErrInventoryUnavailable):
+		log.Printf("CreateBooking failed: %v", err)
+		utils.WriteError(w, http.StatusServiceUnavailable, model.ErrInventoryUnavailable)
+		return
+	case err != nil:
 		log.Printf("CreateBooking failed: %v", err)
 		utils.WriteError(w, http.StatusInternalServerError, err)
 		return
@@ -48,6 +80,93 @@ func (h *Handler) CreateBooking(w http.ResponseWriter, r *http.Request) {
 	utils.WriteJSON(w, http.StatusCreated, booking)
 }
 
+// ListBookings returns the caller's bookings. Query parameters:
+// status (comma-separated), from/to (RFC 3339 or YYYY-MM-DD, on created_at;
+// a bare "to" date is inclusive), order (desc|asc), limit and cursor.
+func (h *Handler) ListBookings(w http.ResponseWriter, r *http.Request) {
+	principal, _ := auth.PrincipalFromContext(r.Context())
+	query := r.URL.Query()
+
+	params := &model.BookingListParams{
+		UserID: principal.UserID,
+		Limit:  model.DefaultListLimit,
+	}
+
+	if raw := query.Get("status"); raw != "" {
+		for _, part := range strings.Split(raw, ",") {
+			status := model.BookingStatus(strings.ToUpper(strings.TrimSpace(part)))
+			if !status.Valid() {
+				utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("invalid status %q", part))
+				return
+			}
+			params.Statuses = append(params.Statuses, status)
+		}
+	}
+
+	var err error
+	if params.CreatedAfter, err = parseTimeParam(query.Get("from"), false); err != nil {
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("invalid from: %w", err))
+		return
+	}
+	if params.CreatedBefore, err = parseTimeParam(query.Get("to"), true); err != nil {
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("invalid to: %w", err))
+		return
+	}
+
+	switch strings.ToLower(query.Get("order")) {
+	case "", "desc":
+	case "asc":
+		params.Ascending = true
+	default:
+		utils.WriteError(w, http.StatusBadRequest, errors.New("invalid order: must be asc or desc"))
+		return
+	}
+
+	if raw := query.Get("limit"); raw != "" {
+		limit, err := strconv.Atoi(raw)
+		if err != nil || limit < 1 || limit > model.MaxListLimit {
+			utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("invalid limit: must be between 1 and %d", model.MaxListLimit))
+			return
+		}
+		params.Limit = limit
+	}
+
+	if raw := query.Get("cursor"); raw != "" {
+		if params.After, err = model.DecodeBookingCursor(raw); err != nil {
+			utils.WriteError(w, http.StatusBadRequest, err)
+			return
+		}
+	}
+
+	page, err := h.svc.ListBookings(r.Context(), params)
+	if err != nil {
+		log.Printf("ListBookings failed: %v", err)
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, page)
+}
+
+// parseTimeParam parses an RFC 3339 timestamp or a YYYY-MM-DD date. With
+// endOfDay a bare date means the end of that day (start of the next).
+func parseTimeParam(value string, endOfDay bool) (*time.Time, error) {
+	if value == "" {
+		return nil, nil
+	}
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return &t, nil
+	}
+	t, err := time.Parse("2006-01-02", value)
+	if err != nil {
+		return nil, errors.New("expected RFC 3339 timestamp or YYYY-MM-DD")
+	}
+	if endOfDay {
+		t = t.AddDate(0, 0, 1)
+	}
+	return &t, nil
+}
+
 func (h *Handler) GetBooking(w http.ResponseWriter, r *http.Request) {
 	id := chi.URLParam(r, "id")
 	if id == "" {
@@ -55,17 +174,94 @@ func (h *Handler) GetBooking(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	booking, ok := h.loadOwnBooking(w, r, id)
+	if !ok {
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, booking)
+}
+
+// loadOwnBooking fetches the booking and checks that the caller owns it (or
+// is an admin). Bookings of other users are reported as not found so their
+// IDs cannot be probed. On failure the response has already been written.
+func (h *Handler) loadOwnBooking(w http.ResponseWriter, r *http.Request, id string) (*model.Booking, bool) {
 	booking, err := h.svc.GetBooking(r.Context(), id)
 	if err != nil {
-		utils.WriteError(w, http.StatusInternalServerError, err) // Or NotFound
+		log.Printf("GetBooking failed: %v", err)
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return nil, false
+	}
+
+	principal, _ := auth.PrincipalFromContext(r.Context())
+	if booking == nil || (booking.UserID != principal.UserID && !principal.IsAdmin()) {
+		utils.WriteError(w, http.StatusNotFound, model.ErrBookingNotFound)
+		return nil, false
+	}
+	return booking, true
+}
+
+func (h *Handler) GetBookingHistory(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	if id == "" {
+		utils.WriteError(w, http.StatusBadRequest, errors.New("missing booking id"))
 		return
 	}
-	if booking == nil {
-		utils.WriteError(w, http.StatusNotFound, errors.New("booking not found"))
+
+	if _, ok := h.loadOwnBooking(w, r, id); !ok {
 		return
 	}
 
-	utils.WriteJSON(w, http.StatusOK, booking)
+	history, err := h.svc.GetBookingHistory(r.Context(), id)
+	if errors.Is(err, model.ErrBookingNotFound) {
+		utils.WriteError(w, http.StatusNotFound, err)
+		return
+	}
+	if err != nil {
+		log.Printf("GetBookingHistory failed: %v", err)
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, history)
+}
+
+func (h *Handler) CancelBooking(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	if id == "" {
+		utils.WriteError(w, http.StatusBadRequest, errors.New("missing booking id"))
+		return
+	}
+
+	if _, ok := h.loadOwnBooking(w, r, id); !ok {
+		return
+	}
+
+	// The body is optional; it only carries a free-text reason
+	var req model.CancelBookingRequest
+	if r.ContentLength != 0 {
+		if err := utils.ReadJSON(r, &req); err != nil && !errors.Is(err, io.EOF) {
+			utils.WriteError(w, http.StatusBadRequest, err)
+			return
+		}
+	}
+
+	booking, err := h.svc.RequestCancellation(r.Context(), id, req.Reason)
+	var terr *model.TransitionError
+	switch {
+	case errors.Is(err, model.ErrBookingNotFound):
+		utils.WriteError(w, http.StatusNotFound, err)
+		return
+	case errors.As(err, &terr):
+		utils.WriteError(w, http.StatusConflict, err)
+		return
+	case err != nil:
+		log.Printf("CancelBooking failed: %v", err)
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusAccepted, booking)
 }
 
 func (h *Handler) Health(w http.ResponseWriter, r *http.Request) {
@@ -0,0 +1,140 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/gavinadlan/tripnest/backend/booking-service/internal/model"
+	"github.com/gavinadlan/tripnest/backend/booking-service/internal/service"
+	"github.com/gavinadlan/tripnest/backend/common/auth"
+	"github.com/go-chi/chi/v5"
+)
+
+// stubService records the list parameters it is called with.
+type stubService struct {
+	service.BookingService
+	params *model.BookingListParams
+	page   *model.BookingPage
+}
+
+func (s *stubService) ListBookings(ctx context.Context, params *model.BookingListParams) (*model.BookingPage, error) {
+	p := *params
+	s.params = &p
+	return s.page, nil
+}
+
+// asUser authenticates every request as user u1.
+func asUser(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		next.ServeHTTP(w, r.WithContext(auth.WithPrincipal(r.Context(), auth.Principal{UserID: "u1", Role: "user"})))
+	})
+}
+
+func listBookings(svc *stubService, query string) *httptest.ResponseRecorder {
+	r := chi.NewRouter()
+	NewHandler(svc, asUser).RegisterRoutes(r)
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/bookings"+query, nil))
+	return rec
+}
+
+func TestListBookingsParams(t *testing.T) {
+	createdAt := time.Date(2026, 7, 1, 12, 0, 0, 123456000, time.UTC)
+	cursor := model.BookingCursor{CreatedAt: createdAt, ID: "b9"}.Encode()
+	date := func(s string) *time.Time {
+		ts, _ := time.Parse(time.RFC3339, s)
+		return &ts
+	}
+
+	cases := []struct {
+		query string
+		want  model.BookingListParams
+	}{
+		{"", model.BookingListParams{Limit: model.DefaultListLimit}},
+		{"?limit=1", model.BookingListParams{Limit: 1}},
+		{"?limit=100", model.BookingListParams{Limit: model.MaxListLimit}},
+		{"?order=ASC", model.BookingListParams{Limit: model.DefaultListLimit, Ascending: true}},
+		{"?order=desc", model.BookingListParams{Limit: model.DefaultListLimit}},
+		{
+			"?status=confirmed,+cancelled",
+			model.BookingListParams{Limit: model.DefaultListLimit, Statuses: []model.BookingStatus{model.StatusConfirmed, model.StatusCancelled}},
+		},
+		{
+			// A bare "to" date includes the whole day
+			"?from=2026-07-01&to=2026-07-31",
+			model.BookingListParams{Limit: model.DefaultListLimit, CreatedAfter: date("2026-07-01T00:00:00Z"), CreatedBefore: date("2026-08-01T00:00:00Z")},
+		},
+		{
+			"?from=2026-07-01T08:00:00%2B07:00&to=2026-07-02T00:00:00Z",
+			model.BookingListParams{Limit: model.DefaultListLimit, CreatedAfter: date("2026-07-01T01:00:00Z"), CreatedBefore: date("2026-07-02T00:00:00Z")},
+		},
+		{
+			"?limit=5&cursor=" + cursor,
+			model.BookingListParams{Limit: 5, After: &model.BookingCursor{CreatedAt: createdAt, ID: "b9"}},
+		},
+	}
+	for _, tc := range cases {
+		svc := &stubService{page: &model.BookingPage{Data: []model.Booking{}}}
+		rec := listBookings(svc, tc.query)
+		if rec.Code != http.StatusOK {
+			t.Errorf("%q: status %d: %s", tc.query, rec.Code, rec.Body)
+			continue
+		}
+		got, want := svc.params, tc.want
+		if got.UserID != "u1" || got.Limit != want.Limit || got.Ascending != want.Ascending ||
+			!slices.Equal(got.Statuses, want.Statuses) ||
+			!equalTime(got.CreatedAfter, want.CreatedAfter) || !equalTime(got.CreatedBefore, want.CreatedBefore) {
+			t.Errorf("%q: params %+v, want %+v", tc.query, got, want)
+		}
+		if (got.After == nil) != (want.After == nil) ||
+			(got.After != nil && (got.After.ID != want.After.ID || !got.After.CreatedAt.Equal(want.After.CreatedAt))) {
+			t.Errorf("%q: cursor %v, want %v", tc.query, got.After, want.After)
+		}
+	}
+}
+
+func equalTime(a, b *time.Time) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return a.Equal(*b)
+}
+
+func TestListBookingsRejects(t *testing.T) {
+	for _, query := range []string{
+		"?limit=0",
+		"?limit=-1",
+		"?limit=101",
+		"?limit=ten",
+		"?limit=1.5",
+		"?cursor=not-a-cursor",
+		"?cursor=" + model.BookingCursor{ID: "b1"}.Encode(),
+		"?order=newest",
+		"?status=confirmed,teleported",
+		"?from=yesterday",
+		"?to=2026-13-01",
+	} {
+		svc := &stubService{}
+		rec := listBookings(svc, query)
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("%q: status %d, want 400", query, rec.Code)
+		}
+		if svc.params != nil {
+			t.Errorf("%q: reached the service", query)
+		}
+	}
+}
+
+func TestListBookingsResponse(t *testing.T) {
+	svc := &stubService{page: &model.BookingPage{Data: []model.Booking{{ID: "b1"}}, NextCursor: "next"}}
+	rec := listBookings(svc, "?limit=1")
+	var page model.BookingPage
+	if err := json.NewDecoder(rec.Body).Decode(&page); err != nil || len(page.Data) != 1 || page.NextCursor != "next" {
+		t.Fatalf("page %+v, %v", page, err)
+	}
+}
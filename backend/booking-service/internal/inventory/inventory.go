@@ -0,0 +1,131 @@
+package inventory
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"regexp"
+	"time"
+
+	"github.com/gavinadlan/tripnest/backend/booking-service/internal/model"
+	"github.com/gavinadlan/tripnest/backend/common/auth"
+)
+
+// Inventory holds listing slots for bookings. The hold ID is the booking ID.
+type Inventory interface {
+	// Hold takes one slot of the listing and reports the listing's trip
+	// start. It returns model.ErrSoldOut, model.ErrListingNotFound or an
+	// error wrapping model.ErrInventoryUnavailable.
+	Hold(ctx context.Context, listingID, holdID string) (*model.Hold, error)
+	// Release gives a held slot back; releasing an unknown hold succeeds.
+	Release(ctx context.Context, listingID, holdID string) error
+}
+
+// listingIDPattern matches search-service listing IDs (MongoDB ObjectIDs).
+// Anything else is refused before it becomes part of a request path, where
+// a "/" or ".." would reach another internal endpoint.
+var listingIDPattern = regexp.MustCompile(`^[0-9a-fA-F]{24}$`)
+
+// dateLayout is the format of listing dates in search-service responses
+const dateLayout = "2006-01-02"
+
+// HTTPInventory calls the internal hold endpoints of search-service, which
+// owns listings.
+type HTTPInventory struct {
+	baseURL string
+	token   string
+	client  *http.Client
+}
+
+func NewHTTPInventory(baseURL, serviceToken string, timeout time.Duration) *HTTPInventory {
+	return &HTTPInventory{
+		baseURL: baseURL,
+		token:   serviceToken,
+		client:  &http.Client{Timeout: timeout},
+	}
+}
+
+func (i *HTTPInventory) Hold(ctx context.Context, listingID, holdID string) (*model.Hold, error) {
+	if !listingIDPattern.MatchString(listingID) {
+		return nil, model.ErrListingNotFound
+	}
+	endpoint, err := url.JoinPath(i.baseURL, "internal", "listings", listingID, "holds")
+	if err != nil {
+		return nil, fmt.Errorf("invalid search service URL: %w", err)
+	}
+	body, err := json.Marshal(map[string]string{"hold_id": holdID})
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := i.do(ctx, http.MethodPost, endpoint, body)
+	if err != nil {
+		return nil, fmt.Errorf("%w: failed to hold listing %s: %v", model.ErrInventoryUnavailable, listingID, err)
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusCreated, http.StatusOK:
+	case http.StatusConflict:
+		return nil, model.ErrSoldOut
+	case http.StatusNotFound:
+		return nil, model.ErrListingNotFound
+	default:
+		return nil, fmt.Errorf("%w: failed to hold listing %s: search service returned %s", model.ErrInventoryUnavailable, listingID, resp.Status)
+	}
+
+	var held struct {
+		StartDate string `json:"start_date"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&held); err != nil {
+		// The slot is held; only the trip start is unknown
+		log.Printf("Failed to decode hold %s on listing %s: %v", holdID, listingID, err)
+	}
+	hold := &model.Hold{ListingID: listingID}
+	if held.StartDate != "" {
+		start, err := time.Parse(dateLayout, held.StartDate)
+		if err != nil {
+			log.Printf("Listing %s has an invalid start date %q", listingID, held.StartDate)
+		} else {
+			hold.TripStart = &start
+		}
+	}
+	return hold, nil
+}
+
+func (i *HTTPInventory) Release(ctx context.Context, listingID, holdID string) error {
+	if !listingIDPattern.MatchString(listingID) {
+		return nil // Never held
+	}
+	endpoint, err := url.JoinPath(i.baseURL, "internal", "listings", listingID, "holds", holdID)
+	if err != nil {
+		return fmt.Errorf("invalid search service URL: %w", err)
+	}
+
+	resp, err := i.do(ctx, http.MethodDelete, endpoint, nil)
+	if err != nil {
+		return fmt.Errorf("failed to release hold %s: %w", holdID, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusNoContent && resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("failed to release hold %s: search service returned %s", holdID, resp.Status)
+	}
+	return nil
+}
+
+func (i *HTTPInventory) do(ctx context.Context, method, endpoint string, body []byte) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, method, endpoint, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	req.Header.Set(auth.HeaderServiceToken, i.token)
+	return i.client.Do(req)
+}
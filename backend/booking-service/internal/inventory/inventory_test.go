@@ -0,0 +1,100 @@
+package inventory
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gavinadlan/tripnest/backend/booking-service/internal/model"
+	"github.com/gavinadlan/tripnest/backend/common/auth"
+)
+
+const listingID = "64b7f0c2a1b2c3d4e5f60718"
+
+// newTestInventory answers every request with status and body and records
+// the paths requested.
+func newTestInventory(t *testing.T, status int, body string) (*HTTPInventory, *[]string) {
+	t.Helper()
+	var paths []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get(auth.HeaderServiceToken) != "token" {
+			t.Errorf("%s %s without the service token", r.Method, r.URL.Path)
+		}
+		paths = append(paths, r.Method+" "+r.URL.EscapedPath())
+		w.WriteHeader(status)
+		io.WriteString(w, body)
+	}))
+	t.Cleanup(srv.Close)
+	return NewHTTPInventory(srv.URL, "token", time.Second), &paths
+}
+
+func TestHoldReportsTripStart(t *testing.T) {
+	inv, paths := newTestInventory(t, http.StatusCreated,
+		`{"hold_id":"b1","listing_id":"`+listingID+`","available_slots":3,"start_date":"2026-07-01"}`)
+
+	hold, err := inv.Hold(context.Background(), listingID, "b1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := time.Date(2026, 7, 1, 0, 0, 0, 0, time.UTC)
+	if hold.ListingID != listingID || hold.TripStart == nil || !hold.TripStart.Equal(want) {
+		t.Fatalf("hold %+v", hold)
+	}
+	if got := (*paths)[0]; got != "POST /internal/listings/"+listingID+"/holds" {
+		t.Fatalf("requested %s", got)
+	}
+}
+
+func TestHoldWithoutStartDate(t *testing.T) {
+	inv, _ := newTestInventory(t, http.StatusCreated, `{"hold_id":"b1"}`)
+	hold, err := inv.Hold(context.Background(), listingID, "b1")
+	if err != nil || hold.TripStart != nil {
+		t.Fatalf("hold %+v, %v", hold, err)
+	}
+}
+
+func TestHoldErrors(t *testing.T) {
+	cases := []struct {
+		status int
+		want   error
+	}{
+		{http.StatusConflict, model.ErrSoldOut},
+		{http.StatusNotFound, model.ErrListingNotFound},
+		{http.StatusInternalServerError, model.ErrInventoryUnavailable},
+	}
+	for _, tc := range cases {
+		inv, _ := newTestInventory(t, tc.status, `{}`)
+		if _, err := inv.Hold(context.Background(), listingID, "b1"); !errors.Is(err, tc.want) {
+			t.Errorf("status %d: %v, want %v", tc.status, err, tc.want)
+		}
+	}
+}
+
+func TestMalformedListingIDsNeverReachSearchService(t *testing.T) {
+	inv, paths := newTestInventory(t, http.StatusCreated, `{}`)
+	for _, id := range []string{"", "..", "../users/42", listingID + "/holds", "a%2Fb", "not-a-listing"} {
+		if _, err := inv.Hold(context.Background(), id, "b1"); !errors.Is(err, model.ErrListingNotFound) {
+			t.Errorf("Hold(%q): %v, want ErrListingNotFound", id, err)
+		}
+		if err := inv.Release(context.Background(), id, "b1"); err != nil {
+			t.Errorf("Release(%q): %v", id, err)
+		}
+	}
+	if len(*paths) != 0 {
+		t.Fatalf("requests sent: %v", *paths)
+	}
+}
+
+func TestRelease(t *testing.T) {
+	inv, paths := newTestInventory(t, http.StatusNoContent, ``)
+	if err := inv.Release(context.Background(), listingID, "b1"); err != nil {
+		t.Fatal(err)
+	}
+	if got := (*paths)[0]; got != "DELETE /internal/listings/"+listingID+"/holds/b1" {
+		t.Fatalf("requested %s", got)
+	}
+}
@@ -1,25 +1,45 @@
 package model
 
 import (
+	"crypto/rand"
+	"fmt"
 	"time"
 )
 
 type Booking struct {
-	ID          string    `json:"id" db:"id"`
-	UserID      string    `json:"user_id" db:"user_id"`
-	ResourceID  string    `json:"resource_id" db:"resource_id"`
-	TotalAmount float64   `json:"total_amount" db:"total_amount"`
-	Status      string    `json:"status" db:"status"`
-	CreatedAt   time.Time `json:"created_at" db:"created_at"`
-	UpdatedAt   time.Time `json:"updated_at" db:"updated_at"`
+	ID          string  `json:"id" db:"id"`
+	UserID      string  `json:"user_id" db:"user_id"`
+	ResourceID  string  `json:"resource_id" db:"resource_id"`
+	TotalAmount float64 `json:"total_amount" db:"total_amount"`
+	// TripStartDate is the listing's start date when the booking was made
+	// (midnight UTC); nil for bookings made before it was recorded.
+	TripStartDate *time.Time    `json:"trip_start_date,omitempty" db:"trip_start_date"`
+	Status        BookingStatus `json:"status" db:"status"`
+	CreatedAt     time.Time     `json:"created_at" db:"created_at"`
+	UpdatedAt     time.Time     `json:"updated_at" db:"updated_at"`
 }
 
 type CreateBookingRequest struct {
-	UserID      string  `json:"user_id"`
-	ResourceID  string  `json:"resource_id"`
-	TotalAmount float64 `json:"total_amount"`
+	// UserID is taken from the access token; a value sent in the body is
+	// accepted for compatibility but ignored.
+	UserID       string  `json:"user_id"`
+	ResourceID   string  `json:"resource_id"`
+	TotalAmount  float64 `json:"total_amount"`
+	PaymentToken string  `json:"payment_token,omitempty"`
 }
 
 type BookingResponse struct {
 	Booking
 }
+
+// NewBookingID returns a random (version 4) UUID. IDs are assigned before
+// insert so that outbox events written in the same transaction can carry them.
+func NewBookingID() (string, error) {
+	var b [16]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		return "", err
+	}
+	b[6] = (b[6] & 0x0f) | 0x40
+	b[8] = (b[8] & 0x3f) | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:16]), nil
+}
@@ -0,0 +1,72 @@
+package model
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// RefundTier refunds Percent of the booking total when the booking is
+// cancelled at least Before ahead of the trip start.
+type RefundTier struct {
+	Before  time.Duration
+	Percent int
+}
+
+// CancellationPolicy decides how much of a booking is refunded when the
+// traveller cancels it. The tier with the longest notice that the
+// cancellation still gives wins; cancellations closer to the trip than every
+// tier, and bookings without a known trip start, get DefaultPercent.
+type CancellationPolicy struct {
+	Tiers          []RefundTier
+	DefaultPercent int
+}
+
+// ParseCancellationPolicy parses tiers written as "720h:100,168h:50".
+func ParseCancellationPolicy(tiers string, defaultPercent int) (CancellationPolicy, error) {
+	policy := CancellationPolicy{DefaultPercent: defaultPercent}
+	for _, part := range strings.Split(tiers, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		before, percent, ok := strings.Cut(part, ":")
+		if !ok {
+			return policy, fmt.Errorf("invalid refund tier %q", part)
+		}
+		d, err := time.ParseDuration(before)
+		if err != nil {
+			return policy, fmt.Errorf("invalid refund tier %q: %w", part, err)
+		}
+		p, err := strconv.Atoi(percent)
+		if err != nil || p < 0 || p > 100 {
+			return policy, fmt.Errorf("invalid refund tier %q: percent must be 0-100", part)
+		}
+		policy.Tiers = append(policy.Tiers, RefundTier{Before: d, Percent: p})
+	}
+	// Longest notice first, so the first tier met is the one that applies
+	sort.Slice(policy.Tiers, func(i, j int) bool { return policy.Tiers[i].Before > policy.Tiers[j].Before })
+	return policy, nil
+}
+
+// RefundAmount returns the amount refunded for b if it is cancelled at now.
+func (p CancellationPolicy) RefundAmount(b *Booking, now time.Time) float64 {
+	percent := p.DefaultPercent
+	if b.TripStartDate != nil {
+		notice := b.TripStartDate.Sub(now)
+		for _, tier := range p.Tiers {
+			if notice >= tier.Before {
+				percent = tier.Percent
+				break
+			}
+		}
+	}
+	return math.Round(b.TotalAmount*float64(percent)) / 100
+}
+
+type CancelBookingRequest struct {
+	Reason string `json:"reason"`
+}
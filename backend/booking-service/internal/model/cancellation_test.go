@@ -0,0 +1,77 @@
+package model
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseCancellationPolicy(t *testing.T) {
+	policy, err := ParseCancellationPolicy(" 168h:50, 720h:100 ,,", 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []RefundTier{{Before: 720 * time.Hour, Percent: 100}, {Before: 168 * time.Hour, Percent: 50}}
+	if len(policy.Tiers) != len(want) || policy.DefaultPercent != 10 {
+		t.Fatalf("policy %+v", policy)
+	}
+	for i := range want {
+		if policy.Tiers[i] != want[i] {
+			t.Fatalf("tier %d is %+v, want %+v", i, policy.Tiers[i], want[i])
+		}
+	}
+
+	for _, tiers := range []string{"720h", "month:100", "24h:101", "24h:-1", "24h:half"} {
+		if _, err := ParseCancellationPolicy(tiers, 0); err == nil {
+			t.Errorf("ParseCancellationPolicy(%q) accepted an invalid tier", tiers)
+		}
+	}
+}
+
+func TestRefundAmountCountsFromTripStart(t *testing.T) {
+	policy, err := ParseCancellationPolicy("720h:100,168h:50", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	day := 24 * time.Hour
+
+	cases := []struct {
+		name   string
+		start  time.Time
+		refund float64
+	}{
+		{"two months ahead", now.Add(60 * day), 200},
+		{"exactly 30 days ahead", now.Add(30 * day), 200},
+		{"two weeks ahead", now.Add(14 * day), 100},
+		{"exactly a week ahead", now.Add(7 * day), 100},
+		{"tomorrow", now.Add(day), 0},
+		{"already started", now.Add(-day), 0},
+	}
+	for _, tc := range cases {
+		start := tc.start
+		// Booked long ago or just now, only the trip start matters
+		for _, created := range []time.Time{now.Add(-90 * day), now} {
+			b := &Booking{TotalAmount: 200, TripStartDate: &start, CreatedAt: created}
+			if got := policy.RefundAmount(b, now); got != tc.refund {
+				t.Errorf("%s, booked %s: refund %.2f, want %.2f", tc.name, created, got, tc.refund)
+			}
+		}
+	}
+}
+
+func TestRefundAmountWithoutTripStart(t *testing.T) {
+	policy := CancellationPolicy{Tiers: []RefundTier{{Before: time.Hour, Percent: 100}}, DefaultPercent: 25}
+	b := &Booking{TotalAmount: 99.99, CreatedAt: time.Now()}
+	if got := policy.RefundAmount(b, time.Now()); got != 25 {
+		t.Fatalf("refund %.2f, want the default 25%% (25.00)", got)
+	}
+}
+
+func TestRefundAmountRoundsToCents(t *testing.T) {
+	start := time.Now().Add(1000 * time.Hour)
+	policy := CancellationPolicy{Tiers: []RefundTier{{Before: 720 * time.Hour, Percent: 33}}}
+	b := &Booking{TotalAmount: 10.01, TripStartDate: &start}
+	if got := policy.RefundAmount(b, time.Now()); got != 3.30 {
+		t.Fatalf("refund %v, want 3.30", got)
+	}
+}
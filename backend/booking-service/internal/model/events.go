@@ -1,10 +1,13 @@
 package model
 
+import "time"
+
 type BookingCreatedEvent struct {
-	BookingID   string  `json:"booking_id"`
-	UserID      string  `json:"user_id"`
-	ResourceID  string  `json:"resource_id"`
-	TotalAmount float64 `json:"total_amount"`
+	BookingID    string  `json:"booking_id"`
+	UserID       string  `json:"user_id"`
+	ResourceID   string  `json:"resource_id"`
+	TotalAmount  float64 `json:"total_amount"`
+	PaymentToken string  `json:"payment_token,omitempty"`
 }
 
 type PaymentProcessedEvent struct {
@@ -13,4 +16,46 @@ type PaymentProcessedEvent struct {
 	Amount        float64 `json:"amount"`
 	Status        string  `json:"status"` // SUCCESS, FAILED
 	TransactionID string  `json:"transaction_id"`
+	Reason        string  `json:"reason,omitempty"`
+}
+
+type BookingExpiredEvent struct {
+	BookingID   string    `json:"booking_id"`
+	UserID      string    `json:"user_id"`
+	ResourceID  string    `json:"resource_id"`
+	TotalAmount float64   `json:"total_amount"`
+	ExpiredAt   time.Time `json:"expired_at"`
+}
+
+type BookingCancelRequestedEvent struct {
+	RequestID    string  `json:"request_id"`
+	BookingID    string  `json:"booking_id"`
+	UserID       string  `json:"user_id"`
+	TotalAmount  float64 `json:"total_amount"`
+	RefundAmount float64 `json:"refund_amount"`
+	Reason       string  `json:"reason,omitempty"`
+}
+
+type BookingCancelledEvent struct {
+	BookingID    string  `json:"booking_id"`
+	UserID       string  `json:"user_id"`
+	RefundAmount float64 `json:"refund_amount"`
+	Reason       string  `json:"reason,omitempty"`
+}
+
+type RefundProcessedEvent struct {
+	RefundID  string  `json:"refund_id"`
+	RequestID string  `json:"request_id"`
+	BookingID string  `json:"booking_id"`
+	Amount    float64 `json:"amount"`
+	Status    string  `json:"status"` // SUCCESS, FAILED
+	Reason    string  `json:"reason,omitempty"`
+}
+
+// InventoryReleaseEvent returns the slot held for a booking (HoldID is the
+// booking ID) to its listing once the booking ends without being used.
+type InventoryReleaseEvent struct {
+	HoldID    string `json:"hold_id"`
+	ListingID string `json:"listing_id"`
+	Reason    string `json:"reason"`
 }
@@ -0,0 +1,22 @@
+package model
+
+import (
+	"errors"
+	"time"
+)
+
+var (
+	ErrListingNotFound = errors.New("listing not found")
+	ErrSoldOut         = errors.New("listing is sold out")
+	// ErrInventoryUnavailable means the hold could not be placed because the
+	// listing owner (search-service) did not answer; the booking can be retried.
+	ErrInventoryUnavailable = errors.New("inventory service unavailable")
+)
+
+// Hold is a slot of a listing held for a booking.
+type Hold struct {
+	ListingID string
+	// TripStart is the listing's start date; nil if search-service did not
+	// report one.
+	TripStart *time.Time
+}
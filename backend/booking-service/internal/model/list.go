@@ -0,0 +1,56 @@
+package model
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"time"
+)
+
+const (
+	DefaultListLimit = 20
+	MaxListLimit     = 100
+)
+
+// BookingCursor is the keyset position after which the next page starts.
+type BookingCursor struct {
+	CreatedAt time.Time `json:"c"`
+	ID        string    `json:"i"`
+}
+
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// Encode returns the cursor as an opaque URL-safe token.
+func (c BookingCursor) Encode() string {
+	data, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func DecodeBookingCursor(token string) (*BookingCursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+	var c BookingCursor
+	if err := json.Unmarshal(data, &c); err != nil || c.ID == "" || c.CreatedAt.IsZero() {
+		return nil, ErrInvalidCursor
+	}
+	return &c, nil
+}
+
+// BookingListParams filters and pages a user's bookings. Results are ordered
+// by (created_at, id), newest first unless Ascending is set.
+type BookingListParams struct {
+	UserID        string
+	Statuses      []BookingStatus
+	CreatedAfter  *time.Time
+	CreatedBefore *time.Time
+	Ascending     bool
+	Limit         int
+	After         *BookingCursor
+}
+
+type BookingPage struct {
+	Data       []Booking `json:"data"`
+	NextCursor string    `json:"next_cursor,omitempty"`
+}
@@ -0,0 +1,61 @@
+package model
+
+import (
+	"encoding/base64"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestBookingCursorRoundTrip(t *testing.T) {
+	jakarta := time.FixedZone("WIB", 7*60*60)
+	cursors := []BookingCursor{
+		{CreatedAt: time.Date(2026, 7, 1, 12, 0, 0, 0, time.UTC), ID: "b1"},
+		// Postgres keeps microseconds; the cursor must not round them away
+		{CreatedAt: time.Date(2026, 7, 1, 12, 0, 0, 123456000, time.UTC), ID: "0190f1c2-7a3b-7c4d-8e5f-60718293a4b5"},
+		{CreatedAt: time.Date(2026, 7, 1, 19, 0, 0, 1, jakarta), ID: "b2"},
+	}
+	for _, c := range cursors {
+		token := c.Encode()
+		if strings.ContainsAny(token, "+/=") {
+			t.Errorf("%v: token %q is not URL-safe", c, token)
+		}
+		got, err := DecodeBookingCursor(token)
+		if err != nil {
+			t.Errorf("%v: %v", c, err)
+			continue
+		}
+		if got.ID != c.ID || !got.CreatedAt.Equal(c.CreatedAt) {
+			t.Errorf("decoded %v, want %v", got, c)
+		}
+	}
+}
+
+func TestDecodeBookingCursorRejects(t *testing.T) {
+	encode := func(s string) string { return base64.RawURLEncoding.EncodeToString([]byte(s)) }
+	valid := BookingCursor{CreatedAt: time.Date(2026, 7, 1, 12, 0, 0, 0, time.UTC), ID: "b1"}.Encode()
+
+	cases := []struct {
+		name  string
+		token string
+	}{
+		{"empty", ""},
+		{"not base64", "not a cursor!"},
+		{"padded", valid + "=="},
+		{"truncated", valid[:len(valid)-4]},
+		{"not JSON", encode("b1")},
+		{"JSON array", encode(`["2026-07-01T12:00:00Z","b1"]`)},
+		{"missing ID", encode(`{"c":"2026-07-01T12:00:00Z"}`)},
+		{"empty ID", encode(`{"c":"2026-07-01T12:00:00Z","i":""}`)},
+		{"missing time", encode(`{"i":"b1"}`)},
+		{"zero time", encode(`{"c":"0001-01-01T00:00:00Z","i":"b1"}`)},
+		{"bad time", encode(`{"c":"yesterday","i":"b1"}`)},
+		{"wrong ID type", encode(`{"c":"2026-07-01T12:00:00Z","i":1}`)},
+	}
+	for _, tc := range cases {
+		if c, err := DecodeBookingCursor(tc.token); !errors.Is(err, ErrInvalidCursor) {
+			t.Errorf("%s: decoded %v, %v, want ErrInvalidCursor", tc.name, c, err)
+		}
+	}
+}
@@ -0,0 +1,32 @@
+package model
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// OutboxEvent is an event stored alongside the booking it describes and
+// relayed to Kafka once the surrounding transaction has committed.
+type OutboxEvent struct {
+	ID          int64           `json:"id" db:"id"`
+	AggregateID string          `json:"aggregate_id" db:"aggregate_id"`
+	Topic       string          `json:"topic" db:"topic"`
+	Key         string          `json:"key" db:"event_key"`
+	Payload     json.RawMessage `json:"payload" db:"payload"`
+	Attempts    int             `json:"attempts" db:"attempts"`
+	CreatedAt   time.Time       `json:"created_at" db:"created_at"`
+	PublishedAt *time.Time      `json:"published_at,omitempty" db:"published_at"`
+}
+
+func NewOutboxEvent(topic, key string, payload interface{}) (*OutboxEvent, error) {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return nil, err
+	}
+	return &OutboxEvent{
+		AggregateID: key,
+		Topic:       topic,
+		Key:         key,
+		Payload:     data,
+	}, nil
+}
@@ -0,0 +1,112 @@
+package model
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+type BookingStatus string
+
+const (
+	StatusPending         BookingStatus = "PENDING"
+	StatusAwaitingPayment BookingStatus = "AWAITING_PAYMENT"
+	StatusConfirmed       BookingStatus = "CONFIRMED"
+	// StatusCancellationRequested means the traveller cancelled a confirmed
+	// booking and the refund is being processed by the payment service.
+	StatusCancellationRequested BookingStatus = "CANCELLATION_REQUESTED"
+	StatusCancelled             BookingStatus = "CANCELLED"
+	StatusExpired               BookingStatus = "EXPIRED"
+	StatusRefunded              BookingStatus = "REFUNDED"
+	StatusCompleted             BookingStatus = "COMPLETED"
+)
+
+// Valid reports whether s is one of the known booking statuses.
+func (s BookingStatus) Valid() bool {
+	switch s {
+	case StatusPending, StatusAwaitingPayment, StatusConfirmed, StatusCancellationRequested,
+		StatusCancelled, StatusExpired, StatusRefunded, StatusCompleted:
+		return true
+	}
+	return false
+}
+
+// transitions lists, for every status, the statuses a booking may move to.
+// Statuses without an entry are terminal.
+var transitions = map[BookingStatus][]BookingStatus{
+	StatusPending:         {StatusAwaitingPayment, StatusConfirmed, StatusCancelled, StatusExpired},
+	StatusAwaitingPayment: {StatusConfirmed, StatusCancelled, StatusExpired},
+	StatusConfirmed:       {StatusCancellationRequested, StatusCancelled, StatusCompleted},
+	// A failed refund returns the booking to CONFIRMED so it can be retried
+	StatusCancellationRequested: {StatusRefunded, StatusConfirmed},
+}
+
+// CanTransition reports whether a booking in status from may move to status to.
+func CanTransition(from, to BookingStatus) bool {
+	for _, next := range transitions[from] {
+		if next == to {
+			return true
+		}
+	}
+	return false
+}
+
+// SourcesOf returns every status from which a booking may move to status to.
+func SourcesOf(to BookingStatus) []BookingStatus {
+	var sources []BookingStatus
+	for from := range transitions {
+		if CanTransition(from, to) {
+			sources = append(sources, from)
+		}
+	}
+	return sources
+}
+
+var ErrBookingNotFound = errors.New("booking not found")
+
+// TransitionError is returned when a booking is asked to move to a status
+// that is not reachable from its current one.
+type TransitionError struct {
+	BookingID string
+	From      BookingStatus
+	To        BookingStatus
+}
+
+func (e *TransitionError) Error() string {
+	return fmt.Sprintf("booking %s cannot transition from %s to %s", e.BookingID, e.From, e.To)
+}
+
+// StatusTransition describes a requested status change and why it happened.
+type StatusTransition struct {
+	BookingID string
+	To        BookingStatus
+	// From optionally narrows the statuses the change applies to; it
+	// defaults to every status that may transition to To.
+	From    []BookingStatus
+	Cause   string
+	EventID string
+}
+
+// Sources returns the statuses from which t may be applied.
+func (t StatusTransition) Sources() []BookingStatus {
+	if len(t.From) == 0 {
+		return SourcesOf(t.To)
+	}
+	var sources []BookingStatus
+	for _, from := range t.From {
+		if CanTransition(from, t.To) {
+			sources = append(sources, from)
+		}
+	}
+	return sources
+}
+
+type StatusHistoryEntry struct {
+	ID         int64          `json:"id" db:"id"`
+	BookingID  string         `json:"booking_id" db:"booking_id"`
+	FromStatus *BookingStatus `json:"from_status" db:"from_status"`
+	ToStatus   BookingStatus  `json:"to_status" db:"to_status"`
+	Cause      string         `json:"cause" db:"cause"`
+	EventID    *string        `json:"event_id,omitempty" db:"event_id"`
+	CreatedAt  time.Time      `json:"created_at" db:"created_at"`
+}
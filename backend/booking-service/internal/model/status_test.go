@@ -0,0 +1,116 @@
+package model
+
+import (
+	"sort"
+	"testing"
+)
+
+var allStatuses = []BookingStatus{
+	StatusPending, StatusAwaitingPayment, StatusConfirmed, StatusCancellationRequested,
+	StatusCancelled, StatusExpired, StatusRefunded, StatusCompleted,
+}
+
+func sorted(statuses []BookingStatus) []BookingStatus {
+	out := append([]BookingStatus(nil), statuses...)
+	sort.Slice(out, func(i, j int) bool { return out[i] < out[j] })
+	return out
+}
+
+func equalStatuses(a, b []BookingStatus) bool {
+	a, b = sorted(a), sorted(b)
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func TestCanTransition(t *testing.T) {
+	allowed := map[[2]BookingStatus]bool{
+		{StatusPending, StatusAwaitingPayment}:         true,
+		{StatusPending, StatusConfirmed}:               true,
+		{StatusPending, StatusCancelled}:               true,
+		{StatusPending, StatusExpired}:                 true,
+		{StatusAwaitingPayment, StatusConfirmed}:       true,
+		{StatusAwaitingPayment, StatusCancelled}:       true,
+		{StatusAwaitingPayment, StatusExpired}:         true,
+		{StatusConfirmed, StatusCancellationRequested}: true,
+		{StatusConfirmed, StatusCancelled}:             true,
+		{StatusConfirmed, StatusCompleted}:             true,
+		{StatusCancellationRequested, StatusRefunded}:  true,
+		{StatusCancellationRequested, StatusConfirmed}: true,
+	}
+	for _, from := range allStatuses {
+		for _, to := range allStatuses {
+			if got := CanTransition(from, to); got != allowed[[2]BookingStatus{from, to}] {
+				t.Errorf("CanTransition(%s, %s) = %t", from, to, got)
+			}
+		}
+	}
+}
+
+func TestTerminalStatuses(t *testing.T) {
+	for _, s := range []BookingStatus{StatusCancelled, StatusExpired, StatusRefunded, StatusCompleted} {
+		for _, to := range allStatuses {
+			if CanTransition(s, to) {
+				t.Errorf("terminal status %s may move to %s", s, to)
+			}
+		}
+	}
+}
+
+func TestSourcesOf(t *testing.T) {
+	cases := map[BookingStatus][]BookingStatus{
+		StatusPending:               nil,
+		StatusConfirmed:             {StatusPending, StatusAwaitingPayment, StatusCancellationRequested},
+		StatusCancelled:             {StatusPending, StatusAwaitingPayment, StatusConfirmed},
+		StatusExpired:               {StatusPending, StatusAwaitingPayment},
+		StatusRefunded:              {StatusCancellationRequested},
+		StatusCancellationRequested: {StatusConfirmed},
+	}
+	for to, want := range cases {
+		if got := SourcesOf(to); !equalStatuses(got, want) {
+			t.Errorf("SourcesOf(%s) = %v, want %v", to, got, want)
+		}
+	}
+}
+
+func TestStatusTransitionSources(t *testing.T) {
+	// Without From every source of To applies
+	all := StatusTransition{To: StatusCancelled}
+	if got := all.Sources(); !equalStatuses(got, SourcesOf(StatusCancelled)) {
+		t.Errorf("Sources() = %v, want %v", got, SourcesOf(StatusCancelled))
+	}
+
+	// From narrows the sources and statuses that cannot reach To are dropped
+	narrowed := StatusTransition{
+		To:   StatusCancelled,
+		From: []BookingStatus{StatusPending, StatusRefunded, StatusAwaitingPayment},
+	}
+	if got, want := narrowed.Sources(), []BookingStatus{StatusPending, StatusAwaitingPayment}; !equalStatuses(got, want) {
+		t.Errorf("Sources() = %v, want %v", got, want)
+	}
+
+	// A From with no valid source must not fall back to every source
+	impossible := StatusTransition{To: StatusConfirmed, From: []BookingStatus{StatusExpired}}
+	if got := impossible.Sources(); len(got) != 0 {
+		t.Errorf("Sources() = %v, want none", got)
+	}
+}
+
+func TestBookingStatusValid(t *testing.T) {
+	for _, s := range allStatuses {
+		if !s.Valid() {
+			t.Errorf("%s is not valid", s)
+		}
+	}
+	for _, s := range []BookingStatus{"", "pending", "UNKNOWN"} {
+		if s.Valid() {
+			t.Errorf("%q is valid", s)
+		}
+	}
+}
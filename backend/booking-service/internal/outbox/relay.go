@@ -0,0 +1,65 @@
+package outbox
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/gavinadlan/tripnest/backend/booking-service/internal/events"
+	"github.com/gavinadlan/tripnest/backend/booking-service/internal/model"
+	"github.com/gavinadlan/tripnest/backend/booking-service/internal/repository"
+)
+
+// Relay drains the booking outbox to Kafka. It polls on a fixed interval and
+// keeps draining without waiting while full batches are being returned.
+type Relay struct {
+	repo           repository.OutboxRepository
+	producer       events.Producer
+	interval       time.Duration
+	batchSize      int
+	publishTimeout time.Duration
+}
+
+func NewRelay(repo repository.OutboxRepository, producer events.Producer, interval time.Duration, batchSize int) *Relay {
+	return &Relay{
+		repo:           repo,
+		producer:       producer,
+		interval:       interval,
+		batchSize:      batchSize,
+		publishTimeout: 5 * time.Second,
+	}
+}
+
+func (r *Relay) Run(ctx context.Context) {
+	log.Printf("Outbox relay started (interval %s, batch %d)", r.interval, r.batchSize)
+	ticker := time.NewTicker(r.interval)
+	defer ticker.Stop()
+
+	for {
+		published, err := r.repo.RelayOutbox(ctx, r.batchSize, r.publish)
+		if err != nil && ctx.Err() == nil {
+			log.Printf("Outbox relay error: %v", err)
+		}
+		if published == r.batchSize {
+			continue // More events are probably waiting
+		}
+
+		select {
+		case <-ctx.Done():
+			log.Println("Outbox relay stopped")
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (r *Relay) publish(ctx context.Context, e *model.OutboxEvent) error {
+	ctx, cancel := context.WithTimeout(ctx, r.publishTimeout)
+	defer cancel()
+
+	if err := r.producer.Publish(ctx, e.Topic, e.Key, e.Payload); err != nil {
+		log.Printf("Failed to publish %s event %d (attempt %d): %v", e.Topic, e.ID, e.Attempts+1, err)
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,149 @@
+package outbox
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gavinadlan/tripnest/backend/booking-service/internal/model"
+)
+
+// fakeOutbox hands out its pending events in order and drops the published
+// ones, stopping a batch at the first failure.
+type fakeOutbox struct {
+	mu      sync.Mutex
+	pending []*model.OutboxEvent
+	batches []int
+}
+
+func (o *fakeOutbox) RelayOutbox(ctx context.Context, limit int, publish func(ctx context.Context, e *model.OutboxEvent) error) (int, error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	published := 0
+	for len(o.pending) > 0 && published < limit {
+		e := o.pending[0]
+		if err := publish(ctx, e); err != nil {
+			e.Attempts++
+			return published, err
+		}
+		o.pending = o.pending[1:]
+		published++
+	}
+	o.batches = append(o.batches, published)
+	return published, nil
+}
+
+func (o *fakeOutbox) remaining() int {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	return len(o.pending)
+}
+
+type fakeProducer struct {
+	mu        sync.Mutex
+	published []string
+	publish   func(ctx context.Context) error
+}
+
+func (p *fakeProducer) Publish(ctx context.Context, topic string, key string, payload interface{}) error {
+	if p.publish != nil {
+		if err := p.publish(ctx); err != nil {
+			return err
+		}
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.published = append(p.published, topic+":"+key)
+	return nil
+}
+
+func (p *fakeProducer) Close() {}
+
+func newOutbox(n int) *fakeOutbox {
+	o := &fakeOutbox{}
+	for i := 1; i <= n; i++ {
+		o.pending = append(o.pending, &model.OutboxEvent{ID: int64(i), Topic: "booking.created", Key: fmt.Sprintf("b%d", i)})
+	}
+	return o
+}
+
+func TestRelayDrainsFullBatchesWithoutWaiting(t *testing.T) {
+	outbox, producer := newOutbox(5), &fakeProducer{}
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		// The ticker never fires during the test
+		NewRelay(outbox, producer, time.Hour, 2).Run(ctx)
+		close(done)
+	}()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for outbox.remaining() > 0 {
+		if time.Now().After(deadline) {
+			t.Fatalf("%d events left waiting for a tick", outbox.remaining())
+		}
+		time.Sleep(time.Millisecond)
+	}
+	cancel()
+	<-done
+
+	want := []string{"booking.created:b1", "booking.created:b2", "booking.created:b3", "booking.created:b4", "booking.created:b5"}
+	if fmt.Sprint(producer.published) != fmt.Sprint(want) {
+		t.Fatalf("published %v, want %v", producer.published, want)
+	}
+	if fmt.Sprint(outbox.batches) != "[2 2 1]" {
+		t.Fatalf("batches %v", outbox.batches)
+	}
+}
+
+func TestRelayBoundsPublish(t *testing.T) {
+	producer := &fakeProducer{publish: func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	}}
+	r := NewRelay(newOutbox(1), producer, time.Hour, 2)
+	r.publishTimeout = 10 * time.Millisecond
+
+	start := time.Now()
+	err := r.publish(context.Background(), &model.OutboxEvent{Topic: "booking.created", Key: "b1"})
+	if !errors.Is(err, context.DeadlineExceeded) || time.Since(start) > time.Second {
+		t.Fatalf("stuck broker: %v after %s", err, time.Since(start))
+	}
+}
+
+func TestRelayRetriesOnNextTick(t *testing.T) {
+	var mu sync.Mutex
+	failures := 1
+	producer := &fakeProducer{publish: func(ctx context.Context) error {
+		mu.Lock()
+		defer mu.Unlock()
+		if failures > 0 {
+			failures--
+			return errors.New("broker unavailable")
+		}
+		return nil
+	}}
+	outbox := newOutbox(1)
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		NewRelay(outbox, producer, 10*time.Millisecond, 2).Run(ctx)
+		close(done)
+	}()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for outbox.remaining() > 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("failed event never retried")
+		}
+		time.Sleep(time.Millisecond)
+	}
+	cancel()
+	<-done
+	if len(producer.published) != 1 {
+		t.Fatalf("published %v", producer.published)
+	}
+}
@@ -0,0 +1,125 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/gavinadlan/tripnest/backend/booking-service/internal/model"
+	"github.com/jackc/pgx/v5"
+)
+
+// outboxRelayLockID is the Postgres advisory lock key held by whichever
+// replica is currently draining the outbox. Only one relay publishes at a
+// time so events sharing a key are never reordered across replicas.
+const outboxRelayLockID = 7_100_001
+
+// maxOutboxBackoff caps the delay between retries of a failing event.
+const maxOutboxBackoff = 5 * time.Minute
+
+type OutboxRepository interface {
+	// RelayOutbox hands up to limit unpublished events to publish in insertion
+	// order and records the outcome of each. Once an event fails, later events
+	// with the same key are held back until it succeeds.
+	RelayOutbox(ctx context.Context, limit int, publish func(ctx context.Context, e *model.OutboxEvent) error) (int, error)
+}
+
+func insertOutboxEvents(ctx context.Context, tx pgx.Tx, events []*model.OutboxEvent) error {
+	query := `
+		INSERT INTO booking_outbox (aggregate_id, topic, event_key, payload)
+		VALUES ($1, $2, $3, $4)
+		RETURNING id, created_at`
+
+	for _, e := range events {
+		if err := tx.QueryRow(ctx, query, e.AggregateID, e.Topic, e.Key, e.Payload).Scan(&e.ID, &e.CreatedAt); err != nil {
+			return fmt.Errorf("failed to insert outbox event: %w", err)
+		}
+	}
+	return nil
+}
+
+func (r *postgresRepository) RelayOutbox(ctx context.Context, limit int, publish func(ctx context.Context, e *model.OutboxEvent) error) (int, error) {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin outbox transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	var locked bool
+	if err := tx.QueryRow(ctx, `SELECT pg_try_advisory_xact_lock($1)`, outboxRelayLockID).Scan(&locked); err != nil {
+		return 0, fmt.Errorf("failed to acquire outbox lock: %w", err)
+	}
+	if !locked {
+		return 0, nil // Another replica is relaying
+	}
+
+	// Skip keys whose oldest pending event is still backing off so that
+	// later events for the same booking are not published ahead of it.
+	query := `
+		SELECT o.id, o.aggregate_id, o.topic, o.event_key, o.payload, o.attempts, o.created_at
+		FROM booking_outbox o
+		WHERE o.published_at IS NULL
+		  AND o.available_at <= NOW()
+		  AND NOT EXISTS (
+			SELECT 1 FROM booking_outbox p
+			WHERE p.event_key = o.event_key
+			  AND p.published_at IS NULL
+			  AND p.id < o.id
+			  AND p.available_at > NOW()
+		  )
+		ORDER BY o.id
+		LIMIT $1`
+
+	rows, err := tx.Query(ctx, query, limit)
+	if err != nil {
+		return 0, fmt.Errorf("failed to load outbox events: %w", err)
+	}
+	pending, err := pgx.CollectRows(rows, func(row pgx.CollectableRow) (*model.OutboxEvent, error) {
+		var e model.OutboxEvent
+		err := row.Scan(&e.ID, &e.AggregateID, &e.Topic, &e.Key, &e.Payload, &e.Attempts, &e.CreatedAt)
+		return &e, err
+	})
+	if err != nil {
+		return 0, fmt.Errorf("failed to scan outbox events: %w", err)
+	}
+
+	published := 0
+	blocked := make(map[string]bool)
+	for _, e := range pending {
+		if blocked[e.Key] {
+			continue
+		}
+
+		if err := publish(ctx, e); err != nil {
+			blocked[e.Key] = true
+			backoff := outboxBackoff(e.Attempts + 1)
+			_, uerr := tx.Exec(ctx, `
+				UPDATE booking_outbox
+				SET attempts = attempts + 1, last_error = $1, available_at = NOW() + make_interval(secs => $2)
+				WHERE id = $3`,
+				err.Error(), backoff.Seconds(), e.ID)
+			if uerr != nil {
+				return published, fmt.Errorf("failed to record outbox failure: %w", uerr)
+			}
+			continue
+		}
+
+		if _, err := tx.Exec(ctx, `UPDATE booking_outbox SET published_at = NOW() WHERE id = $1`, e.ID); err != nil {
+			return published, fmt.Errorf("failed to mark outbox event published: %w", err)
+		}
+		published++
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return 0, fmt.Errorf("failed to commit outbox transaction: %w", err)
+	}
+	return published, nil
+}
+
+func outboxBackoff(attempts int) time.Duration {
+	backoff := time.Second << min(attempts, 16)
+	if backoff > maxOutboxBackoff {
+		return maxOutboxBackoff
+	}
+	return backoff
+}
@@ -2,17 +2,34 @@ package repository
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"time"
 
 	"github.com/gavinadlan/tripnest/backend/booking-service/internal/model"
+	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
 type BookingRepository interface {
-	Create(ctx context.Context, booking *model.Booking) error
+	// Create inserts the booking together with its outbox events in a single
+	// transaction, so an event is stored if and only if the booking is.
+	Create(ctx context.Context, booking *model.Booking, events ...*model.OutboxEvent) error
 	GetByID(ctx context.Context, id string) (*model.Booking, error)
-	GetByUserID(ctx context.Context, userID string) ([]model.Booking, error)
-	UpdateStatus(ctx context.Context, id, status string) error
+	// GetByUserID returns up to params.Limit bookings of params.UserID
+	// matching the filters, starting after params.After in sort order.
+	GetByUserID(ctx context.Context, params *model.BookingListParams) ([]model.Booking, error)
+	// UpdateStatus moves the booking to t.To only if its current status may
+	// transition there (compare-and-set), records the change in the status
+	// history and stores events, all in one transaction. It returns
+	// model.ErrBookingNotFound or a *model.TransitionError otherwise.
+	UpdateStatus(ctx context.Context, t model.StatusTransition, events ...*model.OutboxEvent) (*model.Booking, error)
+	GetStatusHistory(ctx context.Context, id string) ([]model.StatusHistoryEntry, error)
+	// ExpirePending moves up to limit PENDING bookings created before cutoff
+	// to EXPIRED and stores the events built by newEvents for each of them.
+	// Rows locked by another replica are skipped.
+	ExpirePending(ctx context.Context, cutoff time.Time, limit int, newEvents func(*model.Booking) ([]*model.OutboxEvent, error)) ([]model.Booking, error)
+	OutboxRepository
 	Close()
 }
 
@@ -38,38 +55,262 @@ func (r *postgresRepository) Close() {
 	r.db.Close()
 }
 
-func (r *postgresRepository) Create(ctx context.Context, b *model.Booking) error {
+func (r *postgresRepository) Create(ctx context.Context, b *model.Booking, events ...*model.OutboxEvent) error {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
 	query := `
-		INSERT INTO bookings (user_id, resource_id, total_amount, status)
-		VALUES ($1, $2, $3, $4)
-		RETURNING id, created_at, updated_at`
+		INSERT INTO bookings (id, user_id, resource_id, total_amount, trip_start_date, status)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING created_at, updated_at`
 
-	err := r.db.QueryRow(ctx, query,
+	err = tx.QueryRow(ctx, query,
+		b.ID,
 		b.UserID,
 		b.ResourceID,
 		b.TotalAmount,
-		"PENDING", // Default status
-	).Scan(&b.ID, &b.CreatedAt, &b.UpdatedAt)
+		b.TripStartDate,
+		model.StatusPending,
+	).Scan(&b.CreatedAt, &b.UpdatedAt)
 
 	if err != nil {
 		return fmt.Errorf("failed to create booking: %w", err)
 	}
-	b.Status = "PENDING"
+
+	if err := insertStatusHistory(ctx, tx, b.ID, nil, model.StatusPending, "created", ""); err != nil {
+		return err
+	}
+	if err := insertOutboxEvents(ctx, tx, events); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit booking: %w", err)
+	}
+	b.Status = model.StatusPending
 	return nil
 }
 
 func (r *postgresRepository) GetByID(ctx context.Context, id string) (*model.Booking, error) {
-	// Implementation needed
-	return nil, nil
+	query := `
+		SELECT id, user_id, resource_id, total_amount, trip_start_date, status, created_at, updated_at
+		FROM bookings WHERE id = $1`
+
+	var b model.Booking
+	err := r.db.QueryRow(ctx, query, id).Scan(
+		&b.ID, &b.UserID, &b.ResourceID, &b.TotalAmount, &b.TripStartDate, &b.Status, &b.CreatedAt, &b.UpdatedAt,
+	)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, nil // Not found
+		}
+		return nil, fmt.Errorf("failed to get booking by id: %w", err)
+	}
+	return &b, nil
 }
 
-func (r *postgresRepository) GetByUserID(ctx context.Context, userID string) ([]model.Booking, error) {
-	// Implementation needed
-	return nil, nil
+func (r *postgresRepository) GetByUserID(ctx context.Context, params *model.BookingListParams) ([]model.Booking, error) {
+	args := []interface{}{params.UserID}
+	where := "user_id = $1"
+	arg := func(v interface{}) string {
+		args = append(args, v)
+		return fmt.Sprintf("$%d", len(args))
+	}
+
+	if len(params.Statuses) > 0 {
+		where += " AND status = ANY(" + arg(params.Statuses) + ")"
+	}
+	if params.CreatedAfter != nil {
+		where += " AND created_at >= " + arg(*params.CreatedAfter)
+	}
+	if params.CreatedBefore != nil {
+		where += " AND created_at < " + arg(*params.CreatedBefore)
+	}
+
+	order, cmp := "DESC", "<"
+	if params.Ascending {
+		order, cmp = "ASC", ">"
+	}
+	if params.After != nil {
+		// Row comparison keeps the keyset stable when created_at ties
+		where += fmt.Sprintf(" AND (created_at, id) %s (%s, %s)", cmp, arg(params.After.CreatedAt), arg(params.After.ID))
+	}
+
+	query := fmt.Sprintf(`
+		SELECT id, user_id, resource_id, total_amount, trip_start_date, status, created_at, updated_at
+		FROM bookings
+		WHERE %s
+		ORDER BY created_at %s, id %s
+		LIMIT %s`, where, order, order, arg(params.Limit))
+
+	rows, err := r.db.Query(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list bookings: %w", err)
+	}
+	bookings, err := pgx.CollectRows(rows, func(row pgx.CollectableRow) (model.Booking, error) {
+		var b model.Booking
+		err := row.Scan(&b.ID, &b.UserID, &b.ResourceID, &b.TotalAmount, &b.TripStartDate, &b.Status, &b.CreatedAt, &b.UpdatedAt)
+		return b, err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to scan bookings: %w", err)
+	}
+	return bookings, nil
 }
 
-func (r *postgresRepository) UpdateStatus(ctx context.Context, id, status string) error {
-	query := `UPDATE bookings SET status = $1, updated_at = NOW() WHERE id = $2`
-	_, err := r.db.Exec(ctx, query, status, id)
-	return err
+func (r *postgresRepository) UpdateStatus(ctx context.Context, t model.StatusTransition, events ...*model.OutboxEvent) (*model.Booking, error) {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	// The CTE reads the previous status under the row lock taken by the
+	// UPDATE, so the history row always records what was actually replaced.
+	query := `
+		WITH previous AS (
+			SELECT id, status FROM bookings WHERE id = $1 FOR UPDATE
+		)
+		UPDATE bookings b SET status = $2, updated_at = NOW()
+		FROM previous p
+		WHERE b.id = p.id AND p.status = ANY($3)
+		RETURNING b.id, b.user_id, b.resource_id, b.total_amount, b.trip_start_date, b.status, b.created_at, b.updated_at, p.status`
+
+	var b model.Booking
+	var from model.BookingStatus
+	err = tx.QueryRow(ctx, query, t.BookingID, t.To, t.Sources()).Scan(
+		&b.ID, &b.UserID, &b.ResourceID, &b.TotalAmount, &b.TripStartDate, &b.Status, &b.CreatedAt, &b.UpdatedAt, &from,
+	)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, r.transitionError(ctx, tx, t)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to update booking status: %w", err)
+	}
+
+	if err := insertStatusHistory(ctx, tx, b.ID, &from, t.To, t.Cause, t.EventID); err != nil {
+		return nil, err
+	}
+	if err := insertOutboxEvents(ctx, tx, events); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("failed to commit booking status: %w", err)
+	}
+	return &b, nil
+}
+
+// transitionError explains why a compare-and-set status update matched no row.
+func (r *postgresRepository) transitionError(ctx context.Context, tx pgx.Tx, t model.StatusTransition) error {
+	var current model.BookingStatus
+	err := tx.QueryRow(ctx, `SELECT status FROM bookings WHERE id = $1`, t.BookingID).Scan(&current)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return model.ErrBookingNotFound
+	}
+	if err != nil {
+		return fmt.Errorf("failed to load booking status: %w", err)
+	}
+	return &model.TransitionError{BookingID: t.BookingID, From: current, To: t.To}
+}
+
+func (r *postgresRepository) GetStatusHistory(ctx context.Context, id string) ([]model.StatusHistoryEntry, error) {
+	query := `
+		SELECT id, booking_id, from_status, to_status, cause, event_id, created_at
+		FROM booking_status_history
+		WHERE booking_id = $1
+		ORDER BY id`
+
+	rows, err := r.db.Query(ctx, query, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get booking status history: %w", err)
+	}
+	history, err := pgx.CollectRows(rows, func(row pgx.CollectableRow) (model.StatusHistoryEntry, error) {
+		var h model.StatusHistoryEntry
+		err := row.Scan(&h.ID, &h.BookingID, &h.FromStatus, &h.ToStatus, &h.Cause, &h.EventID, &h.CreatedAt)
+		return h, err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to scan booking status history: %w", err)
+	}
+	return history, nil
+}
+
+func (r *postgresRepository) ExpirePending(ctx context.Context, cutoff time.Time, limit int, newEvents func(*model.Booking) ([]*model.OutboxEvent, error)) ([]model.Booking, error) {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	query := `
+		WITH stale AS (
+			SELECT id, status FROM bookings
+			WHERE status = ANY($3) AND created_at < $1
+			ORDER BY created_at
+			LIMIT $2
+			FOR UPDATE SKIP LOCKED
+		)
+		UPDATE bookings b SET status = $4, updated_at = NOW()
+		FROM stale s
+		WHERE b.id = s.id
+		RETURNING b.id, b.user_id, b.resource_id, b.total_amount, b.trip_start_date, b.status, b.created_at, b.updated_at, s.status`
+
+	// Only bookings still waiting on the saga time out; both waiting states
+	// are sources of EXPIRED in the state machine.
+	waiting := []model.BookingStatus{model.StatusPending, model.StatusAwaitingPayment}
+	rows, err := tx.Query(ctx, query, cutoff, limit, waiting, model.StatusExpired)
+	if err != nil {
+		return nil, fmt.Errorf("failed to expire bookings: %w", err)
+	}
+
+	var expired []model.Booking
+	var previous []model.BookingStatus
+	for rows.Next() {
+		var b model.Booking
+		var from model.BookingStatus
+		if err := rows.Scan(&b.ID, &b.UserID, &b.ResourceID, &b.TotalAmount, &b.TripStartDate, &b.Status, &b.CreatedAt, &b.UpdatedAt, &from); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("failed to scan expired bookings: %w", err)
+		}
+		expired = append(expired, b)
+		previous = append(previous, from)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to expire bookings: %w", err)
+	}
+
+	var events []*model.OutboxEvent
+	for i := range expired {
+		if err := insertStatusHistory(ctx, tx, expired[i].ID, &previous[i], model.StatusExpired, "timeout", ""); err != nil {
+			return nil, err
+		}
+		e, err := newEvents(&expired[i])
+		if err != nil {
+			return nil, err
+		}
+		events = append(events, e...)
+	}
+	if err := insertOutboxEvents(ctx, tx, events); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("failed to commit expired bookings: %w", err)
+	}
+	return expired, nil
+}
+
+func insertStatusHistory(ctx context.Context, tx pgx.Tx, bookingID string, from *model.BookingStatus, to model.BookingStatus, cause, eventID string) error {
+	query := `
+		INSERT INTO booking_status_history (booking_id, from_status, to_status, cause, event_id)
+		VALUES ($1, $2, $3, $4, NULLIF($5, ''))`
+
+	if _, err := tx.Exec(ctx, query, bookingID, from, to, cause, eventID); err != nil {
+		return fmt.Errorf("failed to record booking status history: %w", err)
+	}
+	return nil
 }
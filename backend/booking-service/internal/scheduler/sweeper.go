@@ -0,0 +1,57 @@
+package scheduler
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/gavinadlan/tripnest/backend/booking-service/internal/service"
+)
+
+// TimeoutSweeper periodically expires bookings that have been PENDING for
+// longer than the payment deadline. Every replica may run one: the
+// repository claims rows with SKIP LOCKED, so each booking expires once.
+type TimeoutSweeper struct {
+	svc       service.BookingService
+	deadline  time.Duration
+	interval  time.Duration
+	batchSize int
+}
+
+func NewTimeoutSweeper(svc service.BookingService, deadline, interval time.Duration, batchSize int) *TimeoutSweeper {
+	return &TimeoutSweeper{
+		svc:       svc,
+		deadline:  deadline,
+		interval:  interval,
+		batchSize: batchSize,
+	}
+}
+
+func (s *TimeoutSweeper) Run(ctx context.Context) {
+	log.Printf("Booking timeout sweeper started (deadline %s, interval %s)", s.deadline, s.interval)
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			log.Println("Booking timeout sweeper stopped")
+			return
+		case <-ticker.C:
+		}
+
+		// Drain in batches so a backlog doesn't wait for further ticks
+		for {
+			n, err := s.svc.ExpirePendingBookings(ctx, s.deadline, s.batchSize)
+			if err != nil {
+				if ctx.Err() == nil {
+					log.Printf("Booking timeout sweep failed: %v", err)
+				}
+				break
+			}
+			if n < s.batchSize {
+				break
+			}
+		}
+	}
+}
@@ -0,0 +1,72 @@
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gavinadlan/tripnest/backend/booking-service/internal/service"
+)
+
+// scriptedService returns the scripted sweep results in turn and cancels the
+// sweeper once they run out.
+type scriptedService struct {
+	service.BookingService
+	mu      sync.Mutex
+	results []error // nil: a sweep expiring counts[i] bookings
+	counts  []int
+	calls   []time.Time
+	cancel  context.CancelFunc
+}
+
+func (s *scriptedService) ExpirePendingBookings(ctx context.Context, olderThan time.Duration, limit int) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if olderThan != 15*time.Minute || limit != 10 {
+		return 0, errors.New("unexpected arguments")
+	}
+	i := len(s.calls)
+	s.calls = append(s.calls, time.Now())
+	if i == len(s.counts) {
+		s.cancel()
+		return 0, ctx.Err()
+	}
+	return s.counts[i], s.results[i]
+}
+
+func TestSweeperDrainsBacklog(t *testing.T) {
+	const interval = 50 * time.Millisecond
+	ctx, cancel := context.WithCancel(context.Background())
+	svc := &scriptedService{
+		// Tick 1 drains two full batches and a partial one; tick 2 fails;
+		// tick 3 finds nothing
+		counts:  []int{10, 10, 3, 0, 0},
+		results: []error{nil, nil, nil, errors.New("connection refused"), nil},
+		cancel:  cancel,
+	}
+
+	done := make(chan struct{})
+	go func() {
+		NewTimeoutSweeper(svc, 15*time.Minute, interval, 10).Run(ctx)
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("sweeper did not stop")
+	}
+
+	calls := svc.calls
+	if len(calls) != 6 {
+		t.Fatalf("%d sweeps, want 6", len(calls))
+	}
+	for i := 1; i < len(calls); i++ {
+		gap := calls[i].Sub(calls[i-1])
+		// Only sweeps 1 to 3 run back to back; the rest wait for a tick
+		if waited := gap >= interval/2; waited != (i >= 3) {
+			t.Errorf("sweep %d ran %s after the previous one", i+1, gap)
+		}
+	}
+}
@@ -2,11 +2,12 @@ package service
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log"
 	"time"
 
-	"github.com/gavinadlan/tripnest/backend/booking-service/internal/events"
+	"github.com/gavinadlan/tripnest/backend/booking-service/internal/inventory"
 	"github.com/gavinadlan/tripnest/backend/booking-service/internal/model"
 	"github.com/gavinadlan/tripnest/backend/booking-service/internal/repository"
 )
@@ -14,46 +15,68 @@ import (
 type BookingService interface {
 	CreateBooking(ctx context.Context, req *model.CreateBookingRequest) (*model.Booking, error)
 	GetBooking(ctx context.Context, id string) (*model.Booking, error)
-	ConfirmBooking(ctx context.Context, bookingID string) error
-	CancelBooking(ctx context.Context, bookingID string) error
+	ListBookings(ctx context.Context, params *model.BookingListParams) (*model.BookingPage, error)
+	GetBookingHistory(ctx context.Context, id string) ([]model.StatusHistoryEntry, error)
+	ConfirmBooking(ctx context.Context, bookingID, eventID string) error
+	CancelBooking(ctx context.Context, bookingID, eventID string) error
+	RequestCancellation(ctx context.Context, bookingID, reason string) (*model.Booking, error)
+	CompleteRefund(ctx context.Context, bookingID, eventID string) error
+	FailRefund(ctx context.Context, bookingID, eventID string) error
+	ExpirePendingBookings(ctx context.Context, olderThan time.Duration, limit int) (int, error)
 }
 
 type bookingService struct {
-	repo     repository.BookingRepository
-	producer *events.KafkaProducer
+	repo      repository.BookingRepository
+	inventory inventory.Inventory
+	policy    model.CancellationPolicy
 }
 
-func NewBookingService(repo repository.BookingRepository, producer *events.KafkaProducer) BookingService {
-	return &bookingService{repo: repo, producer: producer}
+func NewBookingService(repo repository.BookingRepository, inv inventory.Inventory, policy model.CancellationPolicy) BookingService {
+	return &bookingService{repo: repo, inventory: inv, policy: policy}
 }
 
 func (s *bookingService) CreateBooking(ctx context.Context, req *model.CreateBookingRequest) (*model.Booking, error) {
+	id, err := model.NewBookingID()
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate booking id: %w", err)
+	}
+
 	booking := &model.Booking{
+		ID:          id,
 		UserID:      req.UserID,
 		ResourceID:  req.ResourceID,
 		TotalAmount: req.TotalAmount,
-		Status:      "PENDING",
+		Status:      model.StatusPending,
 	}
 
-	if err := s.repo.Create(ctx, booking); err != nil {
+	// Stored in the outbox with the booking; the relay publishes it
+	event, err := model.NewOutboxEvent("booking.created", booking.ID, model.BookingCreatedEvent{
+		BookingID:    booking.ID,
+		UserID:       booking.UserID,
+		ResourceID:   booking.ResourceID,
+		TotalAmount:  booking.TotalAmount,
+		PaymentToken: req.PaymentToken,
+	})
+	if err != nil {
 		return nil, err
 	}
 
-	// Publish event
-	event := model.BookingCreatedEvent{
-		BookingID:   booking.ID,
-		UserID:      booking.UserID,
-		ResourceID:  booking.ResourceID,
-		TotalAmount: booking.TotalAmount,
+	// Hold a slot first so a sold-out listing is rejected up front. From
+	// here on the hold is released by the inventory.release event stored
+	// when the booking ends unused.
+	hold, err := s.inventory.Hold(ctx, booking.ResourceID, booking.ID)
+	if err != nil {
+		return nil, err
 	}
+	booking.TripStartDate = hold.TripStart
 
-	go func() {
-		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-		defer cancel()
-		if err := s.producer.Publish(ctx, "booking.created", booking.ID, event); err != nil {
-			fmt.Printf("Failed to publish booking.created event: %v\n", err)
+	if err := s.repo.Create(ctx, booking, event); err != nil {
+		// The booking was not stored, so nothing would ever release the hold
+		if rerr := s.inventory.Release(context.WithoutCancel(ctx), booking.ResourceID, booking.ID); rerr != nil {
+			log.Printf("Failed to release hold for unsaved booking %s: %v", booking.ID, rerr)
 		}
-	}()
+		return nil, err
+	}
 
 	return booking, nil
 }
@@ -62,14 +85,214 @@ func (s *bookingService) GetBooking(ctx context.Context, id string) (*model.Book
 	return s.repo.GetByID(ctx, id)
 }
 
-func (s *bookingService) ConfirmBooking(ctx context.Context, bookingID string) error {
+func (s *bookingService) ListBookings(ctx context.Context, params *model.BookingListParams) (*model.BookingPage, error) {
+	limit := params.Limit
+	// Fetch one extra row to learn whether another page exists
+	params.Limit = limit + 1
+	bookings, err := s.repo.GetByUserID(ctx, params)
+	params.Limit = limit
+	if err != nil {
+		return nil, err
+	}
+
+	page := &model.BookingPage{Data: bookings}
+	if len(bookings) > limit {
+		page.Data = bookings[:limit]
+		last := page.Data[limit-1]
+		page.NextCursor = model.BookingCursor{CreatedAt: last.CreatedAt, ID: last.ID}.Encode()
+	}
+	if page.Data == nil {
+		page.Data = []model.Booking{}
+	}
+	return page, nil
+}
+
+func (s *bookingService) GetBookingHistory(ctx context.Context, id string) ([]model.StatusHistoryEntry, error) {
+	history, err := s.repo.GetStatusHistory(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	// Every booking gets a history row on creation
+	if len(history) == 0 {
+		return nil, model.ErrBookingNotFound
+	}
+	return history, nil
+}
+
+func (s *bookingService) ConfirmBooking(ctx context.Context, bookingID, eventID string) error {
 	log.Printf("Confirming booking %s", bookingID)
-	// Additional logic here: e.g. send confirmation email event
-	return s.repo.UpdateStatus(ctx, bookingID, "CONFIRMED")
+	// notification-service emails the confirmation from payment.success
+	return s.transition(ctx, model.StatusTransition{
+		BookingID: bookingID,
+		To:        model.StatusConfirmed,
+		From:      []model.BookingStatus{model.StatusPending, model.StatusAwaitingPayment},
+		Cause:     "payment.success",
+		EventID:   eventID,
+	})
 }
 
-func (s *bookingService) CancelBooking(ctx context.Context, bookingID string) error {
+func (s *bookingService) CancelBooking(ctx context.Context, bookingID, eventID string) error {
 	log.Printf("Cancelling booking %s", bookingID)
-	// Additional logic here: e.g. revert resource reservation
-	return s.repo.UpdateStatus(ctx, bookingID, "CANCELLED")
+	release, err := s.releaseEventFor(ctx, bookingID, "payment.failed")
+	if err != nil {
+		return err
+	}
+	return s.transition(ctx, model.StatusTransition{
+		BookingID: bookingID,
+		To:        model.StatusCancelled,
+		From:      []model.BookingStatus{model.StatusPending, model.StatusAwaitingPayment},
+		Cause:     "payment.failed",
+		EventID:   eventID,
+	}, release)
+}
+
+// RequestCancellation cancels a confirmed booking on behalf of the traveller.
+// When the cancellation policy grants a refund the booking waits in
+// CANCELLATION_REQUESTED for the payment service; otherwise it is cancelled
+// straight away.
+func (s *bookingService) RequestCancellation(ctx context.Context, bookingID, reason string) (*model.Booking, error) {
+	booking, err := s.repo.GetByID(ctx, bookingID)
+	if err != nil {
+		return nil, err
+	}
+	if booking == nil {
+		return nil, model.ErrBookingNotFound
+	}
+
+	refund := s.policy.RefundAmount(booking, time.Now())
+	log.Printf("Cancellation requested for booking %s, refunding %.2f of %.2f", bookingID, refund, booking.TotalAmount)
+
+	if refund <= 0 {
+		event, err := model.NewOutboxEvent("booking.cancelled", booking.ID, model.BookingCancelledEvent{
+			BookingID: booking.ID,
+			UserID:    booking.UserID,
+			Reason:    reason,
+		})
+		if err != nil {
+			return nil, err
+		}
+		release, err := releaseEvent(booking, "user.cancel")
+		if err != nil {
+			return nil, err
+		}
+		return s.repo.UpdateStatus(ctx, model.StatusTransition{
+			BookingID: bookingID,
+			To:        model.StatusCancelled,
+			From:      []model.BookingStatus{model.StatusConfirmed},
+			Cause:     "user.cancel",
+		}, event, release)
+	}
+
+	requestID, err := model.NewBookingID()
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate cancellation request id: %w", err)
+	}
+	event, err := model.NewOutboxEvent("booking.cancel_requested", booking.ID, model.BookingCancelRequestedEvent{
+		RequestID:    requestID,
+		BookingID:    booking.ID,
+		UserID:       booking.UserID,
+		TotalAmount:  booking.TotalAmount,
+		RefundAmount: refund,
+		Reason:       reason,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return s.repo.UpdateStatus(ctx, model.StatusTransition{
+		BookingID: bookingID,
+		To:        model.StatusCancellationRequested,
+		Cause:     "user.cancel",
+		EventID:   requestID,
+	}, event)
+}
+
+func (s *bookingService) CompleteRefund(ctx context.Context, bookingID, eventID string) error {
+	log.Printf("Refund completed for booking %s", bookingID)
+	release, err := s.releaseEventFor(ctx, bookingID, "payment.refunded")
+	if err != nil {
+		return err
+	}
+	return s.transition(ctx, model.StatusTransition{
+		BookingID: bookingID,
+		To:        model.StatusRefunded,
+		Cause:     "payment.refunded",
+		EventID:   eventID,
+	}, release)
+}
+
+func (s *bookingService) FailRefund(ctx context.Context, bookingID, eventID string) error {
+	log.Printf("Refund failed for booking %s, restoring confirmation", bookingID)
+	return s.transition(ctx, model.StatusTransition{
+		BookingID: bookingID,
+		To:        model.StatusConfirmed,
+		From:      []model.BookingStatus{model.StatusCancellationRequested},
+		Cause:     "payment.refund_failed",
+		EventID:   eventID,
+	})
+}
+
+// releaseEventFor builds the inventory.release event for a booking that is
+// about to end unused.
+func (s *bookingService) releaseEventFor(ctx context.Context, bookingID, reason string) (*model.OutboxEvent, error) {
+	booking, err := s.repo.GetByID(ctx, bookingID)
+	if err != nil {
+		return nil, err
+	}
+	if booking == nil {
+		return nil, model.ErrBookingNotFound
+	}
+	return releaseEvent(booking, reason)
+}
+
+// releaseEvent gives the booking's slot back to its listing. It is stored
+// with the status change, so the slot is released if and only if the
+// booking really ended.
+func releaseEvent(b *model.Booking, reason string) (*model.OutboxEvent, error) {
+	return model.NewOutboxEvent("inventory.release", b.ID, model.InventoryReleaseEvent{
+		HoldID:    b.ID,
+		ListingID: b.ResourceID,
+		Reason:    reason,
+	})
+}
+
+// transition applies t, treating a booking that is already in the target
+// status as success so redelivered events are harmless.
+func (s *bookingService) transition(ctx context.Context, t model.StatusTransition, events ...*model.OutboxEvent) error {
+	_, err := s.repo.UpdateStatus(ctx, t, events...)
+
+	var terr *model.TransitionError
+	if errors.As(err, &terr) && terr.From == t.To {
+		log.Printf("Booking %s already %s, ignoring %s", t.BookingID, t.To, t.Cause)
+		return nil
+	}
+	return err
+}
+
+func (s *bookingService) ExpirePendingBookings(ctx context.Context, olderThan time.Duration, limit int) (int, error) {
+	cutoff := time.Now().Add(-olderThan)
+	expired, err := s.repo.ExpirePending(ctx, cutoff, limit, func(b *model.Booking) ([]*model.OutboxEvent, error) {
+		expiredEvent, err := model.NewOutboxEvent("booking.expired", b.ID, model.BookingExpiredEvent{
+			BookingID:   b.ID,
+			UserID:      b.UserID,
+			ResourceID:  b.ResourceID,
+			TotalAmount: b.TotalAmount,
+			ExpiredAt:   b.UpdatedAt,
+		})
+		if err != nil {
+			return nil, err
+		}
+		release, err := releaseEvent(b, "timeout")
+		if err != nil {
+			return nil, err
+		}
+		return []*model.OutboxEvent{expiredEvent, release}, nil
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	for _, b := range expired {
+		log.Printf("Expired booking %s (pending since %s)", b.ID, b.CreatedAt.Format(time.RFC3339))
+	}
+	return len(expired), nil
 }
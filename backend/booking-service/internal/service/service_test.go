@@ -0,0 +1,129 @@
+package service
+
+import (
+	"context"
+	"slices"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/gavinadlan/tripnest/backend/booking-service/internal/model"
+	"github.com/gavinadlan/tripnest/backend/booking-service/internal/repository"
+)
+
+// fakeRepo pages bookings the way the Postgres keyset query does.
+type fakeRepo struct {
+	repository.BookingRepository
+	bookings []model.Booking
+	limits   []int
+}
+
+func (r *fakeRepo) GetByUserID(ctx context.Context, params *model.BookingListParams) ([]model.Booking, error) {
+	r.limits = append(r.limits, params.Limit)
+	rows := slices.Clone(r.bookings)
+	sort.Slice(rows, func(i, j int) bool {
+		a, b := rows[i], rows[j]
+		if params.Ascending {
+			a, b = b, a
+		}
+		if !a.CreatedAt.Equal(b.CreatedAt) {
+			return a.CreatedAt.After(b.CreatedAt)
+		}
+		return a.ID > b.ID
+	})
+
+	var out []model.Booking
+	for _, b := range rows {
+		if b.UserID != params.UserID {
+			continue
+		}
+		if after := params.After; after != nil {
+			// (created_at, id) < or > the cursor
+			past := b.CreatedAt.Before(after.CreatedAt) || (b.CreatedAt.Equal(after.CreatedAt) && b.ID < after.ID)
+			if params.Ascending {
+				past = b.CreatedAt.After(after.CreatedAt) || (b.CreatedAt.Equal(after.CreatedAt) && b.ID > after.ID)
+			}
+			if !past {
+				continue
+			}
+		}
+		if len(out) == params.Limit {
+			break
+		}
+		out = append(out, b)
+	}
+	return out, nil
+}
+
+func TestListBookingsPages(t *testing.T) {
+	base := time.Date(2026, 7, 1, 12, 0, 0, 0, time.UTC)
+	repo := &fakeRepo{bookings: []model.Booking{
+		{ID: "b1", UserID: "u1", CreatedAt: base},
+		// b2 to b4 share a timestamp and straddle a page boundary
+		{ID: "b2", UserID: "u1", CreatedAt: base.Add(time.Minute)},
+		{ID: "b3", UserID: "u1", CreatedAt: base.Add(time.Minute)},
+		{ID: "b4", UserID: "u1", CreatedAt: base.Add(time.Minute)},
+		{ID: "b5", UserID: "u1", CreatedAt: base.Add(time.Hour)},
+		{ID: "x1", UserID: "u2", CreatedAt: base.Add(time.Minute)},
+	}}
+	svc := NewBookingService(repo, nil, model.CancellationPolicy{})
+
+	for _, ascending := range []bool{false, true} {
+		params := &model.BookingListParams{UserID: "u1", Limit: 2, Ascending: ascending}
+		var ids []string
+		pages := 0
+		for {
+			page, err := svc.ListBookings(context.Background(), params)
+			if err != nil {
+				t.Fatal(err)
+			}
+			pages++
+			if len(page.Data) > 2 {
+				t.Fatalf("page of %d", len(page.Data))
+			}
+			for _, b := range page.Data {
+				ids = append(ids, b.ID)
+			}
+			if page.NextCursor == "" {
+				break
+			}
+			if params.After, err = model.DecodeBookingCursor(page.NextCursor); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		want := []string{"b5", "b4", "b3", "b2", "b1"}
+		if ascending {
+			slices.Reverse(want)
+		}
+		if !slices.Equal(ids, want) || pages != 3 {
+			t.Errorf("ascending %t: listed %v in %d pages, want %v in 3", ascending, ids, pages, want)
+		}
+		if params.Limit != 2 {
+			t.Errorf("caller's limit changed to %d", params.Limit)
+		}
+	}
+	// One extra row tells whether another page exists
+	if repo.limits[0] != 3 {
+		t.Errorf("fetched %d rows for a page of 2", repo.limits[0])
+	}
+}
+
+func TestListBookingsExactPage(t *testing.T) {
+	repo := &fakeRepo{bookings: []model.Booking{
+		{ID: "b1", UserID: "u1", CreatedAt: time.Now()},
+		{ID: "b2", UserID: "u1", CreatedAt: time.Now()},
+	}}
+	svc := NewBookingService(repo, nil, model.CancellationPolicy{})
+
+	page, err := svc.ListBookings(context.Background(), &model.BookingListParams{UserID: "u1", Limit: 2})
+	if err != nil || len(page.Data) != 2 || page.NextCursor != "" {
+		t.Fatalf("page %+v, %v, want two bookings and no cursor", page, err)
+	}
+
+	// No bookings is an empty list, not null
+	page, err = svc.ListBookings(context.Background(), &model.BookingListParams{UserID: "u2", Limit: 2})
+	if err != nil || page.Data == nil || page.NextCursor != "" {
+		t.Fatalf("empty page %+v, %v", page, err)
+	}
+}
@@ -0,0 +1 @@
+CREATE INDEX IF NOT EXISTS idx_bookings_pending_created_at ON bookings(created_at) WHERE status = 'PENDING';
@@ -0,0 +1,11 @@
+CREATE TABLE IF NOT EXISTS booking_status_history (
+    id BIGSERIAL PRIMARY KEY,
+    booking_id UUID NOT NULL REFERENCES bookings(id) ON DELETE CASCADE,
+    from_status VARCHAR(50),
+    to_status VARCHAR(50) NOT NULL,
+    cause VARCHAR(255) NOT NULL,
+    event_id VARCHAR(255),
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_booking_status_history_booking_id ON booking_status_history(booking_id, id);
@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_bookings_status_created_at;
+CREATE INDEX IF NOT EXISTS idx_bookings_pending_created_at ON bookings(created_at) WHERE status = 'PENDING';
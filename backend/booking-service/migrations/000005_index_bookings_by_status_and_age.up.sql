@@ -0,0 +1,4 @@
+-- The timeout sweeper looks up bookings in any waiting status by age, which
+-- the PENDING-only partial index cannot serve.
+DROP INDEX IF EXISTS idx_bookings_pending_created_at;
+CREATE INDEX IF NOT EXISTS idx_bookings_status_created_at ON bookings(status, created_at);
@@ -0,0 +1,3 @@
+-- Start date of the booked trip, copied from the listing when its slot is
+-- held; the cancellation policy counts from it. NULL for older bookings.
+ALTER TABLE bookings ADD COLUMN IF NOT EXISTS trip_start_date DATE;
@@ -0,0 +1,63 @@
+package auth
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gavinadlan/tripnest/backend/common/utils"
+)
+
+// Headers the api-gateway uses to forward the authenticated principal to
+// upstream services. The gateway strips them from client requests first.
+const (
+	HeaderUserID   = "X-User-ID"
+	HeaderUserRole = "X-User-Role"
+)
+
+// SetPrincipalHeaders replaces the principal headers of h with p.
+func SetPrincipalHeaders(h http.Header, p Principal) {
+	h.Set(HeaderUserID, p.UserID)
+	h.Set(HeaderUserRole, p.Role)
+}
+
+// ClearPrincipalHeaders removes the principal headers from h.
+func ClearPrincipalHeaders(h http.Header) {
+	h.Del(HeaderUserID)
+	h.Del(HeaderUserRole)
+}
+
+// TrustGatewayHeaders is the counterpart of Authenticate for services that
+// are only reachable through the api-gateway: it takes the Principal from
+// the headers set by the gateway instead of verifying a token again.
+func TrustGatewayHeaders() func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			userID := r.Header.Get(HeaderUserID)
+			if userID == "" {
+				utils.WriteError(w, http.StatusUnauthorized, errors.New("authentication required"))
+				return
+			}
+
+			ctx := WithPrincipal(r.Context(), Principal{UserID: userID, Role: r.Header.Get(HeaderUserRole)})
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+// IdentifyGatewayHeaders is the counterpart of Identify for services behind
+// the api-gateway: it takes the Principal from the gateway's headers when
+// they are present and lets anonymous requests through.
+func IdentifyGatewayHeaders() func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			userID := r.Header.Get(HeaderUserID)
+			if userID == "" {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			ctx := WithPrincipal(r.Context(), Principal{UserID: userID, Role: r.Header.Get(HeaderUserRole)})
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
@@ -0,0 +1,79 @@
+package auth
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestGatewayHeaders(t *testing.T) {
+	cases := []struct {
+		name      string
+		header    http.Header
+		principal string
+	}{
+		{"user", http.Header{HeaderUserID: {"u1"}, HeaderUserRole: {"user"}}, "u1/user"},
+		{"admin", http.Header{HeaderUserID: {"u2"}, HeaderUserRole: {RoleAdmin}}, "u2/admin"},
+		{"without role", http.Header{HeaderUserID: {"u1"}}, "u1/"},
+		{"anonymous", nil, ""},
+		{"role without user", http.Header{HeaderUserRole: {RoleAdmin}}, ""},
+		// Tokens are the gateway's business; they never identify anyone here
+		{"bearer token only", http.Header{"Authorization": {"Bearer x.y.z"}}, ""},
+	}
+
+	trust, identify := TrustGatewayHeaders()(echoPrincipal), IdentifyGatewayHeaders()(echoPrincipal)
+	for _, tc := range cases {
+		want := http.StatusOK
+		if tc.principal == "" {
+			want = http.StatusUnauthorized
+		}
+		w := serve(trust, tc.header)
+		if w.Code != want || w.Header().Get("X-Principal") != tc.principal {
+			t.Errorf("TrustGatewayHeaders, %s: status %d as %q, want %d as %q", tc.name, w.Code, w.Header().Get("X-Principal"), want, tc.principal)
+		}
+
+		w = serve(identify, tc.header)
+		if w.Code != http.StatusOK || w.Header().Get("X-Principal") != tc.principal {
+			t.Errorf("IdentifyGatewayHeaders, %s: status %d as %q, want 200 as %q", tc.name, w.Code, w.Header().Get("X-Principal"), tc.principal)
+		}
+	}
+}
+
+func TestPrincipalHeaders(t *testing.T) {
+	h := http.Header{}
+	h.Set(HeaderUserID, "spoofed")
+	h.Set(HeaderUserRole, RoleAdmin)
+	ClearPrincipalHeaders(h)
+	if len(h) != 0 {
+		t.Fatalf("cleared headers %v", h)
+	}
+
+	SetPrincipalHeaders(h, Principal{UserID: "u1", Role: "user"})
+	w := serve(TrustGatewayHeaders()(echoPrincipal), h)
+	if got := w.Header().Get("X-Principal"); got != "u1/user" {
+		t.Fatalf("principal %q after a round trip through the headers", got)
+	}
+}
+
+func TestRequireServiceToken(t *testing.T) {
+	cases := []struct {
+		name       string
+		configured string
+		presented  string
+		want       int
+	}{
+		{"matching", "secret", "secret", http.StatusOK},
+		{"wrong", "secret", "guess", http.StatusUnauthorized},
+		{"missing", "secret", "", http.StatusUnauthorized},
+		{"disabled", "", "", http.StatusForbidden},
+		{"disabled, token presented", "", "secret", http.StatusForbidden},
+	}
+	for _, tc := range cases {
+		header := http.Header{}
+		if tc.presented != "" {
+			header.Set(HeaderServiceToken, tc.presented)
+		}
+		if w := serve(RequireServiceToken(tc.configured)(echoPrincipal), header); w.Code != tc.want {
+			t.Errorf("%s: status %d, want %d", tc.name, w.Code, tc.want)
+		}
+	}
+}
@@ -0,0 +1,98 @@
+package auth
+
+import (
+	"crypto"
+	"encoding/json"
+	"errors"
+	"testing"
+)
+
+func TestThumbprint(t *testing.T) {
+	// The examples of RFC 7638 section 3.1 and RFC 8037 appendix A.3
+	cases := []struct {
+		name string
+		jwk  JWK
+		want string
+	}{
+		{
+			"RSA",
+			JWK{
+				Kty: "RSA",
+				N:   "0vx7agoebGcQSuuPiLJXZptN9nndrQmbXEps2aiAFbWhM78LhWx4cbbfAAtVT86zwu1RK7aPFFxuhDR1L6tSoc_BJECPebWKRXjBZCiFV4n3oknjhMstn64tZ_2W-5JsGY4Hc5n9yBXArwl93lqt7_RN5w6Cf0h4QyQ5v-65YGjQR0_FDW2QvzqY368QQMicAtaSqzs8KJZgnYb9c7d0zgdAZHzu6qMQvRL5hajrn1n91CbOpbISD08qNLyrdkt-bFTWhAI4vMQFh6WeZu0fM4lFd2NcRwr3XPksINHaQ-G_xBniIqbw0Ls1jF44-csFCur-kEgU8awapJzKnqDKgw",
+				E:   "AQAB",
+			},
+			"NzbLsXh8uDCcd-6MNwXF4W_7noWXFZAfHkxZsRGC9Xs",
+		},
+		{
+			"Ed25519",
+			JWK{Kty: "OKP", Crv: "Ed25519", X: "11qYAYKxCrfVS_7TyWQHOg7hcvPapiMlrwIaaPcHURo"},
+			"kPrK_qmxVWaYVA9wwBF6Iuo3vVzz7TxHCTwXBygrS4k",
+		},
+	}
+	for _, tc := range cases {
+		pub, err := tc.jwk.PublicKey()
+		if err != nil {
+			t.Fatalf("%s: %v", tc.name, err)
+		}
+		got, err := Thumbprint(pub)
+		if err != nil {
+			t.Fatalf("%s: %v", tc.name, err)
+		}
+		if got != tc.want {
+			t.Errorf("%s: thumbprint %s, want %s", tc.name, got, tc.want)
+		}
+	}
+}
+
+func TestJWKRoundTrip(t *testing.T) {
+	keys := map[string]crypto.PublicKey{
+		"RSA":     &testRSAKey(t).PublicKey,
+		"Ed25519": testEdKey(t).Public(),
+	}
+	for name, pub := range keys {
+		jwk, err := NewJWK("kid-1", pub)
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		data, err := json.Marshal(JWKS{Keys: []JWK{jwk}})
+		if err != nil {
+			t.Fatal(err)
+		}
+		var set JWKS
+		if err := json.Unmarshal(data, &set); err != nil {
+			t.Fatal(err)
+		}
+
+		got := set.Keys[0]
+		if got.Kid != "kid-1" || got.Use != "sig" {
+			t.Errorf("%s: decoded %+v", name, got)
+		}
+		decoded, err := got.PublicKey()
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		if !decoded.(interface{ Equal(crypto.PublicKey) bool }).Equal(pub) {
+			t.Errorf("%s: round trip changed the key", name)
+		}
+	}
+
+	if _, err := NewJWK("kid-1", "not a key"); !errors.Is(err, ErrUnsupportedKey) {
+		t.Errorf("NewJWK of an unsupported key: %v", err)
+	}
+}
+
+func TestJWKPublicKeyRejects(t *testing.T) {
+	for name, jwk := range map[string]JWK{
+		"EC":                  {Kty: "EC", Crv: "P-256", X: "AAAA", N: "AAAA"},
+		"X25519":              {Kty: "OKP", Crv: "X25519", X: "11qYAYKxCrfVS_7TyWQHOg7hcvPapiMlrwIaaPcHURo"},
+		"short Ed25519 key":   {Kty: "OKP", Crv: "Ed25519", X: "11qYAYKx"},
+		"padded Ed25519 key":  {Kty: "OKP", Crv: "Ed25519", X: "11qYAYKxCrfVS_7TyWQHOg7hcvPapiMlrwIaaPcHURo="},
+		"RSA modulus base64":  {Kty: "RSA", N: "not base64!", E: "AQAB"},
+		"RSA exponent base64": {Kty: "RSA", N: "AQAB", E: "not base64!"},
+		"no type":             {},
+	} {
+		if key, err := jwk.PublicKey(); err == nil {
+			t.Errorf("%s: decoded %v", name, key)
+		}
+	}
+}
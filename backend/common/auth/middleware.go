@@ -0,0 +1,89 @@
+package auth
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/gavinadlan/tripnest/backend/common/utils"
+)
+
+// Authenticate rejects requests without a valid bearer token and stores the
+// caller's Principal in the request context.
+func Authenticate(v Verifier) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			token, ok := bearerToken(r)
+			if !ok {
+				utils.WriteError(w, http.StatusUnauthorized, errors.New("missing bearer token"))
+				return
+			}
+
+			claims, err := v.Verify(token)
+			if err != nil {
+				utils.WriteError(w, http.StatusUnauthorized, ErrInvalidToken)
+				return
+			}
+
+			ctx := WithPrincipal(r.Context(), Principal{UserID: claims.UserID, Role: claims.Role})
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+// Identify stores the caller's Principal in the request context when the
+// request carries a valid bearer token and lets every other request through
+// as anonymous, so public routes keep working for a client whose token has
+// expired. Routes needing a caller reject anonymous requests themselves
+// (RequireRole, or the gateway's RequireAuth routes).
+func Identify(v Verifier) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			token, ok := bearerToken(r)
+			if !ok {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			claims, err := v.Verify(token)
+			if err != nil {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			ctx := WithPrincipal(r.Context(), Principal{UserID: claims.UserID, Role: claims.Role})
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+// RequireRole allows the request through only if the authenticated principal
+// has one of roles. It must run after Authenticate, e.g.
+// r.With(auth.RequireRole(auth.RoleAdmin)).Delete("/x", h).
+func RequireRole(roles ...string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			p, ok := PrincipalFromContext(r.Context())
+			if !ok {
+				utils.WriteError(w, http.StatusUnauthorized, errors.New("authentication required"))
+				return
+			}
+			for _, role := range roles {
+				if p.Role == role {
+					next.ServeHTTP(w, r)
+					return
+				}
+			}
+			utils.WriteError(w, http.StatusForbidden, errors.New("insufficient permissions"))
+		})
+	}
+}
+
+func bearerToken(r *http.Request) (string, bool) {
+	header := r.Header.Get("Authorization")
+	scheme, token, ok := strings.Cut(header, " ")
+	if !ok || !strings.EqualFold(scheme, "Bearer") || token == "" {
+		return "", false
+	}
+	return strings.TrimSpace(token), true
+}
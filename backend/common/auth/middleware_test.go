@@ -0,0 +1,117 @@
+package auth
+
+import (
+	"crypto"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// echoPrincipal answers 200, naming the caller in X-Principal unless the
+// request is anonymous.
+var echoPrincipal = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+	if p, ok := PrincipalFromContext(r.Context()); ok {
+		w.Header().Set("X-Principal", p.UserID+"/"+p.Role)
+	}
+	w.WriteHeader(http.StatusOK)
+})
+
+func serve(h http.Handler, header http.Header) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	for k, values := range header {
+		for _, v := range values {
+			req.Header.Add(k, v)
+		}
+	}
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, req)
+	return w
+}
+
+type tokenCase struct {
+	name   string
+	header http.Header
+	// principal is the caller the token identifies, empty if it is rejected
+	principal string
+}
+
+// tokenCases returns a verifier for user-service tokens and requests to
+// present to it.
+func tokenCases(t *testing.T) (Verifier, []tokenCase) {
+	priv := testEdKey(t)
+	v := NewStaticVerifier(map[string]crypto.PublicKey{"k1": priv.Public()}, "user-service")
+	bearer := func(claims Claims) http.Header {
+		return http.Header{"Authorization": {"Bearer " + signToken(t, jwt.SigningMethodEdDSA, priv, "k1", claims)}}
+	}
+	admin := testClaims("u2", "user-service", time.Minute)
+	admin.Role = RoleAdmin
+	forged := signToken(t, jwt.SigningMethodEdDSA, testEdKey(t), "k1", testClaims("u1", "user-service", time.Minute))
+
+	return v, []tokenCase{
+		{"valid", bearer(testClaims("u1", "user-service", time.Minute)), "u1/user"},
+		{"admin", bearer(admin), "u2/admin"},
+		{"missing", nil, ""},
+		{"not bearer", http.Header{"Authorization": {"Basic dTE6cHc="}}, ""},
+		{"empty bearer", http.Header{"Authorization": {"Bearer "}}, ""},
+		{"malformed", http.Header{"Authorization": {"Bearer not.a.jwt"}}, ""},
+		{"expired", bearer(testClaims("u1", "user-service", -time.Minute)), ""},
+		{"wrong issuer", bearer(testClaims("u1", "someone-else", time.Minute)), ""},
+		{"forged", http.Header{"Authorization": {"Bearer " + forged}}, ""},
+	}
+}
+
+func TestAuthenticate(t *testing.T) {
+	v, cases := tokenCases(t)
+	h := Authenticate(v)(echoPrincipal)
+
+	for _, tc := range cases {
+		w := serve(h, tc.header)
+		want := http.StatusOK
+		if tc.principal == "" {
+			want = http.StatusUnauthorized
+		}
+		if w.Code != want || w.Header().Get("X-Principal") != tc.principal {
+			t.Errorf("%s: status %d as %q, want %d as %q", tc.name, w.Code, w.Header().Get("X-Principal"), want, tc.principal)
+		}
+	}
+}
+
+func TestIdentify(t *testing.T) {
+	v, cases := tokenCases(t)
+	h := Identify(v)(echoPrincipal)
+
+	// Rejected tokens leave the request anonymous rather than failing it
+	for _, tc := range cases {
+		w := serve(h, tc.header)
+		if w.Code != http.StatusOK || w.Header().Get("X-Principal") != tc.principal {
+			t.Errorf("%s: status %d as %q, want 200 as %q", tc.name, w.Code, w.Header().Get("X-Principal"), tc.principal)
+		}
+	}
+}
+
+func TestRequireRole(t *testing.T) {
+	v, cases := tokenCases(t)
+	h := Identify(v)(RequireRole(RoleAdmin)(echoPrincipal))
+
+	for _, tc := range cases {
+		want := http.StatusUnauthorized
+		switch tc.principal {
+		case "u2/admin":
+			want = http.StatusOK
+		case "u1/user":
+			want = http.StatusForbidden
+		}
+		if w := serve(h, tc.header); w.Code != want {
+			t.Errorf("%s: status %d, want %d", tc.name, w.Code, want)
+		}
+	}
+
+	// Any of several roles passes
+	h = Authenticate(v)(RequireRole(RoleAdmin, "user")(echoPrincipal))
+	if w := serve(h, cases[0].header); w.Code != http.StatusOK {
+		t.Errorf("user among the allowed roles: status %d", w.Code)
+	}
+}
@@ -0,0 +1,32 @@
+package auth
+
+import (
+	"crypto/subtle"
+	"errors"
+	"net/http"
+
+	"github.com/gavinadlan/tripnest/backend/common/utils"
+)
+
+// HeaderServiceToken carries the shared secret services present when
+// calling each other's internal endpoints.
+const HeaderServiceToken = "X-Service-Token"
+
+// RequireServiceToken only lets requests presenting token through. An empty
+// token disables the routes it guards (403 for every caller).
+func RequireServiceToken(token string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if token == "" {
+				utils.WriteError(w, http.StatusForbidden, errors.New("internal endpoints are disabled"))
+				return
+			}
+			got := r.Header.Get(HeaderServiceToken)
+			if subtle.ConstantTimeCompare([]byte(got), []byte(token)) != 1 {
+				utils.WriteError(w, http.StatusUnauthorized, errors.New("invalid service token"))
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
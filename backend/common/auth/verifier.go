@@ -0,0 +1,217 @@
+package auth
+
+import (
+	"context"
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// KeyFunc returns the public key registered under kid.
+type KeyFunc func(kid string) (crypto.PublicKey, error)
+
+type keyVerifier struct {
+	keys   KeyFunc
+	issuer string
+}
+
+// NewVerifier verifies RS256 and EdDSA tokens whose "kid" header resolves
+// through keys. An empty issuer disables the issuer check.
+func NewVerifier(keys KeyFunc, issuer string) Verifier {
+	return &keyVerifier{keys: keys, issuer: issuer}
+}
+
+// NewStaticVerifier verifies tokens against a fixed set of public keys.
+func NewStaticVerifier(keys map[string]crypto.PublicKey, issuer string) Verifier {
+	return NewVerifier(func(kid string) (crypto.PublicKey, error) {
+		key, ok := keys[kid]
+		if !ok {
+			return nil, fmt.Errorf("unknown key id %q", kid)
+		}
+		return key, nil
+	}, issuer)
+}
+
+func (v *keyVerifier) Verify(tokenString string) (*Claims, error) {
+	opts := []jwt.ParserOption{
+		jwt.WithValidMethods([]string{jwt.SigningMethodRS256.Alg(), jwt.SigningMethodEdDSA.Alg()}),
+		jwt.WithExpirationRequired(),
+	}
+	if v.issuer != "" {
+		opts = append(opts, jwt.WithIssuer(v.issuer))
+	}
+
+	var claims Claims
+	_, err := jwt.ParseWithClaims(tokenString, &claims, func(t *jwt.Token) (interface{}, error) {
+		kid, _ := t.Header["kid"].(string)
+		key, err := v.keys(kid)
+		if err != nil {
+			return nil, err
+		}
+		// Bind the algorithm to the key type so an RSA key can never be
+		// used to check an EdDSA signature or vice versa.
+		switch key.(type) {
+		case *rsa.PublicKey:
+			if t.Method != jwt.SigningMethodRS256 {
+				return nil, fmt.Errorf("key %q requires RS256", kid)
+			}
+		case ed25519.PublicKey:
+			if t.Method != jwt.SigningMethodEdDSA {
+				return nil, fmt.Errorf("key %q requires EdDSA", kid)
+			}
+		default:
+			return nil, ErrUnsupportedKey
+		}
+		return key, nil
+	}, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidToken, err)
+	}
+	if claims.UserID == "" {
+		return nil, fmt.Errorf("%w: missing user_id", ErrInvalidToken)
+	}
+	return &claims, nil
+}
+
+// JWKSCache fetches and caches the key set published by user-service. Keys
+// are refreshed every refreshInterval and, at most once per minRefetch,
+// whenever a token names a kid that is not cached yet (i.e. after a rotation).
+// Fetches run outside mu, one at a time, so verifying tokens with cached keys
+// never waits on the key server.
+type JWKSCache struct {
+	url             string
+	client          *http.Client
+	refreshInterval time.Duration
+	minRefetch      time.Duration
+
+	refreshMu sync.Mutex // Held while fetching
+
+	mu          sync.Mutex
+	keys        map[string]crypto.PublicKey
+	fetchedAt   time.Time
+	lastAttempt time.Time
+	fetching    bool
+}
+
+func NewJWKSCache(url string, refreshInterval time.Duration) *JWKSCache {
+	return &JWKSCache{
+		url:             url,
+		client:          &http.Client{Timeout: 5 * time.Second},
+		refreshInterval: refreshInterval,
+		minRefetch:      30 * time.Second,
+		keys:            make(map[string]crypto.PublicKey),
+	}
+}
+
+// NewJWKSVerifier verifies tokens against the key set published at url.
+func NewJWKSVerifier(url, issuer string, refreshInterval time.Duration) Verifier {
+	return NewVerifier(NewJWKSCache(url, refreshInterval).Key, issuer)
+}
+
+// Key implements KeyFunc.
+func (c *JWKSCache) Key(kid string) (crypto.PublicKey, error) {
+	key, ok, due := c.lookup(kid)
+	if due {
+		if ok {
+			// Stale but usable: refresh unless another caller already is
+			if c.refreshMu.TryLock() {
+				c.refresh()
+				c.refreshMu.Unlock()
+			}
+		} else {
+			// Unknown: wait for the fetch in flight, if any, rather than
+			// starting another one
+			c.refreshMu.Lock()
+			c.refresh()
+			c.refreshMu.Unlock()
+		}
+		key, ok, _ = c.lookup(kid)
+	}
+	if !ok {
+		return nil, fmt.Errorf("unknown key id %q", kid)
+	}
+	return key, nil
+}
+
+// lookup returns the cached key for kid and whether to refresh the key set,
+// or wait for the refresh in flight, on its account.
+func (c *JWKSCache) lookup(kid string) (crypto.PublicKey, bool, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	key, ok := c.keys[kid]
+	stale := time.Since(c.fetchedAt) > c.refreshInterval
+	return key, ok, (!ok || stale) && (c.fetching || time.Since(c.lastAttempt) > c.minRefetch)
+}
+
+// refresh fetches the key set unless another caller did so within
+// minRefetch. It must be called with refreshMu held.
+func (c *JWKSCache) refresh() {
+	c.mu.Lock()
+	if time.Since(c.lastAttempt) <= c.minRefetch {
+		c.mu.Unlock()
+		return
+	}
+	c.lastAttempt = time.Now()
+	c.fetching = true
+	c.mu.Unlock()
+
+	keys, err := c.fetch()
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.fetching = false
+	if err != nil {
+		// Keep serving cached keys if the key server is unavailable
+		log.Printf("Failed to refresh JWKS from %s: %v", c.url, err)
+		return
+	}
+	c.keys = keys
+	c.fetchedAt = time.Now()
+}
+
+func (c *JWKSCache) fetch() (map[string]crypto.PublicKey, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), c.client.Timeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.url, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+
+	var set JWKS
+	if err := json.NewDecoder(resp.Body).Decode(&set); err != nil {
+		return nil, fmt.Errorf("invalid JWKS: %w", err)
+	}
+
+	keys := make(map[string]crypto.PublicKey, len(set.Keys))
+	for _, jwk := range set.Keys {
+		key, err := jwk.PublicKey()
+		if err != nil {
+			log.Printf("Skipping JWKS key %q: %v", jwk.Kid, err)
+			continue
+		}
+		keys[jwk.Kid] = key
+	}
+	if len(keys) == 0 {
+		// Replacing the cache would reject every token until the next fetch
+		return nil, errors.New("JWKS has no usable keys")
+	}
+	return keys, nil
+}
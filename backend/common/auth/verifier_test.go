@@ -0,0 +1,245 @@
+package auth
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+var (
+	rsaKeyOnce sync.Once
+	rsaKey     *rsa.PrivateKey
+)
+
+// testRSAKey returns a 2048-bit key shared by the tests, generating it once.
+func testRSAKey(t *testing.T) *rsa.PrivateKey {
+	t.Helper()
+	rsaKeyOnce.Do(func() {
+		var err error
+		if rsaKey, err = rsa.GenerateKey(rand.Reader, 2048); err != nil {
+			t.Fatal(err)
+		}
+	})
+	return rsaKey
+}
+
+func testEdKey(t *testing.T) ed25519.PrivateKey {
+	t.Helper()
+	_, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return priv
+}
+
+func testClaims(userID, issuer string, expiresIn time.Duration) Claims {
+	return Claims{
+		UserID: userID,
+		Role:   "user",
+		RegisteredClaims: jwt.RegisteredClaims{
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(expiresIn)),
+			Issuer:    issuer,
+		},
+	}
+}
+
+func signToken(t *testing.T, method jwt.SigningMethod, key interface{}, kid string, claims jwt.Claims) string {
+	t.Helper()
+	token := jwt.NewWithClaims(method, claims)
+	token.Header["kid"] = kid
+	signed, err := token.SignedString(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return signed
+}
+
+func TestVerify(t *testing.T) {
+	rsaPriv, edPriv := testRSAKey(t), testEdKey(t)
+	v := NewStaticVerifier(map[string]crypto.PublicKey{
+		"rsa": &rsaPriv.PublicKey,
+		"ed":  edPriv.Public(),
+	}, "user-service")
+	valid := testClaims("u1", "user-service", time.Minute)
+
+	cases := []struct {
+		name  string
+		token string
+		ok    bool
+	}{
+		{"RS256", signToken(t, jwt.SigningMethodRS256, rsaPriv, "rsa", valid), true},
+		{"EdDSA", signToken(t, jwt.SigningMethodEdDSA, edPriv, "ed", valid), true},
+		{"RS256 under an Ed25519 kid", signToken(t, jwt.SigningMethodRS256, rsaPriv, "ed", valid), false},
+		{"EdDSA under an RSA kid", signToken(t, jwt.SigningMethodEdDSA, edPriv, "rsa", valid), false},
+		{"PS256 with the RSA key", signToken(t, jwt.SigningMethodPS256, rsaPriv, "rsa", valid), false},
+		{"HS256 keyed with the public key", signToken(t, jwt.SigningMethodHS256, rsaPriv.PublicKey.N.Bytes(), "rsa", valid), false},
+		{"unknown kid", signToken(t, jwt.SigningMethodEdDSA, edPriv, "other", valid), false},
+		{"signed by another key", signToken(t, jwt.SigningMethodEdDSA, testEdKey(t), "ed", valid), false},
+		{"expired", signToken(t, jwt.SigningMethodEdDSA, edPriv, "ed", testClaims("u1", "user-service", -time.Minute)), false},
+		{"without expiry", signToken(t, jwt.SigningMethodEdDSA, edPriv, "ed", Claims{UserID: "u1", RegisteredClaims: jwt.RegisteredClaims{Issuer: "user-service"}}), false},
+		{"wrong issuer", signToken(t, jwt.SigningMethodEdDSA, edPriv, "ed", testClaims("u1", "someone-else", time.Minute)), false},
+		{"without user_id", signToken(t, jwt.SigningMethodEdDSA, edPriv, "ed", testClaims("", "user-service", time.Minute)), false},
+		{"malformed", "not.a.jwt", false},
+	}
+	for _, tc := range cases {
+		claims, err := v.Verify(tc.token)
+		if tc.ok && (err != nil || claims.UserID != "u1") {
+			t.Errorf("%s: %+v, %v", tc.name, claims, err)
+		}
+		if !tc.ok && err == nil {
+			t.Errorf("%s: accepted", tc.name)
+		}
+	}
+}
+
+// jwksServer serves the key set set holds and counts the fetches.
+type jwksServer struct {
+	set     atomic.Value // JWKS
+	fetches atomic.Int32
+	// Closed by the handler on entry and waited on before answering, when set
+	entered, release chan struct{}
+}
+
+func newJWKSServer(t *testing.T, keys ...JWK) (*jwksServer, *JWKSCache) {
+	t.Helper()
+	s := &jwksServer{}
+	s.set.Store(JWKS{Keys: keys})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.fetches.Add(1)
+		if s.entered != nil {
+			close(s.entered)
+			<-s.release
+		}
+		json.NewEncoder(w).Encode(s.set.Load())
+	}))
+	t.Cleanup(srv.Close)
+	return s, NewJWKSCache(srv.URL, time.Hour)
+}
+
+func testJWK(t *testing.T, kid string) JWK {
+	t.Helper()
+	jwk, err := NewJWK(kid, testEdKey(t).Public())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return jwk
+}
+
+// expire makes the cached key set stale and lets it be fetched again.
+func (c *JWKSCache) expire() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.fetchedAt = time.Now().Add(-2 * c.refreshInterval)
+	c.lastAttempt = time.Now().Add(-2 * c.minRefetch)
+}
+
+func TestJWKSCacheFetchesUnknownKidOnce(t *testing.T) {
+	srv, cache := newJWKSServer(t, testJWK(t, "k1"))
+
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := cache.Key("k1"); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+	if n := srv.fetches.Load(); n != 1 {
+		t.Fatalf("fetched %d times, want 1", n)
+	}
+}
+
+func TestJWKSCacheRateLimitsUnknownKids(t *testing.T) {
+	srv, cache := newJWKSServer(t, testJWK(t, "k1"))
+
+	for i := 0; i < 3; i++ {
+		if _, err := cache.Key("forged"); err == nil {
+			t.Fatal("unknown kid resolved")
+		}
+	}
+	if n := srv.fetches.Load(); n != 1 {
+		t.Fatalf("fetched %d times for unknown kids, want 1", n)
+	}
+
+	// Once minRefetch has passed, a rotated-in key is picked up
+	srv.set.Store(JWKS{Keys: []JWK{testJWK(t, "k1"), testJWK(t, "k2")}})
+	cache.mu.Lock()
+	cache.lastAttempt = time.Now().Add(-2 * cache.minRefetch)
+	cache.mu.Unlock()
+	if _, err := cache.Key("k2"); err != nil {
+		t.Fatal(err)
+	}
+	if n := srv.fetches.Load(); n != 2 {
+		t.Fatalf("fetched %d times, want 2", n)
+	}
+}
+
+func TestJWKSCacheKeepsKeysWithoutUsableReplacement(t *testing.T) {
+	for name, set := range map[string]JWKS{
+		"empty":       {Keys: []JWK{}},
+		"unsupported": {Keys: []JWK{{Kty: "EC", Kid: "ec", Crv: "P-256", X: "AAAA"}}},
+	} {
+		srv, cache := newJWKSServer(t, testJWK(t, "k1"))
+		if _, err := cache.Key("k1"); err != nil {
+			t.Fatal(err)
+		}
+
+		srv.set.Store(set)
+		cache.expire()
+		if _, err := cache.Key("k1"); err != nil {
+			t.Errorf("%s key set: dropped the cached keys: %v", name, err)
+		}
+		if n := srv.fetches.Load(); n != 2 {
+			t.Errorf("%s key set: fetched %d times, want 2", name, n)
+		}
+	}
+}
+
+func TestJWKSCacheServesCachedKeysDuringRefresh(t *testing.T) {
+	srv, cache := newJWKSServer(t, testJWK(t, "k1"))
+	if _, err := cache.Key("k1"); err != nil {
+		t.Fatal(err)
+	}
+
+	srv.entered, srv.release = make(chan struct{}), make(chan struct{})
+	cache.expire()
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		cache.Key("k1") // Refreshes the stale set
+	}()
+	<-srv.entered
+
+	lookup := make(chan error)
+	go func() {
+		_, err := cache.Key("k1")
+		lookup <- err
+	}()
+	select {
+	case err := <-lookup:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("cached key lookup waited for the refresh")
+	}
+
+	close(srv.release)
+	<-done
+	if n := srv.fetches.Load(); n != 2 {
+		t.Fatalf("fetched %d times, want 2", n)
+	}
+}
@@ -1,3 +1,5 @@
 module github.com/gavinadlan/tripnest/backend/common
 
 go 1.23.4
+
+require github.com/golang-jwt/jwt/v5 v5.3.1
@@ -1,3 +1,5 @@
 CREATE DATABASE tripnest_users;
 CREATE DATABASE tripnest_bookings;
 CREATE DATABASE tripnest_payments;
+CREATE DATABASE tripnest_notifications;
+CREATE DATABASE tripnest_recommendations;
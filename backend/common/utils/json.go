@@ -7,6 +7,9 @@ import (
 
 type ErrorResponse struct {
 	Error string `json:"error"`
+	// Code is a stable, machine-readable reason for errors clients are
+	// expected to handle (e.g. "SOLD_OUT")
+	Code string `json:"code,omitempty"`
 }
 
 // ReadJSON reads JSON body into dst
@@ -25,6 +28,11 @@ func WriteJSON(w http.ResponseWriter, status int, data interface{}) error {
 
 // WriteError writes error response safely (nil-safe)
 func WriteError(w http.ResponseWriter, status int, err error) {
+	WriteErrorCode(w, status, "", err)
+}
+
+// WriteErrorCode writes an error response carrying a machine-readable code
+func WriteErrorCode(w http.ResponseWriter, status int, code string, err error) {
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(status)
 
@@ -35,5 +43,6 @@ func WriteError(w http.ResponseWriter, status int, err error) {
 
 	_ = json.NewEncoder(w).Encode(ErrorResponse{
 		Error: message,
+		Code:  code,
 	})
 }
@@ -0,0 +1,151 @@
+package main
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+	// Quiet hours need time zones even where the image has no zoneinfo
+	_ "time/tzdata"
+
+	"github.com/gavinadlan/tripnest/backend/common/auth"
+	"github.com/gavinadlan/tripnest/backend/notification-service/internal/config"
+	"github.com/gavinadlan/tripnest/backend/notification-service/internal/db"
+	"github.com/gavinadlan/tripnest/backend/notification-service/internal/directory"
+	"github.com/gavinadlan/tripnest/backend/notification-service/internal/handler"
+	"github.com/gavinadlan/tripnest/backend/notification-service/internal/msgbroker"
+	"github.com/gavinadlan/tripnest/backend/notification-service/internal/repository"
+	"github.com/gavinadlan/tripnest/backend/notification-service/internal/scheduler"
+	"github.com/gavinadlan/tripnest/backend/notification-service/internal/sender"
+	"github.com/gavinadlan/tripnest/backend/notification-service/internal/service"
+	"github.com/gavinadlan/tripnest/backend/notification-service/internal/templates"
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/joho/godotenv"
+)
+
+func main() {
+	godotenv.Load()
+	cfg := config.Load()
+
+	db.RunMigrations(cfg.DatabaseURL)
+
+	repo, err := repository.NewPostgresRepository(cfg.DatabaseURL)
+	if err != nil {
+		log.Fatalf("Database connection failed: %v", err)
+	}
+	defer repo.Close()
+
+	renderer, err := templates.NewRenderer()
+	if err != nil {
+		log.Fatalf("Failed to load email templates: %v", err)
+	}
+
+	var email sender.EmailSender
+	switch cfg.EmailSender {
+	case "smtp":
+		email = sender.NewSMTPSender(sender.SMTPConfig{
+			Host:     cfg.SMTPHost,
+			Port:     cfg.SMTPPort,
+			Username: cfg.SMTPUsername,
+			Password: cfg.SMTPPassword,
+			From:     cfg.EmailFrom,
+			Timeout:  cfg.SMTPTimeout,
+		})
+	case "log":
+		email, err = sender.NewLogSender(cfg.EmailFrom, cfg.EmailOutboxDir)
+		if err != nil {
+			log.Fatalf("Failed to set up email sink: %v", err)
+		}
+	default:
+		log.Fatalf("Unknown email sender %q", cfg.EmailSender)
+	}
+	log.Printf("Using %s email sender", cfg.EmailSender)
+
+	var sms sender.SMSSender
+	switch cfg.SMSSender {
+	case "http":
+		if cfg.SMSProviderURL == "" {
+			log.Fatalf("SMS_PROVIDER_URL is required for the http SMS sender")
+		}
+		sms = sender.NewHTTPSMSSender(cfg.SMSProviderURL, cfg.SMSAPIKey, cfg.SMSFrom, cfg.SMSTimeout)
+	case "log":
+		sms = sender.LogSMSSender{}
+	default:
+		log.Fatalf("Unknown SMS sender %q", cfg.SMSSender)
+	}
+	log.Printf("Using %s SMS sender", cfg.SMSSender)
+
+	dir := directory.NewHTTPDirectory(cfg.UserServiceURL, cfg.ServiceToken, 5*time.Second)
+	svc := service.NewNotificationService(repo, dir, renderer, service.Senders{
+		Email:   email,
+		SMS:     sms,
+		Webhook: sender.NewWebhookSender(cfg.WebhookTimeout, cfg.WebhookAllowPrivate),
+	}, cfg.RetryMaxAttempts)
+
+	authenticate := auth.Authenticate(auth.NewJWKSVerifier(cfg.JWKSURL, cfg.JWTIssuer, cfg.JWKSRefreshTime))
+	if cfg.TrustGatewayHeaders {
+		authenticate = auth.TrustGatewayHeaders()
+	}
+	h := handler.NewHandler(svc, authenticate)
+
+	r := chi.NewRouter()
+
+	r.Use(middleware.Logger)
+	r.Use(middleware.Recoverer)
+
+	h.RegisterRoutes(r)
+
+	server := &http.Server{
+		Addr:    ":" + cfg.Port,
+		Handler: r,
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	retrier := scheduler.NewDeliveryRetrier(svc, cfg.RetryInterval, cfg.RetryBatchSize)
+	go retrier.Run(ctx)
+
+	for _, topic := range service.Topics {
+		consumer := msgbroker.NewConsumer(cfg.KafkaBrokers, topic, "notification-service-group")
+		defer consumer.Close()
+
+		go func() {
+			log.Printf("Listening for %s events...", topic)
+			for {
+				msg, err := consumer.ReadMessage(ctx)
+				if err != nil {
+					log.Printf("%s consumer error: %v", topic, err)
+					break
+				}
+
+				if err := svc.HandleEvent(ctx, msg.Topic, msg.Value); err != nil {
+					log.Printf("Failed to handle %s event: %v", msg.Topic, err)
+				}
+			}
+		}()
+	}
+
+	go func() {
+		log.Printf("Notification Service starting on port %s", cfg.Port)
+		if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			log.Fatalf("Server error: %v", err)
+		}
+	}()
+
+	quit := make(chan os.Signal, 1)
+	signal.Notify(quit, os.Interrupt, syscall.SIGTERM)
+	<-quit
+	log.Println("Shutting down Notification Service")
+
+	shutdownCtx, shutdownCancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer shutdownCancel()
+
+	if err := server.Shutdown(shutdownCtx); err != nil {
+		log.Fatalf("Server forced to shutdown: %v", err)
+	}
+}
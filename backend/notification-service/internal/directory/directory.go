@@ -0,0 +1,67 @@
+package directory
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/gavinadlan/tripnest/backend/common/auth"
+	"github.com/gavinadlan/tripnest/backend/notification-service/internal/model"
+)
+
+var ErrUserNotFound = errors.New("user not found")
+
+// UserDirectory resolves how to reach a user.
+type UserDirectory interface {
+	GetContact(ctx context.Context, userID string) (*model.Contact, error)
+}
+
+// HTTPDirectory looks contacts up on user-service's internal endpoint.
+type HTTPDirectory struct {
+	baseURL string
+	token   string
+	client  *http.Client
+}
+
+func NewHTTPDirectory(baseURL, serviceToken string, timeout time.Duration) *HTTPDirectory {
+	return &HTTPDirectory{
+		baseURL: baseURL,
+		token:   serviceToken,
+		client:  &http.Client{Timeout: timeout},
+	}
+}
+
+func (d *HTTPDirectory) GetContact(ctx context.Context, userID string) (*model.Contact, error) {
+	endpoint, err := url.JoinPath(d.baseURL, "internal", "users", userID)
+	if err != nil {
+		return nil, fmt.Errorf("invalid user service URL: %w", err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set(auth.HeaderServiceToken, d.token)
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to look up user %s: %w", userID, err)
+	}
+	defer resp.Body.Close()
+
+	switch {
+	case resp.StatusCode == http.StatusNotFound:
+		return nil, ErrUserNotFound
+	case resp.StatusCode != http.StatusOK:
+		return nil, fmt.Errorf("failed to look up user %s: user service returned %s", userID, resp.Status)
+	}
+
+	var contact model.Contact
+	if err := json.NewDecoder(resp.Body).Decode(&contact); err != nil {
+		return nil, fmt.Errorf("failed to decode user %s: %w", userID, err)
+	}
+	return &contact, nil
+}
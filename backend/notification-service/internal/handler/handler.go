@@ -0,0 +1,163 @@
+package handler
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/gavinadlan/tripnest/backend/common/auth"
+	"github.com/gavinadlan/tripnest/backend/common/utils"
+	"github.com/gavinadlan/tripnest/backend/notification-service/internal/model"
+	"github.com/gavinadlan/tripnest/backend/notification-service/internal/service"
+	"github.com/go-chi/chi/v5"
+)
+
+type Handler struct {
+	svc          service.NotificationService
+	authenticate func(http.Handler) http.Handler
+}
+
+// NewHandler serves the caller's inbox and preferences, identifying callers
+// with authenticate (auth.Authenticate or, behind the api-gateway,
+// auth.TrustGatewayHeaders).
+func NewHandler(svc service.NotificationService, authenticate func(http.Handler) http.Handler) *Handler {
+	return &Handler{svc: svc, authenticate: authenticate}
+}
+
+func (h *Handler) RegisterRoutes(r chi.Router) {
+	r.Get("/health", h.Health)
+
+	r.Group(func(r chi.Router) {
+		r.Use(h.authenticate)
+
+		r.Get("/notifications", h.ListNotifications)
+		r.Post("/notifications/read-all", h.MarkAllRead)
+		r.Post("/notifications/{id}/read", h.MarkRead)
+		r.Get("/notifications/preferences", h.GetPreferences)
+		r.Put("/notifications/preferences", h.UpdatePreferences)
+	})
+}
+
+// ListNotifications returns the caller's in-app notifications, newest
+// first. Query parameters: unread (true to skip read ones), limit and
+// cursor.
+func (h *Handler) ListNotifications(w http.ResponseWriter, r *http.Request) {
+	principal, _ := auth.PrincipalFromContext(r.Context())
+	query := r.URL.Query()
+
+	params := &model.InboxParams{
+		UserID: principal.UserID,
+		Limit:  model.DefaultInboxLimit,
+	}
+
+	if raw := query.Get("unread"); raw != "" {
+		unread, err := strconv.ParseBool(raw)
+		if err != nil {
+			utils.WriteError(w, http.StatusBadRequest, errors.New("invalid unread: must be true or false"))
+			return
+		}
+		params.UnreadOnly = unread
+	}
+
+	if raw := query.Get("limit"); raw != "" {
+		limit, err := strconv.Atoi(raw)
+		if err != nil || limit < 1 || limit > model.MaxInboxLimit {
+			utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("invalid limit: must be between 1 and %d", model.MaxInboxLimit))
+			return
+		}
+		params.Limit = limit
+	}
+
+	if raw := query.Get("cursor"); raw != "" {
+		var err error
+		if params.After, err = model.DecodeInboxCursor(raw); err != nil {
+			utils.WriteError(w, http.StatusBadRequest, err)
+			return
+		}
+	}
+
+	page, err := h.svc.ListInbox(r.Context(), params)
+	if err != nil {
+		log.Printf("ListInbox failed: %v", err)
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, page)
+}
+
+func (h *Handler) MarkRead(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	if id == "" {
+		utils.WriteError(w, http.StatusBadRequest, errors.New("missing notification id"))
+		return
+	}
+
+	// Notifications of other users are reported as not found
+	principal, _ := auth.PrincipalFromContext(r.Context())
+	err := h.svc.MarkRead(r.Context(), principal.UserID, id)
+	if errors.Is(err, model.ErrNotificationNotFound) {
+		utils.WriteError(w, http.StatusNotFound, err)
+		return
+	}
+	if err != nil {
+		log.Printf("MarkRead failed: %v", err)
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *Handler) MarkAllRead(w http.ResponseWriter, r *http.Request) {
+	principal, _ := auth.PrincipalFromContext(r.Context())
+	updated, err := h.svc.MarkAllRead(r.Context(), principal.UserID)
+	if err != nil {
+		log.Printf("MarkAllRead failed: %v", err)
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, map[string]int64{"updated": updated})
+}
+
+func (h *Handler) GetPreferences(w http.ResponseWriter, r *http.Request) {
+	principal, _ := auth.PrincipalFromContext(r.Context())
+	prefs, err := h.svc.GetPreferences(r.Context(), principal.UserID)
+	if err != nil {
+		log.Printf("GetPreferences failed: %v", err)
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, prefs)
+}
+
+func (h *Handler) UpdatePreferences(w http.ResponseWriter, r *http.Request) {
+	var req model.UpdatePreferencesRequest
+	if err := utils.ReadJSON(r, &req); err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	principal, _ := auth.PrincipalFromContext(r.Context())
+	prefs, err := h.svc.UpdatePreferences(r.Context(), principal.UserID, &req)
+	if errors.Is(err, model.ErrInvalidPreferences) {
+		utils.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+	if err != nil {
+		log.Printf("UpdatePreferences failed: %v", err)
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, prefs)
+}
+
+func (h *Handler) Health(w http.ResponseWriter, r *http.Request) {
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte("OK"))
+}
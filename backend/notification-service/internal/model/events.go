@@ -0,0 +1,56 @@
+package model
+
+import "time"
+
+// Events consumed from the booking saga. Only the fields needed to address
+// and render a notification are decoded.
+
+type BookingCreatedEvent struct {
+	BookingID   string  `json:"booking_id"`
+	UserID      string  `json:"user_id"`
+	ResourceID  string  `json:"resource_id"`
+	TotalAmount float64 `json:"total_amount"`
+}
+
+type PaymentEvent struct {
+	PaymentID string  `json:"payment_id"`
+	BookingID string  `json:"booking_id"`
+	UserID    string  `json:"user_id"`
+	Amount    float64 `json:"amount"`
+	Status    string  `json:"status"` // SUCCESS, FAILED
+	Reason    string  `json:"reason,omitempty"`
+}
+
+type BookingExpiredEvent struct {
+	BookingID   string    `json:"booking_id"`
+	UserID      string    `json:"user_id"`
+	ResourceID  string    `json:"resource_id"`
+	TotalAmount float64   `json:"total_amount"`
+	ExpiredAt   time.Time `json:"expired_at"`
+}
+
+type BookingCancelRequestedEvent struct {
+	RequestID    string  `json:"request_id"`
+	BookingID    string  `json:"booking_id"`
+	UserID       string  `json:"user_id"`
+	TotalAmount  float64 `json:"total_amount"`
+	RefundAmount float64 `json:"refund_amount"`
+	Reason       string  `json:"reason,omitempty"`
+}
+
+type BookingCancelledEvent struct {
+	BookingID    string  `json:"booking_id"`
+	UserID       string  `json:"user_id"`
+	RefundAmount float64 `json:"refund_amount"`
+	Reason       string  `json:"reason,omitempty"`
+}
+
+type RefundEvent struct {
+	RefundID  string  `json:"refund_id"`
+	RequestID string  `json:"request_id"`
+	BookingID string  `json:"booking_id"`
+	UserID    string  `json:"user_id"`
+	Amount    float64 `json:"amount"`
+	Status    string  `json:"status"` // SUCCESS, FAILED
+	Reason    string  `json:"reason,omitempty"`
+}
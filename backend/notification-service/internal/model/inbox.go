@@ -0,0 +1,69 @@
+package model
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"time"
+)
+
+// InboxNotification is an in-app notification shown to the user.
+type InboxNotification struct {
+	ID         string          `json:"id" db:"id"`
+	UserID     string          `json:"user_id" db:"user_id"`
+	DeliveryID string          `json:"-" db:"delivery_id"`
+	Template   string          `json:"type" db:"template"`
+	Title      string          `json:"title" db:"title"`
+	Body       string          `json:"body" db:"body"`
+	Data       json.RawMessage `json:"data" db:"data"`
+	ReadAt     *time.Time      `json:"read_at,omitempty" db:"read_at"`
+	CreatedAt  time.Time       `json:"created_at" db:"created_at"`
+}
+
+const (
+	DefaultInboxLimit = 20
+	MaxInboxLimit     = 100
+)
+
+var ErrNotificationNotFound = errors.New("notification not found")
+
+// InboxParams selects a page of a user's inbox, newest first.
+type InboxParams struct {
+	UserID     string
+	UnreadOnly bool
+	Limit      int
+	After      *InboxCursor
+}
+
+// InboxPage is one page of GET /notifications.
+type InboxPage struct {
+	Data        []InboxNotification `json:"data"`
+	UnreadCount int                 `json:"unread_count"`
+	NextCursor  string              `json:"next_cursor,omitempty"`
+}
+
+// InboxCursor is the keyset position after which the next page starts.
+type InboxCursor struct {
+	CreatedAt time.Time `json:"c"`
+	ID        string    `json:"i"`
+}
+
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// Encode returns the cursor as an opaque URL-safe token.
+func (c InboxCursor) Encode() string {
+	data, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func DecodeInboxCursor(token string) (*InboxCursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+	var c InboxCursor
+	if err := json.Unmarshal(data, &c); err != nil || c.ID == "" || c.CreatedAt.IsZero() {
+		return nil, ErrInvalidCursor
+	}
+	return &c, nil
+}
@@ -0,0 +1,71 @@
+package model
+
+import (
+	"encoding/json"
+	"time"
+)
+
+const (
+	DeliveryStatusPending = "PENDING"
+	DeliveryStatusSent    = "SENT"
+	DeliveryStatusFailed  = "FAILED"
+	// DeliveryStatusSkipped is final: the delivery can never succeed, e.g.
+	// the user no longer exists.
+	DeliveryStatusSkipped = "SKIPPED"
+	// DeliveryStatusDeferred waits out the user's quiet hours until
+	// NextAttemptAt.
+	DeliveryStatusDeferred = "DEFERRED"
+)
+
+const (
+	ChannelEmail   = "email"
+	ChannelSMS     = "sms"
+	ChannelWebhook = "webhook"
+	ChannelInApp   = "in_app"
+)
+
+var Channels = []string{ChannelEmail, ChannelSMS, ChannelWebhook, ChannelInApp}
+
+// Notification is what a saga event asks to tell a user. Key identifies the
+// event so redeliveries map to the same notification.
+type Notification struct {
+	Key      string
+	UserID   string
+	Template string
+	Data     TemplateData
+}
+
+// TemplateData is the event-specific content of a notification.
+type TemplateData struct {
+	BookingID    string  `json:"booking_id"`
+	ResourceID   string  `json:"resource_id,omitempty"`
+	Amount       float64 `json:"amount,omitempty"`
+	RefundAmount float64 `json:"refund_amount,omitempty"`
+	Reason       string  `json:"reason,omitempty"`
+}
+
+// Delivery records one attempt to notify a user over a channel.
+type Delivery struct {
+	ID            string          `json:"id" db:"id"`
+	EventKey      string          `json:"event_key" db:"event_key"`
+	Channel       string          `json:"channel" db:"channel"`
+	UserID        string          `json:"user_id" db:"user_id"`
+	Template      string          `json:"template" db:"template"`
+	Data          json.RawMessage `json:"data" db:"data"`
+	Recipient     string          `json:"recipient" db:"recipient"`
+	Status        string          `json:"status" db:"status"`
+	Attempts      int             `json:"attempts" db:"attempts"`
+	LastError     string          `json:"last_error,omitempty" db:"last_error"`
+	NextAttemptAt *time.Time      `json:"next_attempt_at,omitempty" db:"next_attempt_at"`
+	SentAt        *time.Time      `json:"sent_at,omitempty" db:"sent_at"`
+	CreatedAt     time.Time       `json:"created_at" db:"created_at"`
+	UpdatedAt     time.Time       `json:"updated_at" db:"updated_at"`
+}
+
+// Contact is how a user can be reached, as known by user-service.
+type Contact struct {
+	ID        string `json:"id"`
+	Email     string `json:"email"`
+	FirstName string `json:"first_name"`
+	LastName  string `json:"last_name"`
+}
@@ -0,0 +1,121 @@
+package model
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// ErrInvalidPreferences wraps every reason an update is rejected.
+var ErrInvalidPreferences = errors.New("invalid preferences")
+
+// Preferences control how and when a user is notified. Users without a
+// stored row get DefaultPreferences.
+type Preferences struct {
+	UserID string `json:"user_id"`
+	// Channels the user receives notifications on
+	Channels []string `json:"channels"`
+	// EventTypes the user wants to hear about; empty means all of them
+	EventTypes []string `json:"event_types"`
+	// QuietHoursStart and QuietHoursEnd ("HH:MM" in Timezone) hold back
+	// email and SMS; the window may wrap past midnight. Empty disables it.
+	QuietHoursStart string    `json:"quiet_hours_start,omitempty"`
+	QuietHoursEnd   string    `json:"quiet_hours_end,omitempty"`
+	Timezone        string    `json:"timezone"`
+	Locale          string    `json:"locale"`
+	Phone           string    `json:"phone,omitempty"`
+	WebhookURL      string    `json:"webhook_url,omitempty"`
+	WebhookSecret   string    `json:"webhook_secret,omitempty"`
+	UpdatedAt       time.Time `json:"updated_at"`
+}
+
+func DefaultPreferences(userID string) *Preferences {
+	return &Preferences{
+		UserID:     userID,
+		Channels:   []string{ChannelEmail, ChannelInApp},
+		EventTypes: []string{},
+		Timezone:   "UTC",
+		Locale:     "en",
+	}
+}
+
+// UpdatePreferencesRequest replaces all of a user's preferences.
+type UpdatePreferencesRequest struct {
+	Channels        []string `json:"channels"`
+	EventTypes      []string `json:"event_types"`
+	QuietHoursStart string   `json:"quiet_hours_start"`
+	QuietHoursEnd   string   `json:"quiet_hours_end"`
+	Timezone        string   `json:"timezone"`
+	Locale          string   `json:"locale"`
+	Phone           string   `json:"phone"`
+	WebhookURL      string   `json:"webhook_url"`
+	// WebhookSecret signs webhook payloads; one is generated when a webhook
+	// is enabled without it.
+	WebhookSecret string `json:"webhook_secret"`
+}
+
+func (p *Preferences) HasChannel(channel string) bool {
+	for _, c := range p.Channels {
+		if c == channel {
+			return true
+		}
+	}
+	return false
+}
+
+// Wants reports whether the user is interested in eventType.
+func (p *Preferences) Wants(eventType string) bool {
+	if len(p.EventTypes) == 0 {
+		return true
+	}
+	for _, t := range p.EventTypes {
+		if t == eventType {
+			return true
+		}
+	}
+	return false
+}
+
+// QuietUntil reports whether now falls in the user's quiet hours and, if
+// so, when they end.
+func (p *Preferences) QuietUntil(now time.Time) (time.Time, bool) {
+	if p.QuietHoursStart == "" || p.QuietHoursEnd == "" {
+		return time.Time{}, false
+	}
+	loc, err := time.LoadLocation(p.Timezone)
+	if err != nil {
+		loc = time.UTC
+	}
+	start, err1 := ParseClock(p.QuietHoursStart)
+	end, err2 := ParseClock(p.QuietHoursEnd)
+	if err1 != nil || err2 != nil || start == end {
+		return time.Time{}, false
+	}
+
+	local := now.In(loc)
+	minute := local.Hour()*60 + local.Minute()
+	var quiet bool
+	if start < end {
+		quiet = minute >= start && minute < end
+	} else {
+		quiet = minute >= start || minute < end
+	}
+	if !quiet {
+		return time.Time{}, false
+	}
+
+	until := time.Date(local.Year(), local.Month(), local.Day(), end/60, end%60, 0, 0, loc)
+	if !until.After(local) {
+		until = until.AddDate(0, 0, 1)
+	}
+	return until, true
+}
+
+// ParseClock parses "HH:MM" into minutes after midnight.
+func ParseClock(s string) (int, error) {
+	t, err := time.Parse("15:04", s)
+	if err != nil {
+		return 0, fmt.Errorf("invalid time %q: want HH:MM", s)
+	}
+	return t.Hour()*60 + t.Minute(), nil
+}
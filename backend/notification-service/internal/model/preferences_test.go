@@ -0,0 +1,71 @@
+package model
+
+import (
+	"testing"
+	"time"
+)
+
+func TestQuietUntil(t *testing.T) {
+	utc := func(s string) time.Time {
+		ts, err := time.Parse(time.RFC3339, s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return ts
+	}
+
+	cases := []struct {
+		name       string
+		start, end string
+		timezone   string
+		now        string
+		until      string // Empty when not quiet
+	}{
+		{"disabled", "", "", "UTC", "2026-07-01T23:00:00Z", ""},
+		{"only start", "22:00", "", "UTC", "2026-07-01T23:00:00Z", ""},
+		{"inside a daytime window", "13:00", "15:00", "UTC", "2026-07-01T14:00:00Z", "2026-07-01T15:00:00Z"},
+		{"at the start", "13:00", "15:00", "UTC", "2026-07-01T13:00:00Z", "2026-07-01T15:00:00Z"},
+		{"at the end", "13:00", "15:00", "UTC", "2026-07-01T15:00:00Z", ""},
+		{"before the window", "13:00", "15:00", "UTC", "2026-07-01T12:59:00Z", ""},
+		{"wrapping, before midnight", "22:00", "07:00", "UTC", "2026-07-01T23:30:00Z", "2026-07-02T07:00:00Z"},
+		{"wrapping, after midnight", "22:00", "07:00", "UTC", "2026-07-02T03:00:00Z", "2026-07-02T07:00:00Z"},
+		{"wrapping, at midnight", "22:00", "07:00", "UTC", "2026-07-02T00:00:00Z", "2026-07-02T07:00:00Z"},
+		{"wrapping, during the day", "22:00", "07:00", "UTC", "2026-07-02T12:00:00Z", ""},
+		{"in the user's timezone", "22:00", "07:00", "Asia/Jakarta", "2026-07-01T16:00:00Z", "2026-07-02T00:00:00Z"},
+		{"outside in the user's timezone", "22:00", "07:00", "Asia/Jakarta", "2026-07-01T14:00:00Z", ""},
+		// 23:00 EST; clocks spring forward at 02:00, so 07:00 EDT is 7 hours on
+		{"across spring forward", "22:00", "07:00", "America/New_York", "2026-03-08T04:00:00Z", "2026-03-08T11:00:00Z"},
+		{"after spring forward", "22:00", "07:00", "America/New_York", "2026-03-08T09:00:00Z", "2026-03-08T11:00:00Z"},
+		// 23:00 EDT; clocks fall back at 02:00, so 07:00 EST is 9 hours on
+		{"across fall back", "22:00", "07:00", "America/New_York", "2026-11-01T03:00:00Z", "2026-11-01T12:00:00Z"},
+		{"same start and end", "08:00", "08:00", "UTC", "2026-07-01T08:00:00Z", ""},
+		{"unparsable clock", "10pm", "07:00", "UTC", "2026-07-01T23:00:00Z", ""},
+		{"unknown timezone counts as UTC", "22:00", "07:00", "Mars/Olympus", "2026-07-01T23:00:00Z", "2026-07-02T07:00:00Z"},
+	}
+	for _, tc := range cases {
+		p := &Preferences{QuietHoursStart: tc.start, QuietHoursEnd: tc.end, Timezone: tc.timezone}
+		until, quiet := p.QuietUntil(utc(tc.now))
+		if tc.until == "" {
+			if quiet {
+				t.Errorf("%s: quiet until %v", tc.name, until)
+			}
+			continue
+		}
+		if !quiet || !until.Equal(utc(tc.until)) {
+			t.Errorf("%s: quiet %v until %v, want until %s", tc.name, quiet, until.UTC(), tc.until)
+		}
+	}
+}
+
+func TestParseClock(t *testing.T) {
+	for in, want := range map[string]int{"00:00": 0, "07:30": 450, "23:59": 1439} {
+		if got, err := ParseClock(in); err != nil || got != want {
+			t.Errorf("ParseClock(%q) = %d, %v, want %d", in, got, err, want)
+		}
+	}
+	for _, in := range []string{"", "24:00", "7:5", "07:60", "7pm", "07:30:00"} {
+		if _, err := ParseClock(in); err == nil {
+			t.Errorf("ParseClock(%q) accepted", in)
+		}
+	}
+}
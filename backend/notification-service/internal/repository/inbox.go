@@ -0,0 +1,103 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/gavinadlan/tripnest/backend/notification-service/internal/model"
+	"github.com/jackc/pgx/v5"
+)
+
+type InboxRepository interface {
+	// AddToInbox stores n unless its delivery is already in the inbox.
+	AddToInbox(ctx context.Context, n *model.InboxNotification) error
+	ListInbox(ctx context.Context, params *model.InboxParams) ([]model.InboxNotification, error)
+	CountUnread(ctx context.Context, userID string) (int, error)
+	// MarkRead returns model.ErrNotificationNotFound unless the notification
+	// exists and belongs to userID.
+	MarkRead(ctx context.Context, userID, id string) error
+	MarkAllRead(ctx context.Context, userID string) (int64, error)
+}
+
+func (r *postgresRepository) AddToInbox(ctx context.Context, n *model.InboxNotification) error {
+	query := `
+        INSERT INTO notifications (user_id, delivery_id, template, title, body, data)
+        VALUES ($1, $2, $3, $4, $5, $6)
+        ON CONFLICT (delivery_id) DO NOTHING
+    `
+	if _, err := r.db.Exec(ctx, query, n.UserID, n.DeliveryID, n.Template, n.Title, n.Body, n.Data); err != nil {
+		return fmt.Errorf("failed to add notification to inbox: %w", err)
+	}
+	return nil
+}
+
+func (r *postgresRepository) ListInbox(ctx context.Context, params *model.InboxParams) ([]model.InboxNotification, error) {
+	args := []interface{}{params.UserID}
+	where := "user_id = $1"
+	arg := func(v interface{}) string {
+		args = append(args, v)
+		return fmt.Sprintf("$%d", len(args))
+	}
+
+	if params.UnreadOnly {
+		where += " AND read_at IS NULL"
+	}
+	if params.After != nil {
+		where += fmt.Sprintf(" AND (created_at, id) < (%s, %s)", arg(params.After.CreatedAt), arg(params.After.ID))
+	}
+
+	query := fmt.Sprintf(`
+        SELECT id, user_id, delivery_id, template, title, body, data, read_at, created_at
+        FROM notifications
+        WHERE %s
+        ORDER BY created_at DESC, id DESC
+        LIMIT %s`, where, arg(params.Limit))
+
+	rows, err := r.db.Query(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list notifications: %w", err)
+	}
+	notifications, err := pgx.CollectRows(rows, func(row pgx.CollectableRow) (model.InboxNotification, error) {
+		var n model.InboxNotification
+		err := row.Scan(&n.ID, &n.UserID, &n.DeliveryID, &n.Template, &n.Title, &n.Body, &n.Data, &n.ReadAt, &n.CreatedAt)
+		return n, err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to scan notifications: %w", err)
+	}
+	return notifications, nil
+}
+
+func (r *postgresRepository) CountUnread(ctx context.Context, userID string) (int, error) {
+	var count int
+	err := r.db.QueryRow(ctx, `SELECT COUNT(*) FROM notifications WHERE user_id = $1 AND read_at IS NULL`, userID).Scan(&count)
+	if err != nil {
+		return 0, fmt.Errorf("failed to count unread notifications: %w", err)
+	}
+	return count, nil
+}
+
+func (r *postgresRepository) MarkRead(ctx context.Context, userID, id string) error {
+	// COALESCE keeps the first read time when marked again
+	tag, err := r.db.Exec(ctx, `
+        UPDATE notifications SET read_at = COALESCE(read_at, NOW())
+        WHERE id = $1 AND user_id = $2`, id, userID)
+	if err != nil {
+		if isInvalidText(err) {
+			return model.ErrNotificationNotFound // Not a UUID
+		}
+		return fmt.Errorf("failed to mark notification read: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return model.ErrNotificationNotFound
+	}
+	return nil
+}
+
+func (r *postgresRepository) MarkAllRead(ctx context.Context, userID string) (int64, error) {
+	tag, err := r.db.Exec(ctx, `UPDATE notifications SET read_at = NOW() WHERE user_id = $1 AND read_at IS NULL`, userID)
+	if err != nil {
+		return 0, fmt.Errorf("failed to mark notifications read: %w", err)
+	}
+	return tag.RowsAffected(), nil
+}
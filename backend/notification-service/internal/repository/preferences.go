@@ -0,0 +1,56 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/gavinadlan/tripnest/backend/notification-service/internal/model"
+	"github.com/jackc/pgx/v5"
+)
+
+type PreferenceRepository interface {
+	// GetPreferences returns nil if the user never saved any.
+	GetPreferences(ctx context.Context, userID string) (*model.Preferences, error)
+	SavePreferences(ctx context.Context, p *model.Preferences) error
+}
+
+func (r *postgresRepository) GetPreferences(ctx context.Context, userID string) (*model.Preferences, error) {
+	query := `
+        SELECT user_id, channels, event_types, COALESCE(quiet_hours_start, ''), COALESCE(quiet_hours_end, ''),
+               timezone, locale, COALESCE(phone, ''), COALESCE(webhook_url, ''), COALESCE(webhook_secret, ''), updated_at
+        FROM notification_preferences WHERE user_id = $1
+    `
+	var p model.Preferences
+	err := r.db.QueryRow(ctx, query, userID).Scan(
+		&p.UserID, &p.Channels, &p.EventTypes, &p.QuietHoursStart, &p.QuietHoursEnd,
+		&p.Timezone, &p.Locale, &p.Phone, &p.WebhookURL, &p.WebhookSecret, &p.UpdatedAt,
+	)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, nil // Not found
+		}
+		return nil, fmt.Errorf("failed to get notification preferences: %w", err)
+	}
+	return &p, nil
+}
+
+func (r *postgresRepository) SavePreferences(ctx context.Context, p *model.Preferences) error {
+	query := `
+        INSERT INTO notification_preferences (user_id, channels, event_types, quiet_hours_start, quiet_hours_end,
+                                              timezone, locale, phone, webhook_url, webhook_secret, updated_at)
+        VALUES ($1, $2, $3, NULLIF($4, ''), NULLIF($5, ''), $6, $7, NULLIF($8, ''), NULLIF($9, ''), NULLIF($10, ''), NOW())
+        ON CONFLICT (user_id) DO UPDATE SET
+            channels = EXCLUDED.channels, event_types = EXCLUDED.event_types,
+            quiet_hours_start = EXCLUDED.quiet_hours_start, quiet_hours_end = EXCLUDED.quiet_hours_end,
+            timezone = EXCLUDED.timezone, locale = EXCLUDED.locale, phone = EXCLUDED.phone,
+            webhook_url = EXCLUDED.webhook_url, webhook_secret = EXCLUDED.webhook_secret, updated_at = NOW()
+        RETURNING updated_at
+    `
+	err := r.db.QueryRow(ctx, query, p.UserID, p.Channels, p.EventTypes, p.QuietHoursStart, p.QuietHoursEnd,
+		p.Timezone, p.Locale, p.Phone, p.WebhookURL, p.WebhookSecret).Scan(&p.UpdatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to save notification preferences: %w", err)
+	}
+	return nil
+}
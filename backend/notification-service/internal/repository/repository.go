@@ -0,0 +1,133 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/gavinadlan/tripnest/backend/notification-service/internal/model"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// ErrDuplicateDelivery is returned by Create when the event has already been
+// delivered (or is being delivered) over the channel.
+var ErrDuplicateDelivery = errors.New("delivery already exists for event")
+
+type DeliveryRepository interface {
+	Create(ctx context.Context, d *model.Delivery) error
+	UpdateResult(ctx context.Context, d *model.Delivery) error
+	// ClaimRetries marks up to limit deliveries as PENDING again and returns
+	// them: FAILED and DEFERRED ones whose next attempt is due and PENDING
+	// ones abandoned before staleBefore, all with fewer than maxAttempts
+	// attempts. Rows claimed by another replica are skipped.
+	ClaimRetries(ctx context.Context, maxAttempts int, staleBefore time.Time, limit int) ([]model.Delivery, error)
+	PreferenceRepository
+	InboxRepository
+	Close()
+}
+
+type postgresRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewPostgresRepository(connString string) (DeliveryRepository, error) {
+	config, err := pgxpool.ParseConfig(connString)
+	if err != nil {
+		return nil, err
+	}
+	pool, err := pgxpool.NewWithConfig(context.Background(), config)
+	if err != nil {
+		return nil, err
+	}
+	return &postgresRepository{db: pool}, nil
+}
+
+func (r *postgresRepository) Close() {
+	r.db.Close()
+}
+
+func scanDelivery(row pgx.Row, d *model.Delivery) error {
+	return row.Scan(
+		&d.ID, &d.EventKey, &d.Channel, &d.UserID, &d.Template, &d.Data, &d.Recipient, &d.Status,
+		&d.Attempts, &d.LastError, &d.NextAttemptAt, &d.SentAt, &d.CreatedAt, &d.UpdatedAt,
+	)
+}
+
+func (r *postgresRepository) Create(ctx context.Context, d *model.Delivery) error {
+	query := `
+        INSERT INTO deliveries (event_key, channel, user_id, template, data, status)
+        VALUES ($1, $2, $3, $4, $5, $6)
+        RETURNING id, created_at, updated_at
+    `
+	err := r.db.QueryRow(ctx, query, d.EventKey, d.Channel, d.UserID, d.Template, d.Data, d.Status).
+		Scan(&d.ID, &d.CreatedAt, &d.UpdatedAt)
+	if err != nil {
+		if isUniqueViolation(err) {
+			return ErrDuplicateDelivery
+		}
+		return fmt.Errorf("failed to create delivery: %w", err)
+	}
+	return nil
+}
+
+func (r *postgresRepository) UpdateResult(ctx context.Context, d *model.Delivery) error {
+	query := `
+        UPDATE deliveries
+        SET recipient = NULLIF($1, ''), status = $2, attempts = $3, last_error = NULLIF($4, ''),
+            next_attempt_at = $5, sent_at = $6, updated_at = NOW()
+        WHERE id = $7
+        RETURNING updated_at
+    `
+	err := r.db.QueryRow(ctx, query, d.Recipient, d.Status, d.Attempts, d.LastError, d.NextAttemptAt, d.SentAt, d.ID).
+		Scan(&d.UpdatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to update delivery: %w", err)
+	}
+	return nil
+}
+
+func (r *postgresRepository) ClaimRetries(ctx context.Context, maxAttempts int, staleBefore time.Time, limit int) ([]model.Delivery, error) {
+	query := `
+        WITH due AS (
+            SELECT id FROM deliveries
+            WHERE attempts < $1
+              AND ((status IN ('FAILED', 'DEFERRED') AND next_attempt_at <= NOW())
+                OR (status = 'PENDING' AND updated_at < $2))
+            ORDER BY updated_at
+            LIMIT $3
+            FOR UPDATE SKIP LOCKED
+        )
+        UPDATE deliveries d SET status = 'PENDING', updated_at = NOW()
+        FROM due
+        WHERE d.id = due.id
+        RETURNING d.id, d.event_key, d.channel, d.user_id, d.template, d.data, COALESCE(d.recipient, ''), d.status,
+                  d.attempts, COALESCE(d.last_error, ''), d.next_attempt_at, d.sent_at, d.created_at, d.updated_at
+    `
+
+	rows, err := r.db.Query(ctx, query, maxAttempts, staleBefore, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to claim deliveries for retry: %w", err)
+	}
+	deliveries, err := pgx.CollectRows(rows, func(row pgx.CollectableRow) (model.Delivery, error) {
+		var d model.Delivery
+		err := scanDelivery(row, &d)
+		return d, err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to scan deliveries: %w", err)
+	}
+	return deliveries, nil
+}
+
+func isUniqueViolation(err error) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && pgErr.Code == "23505"
+}
+
+func isInvalidText(err error) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && pgErr.Code == "22P02"
+}
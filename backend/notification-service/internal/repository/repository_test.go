@@ -0,0 +1,34 @@
+package repository
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+func TestPgErrorClassification(t *testing.T) {
+	unique := &pgconn.PgError{Code: "23505"}
+	invalid := &pgconn.PgError{Code: "22P02"}
+
+	cases := []struct {
+		err             error
+		unique, invalid bool
+	}{
+		{unique, true, false},
+		{fmt.Errorf("insert: %w", unique), true, false},
+		{invalid, false, true},
+		{&pgconn.PgError{Code: "23503"}, false, false}, // Foreign key
+		{errors.New("23505"), false, false},
+		{nil, false, false},
+	}
+	for _, tc := range cases {
+		if got := isUniqueViolation(tc.err); got != tc.unique {
+			t.Errorf("isUniqueViolation(%v) = %v", tc.err, got)
+		}
+		if got := isInvalidText(tc.err); got != tc.invalid {
+			t.Errorf("isInvalidText(%v) = %v", tc.err, got)
+		}
+	}
+}
@@ -0,0 +1,53 @@
+package scheduler
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/gavinadlan/tripnest/backend/notification-service/internal/service"
+)
+
+// DeliveryRetrier periodically re-attempts failed deliveries. Every replica
+// may run one: the repository claims rows with SKIP LOCKED.
+type DeliveryRetrier struct {
+	svc       service.NotificationService
+	interval  time.Duration
+	batchSize int
+}
+
+func NewDeliveryRetrier(svc service.NotificationService, interval time.Duration, batchSize int) *DeliveryRetrier {
+	return &DeliveryRetrier{
+		svc:       svc,
+		interval:  interval,
+		batchSize: batchSize,
+	}
+}
+
+func (r *DeliveryRetrier) Run(ctx context.Context) {
+	log.Printf("Delivery retrier started (interval %s)", r.interval)
+	ticker := time.NewTicker(r.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			log.Println("Delivery retrier stopped")
+			return
+		case <-ticker.C:
+		}
+
+		for {
+			n, err := r.svc.RetryDeliveries(ctx, r.batchSize)
+			if err != nil {
+				if ctx.Err() == nil {
+					log.Printf("Delivery retry failed: %v", err)
+				}
+				break
+			}
+			if n < r.batchSize {
+				break
+			}
+		}
+	}
+}
@@ -0,0 +1,54 @@
+package sender
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// LogSender is a development sink: it logs every email and, when dir is
+// set, writes it there as an .eml file that mail clients can open.
+type LogSender struct {
+	from string
+	dir  string
+}
+
+func NewLogSender(from, dir string) (*LogSender, error) {
+	if dir != "" {
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			return nil, fmt.Errorf("failed to create email outbox dir: %w", err)
+		}
+	}
+	return &LogSender{from: from, dir: dir}, nil
+}
+
+func (s *LogSender) Send(ctx context.Context, msg Message) error {
+	log.Printf("Email to %s: %q", msg.To, msg.Subject)
+	if s.dir == "" {
+		log.Printf("%s", msg.Text)
+		return nil
+	}
+
+	data, err := buildMIME(s.from, msg)
+	if err != nil {
+		return fmt.Errorf("failed to encode email: %w", err)
+	}
+	name := fmt.Sprintf("%s-%s-%s.eml", time.Now().UTC().Format("20060102T150405.000"), safeName(msg.To), randomID()[:6])
+	if err := os.WriteFile(filepath.Join(s.dir, name), data, 0o644); err != nil {
+		return fmt.Errorf("failed to write email: %w", err)
+	}
+	return nil
+}
+
+func safeName(s string) string {
+	return strings.Map(func(r rune) rune {
+		if r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '.' || r == '-' {
+			return r
+		}
+		return '_'
+	}, s)
+}
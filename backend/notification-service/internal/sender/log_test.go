@@ -0,0 +1,58 @@
+package sender
+
+import (
+	"context"
+	"net/mail"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestLogSenderWritesEML(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "outbox")
+	s, err := NewLogSender("no-reply@tripnest.test", dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// A newline in the address must not smuggle in another header
+	msg := Message{To: "ada@example.com\r\nBcc: eve@example.com", Subject: "Booking confirmed", Text: "Hi Ada", HTML: "<p>Hi Ada</p>"}
+	if err := s.Send(context.Background(), msg); err != nil {
+		t.Fatal(err)
+	}
+
+	files, err := filepath.Glob(filepath.Join(dir, "*.eml"))
+	if err != nil || len(files) != 1 {
+		t.Fatalf("outbox holds %v, %v", files, err)
+	}
+	if name := filepath.Base(files[0]); strings.ContainsAny(name, "\r\n :@") {
+		t.Fatalf("unsafe file name %q", name)
+	}
+	f, err := os.Open(files[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	parsed, err := mail.ReadMessage(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if parsed.Header.Get("From") != "no-reply@tripnest.test" || parsed.Header.Get("Bcc") != "" {
+		t.Fatalf("headers %v", parsed.Header)
+	}
+	if parsed.Header.Get("Message-Id") == "" || parsed.Header.Get("Date") == "" {
+		t.Fatalf("headers %v lack Message-ID or Date", parsed.Header)
+	}
+	assertAlternatives(t, parsed, msg)
+}
+
+func TestLogSenderWithoutDir(t *testing.T) {
+	s, err := NewLogSender("no-reply@tripnest.test", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Send(context.Background(), Message{To: "ada@example.com", Subject: "s", Text: "t"}); err != nil {
+		t.Fatal(err)
+	}
+}
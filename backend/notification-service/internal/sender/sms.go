@@ -0,0 +1,83 @@
+package sender
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"time"
+)
+
+// SMSMessage is a plain-text message to a phone number in E.164 format.
+type SMSMessage struct {
+	To   string
+	Body string
+}
+
+// SMSSender delivers an SMS. Implementations must be safe for concurrent
+// use.
+type SMSSender interface {
+	Send(ctx context.Context, msg SMSMessage) error
+}
+
+// HTTPSMSSender posts messages to an SMS provider's HTTP API as
+// {"from", "to", "body"} JSON, authenticated with a bearer API key. Most
+// providers accept this shape or sit behind a thin adapter that does.
+type HTTPSMSSender struct {
+	url    string
+	apiKey string
+	from   string
+	client *http.Client
+}
+
+func NewHTTPSMSSender(url, apiKey, from string, timeout time.Duration) *HTTPSMSSender {
+	return &HTTPSMSSender{
+		url:    url,
+		apiKey: apiKey,
+		from:   from,
+		client: &http.Client{Timeout: timeout},
+	}
+}
+
+func (s *HTTPSMSSender) Send(ctx context.Context, msg SMSMessage) error {
+	body, err := json.Marshal(map[string]string{
+		"from": s.from,
+		"to":   msg.To,
+		"body": msg.Body,
+	})
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if s.apiKey != "" {
+		req.Header.Set("Authorization", "Bearer "+s.apiKey)
+	}
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to reach SMS provider: %w", err)
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 4096))
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("SMS provider returned %s", resp.Status)
+	}
+	return nil
+}
+
+// LogSMSSender is a development sink that only logs messages.
+type LogSMSSender struct{}
+
+func (LogSMSSender) Send(ctx context.Context, msg SMSMessage) error {
+	log.Printf("SMS to %s: %q", msg.To, msg.Body)
+	return nil
+}
@@ -0,0 +1,35 @@
+package sender
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestHTTPSMSSender(t *testing.T) {
+	var got map[string]string
+	var auth string
+	status := http.StatusAccepted
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		auth = r.Header.Get("Authorization")
+		json.NewDecoder(r.Body).Decode(&got)
+		w.WriteHeader(status)
+	}))
+	defer srv.Close()
+	s := NewHTTPSMSSender(srv.URL, "key", "TripNest", time.Second)
+
+	if err := s.Send(context.Background(), SMSMessage{To: "+6281234567890", Body: "Booking confirmed"}); err != nil {
+		t.Fatal(err)
+	}
+	if auth != "Bearer key" || got["from"] != "TripNest" || got["to"] != "+6281234567890" || got["body"] != "Booking confirmed" {
+		t.Fatalf("provider got %v with %q", got, auth)
+	}
+
+	status = http.StatusTooManyRequests
+	if err := s.Send(context.Background(), SMSMessage{To: "+6281234567890", Body: "x"}); err == nil {
+		t.Fatal("provider error reported as sent")
+	}
+}
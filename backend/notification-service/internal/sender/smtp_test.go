@@ -0,0 +1,181 @@
+package sender
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net"
+	"net/mail"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeSMTP is a minimal SMTP server without STARTTLS or AUTH. It accepts
+// every message unless rejectRcpt is set and records the envelope and data.
+type fakeSMTP struct {
+	addr       *net.TCPAddr
+	rejectRcpt bool
+
+	mu   sync.Mutex
+	from string
+	to   []string
+	data string
+}
+
+func newFakeSMTP(t *testing.T) *fakeSMTP {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { ln.Close() })
+	s := &fakeSMTP{addr: ln.Addr().(*net.TCPAddr)}
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go s.serve(conn)
+		}
+	}()
+	return s
+}
+
+func (s *fakeSMTP) serve(conn net.Conn) {
+	defer conn.Close()
+	r := bufio.NewReader(conn)
+	reply := func(line string) { io.WriteString(conn, line+"\r\n") }
+
+	reply("220 fake ESMTP")
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return
+		}
+		cmd := strings.ToUpper(strings.TrimSpace(line))
+		switch {
+		case strings.HasPrefix(cmd, "EHLO"), strings.HasPrefix(cmd, "HELO"):
+			reply("250 fake")
+		case strings.HasPrefix(cmd, "MAIL FROM:"):
+			s.mu.Lock()
+			s.from = strings.TrimSpace(line)[len("MAIL FROM:"):]
+			s.mu.Unlock()
+			reply("250 OK")
+		case strings.HasPrefix(cmd, "RCPT TO:"):
+			if s.rejectRcpt {
+				reply("550 no such user")
+				continue
+			}
+			s.mu.Lock()
+			s.to = append(s.to, strings.TrimSpace(line)[len("RCPT TO:"):])
+			s.mu.Unlock()
+			reply("250 OK")
+		case cmd == "DATA":
+			reply("354 go ahead")
+			var data strings.Builder
+			for {
+				l, err := r.ReadString('\n')
+				if err != nil {
+					return
+				}
+				if l == ".\r\n" {
+					break
+				}
+				data.WriteString(l)
+			}
+			s.mu.Lock()
+			s.data = data.String()
+			s.mu.Unlock()
+			reply("250 queued")
+		case cmd == "QUIT":
+			reply("221 bye")
+			return
+		default:
+			reply("502 not implemented")
+		}
+	}
+}
+
+func (s *fakeSMTP) sender() *SMTPSender {
+	return NewSMTPSender(SMTPConfig{
+		Host:    s.addr.IP.String(),
+		Port:    s.addr.Port,
+		From:    "TripNest <no-reply@tripnest.test>",
+		Timeout: 5 * time.Second,
+	})
+}
+
+func TestSMTPSenderSends(t *testing.T) {
+	srv := newFakeSMTP(t)
+	msg := Message{To: "Ada <ada@example.com>", Subject: "Booking dikonfirmasi ✓", Text: "Hi Ada", HTML: "<p>Hi Ada</p>"}
+
+	if err := srv.sender().Send(context.Background(), msg); err != nil {
+		t.Fatal(err)
+	}
+
+	srv.mu.Lock()
+	defer srv.mu.Unlock()
+	if srv.from != "<no-reply@tripnest.test>" || len(srv.to) != 1 || srv.to[0] != "<ada@example.com>" {
+		t.Fatalf("envelope from %s to %v", srv.from, srv.to)
+	}
+	parsed, err := mail.ReadMessage(strings.NewReader(srv.data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	subject, err := new(mime.WordDecoder).DecodeHeader(parsed.Header.Get("Subject"))
+	if err != nil || subject != msg.Subject {
+		t.Fatalf("subject %q, %v", subject, err)
+	}
+	assertAlternatives(t, parsed, msg)
+}
+
+func TestSMTPSenderErrors(t *testing.T) {
+	srv := newFakeSMTP(t)
+	srv.rejectRcpt = true
+	if err := srv.sender().Send(context.Background(), Message{To: "ada@example.com", Subject: "s"}); err == nil {
+		t.Fatal("rejected recipient reported as sent")
+	}
+
+	if err := newFakeSMTP(t).sender().Send(context.Background(), Message{To: "not an address"}); err == nil {
+		t.Fatal("invalid recipient accepted")
+	}
+
+	// Nothing listening
+	ln, _ := net.Listen("tcp", "127.0.0.1:0")
+	port := ln.Addr().(*net.TCPAddr).Port
+	ln.Close()
+	s := NewSMTPSender(SMTPConfig{Host: "127.0.0.1", Port: port, From: "no-reply@tripnest.test", Timeout: time.Second})
+	if err := s.Send(context.Background(), Message{To: "ada@example.com"}); err == nil || !strings.Contains(err.Error(), strconv.Itoa(port)) {
+		t.Fatalf("unreachable server: %v", err)
+	}
+}
+
+// assertAlternatives checks that m carries msg's text and HTML as
+// multipart/alternative parts.
+func assertAlternatives(t *testing.T, m *mail.Message, msg Message) {
+	t.Helper()
+	mediaType, params, err := mime.ParseMediaType(m.Header.Get("Content-Type"))
+	if err != nil || mediaType != "multipart/alternative" {
+		t.Fatalf("content type %q, %v", mediaType, err)
+	}
+	mr := multipart.NewReader(m.Body, params["boundary"])
+	for _, want := range []struct{ contentType, body string }{
+		{"text/plain", msg.Text},
+		{"text/html", msg.HTML},
+	} {
+		part, err := mr.NextPart() // Decodes quoted-printable
+		if err != nil {
+			t.Fatal(err)
+		}
+		body, _ := io.ReadAll(part)
+		if !strings.HasPrefix(part.Header.Get("Content-Type"), want.contentType) || string(body) != want.body {
+			t.Errorf("part %s: %q, want %s: %q", part.Header.Get("Content-Type"), body, want.contentType, want.body)
+		}
+	}
+}
@@ -0,0 +1,154 @@
+package sender
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"syscall"
+	"time"
+)
+
+// Webhook headers sent with every call. The signature is
+// "sha256=" + hex(HMAC-SHA256(secret, timestamp + "." + body)), so receivers
+// can check both origin and freshness.
+const (
+	HeaderWebhookEvent     = "X-TripNest-Event"
+	HeaderWebhookDelivery  = "X-TripNest-Delivery"
+	HeaderWebhookTimestamp = "X-TripNest-Timestamp"
+	HeaderWebhookSignature = "X-TripNest-Signature"
+)
+
+// ErrForbiddenAddress is returned when a webhook URL resolves to an address
+// the sender may not call.
+var ErrForbiddenAddress = errors.New("webhook address is not publicly routable")
+
+// WebhookPayload is the JSON body of a webhook call.
+type WebhookPayload struct {
+	ID        string          `json:"id"`
+	Event     string          `json:"event"`
+	EventKey  string          `json:"event_key"`
+	UserID    string          `json:"user_id"`
+	Subject   string          `json:"subject"`
+	Data      json.RawMessage `json:"data"`
+	CreatedAt time.Time       `json:"created_at"`
+}
+
+// WebhookSender posts signed payloads to user-configured URLs.
+type WebhookSender struct {
+	client       *http.Client
+	allowPrivate bool
+}
+
+// NewWebhookSender returns a sender whose calls time out after timeout.
+// Unless allowPrivate is set, it refuses to connect to loopback, private
+// and link-local addresses so users cannot point webhooks at internal
+// services.
+func NewWebhookSender(timeout time.Duration, allowPrivate bool) *WebhookSender {
+	dialer := &net.Dialer{Timeout: timeout}
+	if !allowPrivate {
+		// Checking the resolved address at connect time also covers DNS
+		// names that point inside the network.
+		dialer.Control = func(network, address string, c syscall.RawConn) error {
+			host, _, err := net.SplitHostPort(address)
+			if err != nil {
+				return err
+			}
+			ip := net.ParseIP(host)
+			if ip == nil || !isPublic(ip) {
+				return ErrForbiddenAddress
+			}
+			return nil
+		}
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = nil
+	transport.DialContext = dialer.DialContext
+	return &WebhookSender{
+		allowPrivate: allowPrivate,
+		client: &http.Client{
+			Timeout:   timeout,
+			Transport: transport,
+			// A redirect could lead anywhere; receivers must answer directly
+			CheckRedirect: func(req *http.Request, via []*http.Request) error {
+				return http.ErrUseLastResponse
+			},
+		},
+	}
+}
+
+// CheckURL reports why raw cannot be used as a webhook URL, if it cannot.
+// Only https is accepted unless private addresses are allowed, which is
+// meant for local development.
+func (s *WebhookSender) CheckURL(raw string) error {
+	u, err := url.Parse(raw)
+	if err != nil || u.Host == "" {
+		return errors.New("webhook_url must be an absolute URL")
+	}
+	if u.Scheme != "https" && !(s.allowPrivate && u.Scheme == "http") {
+		return errors.New("webhook_url must use https")
+	}
+	if u.User != nil {
+		return errors.New("webhook_url must not contain credentials")
+	}
+	if ip := net.ParseIP(u.Hostname()); ip != nil && !s.allowPrivate && !isPublic(ip) {
+		return ErrForbiddenAddress
+	}
+	return nil
+}
+
+func (s *WebhookSender) Send(ctx context.Context, webhookURL, secret string, payload WebhookPayload) error {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, webhookURL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "TripNest-Webhooks/1.0")
+	req.Header.Set(HeaderWebhookEvent, payload.Event)
+	req.Header.Set(HeaderWebhookDelivery, payload.ID)
+	req.Header.Set(HeaderWebhookTimestamp, timestamp)
+	req.Header.Set(HeaderWebhookSignature, SignWebhook(secret, timestamp, body))
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to call webhook: %w", err)
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 4096))
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook returned %s", resp.Status)
+	}
+	return nil
+}
+
+// SignWebhook returns the signature header value for body sent at
+// timestamp.
+func SignWebhook(secret, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+func isPublic(ip net.IP) bool {
+	return !(ip.IsLoopback() || ip.IsPrivate() || ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() ||
+		ip.IsInterfaceLocalMulticast() || ip.IsMulticast() || ip.IsUnspecified())
+}
@@ -0,0 +1,112 @@
+package service
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/gavinadlan/tripnest/backend/notification-service/internal/model"
+	"github.com/gavinadlan/tripnest/backend/notification-service/internal/templates"
+)
+
+// Topics are the saga events that notify the traveller.
+var Topics = []string{
+	"booking.created",
+	"payment.success",
+	"payment.failed",
+	"booking.expired",
+	"booking.cancelled",
+	"booking.cancel_requested",
+	"payment.refunded",
+	"payment.refund_failed",
+}
+
+// notificationFor maps a saga event to the notification it triggers. The key
+// is derived from the event's business identity (booking or cancellation
+// request) rather than its Kafka offset, so an outcome republished by a
+// replay is still recognised as a duplicate.
+func notificationFor(topic string, payload []byte) (*model.Notification, error) {
+	switch topic {
+	case "booking.created":
+		var e model.BookingCreatedEvent
+		if err := json.Unmarshal(payload, &e); err != nil {
+			return nil, err
+		}
+		return &model.Notification{
+			Key:      topic + ":" + e.BookingID,
+			UserID:   e.UserID,
+			Template: templates.BookingCreated,
+			Data:     model.TemplateData{BookingID: e.BookingID, ResourceID: e.ResourceID, Amount: e.TotalAmount},
+		}, nil
+
+	case "payment.success", "payment.failed":
+		var e model.PaymentEvent
+		if err := json.Unmarshal(payload, &e); err != nil {
+			return nil, err
+		}
+		template := templates.BookingConfirmed
+		if topic == "payment.failed" {
+			template = templates.PaymentFailed
+		}
+		return &model.Notification{
+			Key:      topic + ":" + e.BookingID,
+			UserID:   e.UserID,
+			Template: template,
+			Data:     model.TemplateData{BookingID: e.BookingID, Amount: e.Amount, Reason: e.Reason},
+		}, nil
+
+	case "booking.expired":
+		var e model.BookingExpiredEvent
+		if err := json.Unmarshal(payload, &e); err != nil {
+			return nil, err
+		}
+		return &model.Notification{
+			Key:      topic + ":" + e.BookingID,
+			UserID:   e.UserID,
+			Template: templates.BookingExpired,
+			Data:     model.TemplateData{BookingID: e.BookingID, ResourceID: e.ResourceID, Amount: e.TotalAmount},
+		}, nil
+
+	case "booking.cancelled":
+		var e model.BookingCancelledEvent
+		if err := json.Unmarshal(payload, &e); err != nil {
+			return nil, err
+		}
+		return &model.Notification{
+			Key:      topic + ":" + e.BookingID,
+			UserID:   e.UserID,
+			Template: templates.BookingCancelled,
+			Data:     model.TemplateData{BookingID: e.BookingID, RefundAmount: e.RefundAmount, Reason: e.Reason},
+		}, nil
+
+	case "booking.cancel_requested":
+		var e model.BookingCancelRequestedEvent
+		if err := json.Unmarshal(payload, &e); err != nil {
+			return nil, err
+		}
+		return &model.Notification{
+			Key:      topic + ":" + e.RequestID,
+			UserID:   e.UserID,
+			Template: templates.CancellationRequested,
+			Data: model.TemplateData{
+				BookingID: e.BookingID, Amount: e.TotalAmount, RefundAmount: e.RefundAmount, Reason: e.Reason,
+			},
+		}, nil
+
+	case "payment.refunded", "payment.refund_failed":
+		var e model.RefundEvent
+		if err := json.Unmarshal(payload, &e); err != nil {
+			return nil, err
+		}
+		template := templates.RefundCompleted
+		if topic == "payment.refund_failed" {
+			template = templates.RefundFailed
+		}
+		return &model.Notification{
+			Key:      topic + ":" + e.RequestID,
+			UserID:   e.UserID,
+			Template: template,
+			Data:     model.TemplateData{BookingID: e.BookingID, RefundAmount: e.Amount, Reason: e.Reason},
+		}, nil
+	}
+	return nil, fmt.Errorf("unsupported topic %q", topic)
+}
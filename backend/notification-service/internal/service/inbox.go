@@ -0,0 +1,42 @@
+package service
+
+import (
+	"context"
+
+	"github.com/gavinadlan/tripnest/backend/notification-service/internal/model"
+)
+
+func (s *notificationService) ListInbox(ctx context.Context, params *model.InboxParams) (*model.InboxPage, error) {
+	limit := params.Limit
+	// Fetch one extra row to learn whether another page exists
+	params.Limit = limit + 1
+	notifications, err := s.repo.ListInbox(ctx, params)
+	params.Limit = limit
+	if err != nil {
+		return nil, err
+	}
+
+	unread, err := s.repo.CountUnread(ctx, params.UserID)
+	if err != nil {
+		return nil, err
+	}
+
+	page := &model.InboxPage{Data: notifications, UnreadCount: unread}
+	if len(notifications) > limit {
+		page.Data = notifications[:limit]
+		last := page.Data[limit-1]
+		page.NextCursor = model.InboxCursor{CreatedAt: last.CreatedAt, ID: last.ID}.Encode()
+	}
+	if page.Data == nil {
+		page.Data = []model.InboxNotification{}
+	}
+	return page, nil
+}
+
+func (s *notificationService) MarkRead(ctx context.Context, userID, id string) error {
+	return s.repo.MarkRead(ctx, userID, id)
+}
+
+func (s *notificationService) MarkAllRead(ctx context.Context, userID string) (int64, error) {
+	return s.repo.MarkAllRead(ctx, userID)
+}
@@ -0,0 +1,141 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"regexp"
+	"slices"
+	"time"
+
+	"github.com/gavinadlan/tripnest/backend/notification-service/internal/model"
+	"github.com/gavinadlan/tripnest/backend/notification-service/internal/templates"
+)
+
+// e164 matches phone numbers in international format, e.g. +6281234567890.
+var e164 = regexp.MustCompile(`^\+[1-9][0-9]{6,14}$`)
+
+func (s *notificationService) GetPreferences(ctx context.Context, userID string) (*model.Preferences, error) {
+	prefs, err := s.repo.GetPreferences(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if prefs == nil {
+		return model.DefaultPreferences(userID), nil
+	}
+	return prefs, nil
+}
+
+func (s *notificationService) UpdatePreferences(ctx context.Context, userID string, req *model.UpdatePreferencesRequest) (*model.Preferences, error) {
+	prefs, err := s.buildPreferences(userID, req)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", model.ErrInvalidPreferences, err)
+	}
+
+	if prefs.WebhookURL != "" && prefs.WebhookSecret == "" {
+		// Keep the current secret so receivers don't break on every update
+		current, err := s.repo.GetPreferences(ctx, userID)
+		if err != nil {
+			return nil, err
+		}
+		if current != nil && current.WebhookSecret != "" {
+			prefs.WebhookSecret = current.WebhookSecret
+		} else {
+			prefs.WebhookSecret = newWebhookSecret()
+		}
+	}
+
+	if err := s.repo.SavePreferences(ctx, prefs); err != nil {
+		return nil, err
+	}
+	return prefs, nil
+}
+
+// buildPreferences validates req and turns it into preferences.
+func (s *notificationService) buildPreferences(userID string, req *model.UpdatePreferencesRequest) (*model.Preferences, error) {
+	if req.Channels == nil {
+		return nil, fmt.Errorf("channels is required")
+	}
+	prefs := &model.Preferences{
+		UserID:          userID,
+		Channels:        []string{},
+		EventTypes:      []string{},
+		QuietHoursStart: req.QuietHoursStart,
+		QuietHoursEnd:   req.QuietHoursEnd,
+		Timezone:        req.Timezone,
+		Locale:          req.Locale,
+		Phone:           req.Phone,
+		WebhookURL:      req.WebhookURL,
+		WebhookSecret:   req.WebhookSecret,
+	}
+
+	for _, c := range req.Channels {
+		if !slices.Contains(model.Channels, c) {
+			return nil, fmt.Errorf("unknown channel %q", c)
+		}
+		if !slices.Contains(prefs.Channels, c) {
+			prefs.Channels = append(prefs.Channels, c)
+		}
+	}
+	for _, t := range req.EventTypes {
+		if !slices.Contains(templates.Names, t) {
+			return nil, fmt.Errorf("unknown event type %q", t)
+		}
+		if !slices.Contains(prefs.EventTypes, t) {
+			prefs.EventTypes = append(prefs.EventTypes, t)
+		}
+	}
+
+	if (prefs.QuietHoursStart == "") != (prefs.QuietHoursEnd == "") {
+		return nil, fmt.Errorf("quiet_hours_start and quiet_hours_end must be set together")
+	}
+	if prefs.QuietHoursStart != "" {
+		start, err := model.ParseClock(prefs.QuietHoursStart)
+		if err != nil {
+			return nil, fmt.Errorf("quiet_hours_start: %v", err)
+		}
+		end, err := model.ParseClock(prefs.QuietHoursEnd)
+		if err != nil {
+			return nil, fmt.Errorf("quiet_hours_end: %v", err)
+		}
+		if start == end {
+			return nil, fmt.Errorf("quiet hours must not start and end at the same time")
+		}
+	}
+
+	if prefs.Timezone == "" {
+		prefs.Timezone = "UTC"
+	}
+	if _, err := time.LoadLocation(prefs.Timezone); err != nil {
+		return nil, fmt.Errorf("unknown timezone %q", prefs.Timezone)
+	}
+	if prefs.Locale == "" {
+		prefs.Locale = templates.DefaultLocale
+	}
+	if !templates.SupportsLocale(prefs.Locale) {
+		return nil, fmt.Errorf("unsupported locale %q: want one of %v", prefs.Locale, templates.Locales)
+	}
+
+	if prefs.Phone != "" && !e164.MatchString(prefs.Phone) {
+		return nil, fmt.Errorf("phone must be in E.164 format, e.g. +6281234567890")
+	}
+	if prefs.HasChannel(model.ChannelSMS) && prefs.Phone == "" {
+		return nil, fmt.Errorf("phone is required for the sms channel")
+	}
+	if prefs.WebhookURL != "" {
+		if err := s.senders.Webhook.CheckURL(prefs.WebhookURL); err != nil {
+			return nil, err
+		}
+	}
+	if prefs.HasChannel(model.ChannelWebhook) && prefs.WebhookURL == "" {
+		return nil, fmt.Errorf("webhook_url is required for the webhook channel")
+	}
+	return prefs, nil
+}
+
+func newWebhookSecret() string {
+	b := make([]byte, 32)
+	rand.Read(b)
+	return "whsec_" + hex.EncodeToString(b)
+}
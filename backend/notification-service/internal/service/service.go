@@ -0,0 +1,277 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/gavinadlan/tripnest/backend/notification-service/internal/directory"
+	"github.com/gavinadlan/tripnest/backend/notification-service/internal/model"
+	"github.com/gavinadlan/tripnest/backend/notification-service/internal/repository"
+	"github.com/gavinadlan/tripnest/backend/notification-service/internal/sender"
+	"github.com/gavinadlan/tripnest/backend/notification-service/internal/templates"
+)
+
+var (
+	// errNoAddress means the user has no address on the delivery's channel.
+	errNoAddress = errors.New("user has no address for channel")
+	// errChannelDisabled means the user turned the channel off after the
+	// delivery was created, e.g. while it waited out quiet hours.
+	errChannelDisabled = errors.New("channel disabled by user")
+)
+
+const (
+	retryBaseDelay = time.Minute
+	retryMaxDelay  = time.Hour
+	// A PENDING delivery not updated for this long was abandoned mid-send,
+	// e.g. by a crash, and is retried.
+	stalePendingAfter = 5 * time.Minute
+)
+
+type NotificationService interface {
+	// HandleEvent notifies the traveller about a saga event on each channel
+	// they enabled, at most once per event and channel however often it is
+	// delivered.
+	HandleEvent(ctx context.Context, topic string, payload []byte) error
+	// RetryDeliveries re-attempts up to limit failed or deferred deliveries
+	// that are due and returns how many it attempted.
+	RetryDeliveries(ctx context.Context, limit int) (int, error)
+
+	// GetPreferences returns the user's preferences, or the defaults if they
+	// never saved any.
+	GetPreferences(ctx context.Context, userID string) (*model.Preferences, error)
+	// UpdatePreferences replaces the user's preferences. Invalid requests
+	// fail with an error wrapping model.ErrInvalidPreferences.
+	UpdatePreferences(ctx context.Context, userID string, req *model.UpdatePreferencesRequest) (*model.Preferences, error)
+
+	ListInbox(ctx context.Context, params *model.InboxParams) (*model.InboxPage, error)
+	MarkRead(ctx context.Context, userID, id string) error
+	MarkAllRead(ctx context.Context, userID string) (int64, error)
+}
+
+// Senders deliver notifications over the external channels.
+type Senders struct {
+	Email   sender.EmailSender
+	SMS     sender.SMSSender
+	Webhook *sender.WebhookSender
+}
+
+type notificationService struct {
+	repo        repository.DeliveryRepository
+	directory   directory.UserDirectory
+	renderer    *templates.Renderer
+	senders     Senders
+	maxAttempts int
+}
+
+func NewNotificationService(repo repository.DeliveryRepository, dir directory.UserDirectory, renderer *templates.Renderer, senders Senders, maxAttempts int) NotificationService {
+	return &notificationService{
+		repo:        repo,
+		directory:   dir,
+		renderer:    renderer,
+		senders:     senders,
+		maxAttempts: maxAttempts,
+	}
+}
+
+func (s *notificationService) HandleEvent(ctx context.Context, topic string, payload []byte) error {
+	n, err := notificationFor(topic, payload)
+	if err != nil {
+		return fmt.Errorf("failed to decode %s event: %w", topic, err)
+	}
+	if n.UserID == "" {
+		log.Printf("No user on %s, nobody to notify", n.Key)
+		return nil
+	}
+
+	prefs, err := s.GetPreferences(ctx, n.UserID)
+	if err != nil {
+		return err
+	}
+	if !prefs.Wants(n.Template) {
+		log.Printf("User %s opted out of %s notifications", n.UserID, n.Template)
+		return nil
+	}
+
+	data, err := json.Marshal(n.Data)
+	if err != nil {
+		return err
+	}
+
+	var errs []error
+	for _, channel := range model.Channels {
+		if !prefs.HasChannel(channel) {
+			continue
+		}
+		d := &model.Delivery{
+			EventKey: n.Key,
+			Channel:  channel,
+			UserID:   n.UserID,
+			Template: n.Template,
+			Data:     data,
+			Status:   model.DeliveryStatusPending,
+		}
+		if err := s.repo.Create(ctx, d); err != nil {
+			if errors.Is(err, repository.ErrDuplicateDelivery) {
+				log.Printf("Notification %s already handled on %s, skipping duplicate event", n.Key, channel)
+				continue
+			}
+			errs = append(errs, err)
+			continue
+		}
+		if err := s.deliver(ctx, d, prefs); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (s *notificationService) RetryDeliveries(ctx context.Context, limit int) (int, error) {
+	deliveries, err := s.repo.ClaimRetries(ctx, s.maxAttempts, time.Now().Add(-stalePendingAfter), limit)
+	if err != nil {
+		return 0, err
+	}
+	for i := range deliveries {
+		d := &deliveries[i]
+		// Preferences are read again: the user may have changed them while
+		// the delivery waited
+		prefs, err := s.GetPreferences(ctx, d.UserID)
+		if err == nil {
+			err = s.deliver(ctx, d, prefs)
+		}
+		if err != nil {
+			log.Printf("Failed to record retry of delivery %s: %v", d.ID, err)
+		}
+	}
+	return len(deliveries), nil
+}
+
+// deliver makes one attempt to send d and records the outcome. Email and
+// SMS falling in the user's quiet hours are deferred until they end without
+// counting as an attempt. Failures are scheduled for retry with exponential
+// backoff unless they can never succeed.
+func (s *notificationService) deliver(ctx context.Context, d *model.Delivery, prefs *model.Preferences) error {
+	now := time.Now()
+	if d.Channel == model.ChannelEmail || d.Channel == model.ChannelSMS {
+		if until, quiet := prefs.QuietUntil(now); quiet && prefs.HasChannel(d.Channel) {
+			until = until.UTC()
+			d.Status = model.DeliveryStatusDeferred
+			d.NextAttemptAt = &until
+			log.Printf("Deferring %s %s for user %s until %s (quiet hours)", d.Channel, d.Template, d.UserID, until.Format(time.RFC3339))
+			return s.repo.UpdateResult(ctx, d)
+		}
+	}
+
+	d.Attempts++
+	err := s.send(ctx, d, prefs)
+
+	now = time.Now()
+	switch {
+	case err == nil:
+		d.Status = model.DeliveryStatusSent
+		d.LastError = ""
+		d.NextAttemptAt = nil
+		d.SentAt = &now
+		log.Printf("Sent %s %s to user %s", d.Channel, d.Template, d.UserID)
+	case errors.Is(err, directory.ErrUserNotFound) || errors.Is(err, errNoAddress) ||
+		errors.Is(err, errChannelDisabled) || errors.Is(err, sender.ErrForbiddenAddress):
+		d.Status = model.DeliveryStatusSkipped
+		d.LastError = err.Error()
+		d.NextAttemptAt = nil
+		log.Printf("Skipping %s %s for user %s: %v", d.Channel, d.EventKey, d.UserID, err)
+	default:
+		d.Status = model.DeliveryStatusFailed
+		d.LastError = err.Error()
+		next := now.Add(backoff(d.Attempts))
+		d.NextAttemptAt = &next
+		if d.Attempts >= s.maxAttempts {
+			log.Printf("Giving up on %s %s for user %s after %d attempts: %v", d.Channel, d.EventKey, d.UserID, d.Attempts, err)
+		} else {
+			log.Printf("Delivery of %s %s for user %s failed (attempt %d): %v", d.Channel, d.EventKey, d.UserID, d.Attempts, err)
+		}
+	}
+
+	return s.repo.UpdateResult(ctx, d)
+}
+
+func (s *notificationService) send(ctx context.Context, d *model.Delivery, prefs *model.Preferences) error {
+	if !prefs.HasChannel(d.Channel) {
+		return errChannelDisabled
+	}
+
+	contact, err := s.directory.GetContact(ctx, d.UserID)
+	if err != nil {
+		return err
+	}
+
+	var data model.TemplateData
+	if err := json.Unmarshal(d.Data, &data); err != nil {
+		return fmt.Errorf("failed to decode template data: %w", err)
+	}
+	msg, err := s.renderer.Render(prefs.Locale, d.Template, templates.View{Contact: *contact, TemplateData: data})
+	if err != nil {
+		return err
+	}
+
+	switch d.Channel {
+	case model.ChannelEmail:
+		if contact.Email == "" {
+			return errNoAddress
+		}
+		d.Recipient = contact.Email
+		return s.senders.Email.Send(ctx, sender.Message{
+			To:      contact.Email,
+			Subject: msg.Subject,
+			Text:    msg.Text,
+			HTML:    msg.HTML,
+		})
+	case model.ChannelSMS:
+		if prefs.Phone == "" {
+			return errNoAddress
+		}
+		d.Recipient = prefs.Phone
+		// Only the subject fits an SMS; the reference lets the user find
+		// the booking
+		return s.senders.SMS.Send(ctx, sender.SMSMessage{
+			To:   prefs.Phone,
+			Body: fmt.Sprintf("TripNest: %s (ref %s)", msg.Subject, data.BookingID),
+		})
+	case model.ChannelWebhook:
+		if prefs.WebhookURL == "" {
+			return errNoAddress
+		}
+		d.Recipient = prefs.WebhookURL
+		return s.senders.Webhook.Send(ctx, prefs.WebhookURL, prefs.WebhookSecret, sender.WebhookPayload{
+			ID:        d.ID,
+			Event:     d.Template,
+			EventKey:  d.EventKey,
+			UserID:    d.UserID,
+			Subject:   msg.Subject,
+			Data:      d.Data,
+			CreatedAt: d.CreatedAt,
+		})
+	case model.ChannelInApp:
+		return s.repo.AddToInbox(ctx, &model.InboxNotification{
+			UserID:     d.UserID,
+			DeliveryID: d.ID,
+			Template:   d.Template,
+			Title:      msg.Subject,
+			Body:       strings.TrimSpace(msg.Text),
+			Data:       d.Data,
+		})
+	default:
+		return fmt.Errorf("unknown channel %q", d.Channel)
+	}
+}
+
+func backoff(attempts int) time.Duration {
+	delay := retryBaseDelay
+	for i := 1; i < attempts && delay < retryMaxDelay; i++ {
+		delay *= 2
+	}
+	return min(delay, retryMaxDelay)
+}
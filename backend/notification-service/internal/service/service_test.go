@@ -0,0 +1,385 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gavinadlan/tripnest/backend/notification-service/internal/directory"
+	"github.com/gavinadlan/tripnest/backend/notification-service/internal/model"
+	"github.com/gavinadlan/tripnest/backend/notification-service/internal/repository"
+	"github.com/gavinadlan/tripnest/backend/notification-service/internal/sender"
+	"github.com/gavinadlan/tripnest/backend/notification-service/internal/templates"
+)
+
+// fakeRepo keeps deliveries, preferences and the inbox in memory, unique by
+// event and channel like the deliveries table.
+type fakeRepo struct {
+	mu         sync.Mutex
+	deliveries []*model.Delivery
+	prefs      map[string]*model.Preferences
+	inbox      []model.InboxNotification
+}
+
+func newFakeRepo() *fakeRepo {
+	return &fakeRepo{prefs: make(map[string]*model.Preferences)}
+}
+
+func (r *fakeRepo) Create(ctx context.Context, d *model.Delivery) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, existing := range r.deliveries {
+		if existing.EventKey == d.EventKey && existing.Channel == d.Channel {
+			return repository.ErrDuplicateDelivery
+		}
+	}
+	d.ID = fmt.Sprintf("d%d", len(r.deliveries)+1)
+	d.CreatedAt, d.UpdatedAt = time.Now(), time.Now()
+	stored := *d
+	r.deliveries = append(r.deliveries, &stored)
+	return nil
+}
+
+func (r *fakeRepo) UpdateResult(ctx context.Context, d *model.Delivery) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, existing := range r.deliveries {
+		if existing.ID == d.ID {
+			d.UpdatedAt = time.Now()
+			*existing = *d
+			return nil
+		}
+	}
+	return fmt.Errorf("delivery %s not found", d.ID)
+}
+
+func (r *fakeRepo) ClaimRetries(ctx context.Context, maxAttempts int, staleBefore time.Time, limit int) ([]model.Delivery, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var claimed []model.Delivery
+	for _, d := range r.deliveries {
+		due := (d.Status == model.DeliveryStatusFailed || d.Status == model.DeliveryStatusDeferred) &&
+			d.NextAttemptAt != nil && !d.NextAttemptAt.After(time.Now())
+		stale := d.Status == model.DeliveryStatusPending && d.UpdatedAt.Before(staleBefore)
+		if d.Attempts < maxAttempts && (due || stale) && len(claimed) < limit {
+			d.Status = model.DeliveryStatusPending
+			claimed = append(claimed, *d)
+		}
+	}
+	return claimed, nil
+}
+
+func (r *fakeRepo) GetPreferences(ctx context.Context, userID string) (*model.Preferences, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.prefs[userID], nil
+}
+
+func (r *fakeRepo) SavePreferences(ctx context.Context, p *model.Preferences) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.prefs[p.UserID] = p
+	return nil
+}
+
+func (r *fakeRepo) AddToInbox(ctx context.Context, n *model.InboxNotification) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.inbox = append(r.inbox, *n)
+	return nil
+}
+
+func (r *fakeRepo) ListInbox(ctx context.Context, params *model.InboxParams) ([]model.InboxNotification, error) {
+	return nil, nil
+}
+
+func (r *fakeRepo) CountUnread(ctx context.Context, userID string) (int, error) { return 0, nil }
+
+func (r *fakeRepo) MarkRead(ctx context.Context, userID, id string) error { return nil }
+
+func (r *fakeRepo) MarkAllRead(ctx context.Context, userID string) (int64, error) { return 0, nil }
+
+func (r *fakeRepo) Close() {}
+
+// delivery returns the stored delivery on channel.
+func (r *fakeRepo) delivery(t *testing.T, channel string) model.Delivery {
+	t.Helper()
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, d := range r.deliveries {
+		if d.Channel == channel {
+			return *d
+		}
+	}
+	t.Fatalf("no %s delivery", channel)
+	return model.Delivery{}
+}
+
+type fakeDirectory map[string]model.Contact
+
+func (d fakeDirectory) GetContact(ctx context.Context, userID string) (*model.Contact, error) {
+	c, ok := d[userID]
+	if !ok {
+		return nil, directory.ErrUserNotFound
+	}
+	return &c, nil
+}
+
+// fakeEmail records the emails sent, failing every send while err is set.
+type fakeEmail struct {
+	mu   sync.Mutex
+	sent []sender.Message
+	err  error
+}
+
+func (s *fakeEmail) Send(ctx context.Context, msg sender.Message) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.err != nil {
+		return s.err
+	}
+	s.sent = append(s.sent, msg)
+	return nil
+}
+
+type fakeSMS struct {
+	mu   sync.Mutex
+	sent []sender.SMSMessage
+}
+
+func (s *fakeSMS) Send(ctx context.Context, msg sender.SMSMessage) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.sent = append(s.sent, msg)
+	return nil
+}
+
+const testMaxAttempts = 3
+
+type testService struct {
+	*notificationService
+	repo  *fakeRepo
+	email *fakeEmail
+	sms   *fakeSMS
+}
+
+func newTestService(t *testing.T) *testService {
+	t.Helper()
+	renderer, err := templates.NewRenderer()
+	if err != nil {
+		t.Fatal(err)
+	}
+	repo, email, sms := newFakeRepo(), &fakeEmail{}, &fakeSMS{}
+	dir := fakeDirectory{
+		"u1": {ID: "u1", Email: "ada@example.com", FirstName: "Ada"},
+		"u2": {ID: "u2", FirstName: "No Email"},
+	}
+	svc := NewNotificationService(repo, dir, renderer, Senders{
+		Email:   email,
+		SMS:     sms,
+		Webhook: sender.NewWebhookSender(time.Second, false),
+	}, testMaxAttempts)
+	return &testService{notificationService: svc.(*notificationService), repo: repo, email: email, sms: sms}
+}
+
+func bookingCreated(t *testing.T, userID string) []byte {
+	t.Helper()
+	payload, err := json.Marshal(model.BookingCreatedEvent{BookingID: "b1", UserID: userID, ResourceID: "l1", TotalAmount: 120})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return payload
+}
+
+func TestHandleEventDeliversOncePerChannel(t *testing.T) {
+	s := newTestService(t)
+	ctx := context.Background()
+	s.repo.prefs["u1"] = &model.Preferences{
+		UserID: "u1", Channels: []string{model.ChannelEmail, model.ChannelSMS, model.ChannelInApp},
+		Timezone: "UTC", Locale: "en", Phone: "+6281234567890",
+	}
+
+	for i := 0; i < 2; i++ {
+		if err := s.HandleEvent(ctx, "booking.created", bookingCreated(t, "u1")); err != nil {
+			t.Fatalf("delivery %d: %v", i, err)
+		}
+	}
+
+	if len(s.email.sent) != 1 || s.email.sent[0].To != "ada@example.com" {
+		t.Fatalf("emails %+v, want one to ada@example.com", s.email.sent)
+	}
+	if len(s.sms.sent) != 1 || s.sms.sent[0].To != "+6281234567890" {
+		t.Fatalf("SMS %+v, want one to +6281234567890", s.sms.sent)
+	}
+	if len(s.repo.inbox) != 1 {
+		t.Fatalf("inbox %+v, want one notification", s.repo.inbox)
+	}
+	if len(s.repo.deliveries) != 3 {
+		t.Fatalf("%d deliveries, want one per channel", len(s.repo.deliveries))
+	}
+	for _, d := range s.repo.deliveries {
+		if d.Status != model.DeliveryStatusSent || d.Attempts != 1 || d.EventKey != "booking.created:b1" {
+			t.Errorf("delivery %+v, want sent once", d)
+		}
+	}
+}
+
+func TestHandleEventOptedOut(t *testing.T) {
+	s := newTestService(t)
+	s.repo.prefs["u1"] = &model.Preferences{
+		UserID: "u1", Channels: []string{model.ChannelEmail}, EventTypes: []string{templates.BookingConfirmed},
+		Timezone: "UTC", Locale: "en",
+	}
+
+	if err := s.HandleEvent(context.Background(), "booking.created", bookingCreated(t, "u1")); err != nil {
+		t.Fatal(err)
+	}
+	if len(s.repo.deliveries) != 0 {
+		t.Fatalf("deliveries %+v for an event the user opted out of", s.repo.deliveries)
+	}
+}
+
+func TestHandleEventRejectsUnknownTopic(t *testing.T) {
+	s := newTestService(t)
+	if err := s.HandleEvent(context.Background(), "booking.teleported", []byte(`{}`)); err == nil {
+		t.Fatal("unknown topic accepted")
+	}
+	if err := s.HandleEvent(context.Background(), "booking.created", []byte(`not json`)); err == nil {
+		t.Fatal("malformed payload accepted")
+	}
+}
+
+func TestDeliverSkipsUndeliverable(t *testing.T) {
+	cases := []struct {
+		name    string
+		userID  string
+		prefs   *model.Preferences
+		channel string
+	}{
+		{"unknown user", "ghost", nil, model.ChannelEmail},
+		{"no email address", "u2", nil, model.ChannelEmail},
+		{
+			"private webhook", "u1",
+			&model.Preferences{Channels: []string{model.ChannelWebhook}, WebhookURL: "https://127.0.0.1/hook"},
+			model.ChannelWebhook,
+		},
+	}
+	for _, tc := range cases {
+		s := newTestService(t)
+		if tc.prefs != nil {
+			tc.prefs.UserID, tc.prefs.Timezone, tc.prefs.Locale = tc.userID, "UTC", "en"
+			s.repo.prefs[tc.userID] = tc.prefs
+		}
+
+		if err := s.HandleEvent(context.Background(), "booking.created", bookingCreated(t, tc.userID)); err != nil {
+			t.Fatalf("%s: %v", tc.name, err)
+		}
+		d := s.repo.delivery(t, tc.channel)
+		if d.Status != model.DeliveryStatusSkipped || d.NextAttemptAt != nil || d.LastError == "" {
+			t.Errorf("%s: delivery %+v, want skipped for good", tc.name, d)
+		}
+	}
+}
+
+func TestDeliverSkipsChannelDisabledWhileWaiting(t *testing.T) {
+	s := newTestService(t)
+	ctx := context.Background()
+	s.email.err = errors.New("relay down")
+	if err := s.HandleEvent(ctx, "booking.created", bookingCreated(t, "u1")); err != nil {
+		t.Fatal(err)
+	}
+
+	// The user turns email off before the retry
+	s.email.err = nil
+	s.repo.prefs["u1"] = &model.Preferences{UserID: "u1", Channels: []string{model.ChannelInApp}, Timezone: "UTC", Locale: "en"}
+	due := time.Now().Add(-time.Second)
+	s.repo.deliveries[0].NextAttemptAt = &due
+
+	if n, err := s.RetryDeliveries(ctx, 10); err != nil || n != 1 {
+		t.Fatalf("retried %d: %v", n, err)
+	}
+	if d := s.repo.delivery(t, model.ChannelEmail); d.Status != model.DeliveryStatusSkipped || len(s.email.sent) != 0 {
+		t.Fatalf("delivery %+v, want skipped without sending", d)
+	}
+}
+
+func TestDeliverRetriesWithBackoffUntilMaxAttempts(t *testing.T) {
+	s := newTestService(t)
+	ctx := context.Background()
+	s.email.err = errors.New("relay down")
+
+	start := time.Now()
+	if err := s.HandleEvent(ctx, "booking.created", bookingCreated(t, "u1")); err != nil {
+		t.Fatal(err)
+	}
+	d := s.repo.delivery(t, model.ChannelEmail)
+	if d.Status != model.DeliveryStatusFailed || d.Attempts != 1 || d.LastError != "relay down" {
+		t.Fatalf("delivery %+v, want failed after one attempt", d)
+	}
+	if d.NextAttemptAt == nil || d.NextAttemptAt.Before(start.Add(retryBaseDelay)) || d.NextAttemptAt.After(time.Now().Add(retryBaseDelay)) {
+		t.Fatalf("next attempt at %v, want a minute from now", d.NextAttemptAt)
+	}
+
+	// Not due yet
+	if n, _ := s.RetryDeliveries(ctx, 10); n != 0 {
+		t.Fatalf("retried %d deliveries before they were due", n)
+	}
+
+	for attempt := 2; attempt <= testMaxAttempts; attempt++ {
+		due := time.Now().Add(-time.Second)
+		s.repo.deliveries[0].NextAttemptAt = &due
+		if n, err := s.RetryDeliveries(ctx, 10); err != nil || n != 1 {
+			t.Fatalf("attempt %d: retried %d: %v", attempt, n, err)
+		}
+		if d := s.repo.delivery(t, model.ChannelEmail); d.Attempts != attempt || d.Status != model.DeliveryStatusFailed {
+			t.Fatalf("attempt %d: delivery %+v", attempt, d)
+		}
+	}
+
+	// Given up: never claimed again, even once due
+	due := time.Now().Add(-time.Second)
+	s.repo.deliveries[0].NextAttemptAt = &due
+	if n, _ := s.RetryDeliveries(ctx, 10); n != 0 {
+		t.Fatalf("retried a delivery past %d attempts", testMaxAttempts)
+	}
+}
+
+func TestRetryDeliversOnceRecovered(t *testing.T) {
+	s := newTestService(t)
+	ctx := context.Background()
+	s.email.err = errors.New("relay down")
+	if err := s.HandleEvent(ctx, "booking.created", bookingCreated(t, "u1")); err != nil {
+		t.Fatal(err)
+	}
+
+	s.email.err = nil
+	due := time.Now().Add(-time.Second)
+	s.repo.deliveries[0].NextAttemptAt = &due
+	if _, err := s.RetryDeliveries(ctx, 10); err != nil {
+		t.Fatal(err)
+	}
+	d := s.repo.delivery(t, model.ChannelEmail)
+	if d.Status != model.DeliveryStatusSent || d.Attempts != 2 || d.LastError != "" || d.NextAttemptAt != nil || d.SentAt == nil {
+		t.Fatalf("delivery %+v, want sent on the second attempt", d)
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	cases := map[int]time.Duration{
+		1:  time.Minute,
+		2:  2 * time.Minute,
+		3:  4 * time.Minute,
+		6:  32 * time.Minute,
+		7:  time.Hour, // 64 minutes, capped
+		50: time.Hour,
+	}
+	for attempts, want := range cases {
+		if got := backoff(attempts); got != want {
+			t.Errorf("backoff(%d) = %v, want %v", attempts, got, want)
+		}
+	}
+}
@@ -0,0 +1,133 @@
+package templates
+
+import (
+	"bytes"
+	"embed"
+	"fmt"
+	htmltemplate "html/template"
+	"strings"
+	texttemplate "text/template"
+
+	"github.com/gavinadlan/tripnest/backend/notification-service/internal/model"
+)
+
+//go:embed files/*/*.tmpl
+var files embed.FS
+
+// Each locale directory has, for every notification, a "<name>.txt.tmpl"
+// defining "subject" and the plain-text body, and a "<name>.html.tmpl"
+// filling in the locale's HTML layout.
+const (
+	BookingCreated        = "booking_created"
+	BookingConfirmed      = "booking_confirmed"
+	PaymentFailed         = "payment_failed"
+	BookingExpired        = "booking_expired"
+	BookingCancelled      = "booking_cancelled"
+	CancellationRequested = "cancellation_requested"
+	RefundCompleted       = "refund_completed"
+	RefundFailed          = "refund_failed"
+)
+
+// Names lists every notification; users subscribe to them as event types.
+var Names = []string{
+	BookingCreated, BookingConfirmed, PaymentFailed, BookingExpired,
+	BookingCancelled, CancellationRequested, RefundCompleted, RefundFailed,
+}
+
+// DefaultLocale is used for users whose locale has no templates.
+const DefaultLocale = "en"
+
+// Locales lists the locales templates are available in.
+var Locales = []string{DefaultLocale, "id"}
+
+var funcs = map[string]any{
+	"money": func(amount float64) string { return fmt.Sprintf("%.2f", amount) },
+}
+
+// View is what templates render: the recipient plus the event's data.
+type View struct {
+	Contact model.Contact
+	model.TemplateData
+}
+
+// Rendered is a message ready to send.
+type Rendered struct {
+	Subject string
+	Text    string
+	HTML    string
+}
+
+type Renderer struct {
+	// text and html are keyed by locale + "/" + name
+	text map[string]*texttemplate.Template
+	html map[string]*htmltemplate.Template
+}
+
+// NewRenderer parses every embedded template up front so a broken or
+// missing template fails at start-up rather than on the first event.
+func NewRenderer() (*Renderer, error) {
+	r := &Renderer{
+		text: make(map[string]*texttemplate.Template),
+		html: make(map[string]*htmltemplate.Template),
+	}
+	for _, locale := range Locales {
+		dir := "files/" + locale + "/"
+		for _, name := range Names {
+			key := locale + "/" + name
+			t, err := texttemplate.New(name+".txt.tmpl").Funcs(funcs).ParseFS(files, dir+name+".txt.tmpl")
+			if err != nil {
+				return nil, fmt.Errorf("failed to parse text template %s: %w", key, err)
+			}
+			if t.Lookup("subject") == nil {
+				return nil, fmt.Errorf("text template %s does not define a subject", key)
+			}
+			h, err := htmltemplate.New("layout.html.tmpl").Funcs(funcs).ParseFS(files, dir+"layout.html.tmpl", dir+name+".html.tmpl")
+			if err != nil {
+				return nil, fmt.Errorf("failed to parse HTML template %s: %w", key, err)
+			}
+			r.text[key] = t
+			r.html[key] = h
+		}
+	}
+	return r, nil
+}
+
+// SupportsLocale reports whether templates exist for locale.
+func SupportsLocale(locale string) bool {
+	for _, l := range Locales {
+		if l == locale {
+			return true
+		}
+	}
+	return false
+}
+
+// Render renders the named template in locale, falling back to
+// DefaultLocale.
+func (r *Renderer) Render(locale, name string, view View) (*Rendered, error) {
+	if !SupportsLocale(locale) {
+		locale = DefaultLocale
+	}
+	name = locale + "/" + name
+	t, ok := r.text[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown template %q", name)
+	}
+
+	var subject, text, html bytes.Buffer
+	if err := t.ExecuteTemplate(&subject, "subject", view); err != nil {
+		return nil, fmt.Errorf("failed to render subject of %s: %w", name, err)
+	}
+	if err := t.Execute(&text, view); err != nil {
+		return nil, fmt.Errorf("failed to render text of %s: %w", name, err)
+	}
+	if err := r.html[name].Execute(&html, view); err != nil {
+		return nil, fmt.Errorf("failed to render HTML of %s: %w", name, err)
+	}
+
+	return &Rendered{
+		Subject: strings.TrimSpace(subject.String()),
+		Text:    strings.TrimSpace(text.String()) + "\n",
+		HTML:    html.String(),
+	}, nil
+}
@@ -0,0 +1,75 @@
+package templates
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/gavinadlan/tripnest/backend/notification-service/internal/model"
+)
+
+func TestRenderEveryTemplate(t *testing.T) {
+	r, err := NewRenderer()
+	if err != nil {
+		t.Fatal(err)
+	}
+	view := View{
+		Contact: model.Contact{ID: "u1", Email: "ada@example.com", FirstName: "Ada <b>"},
+		TemplateData: model.TemplateData{
+			BookingID: "b-123", ResourceID: "l-456", Amount: 120, RefundAmount: 60, Reason: "changed plans",
+		},
+	}
+
+	for _, locale := range Locales {
+		for _, name := range Names {
+			msg, err := r.Render(locale, name, view)
+			if err != nil {
+				t.Errorf("%s/%s: %v", locale, name, err)
+				continue
+			}
+			if msg.Subject == "" || strings.Contains(msg.Subject, "\n") {
+				t.Errorf("%s/%s: subject %q", locale, name, msg.Subject)
+			}
+			for part, body := range map[string]string{"text": msg.Text, "HTML": msg.HTML} {
+				if !strings.Contains(body, "b-123") {
+					t.Errorf("%s/%s: %s body lacks the booking reference", locale, name, part)
+				}
+				if strings.Contains(body, "<no value>") {
+					t.Errorf("%s/%s: %s body renders a missing field", locale, name, part)
+				}
+			}
+			if !strings.Contains(msg.Text, "Ada <b>") {
+				t.Errorf("%s/%s: text does not greet the user", locale, name)
+			}
+			if strings.Contains(msg.HTML, "Ada <b>") || !strings.Contains(msg.HTML, "Ada &lt;b&gt;") {
+				t.Errorf("%s/%s: HTML does not escape the user's name", locale, name)
+			}
+		}
+	}
+}
+
+func TestRenderFallsBackToDefaultLocale(t *testing.T) {
+	r, err := NewRenderer()
+	if err != nil {
+		t.Fatal(err)
+	}
+	view := View{TemplateData: model.TemplateData{BookingID: "b-123"}}
+
+	want, err := r.Render(DefaultLocale, BookingConfirmed, view)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := r.Render("fr", BookingConfirmed, view)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Subject != want.Subject || got.Text != want.Text {
+		t.Fatalf("unsupported locale rendered %q, want the %s template", got.Subject, DefaultLocale)
+	}
+	if !strings.Contains(got.Text, "there") {
+		t.Fatalf("text %q does not fall back to a generic greeting", got.Text)
+	}
+
+	if _, err := r.Render(DefaultLocale, "no_such_template", view); err == nil {
+		t.Fatal("unknown template rendered")
+	}
+}
@@ -0,0 +1,21 @@
+CREATE TABLE IF NOT EXISTS deliveries (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    event_key VARCHAR(255) NOT NULL,
+    channel VARCHAR(50) NOT NULL,
+    user_id UUID NOT NULL,
+    template VARCHAR(100) NOT NULL,
+    data JSONB NOT NULL DEFAULT '{}',
+    recipient VARCHAR(255),
+    status VARCHAR(50) NOT NULL DEFAULT 'PENDING',
+    attempts INT NOT NULL DEFAULT 0,
+    last_error TEXT,
+    next_attempt_at TIMESTAMP WITH TIME ZONE,
+    sent_at TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+-- One delivery per event and channel, however often the event is redelivered
+CREATE UNIQUE INDEX idx_deliveries_event_channel ON deliveries(event_key, channel);
+CREATE INDEX idx_deliveries_user_id ON deliveries(user_id);
+CREATE INDEX idx_deliveries_retry ON deliveries(next_attempt_at) WHERE status = 'FAILED';
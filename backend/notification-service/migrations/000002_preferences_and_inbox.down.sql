@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS idx_deliveries_retry;
+CREATE INDEX idx_deliveries_retry ON deliveries(next_attempt_at) WHERE status = 'FAILED';
+
+DROP TABLE IF EXISTS notifications;
+DROP TABLE IF EXISTS notification_preferences;
@@ -0,0 +1,33 @@
+CREATE TABLE IF NOT EXISTS notification_preferences (
+    user_id UUID PRIMARY KEY,
+    channels TEXT[] NOT NULL,
+    event_types TEXT[] NOT NULL DEFAULT '{}',
+    quiet_hours_start VARCHAR(5),
+    quiet_hours_end VARCHAR(5),
+    timezone VARCHAR(64) NOT NULL DEFAULT 'UTC',
+    locale VARCHAR(10) NOT NULL DEFAULT 'en',
+    phone VARCHAR(32),
+    webhook_url TEXT,
+    webhook_secret TEXT,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS notifications (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id UUID NOT NULL,
+    delivery_id UUID NOT NULL REFERENCES deliveries(id),
+    template VARCHAR(100) NOT NULL,
+    title TEXT NOT NULL,
+    body TEXT NOT NULL,
+    data JSONB NOT NULL DEFAULT '{}',
+    read_at TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE UNIQUE INDEX idx_notifications_delivery_id ON notifications(delivery_id);
+CREATE INDEX idx_notifications_user_created ON notifications(user_id, created_at DESC, id DESC);
+CREATE INDEX idx_notifications_unread ON notifications(user_id) WHERE read_at IS NULL;
+
+-- Deliveries held back by quiet hours wait as DEFERRED until next_attempt_at
+DROP INDEX IF EXISTS idx_deliveries_retry;
+CREATE INDEX idx_deliveries_retry ON deliveries(next_attempt_at) WHERE status IN ('FAILED', 'DEFERRED');
@@ -10,6 +10,7 @@ import (
 
 	"github.com/gavinadlan/tripnest/backend/payment-service/internal/config"
 	"github.com/gavinadlan/tripnest/backend/payment-service/internal/db"
+	"github.com/gavinadlan/tripnest/backend/payment-service/internal/gateway"
 	"github.com/gavinadlan/tripnest/backend/payment-service/internal/model"
 	"github.com/gavinadlan/tripnest/backend/payment-service/internal/msgbroker"
 	"github.com/gavinadlan/tripnest/backend/payment-service/internal/repository"
@@ -32,12 +33,33 @@ func main() {
 	producer := msgbroker.NewProducer(cfg.KafkaBrokers)
 	defer producer.Close()
 
-	svc := service.NewPaymentService(repo, producer)
+	var gw gateway.PaymentGateway
+	switch cfg.Gateway {
+	case "http":
+		gw = gateway.NewHTTPGateway(cfg.GatewayURL, cfg.GatewayAPIKey, cfg.GatewayTimeout)
+	case "simulator":
+		gw = gateway.NewSimulator(gateway.SimulatorConfig{
+			FailAmountAbove: cfg.SimFailAbove,
+			FailTokens:      cfg.SimFailTokens,
+			FailPercent:     cfg.SimFailPercent,
+		})
+	default:
+		log.Fatalf("Unknown payment gateway %q", cfg.Gateway)
+	}
+	log.Printf("Using %s payment gateway", cfg.Gateway)
+
+	svc := service.NewPaymentService(repo, producer, gw, cfg.Currency, cfg.PaymentLease)
 
 	consumer := msgbroker.NewConsumer(cfg.KafkaBrokers, "booking.created", "payment-service-group")
 	defer consumer.Close()
 
-	log.Println("Payment Service Started (Listening for booking.created)")
+	refundConsumer := msgbroker.NewConsumer(cfg.KafkaBrokers, "booking.cancel_requested", "payment-service-group")
+	defer refundConsumer.Close()
+
+	expiredConsumer := msgbroker.NewConsumer(cfg.KafkaBrokers, "booking.expired", "payment-service-group")
+	defer expiredConsumer.Close()
+
+	log.Println("Payment Service Started (Listening for booking.created, booking.cancel_requested, booking.expired)")
 
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
@@ -57,7 +79,53 @@ func main() {
 			}
 
 			log.Printf("Received booking event: %v", event.BookingID)
-			go svc.ProcessPayment(ctx, event)
+			go func() {
+				if err := svc.ProcessPayment(ctx, event); err != nil {
+					log.Printf("Failed to process payment for booking %s: %v", event.BookingID, err)
+				}
+			}()
+		}
+	}()
+
+	go func() {
+		for {
+			msg, err := refundConsumer.ReadMessage(ctx)
+			if err != nil {
+				log.Printf("Refund consumer error: %v", err)
+				break
+			}
+
+			var event model.CancelRequestedEvent
+			if err := json.Unmarshal(msg.Value, &event); err != nil {
+				log.Printf("Failed to unmarshal cancellation event: %v", err)
+				continue
+			}
+
+			log.Printf("Received cancellation request for booking: %v", event.BookingID)
+			if err := svc.ProcessRefund(ctx, event); err != nil {
+				log.Printf("Failed to process refund for booking %s: %v", event.BookingID, err)
+			}
+		}
+	}()
+
+	go func() {
+		for {
+			msg, err := expiredConsumer.ReadMessage(ctx)
+			if err != nil {
+				log.Printf("Expiry consumer error: %v", err)
+				break
+			}
+
+			var event model.BookingExpiredEvent
+			if err := json.Unmarshal(msg.Value, &event); err != nil {
+				log.Printf("Failed to unmarshal expiry event: %v", err)
+				continue
+			}
+
+			log.Printf("Received expiry for booking: %v", event.BookingID)
+			if err := svc.ProcessExpiry(ctx, event); err != nil {
+				log.Printf("Failed to settle payment for expired booking %s: %v", event.BookingID, err)
+			}
 		}
 	}()
 
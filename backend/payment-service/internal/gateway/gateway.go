@@ -0,0 +1,71 @@
+package gateway
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// PaymentGateway is the card processor used to charge bookings. Charges are
+// two-step: Authorize places a hold that is later captured or voided.
+type PaymentGateway interface {
+	Authorize(ctx context.Context, req AuthorizeRequest) (*Authorization, error)
+	Capture(ctx context.Context, authorizationID string, amount float64) (*Transaction, error)
+	Void(ctx context.Context, authorizationID string) error
+	Refund(ctx context.Context, req RefundRequest) (*Refund, error)
+}
+
+type AuthorizeRequest struct {
+	BookingID    string
+	Amount       float64
+	Currency     string
+	PaymentToken string
+	// IdempotencyKey lets the gateway collapse retries of the same request.
+	IdempotencyKey string
+}
+
+type RefundRequest struct {
+	TransactionID string
+	Amount        float64
+	// IdempotencyKey identifies the refund (e.g. the cancellation request),
+	// so retries collapse while separate refunds of the same amount do not.
+	IdempotencyKey string
+}
+
+type Authorization struct {
+	ID     string
+	Amount float64
+}
+
+type Transaction struct {
+	ID     string
+	Amount float64
+}
+
+type Refund struct {
+	ID            string
+	TransactionID string
+	Amount        float64
+}
+
+var (
+	ErrNotFound       = errors.New("gateway: unknown authorization or transaction")
+	ErrInvalidRequest = errors.New("gateway: invalid request")
+)
+
+// DeclineError is returned when the processor refuses a charge. Unlike
+// transport errors it is a definitive answer and must not be retried.
+type DeclineError struct {
+	Code    string
+	Message string
+}
+
+func (e *DeclineError) Error() string {
+	return fmt.Sprintf("payment declined (%s): %s", e.Code, e.Message)
+}
+
+// IsDecline reports whether err is a definitive decline from the processor.
+func IsDecline(err error) bool {
+	var d *DeclineError
+	return errors.As(err, &d)
+}
@@ -0,0 +1,157 @@
+package gateway
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// HTTPGateway talks to a Stripe-style REST API: form-encoded requests,
+// bearer authentication, amounts in minor units and an Idempotency-Key
+// header on every mutating call.
+type HTTPGateway struct {
+	baseURL string
+	apiKey  string
+	client  *http.Client
+}
+
+func NewHTTPGateway(baseURL, apiKey string, timeout time.Duration) *HTTPGateway {
+	return &HTTPGateway{
+		baseURL: strings.TrimRight(baseURL, "/"),
+		apiKey:  apiKey,
+		client:  &http.Client{Timeout: timeout},
+	}
+}
+
+type paymentIntent struct {
+	ID             string `json:"id"`
+	Status         string `json:"status"`
+	Amount         int64  `json:"amount"`
+	AmountReceived int64  `json:"amount_received"`
+}
+
+type refundObject struct {
+	ID            string `json:"id"`
+	PaymentIntent string `json:"payment_intent"`
+	Amount        int64  `json:"amount"`
+	Status        string `json:"status"`
+}
+
+type apiError struct {
+	Error struct {
+		Type    string `json:"type"`
+		Code    string `json:"code"`
+		Message string `json:"message"`
+	} `json:"error"`
+}
+
+func (g *HTTPGateway) Authorize(ctx context.Context, req AuthorizeRequest) (*Authorization, error) {
+	form := url.Values{}
+	form.Set("amount", strconv.FormatInt(toMinorUnits(req.Amount), 10))
+	form.Set("currency", strings.ToLower(req.Currency))
+	form.Set("payment_method", req.PaymentToken)
+	form.Set("capture_method", "manual")
+	form.Set("confirm", "true")
+	form.Set("metadata[booking_id]", req.BookingID)
+
+	var pi paymentIntent
+	if err := g.post(ctx, "/v1/payment_intents", form, "authorize-"+req.IdempotencyKey, &pi); err != nil {
+		return nil, err
+	}
+	if pi.Status != "requires_capture" {
+		return nil, &DeclineError{Code: pi.Status, Message: "payment intent was not authorized"}
+	}
+	return &Authorization{ID: pi.ID, Amount: fromMinorUnits(pi.Amount)}, nil
+}
+
+func (g *HTTPGateway) Capture(ctx context.Context, authorizationID string, amount float64) (*Transaction, error) {
+	form := url.Values{}
+	form.Set("amount_to_capture", strconv.FormatInt(toMinorUnits(amount), 10))
+
+	var pi paymentIntent
+	path := "/v1/payment_intents/" + url.PathEscape(authorizationID) + "/capture"
+	if err := g.post(ctx, path, form, "capture-"+authorizationID, &pi); err != nil {
+		return nil, err
+	}
+	return &Transaction{ID: pi.ID, Amount: fromMinorUnits(pi.AmountReceived)}, nil
+}
+
+func (g *HTTPGateway) Void(ctx context.Context, authorizationID string) error {
+	path := "/v1/payment_intents/" + url.PathEscape(authorizationID) + "/cancel"
+	return g.post(ctx, path, url.Values{}, "void-"+authorizationID, nil)
+}
+
+func (g *HTTPGateway) Refund(ctx context.Context, req RefundRequest) (*Refund, error) {
+	form := url.Values{}
+	form.Set("payment_intent", req.TransactionID)
+	form.Set("amount", strconv.FormatInt(toMinorUnits(req.Amount), 10))
+
+	var re refundObject
+	if err := g.post(ctx, "/v1/refunds", form, "refund-"+req.IdempotencyKey, &re); err != nil {
+		return nil, err
+	}
+	return &Refund{ID: re.ID, TransactionID: req.TransactionID, Amount: fromMinorUnits(re.Amount)}, nil
+}
+
+func (g *HTTPGateway) post(ctx context.Context, path string, form url.Values, idempotencyKey string, dst interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, g.baseURL+path, strings.NewReader(form.Encode()))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+g.apiKey)
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Idempotency-Key", idempotencyKey)
+
+	resp, err := g.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("gateway request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return fmt.Errorf("failed to read gateway response: %w", err)
+	}
+
+	if resp.StatusCode >= 300 {
+		return decodeAPIError(resp.StatusCode, body)
+	}
+	if dst == nil {
+		return nil
+	}
+	if err := json.Unmarshal(body, dst); err != nil {
+		return fmt.Errorf("failed to decode gateway response: %w", err)
+	}
+	return nil
+}
+
+func decodeAPIError(status int, body []byte) error {
+	var apiErr apiError
+	_ = json.Unmarshal(body, &apiErr)
+
+	switch {
+	case status == http.StatusPaymentRequired || apiErr.Error.Type == "card_error":
+		return &DeclineError{Code: apiErr.Error.Code, Message: apiErr.Error.Message}
+	case status == http.StatusNotFound:
+		return ErrNotFound
+	case status == http.StatusBadRequest:
+		return fmt.Errorf("%w: %s", ErrInvalidRequest, apiErr.Error.Message)
+	default:
+		return fmt.Errorf("gateway returned status %d: %s", status, apiErr.Error.Message)
+	}
+}
+
+func toMinorUnits(amount float64) int64 {
+	return int64(math.Round(amount * 100))
+}
+
+func fromMinorUnits(amount int64) float64 {
+	return float64(amount) / 100
+}
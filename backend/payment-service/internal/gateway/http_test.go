@@ -0,0 +1,181 @@
+package gateway
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+)
+
+type recordedRequest struct {
+	method, path, idempotencyKey, authorization, contentType string
+	form                                                     url.Values
+}
+
+// newTestGateway serves every request with status and body, recording it.
+func newTestGateway(t *testing.T, status int, body string) (*HTTPGateway, *[]recordedRequest) {
+	t.Helper()
+	var requests []recordedRequest
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		raw, _ := io.ReadAll(r.Body)
+		form, _ := url.ParseQuery(string(raw))
+		requests = append(requests, recordedRequest{
+			method:         r.Method,
+			path:           r.URL.EscapedPath(),
+			idempotencyKey: r.Header.Get("Idempotency-Key"),
+			authorization:  r.Header.Get("Authorization"),
+			contentType:    r.Header.Get("Content-Type"),
+			form:           form,
+		})
+		w.WriteHeader(status)
+		io.WriteString(w, body)
+	}))
+	t.Cleanup(srv.Close)
+	return NewHTTPGateway(srv.URL+"/", "sk_test", time.Second), &requests
+}
+
+func TestHTTPGatewayAuthorize(t *testing.T) {
+	gw, requests := newTestGateway(t, http.StatusOK, `{"id":"pi_1","status":"requires_capture","amount":12345}`)
+
+	auth, err := gw.Authorize(context.Background(), AuthorizeRequest{
+		BookingID:      "b1",
+		Amount:         123.45,
+		Currency:       "USD",
+		PaymentToken:   "pm_card",
+		IdempotencyKey: "b1",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if auth.ID != "pi_1" || auth.Amount != 123.45 {
+		t.Fatalf("authorization %+v", auth)
+	}
+
+	req := (*requests)[0]
+	if req.method != http.MethodPost || req.path != "/v1/payment_intents" {
+		t.Fatalf("request %s %s", req.method, req.path)
+	}
+	if req.authorization != "Bearer sk_test" || req.contentType != "application/x-www-form-urlencoded" {
+		t.Fatalf("headers Authorization=%q Content-Type=%q", req.authorization, req.contentType)
+	}
+	if req.idempotencyKey != "authorize-b1" {
+		t.Fatalf("Idempotency-Key %q", req.idempotencyKey)
+	}
+	want := map[string]string{
+		"amount":               "12345",
+		"currency":             "usd",
+		"payment_method":       "pm_card",
+		"capture_method":       "manual",
+		"confirm":              "true",
+		"metadata[booking_id]": "b1",
+	}
+	for field, value := range want {
+		if got := req.form.Get(field); got != value {
+			t.Errorf("form %s = %q, want %q", field, got, value)
+		}
+	}
+}
+
+func TestHTTPGatewayAuthorizeNotAuthorizedIsDecline(t *testing.T) {
+	gw, _ := newTestGateway(t, http.StatusOK, `{"id":"pi_1","status":"requires_payment_method","amount":100}`)
+
+	_, err := gw.Authorize(context.Background(), AuthorizeRequest{Amount: 1, IdempotencyKey: "b1"})
+	var decline *DeclineError
+	if !errors.As(err, &decline) || decline.Code != "requires_payment_method" {
+		t.Fatalf("got %v, want a decline with the intent status", err)
+	}
+}
+
+func TestHTTPGatewayCaptureAndVoid(t *testing.T) {
+	gw, requests := newTestGateway(t, http.StatusOK, `{"id":"pi_1/x","status":"succeeded","amount_received":5000}`)
+
+	txn, err := gw.Capture(context.Background(), "pi_1/x", 50)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if txn.ID != "pi_1/x" || txn.Amount != 50 {
+		t.Fatalf("transaction %+v", txn)
+	}
+	if err := gw.Void(context.Background(), "pi_1/x"); err != nil {
+		t.Fatal(err)
+	}
+
+	capture, void := (*requests)[0], (*requests)[1]
+	if capture.path != "/v1/payment_intents/pi_1%2Fx/capture" || capture.idempotencyKey != "capture-pi_1/x" {
+		t.Fatalf("capture %s with key %q", capture.path, capture.idempotencyKey)
+	}
+	if got := capture.form.Get("amount_to_capture"); got != "5000" {
+		t.Fatalf("amount_to_capture %q", got)
+	}
+	if void.path != "/v1/payment_intents/pi_1%2Fx/cancel" || void.idempotencyKey != "void-pi_1/x" {
+		t.Fatalf("void %s with key %q", void.path, void.idempotencyKey)
+	}
+}
+
+func TestHTTPGatewayRefundKeyedByRequest(t *testing.T) {
+	gw, requests := newTestGateway(t, http.StatusOK, `{"id":"re_1","payment_intent":"pi_1","amount":2500,"status":"succeeded"}`)
+
+	for _, key := range []string{"cancel-1", "cancel-2"} {
+		re, err := gw.Refund(context.Background(), RefundRequest{TransactionID: "pi_1", Amount: 25, IdempotencyKey: key})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if re.ID != "re_1" || re.TransactionID != "pi_1" || re.Amount != 25 {
+			t.Fatalf("refund %+v", re)
+		}
+	}
+
+	first, second := (*requests)[0], (*requests)[1]
+	if first.path != "/v1/refunds" || first.form.Get("payment_intent") != "pi_1" || first.form.Get("amount") != "2500" {
+		t.Fatalf("refund request %s %v", first.path, first.form)
+	}
+	if first.idempotencyKey != "refund-cancel-1" || second.idempotencyKey != "refund-cancel-2" {
+		t.Fatalf("two refunds of the same amount sent keys %q and %q", first.idempotencyKey, second.idempotencyKey)
+	}
+}
+
+func TestHTTPGatewayErrorClassification(t *testing.T) {
+	cases := []struct {
+		name    string
+		status  int
+		body    string
+		decline bool
+		target  error
+	}{
+		{"payment required", http.StatusPaymentRequired, `{"error":{"type":"card_error","code":"card_declined","message":"declined"}}`, true, nil},
+		{"card error on 400", http.StatusBadRequest, `{"error":{"type":"card_error","code":"expired_card","message":"expired"}}`, true, nil},
+		{"not found", http.StatusNotFound, `{"error":{"type":"invalid_request_error","message":"no such intent"}}`, false, ErrNotFound},
+		{"invalid request", http.StatusBadRequest, `{"error":{"type":"invalid_request_error","message":"bad amount"}}`, false, ErrInvalidRequest},
+		{"server error", http.StatusInternalServerError, `not json`, false, nil},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			gw, _ := newTestGateway(t, tc.status, tc.body)
+			_, err := gw.Capture(context.Background(), "pi_1", 10)
+			if err == nil {
+				t.Fatal("expected an error")
+			}
+			if IsDecline(err) != tc.decline {
+				t.Fatalf("IsDecline(%v) = %t, want %t", err, IsDecline(err), tc.decline)
+			}
+			if tc.target != nil && !errors.Is(err, tc.target) {
+				t.Fatalf("error %v is not %v", err, tc.target)
+			}
+		})
+	}
+}
+
+func TestHTTPGatewayTransportErrorIsNotDecline(t *testing.T) {
+	srv := httptest.NewServer(http.NotFoundHandler())
+	srv.Close()
+	gw := NewHTTPGateway(srv.URL, "sk_test", time.Second)
+
+	_, err := gw.Authorize(context.Background(), AuthorizeRequest{Amount: 1, IdempotencyKey: "b1"})
+	if err == nil || IsDecline(err) {
+		t.Fatalf("unreachable gateway gave %v, want a retryable error", err)
+	}
+}
@@ -0,0 +1,146 @@
+package gateway
+
+import (
+	"context"
+	"fmt"
+	"hash/fnv"
+	"sync"
+)
+
+// SimulatorConfig decides which charges the simulator declines. All rules
+// are deterministic so QA can drive the payment.failed branch on purpose.
+type SimulatorConfig struct {
+	// FailAmountAbove declines authorizations above this amount (0 disables).
+	FailAmountAbove float64
+	// FailTokens declines authorizations using any of these payment tokens.
+	FailTokens []string
+	// FailPercent declines this share of bookings, chosen by hashing the
+	// idempotency key so a given booking always gets the same answer.
+	FailPercent int
+}
+
+// Simulator is an in-memory PaymentGateway for local development and QA.
+type Simulator struct {
+	cfg SimulatorConfig
+
+	mu             sync.Mutex
+	authorizations map[string]*simAuthorization
+	captured       map[string]float64
+	refunded       map[string]float64
+	refunds        map[string]*Refund // By idempotency key
+	seq            int
+}
+
+type simAuthorization struct {
+	amount   float64
+	captured bool
+	voided   bool
+}
+
+func NewSimulator(cfg SimulatorConfig) *Simulator {
+	return &Simulator{
+		cfg:            cfg,
+		authorizations: make(map[string]*simAuthorization),
+		captured:       make(map[string]float64),
+		refunded:       make(map[string]float64),
+		refunds:        make(map[string]*Refund),
+	}
+}
+
+func (s *Simulator) Authorize(ctx context.Context, req AuthorizeRequest) (*Authorization, error) {
+	if req.Amount <= 0 {
+		return nil, ErrInvalidRequest
+	}
+	if err := s.decline(req); err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	id := "auth_" + req.IdempotencyKey
+	if _, ok := s.authorizations[id]; !ok {
+		s.authorizations[id] = &simAuthorization{amount: req.Amount}
+	}
+	return &Authorization{ID: id, Amount: s.authorizations[id].amount}, nil
+}
+
+func (s *Simulator) decline(req AuthorizeRequest) error {
+	if s.cfg.FailAmountAbove > 0 && req.Amount > s.cfg.FailAmountAbove {
+		return &DeclineError{Code: "amount_too_large", Message: fmt.Sprintf("amount %.2f exceeds limit %.2f", req.Amount, s.cfg.FailAmountAbove)}
+	}
+	for _, token := range s.cfg.FailTokens {
+		if token != "" && token == req.PaymentToken {
+			return &DeclineError{Code: "card_declined", Message: "card token is configured to decline"}
+		}
+	}
+	if s.cfg.FailPercent > 0 {
+		h := fnv.New32a()
+		h.Write([]byte(req.IdempotencyKey))
+		if int(h.Sum32()%100) < s.cfg.FailPercent {
+			return &DeclineError{Code: "simulated_failure", Message: "declined by simulated failure rate"}
+		}
+	}
+	return nil
+}
+
+func (s *Simulator) Capture(ctx context.Context, authorizationID string, amount float64) (*Transaction, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	auth, ok := s.authorizations[authorizationID]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	if auth.voided || amount <= 0 || amount > auth.amount {
+		return nil, ErrInvalidRequest
+	}
+	auth.captured = true
+	txnID := "txn_" + authorizationID[len("auth_"):]
+	s.captured[txnID] = amount
+	return &Transaction{ID: txnID, Amount: amount}, nil
+}
+
+func (s *Simulator) Void(ctx context.Context, authorizationID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	auth, ok := s.authorizations[authorizationID]
+	if !ok {
+		return ErrNotFound
+	}
+	if auth.captured {
+		return ErrInvalidRequest
+	}
+	auth.voided = true
+	return nil
+}
+
+func (s *Simulator) Refund(ctx context.Context, req RefundRequest) (*Refund, error) {
+	if req.Amount <= 0 {
+		return nil, ErrInvalidRequest
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if re, ok := s.refunds[req.IdempotencyKey]; ok {
+		return re, nil
+	}
+	// The simulator forgets captures on restart, so refunds for unknown
+	// transactions are accepted rather than rejected.
+	if captured, ok := s.captured[req.TransactionID]; ok && s.refunded[req.TransactionID]+req.Amount > captured+0.005 {
+		return nil, ErrInvalidRequest
+	}
+	s.seq++
+	s.refunded[req.TransactionID] += req.Amount
+	re := &Refund{
+		ID:            fmt.Sprintf("re_%s_%d", req.TransactionID, s.seq),
+		TransactionID: req.TransactionID,
+		Amount:        req.Amount,
+	}
+	if req.IdempotencyKey != "" {
+		s.refunds[req.IdempotencyKey] = re
+	}
+	return re, nil
+}
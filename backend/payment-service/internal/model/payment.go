@@ -4,28 +4,54 @@ import (
 	"time"
 )
 
+const (
+	PaymentStatusPending = "PENDING"
+	PaymentStatusSuccess = "SUCCESS"
+	PaymentStatusFailed  = "FAILED"
+
+	PaymentStatusPartiallyRefunded = "PARTIALLY_REFUNDED"
+	PaymentStatusRefunded          = "REFUNDED"
+)
+
 type Payment struct {
-	ID            string    `json:"id" db:"id"`
-	BookingID     string    `json:"booking_id" db:"booking_id"`
-	Amount        float64   `json:"amount" db:"amount"`
-	Status        string    `json:"status" db:"status"`
-	TransactionID string    `json:"transaction_id" db:"transaction_id"`
-	CreatedAt     time.Time `json:"created_at" db:"created_at"`
-	UpdatedAt     time.Time `json:"updated_at" db:"updated_at"`
+	ID              string  `json:"id" db:"id"`
+	BookingID       string  `json:"booking_id" db:"booking_id"`
+	UserID          string  `json:"user_id" db:"user_id"`
+	Amount          float64 `json:"amount" db:"amount"`
+	Status          string  `json:"status" db:"status"`
+	TransactionID   string  `json:"transaction_id" db:"transaction_id"`
+	AuthorizationID string  `json:"authorization_id" db:"authorization_id"`
+	FailureReason   string  `json:"failure_reason,omitempty" db:"failure_reason"`
+	// BookingExpiredAt is set once booking-service has expired the booking;
+	// any charge made for it afterwards is reversed.
+	BookingExpiredAt *time.Time `json:"booking_expired_at,omitempty" db:"booking_expired_at"`
+	CreatedAt        time.Time  `json:"created_at" db:"created_at"`
+	UpdatedAt        time.Time  `json:"updated_at" db:"updated_at"`
 }
 
 type BookingEvent struct {
-	BookingID   string  `json:"booking_id"`
-	UserID      string  `json:"user_id"`
-	ResourceID  string  `json:"resource_id"`
-	TotalAmount float64 `json:"total_amount"`
-	Type        string  `json:"type"` // created, confirmed, failed
+	BookingID    string  `json:"booking_id"`
+	UserID       string  `json:"user_id"`
+	ResourceID   string  `json:"resource_id"`
+	TotalAmount  float64 `json:"total_amount"`
+	PaymentToken string  `json:"payment_token,omitempty"`
+	Type         string  `json:"type"` // created, confirmed, failed
+}
+
+type BookingExpiredEvent struct {
+	BookingID   string    `json:"booking_id"`
+	UserID      string    `json:"user_id"`
+	ResourceID  string    `json:"resource_id"`
+	TotalAmount float64   `json:"total_amount"`
+	ExpiredAt   time.Time `json:"expired_at"`
 }
 
 type PaymentEvent struct {
 	PaymentID     string  `json:"payment_id"`
 	BookingID     string  `json:"booking_id"`
+	UserID        string  `json:"user_id,omitempty"`
 	Amount        float64 `json:"amount"`
 	Status        string  `json:"status"` // SUCCESS, FAILED
 	TransactionID string  `json:"transaction_id"`
+	Reason        string  `json:"reason,omitempty"`
 }
@@ -0,0 +1,44 @@
+package model
+
+import (
+	"time"
+)
+
+const (
+	RefundStatusPending = "PENDING"
+	RefundStatusSuccess = "SUCCESS"
+	RefundStatusFailed  = "FAILED"
+)
+
+type Refund struct {
+	ID              string    `json:"id" db:"id"`
+	RequestID       string    `json:"request_id" db:"request_id"`
+	PaymentID       string    `json:"payment_id" db:"payment_id"`
+	BookingID       string    `json:"booking_id" db:"booking_id"`
+	UserID          string    `json:"user_id" db:"user_id"`
+	Amount          float64   `json:"amount" db:"amount"`
+	Status          string    `json:"status" db:"status"`
+	GatewayRefundID string    `json:"gateway_refund_id" db:"gateway_refund_id"`
+	FailureReason   string    `json:"failure_reason,omitempty" db:"failure_reason"`
+	CreatedAt       time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt       time.Time `json:"updated_at" db:"updated_at"`
+}
+
+type CancelRequestedEvent struct {
+	RequestID    string  `json:"request_id"`
+	BookingID    string  `json:"booking_id"`
+	UserID       string  `json:"user_id"`
+	TotalAmount  float64 `json:"total_amount"`
+	RefundAmount float64 `json:"refund_amount"`
+	Reason       string  `json:"reason,omitempty"`
+}
+
+type RefundEvent struct {
+	RefundID  string  `json:"refund_id"`
+	RequestID string  `json:"request_id"`
+	BookingID string  `json:"booking_id"`
+	UserID    string  `json:"user_id,omitempty"`
+	Amount    float64 `json:"amount"`
+	Status    string  `json:"status"` // SUCCESS, FAILED
+	Reason    string  `json:"reason,omitempty"`
+}
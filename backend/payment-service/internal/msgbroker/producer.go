@@ -7,6 +7,11 @@ import (
 	"github.com/segmentio/kafka-go"
 )
 
+// Publisher is the part of Producer the services depend on.
+type Publisher interface {
+	Publish(ctx context.Context, topic string, key string, payload interface{}) error
+}
+
 type Producer struct {
 	writer *kafka.Writer
 }
@@ -0,0 +1,100 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/gavinadlan/tripnest/backend/payment-service/internal/model"
+	"github.com/jackc/pgx/v5"
+)
+
+// ErrDuplicateRefund is returned by CreateRefund when the cancellation
+// request has already been recorded (unique index on request_id).
+var ErrDuplicateRefund = errors.New("refund already exists for request")
+
+func (r *postgresRepository) CreateRefund(ctx context.Context, rf *model.Refund) error {
+	query := `
+        INSERT INTO refunds (request_id, payment_id, booking_id, user_id, amount, status, created_at, updated_at)
+        VALUES ($1, NULLIF($2, '')::uuid, $3, NULLIF($4, '')::uuid, $5, $6, $7, $7)
+        RETURNING id
+    `
+	rf.CreatedAt = time.Now()
+	rf.UpdatedAt = rf.CreatedAt
+	err := r.db.QueryRow(ctx, query, rf.RequestID, rf.PaymentID, rf.BookingID, rf.UserID, rf.Amount, rf.Status, rf.CreatedAt).Scan(&rf.ID)
+	if err != nil {
+		if isUniqueViolation(err) {
+			return ErrDuplicateRefund
+		}
+		return fmt.Errorf("failed to create refund: %w", err)
+	}
+	return nil
+}
+
+func (r *postgresRepository) GetRefundByRequestID(ctx context.Context, requestID string) (*model.Refund, error) {
+	query := `
+        SELECT id, request_id, COALESCE(payment_id::text, ''), booking_id, COALESCE(user_id::text, ''), amount, status,
+               COALESCE(gateway_refund_id, ''), COALESCE(failure_reason, ''), created_at, updated_at
+        FROM refunds WHERE request_id = $1
+    `
+	var rf model.Refund
+	err := r.db.QueryRow(ctx, query, requestID).Scan(
+		&rf.ID, &rf.RequestID, &rf.PaymentID, &rf.BookingID, &rf.UserID, &rf.Amount, &rf.Status,
+		&rf.GatewayRefundID, &rf.FailureReason, &rf.CreatedAt, &rf.UpdatedAt,
+	)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, nil // Not found
+		}
+		return nil, fmt.Errorf("failed to get refund by request id: %w", err)
+	}
+	return &rf, nil
+}
+
+func (r *postgresRepository) ClaimStaleRefund(ctx context.Context, rf *model.Refund, staleBefore time.Time) (bool, error) {
+	query := `
+        UPDATE refunds SET updated_at = NOW()
+        WHERE id = $1 AND status = $2 AND updated_at < $3
+        RETURNING updated_at
+    `
+	err := r.db.QueryRow(ctx, query, rf.ID, model.RefundStatusPending, staleBefore).Scan(&rf.UpdatedAt)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return false, nil
+		}
+		return false, fmt.Errorf("failed to claim stale refund: %w", err)
+	}
+	return true, nil
+}
+
+// CompleteRefund records the outcome of rf and, when it succeeded, moves
+// payment p to its refunded status in the same transaction.
+func (r *postgresRepository) CompleteRefund(ctx context.Context, rf *model.Refund, p *model.Payment) error {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	query := `
+        UPDATE refunds
+        SET status = $1, gateway_refund_id = NULLIF($2, ''), failure_reason = NULLIF($3, ''), updated_at = NOW()
+        WHERE id = $4
+        RETURNING updated_at
+    `
+	if err := tx.QueryRow(ctx, query, rf.Status, rf.GatewayRefundID, rf.FailureReason, rf.ID).Scan(&rf.UpdatedAt); err != nil {
+		return fmt.Errorf("failed to update refund: %w", err)
+	}
+
+	if p != nil {
+		if _, err := tx.Exec(ctx, `UPDATE payments SET status = $1, updated_at = NOW() WHERE id = $2`, p.Status, p.ID); err != nil {
+			return fmt.Errorf("failed to update payment status: %w", err)
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit refund: %w", err)
+	}
+	return nil
+}
@@ -2,16 +2,39 @@ package repository
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"time"
 
 	"github.com/gavinadlan/tripnest/backend/payment-service/internal/model"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
+// ErrDuplicatePayment is returned by Create when a payment already exists
+// for the booking (unique index on booking_id).
+var ErrDuplicatePayment = errors.New("payment already exists for booking")
+
 type PaymentRepository interface {
 	Create(ctx context.Context, p *model.Payment) error
 	GetByBookingID(ctx context.Context, bookingID string) (*model.Payment, error)
+	UpdateResult(ctx context.Context, p *model.Payment) error
+	// ClaimStale takes over a PENDING payment last touched before
+	// staleBefore, reporting false when it has been finished or claimed
+	// again meanwhile.
+	ClaimStale(ctx context.Context, p *model.Payment, staleBefore time.Time) (bool, error)
+	// MarkBookingExpired records that the booking of p has expired and loads
+	// the stored payment into p. When no payment exists yet p is stored as
+	// given, so a late booking.created finds it and does not charge.
+	MarkBookingExpired(ctx context.Context, p *model.Payment) error
+	CreateRefund(ctx context.Context, rf *model.Refund) error
+	GetRefundByRequestID(ctx context.Context, requestID string) (*model.Refund, error)
+	// ClaimStaleRefund takes over a PENDING refund last touched before
+	// staleBefore, reporting false when it has been finished or claimed
+	// again meanwhile.
+	ClaimStaleRefund(ctx context.Context, rf *model.Refund, staleBefore time.Time) (bool, error)
+	CompleteRefund(ctx context.Context, rf *model.Refund, p *model.Payment) error
 	Close()
 }
 
@@ -37,19 +60,93 @@ func (r *postgresRepository) Close() {
 
 func (r *postgresRepository) Create(ctx context.Context, p *model.Payment) error {
 	query := `
-        INSERT INTO payments (booking_id, amount, status, transaction_id, created_at, updated_at)
-        VALUES ($1, $2, $3, $4, $5, $5)
+        INSERT INTO payments (booking_id, user_id, amount, status, transaction_id, created_at, updated_at)
+        VALUES ($1, NULLIF($2, '')::uuid, $3, $4, $5, $6, $6)
         RETURNING id
     `
 	p.CreatedAt = time.Now()
-	err := r.db.QueryRow(ctx, query, p.BookingID, p.Amount, p.Status, p.TransactionID, p.CreatedAt).Scan(&p.ID)
+	p.UpdatedAt = p.CreatedAt
+	err := r.db.QueryRow(ctx, query, p.BookingID, p.UserID, p.Amount, p.Status, p.TransactionID, p.CreatedAt).Scan(&p.ID)
 	if err != nil {
+		if isUniqueViolation(err) {
+			return ErrDuplicatePayment
+		}
 		return fmt.Errorf("failed to create payment: %w", err)
 	}
 	return nil
 }
 
 func (r *postgresRepository) GetByBookingID(ctx context.Context, bookingID string) (*model.Payment, error) {
-	// Implement check for idempotency (if needed later)
-	return nil, nil // Placeholder
+	query := `
+        SELECT id, booking_id, COALESCE(user_id::text, ''), amount, status, COALESCE(transaction_id, ''),
+               COALESCE(authorization_id, ''), COALESCE(failure_reason, ''), booking_expired_at, created_at, updated_at
+        FROM payments WHERE booking_id = $1
+    `
+	var p model.Payment
+	err := r.db.QueryRow(ctx, query, bookingID).Scan(
+		&p.ID, &p.BookingID, &p.UserID, &p.Amount, &p.Status, &p.TransactionID,
+		&p.AuthorizationID, &p.FailureReason, &p.BookingExpiredAt, &p.CreatedAt, &p.UpdatedAt,
+	)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, nil // Not found
+		}
+		return nil, fmt.Errorf("failed to get payment by booking id: %w", err)
+	}
+	return &p, nil
+}
+
+func (r *postgresRepository) UpdateResult(ctx context.Context, p *model.Payment) error {
+	query := `
+        UPDATE payments
+        SET status = $1, transaction_id = NULLIF($2, ''), authorization_id = NULLIF($3, ''),
+            failure_reason = NULLIF($4, ''), updated_at = NOW()
+        WHERE id = $5
+        RETURNING updated_at, booking_expired_at
+    `
+	err := r.db.QueryRow(ctx, query, p.Status, p.TransactionID, p.AuthorizationID, p.FailureReason, p.ID).Scan(&p.UpdatedAt, &p.BookingExpiredAt)
+	if err != nil {
+		return fmt.Errorf("failed to update payment: %w", err)
+	}
+	return nil
+}
+
+func (r *postgresRepository) ClaimStale(ctx context.Context, p *model.Payment, staleBefore time.Time) (bool, error) {
+	query := `
+        UPDATE payments SET updated_at = NOW()
+        WHERE id = $1 AND status = $2 AND updated_at < $3
+        RETURNING updated_at
+    `
+	err := r.db.QueryRow(ctx, query, p.ID, model.PaymentStatusPending, staleBefore).Scan(&p.UpdatedAt)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return false, nil
+		}
+		return false, fmt.Errorf("failed to claim stale payment: %w", err)
+	}
+	return true, nil
+}
+
+func (r *postgresRepository) MarkBookingExpired(ctx context.Context, p *model.Payment) error {
+	// updated_at is left alone so the lease of a charge in progress holds
+	query := `
+        INSERT INTO payments (booking_id, user_id, amount, status, failure_reason, booking_expired_at, created_at, updated_at)
+        VALUES ($1, NULLIF($2, '')::uuid, $3, $4, NULLIF($5, ''), NOW(), NOW(), NOW())
+        ON CONFLICT (booking_id) DO UPDATE SET booking_expired_at = COALESCE(payments.booking_expired_at, NOW())
+        RETURNING id, COALESCE(user_id::text, ''), amount, status, COALESCE(transaction_id, ''),
+                  COALESCE(authorization_id, ''), COALESCE(failure_reason, ''), booking_expired_at, created_at, updated_at
+    `
+	err := r.db.QueryRow(ctx, query, p.BookingID, p.UserID, p.Amount, p.Status, p.FailureReason).Scan(
+		&p.ID, &p.UserID, &p.Amount, &p.Status, &p.TransactionID,
+		&p.AuthorizationID, &p.FailureReason, &p.BookingExpiredAt, &p.CreatedAt, &p.UpdatedAt,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to mark booking expired: %w", err)
+	}
+	return nil
+}
+
+func isUniqueViolation(err error) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && pgErr.Code == "23505"
 }
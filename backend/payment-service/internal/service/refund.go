@@ -0,0 +1,152 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/gavinadlan/tripnest/backend/payment-service/internal/gateway"
+	"github.com/gavinadlan/tripnest/backend/payment-service/internal/model"
+	"github.com/gavinadlan/tripnest/backend/payment-service/internal/repository"
+)
+
+// ProcessRefund refunds a cancelled booking through the gateway. Like
+// ProcessPayment it claims a refund row (keyed by the cancellation request)
+// before calling the gateway, so redelivered requests replay the recorded
+// outcome instead of refunding twice.
+func (s *paymentService) ProcessRefund(ctx context.Context, event model.CancelRequestedEvent) error {
+	existing, err := s.repo.GetRefundByRequestID(ctx, event.RequestID)
+	if err != nil {
+		return err
+	}
+	if existing != nil {
+		return s.replayRefund(ctx, existing)
+	}
+
+	payment, err := s.repo.GetByBookingID(ctx, event.BookingID)
+	if err != nil {
+		return err
+	}
+
+	refund := &model.Refund{
+		RequestID: event.RequestID,
+		BookingID: event.BookingID,
+		UserID:    event.UserID,
+		Amount:    event.RefundAmount,
+		Status:    model.RefundStatusPending,
+	}
+	if payment != nil {
+		refund.PaymentID = payment.ID
+	}
+
+	if err := s.repo.CreateRefund(ctx, refund); err != nil {
+		if errors.Is(err, repository.ErrDuplicateRefund) {
+			existing, err := s.repo.GetRefundByRequestID(ctx, event.RequestID)
+			if err != nil {
+				return err
+			}
+			if existing == nil {
+				return fmt.Errorf("refund for request %s vanished after duplicate insert", event.RequestID)
+			}
+			return s.replayRefund(ctx, existing)
+		}
+		return fmt.Errorf("failed to claim refund for booking %s: %w", event.BookingID, err)
+	}
+
+	log.Printf("Processing refund for booking %s amount %.2f", event.BookingID, event.RefundAmount)
+	return s.completeRefund(ctx, refund, payment)
+}
+
+// completeRefund refunds a claimed refund through the gateway, stores the
+// outcome and publishes it. The gateway call is keyed by the cancellation
+// request, so a resumed refund is not paid out twice.
+func (s *paymentService) completeRefund(ctx context.Context, refund *model.Refund, payment *model.Payment) error {
+	var updated *model.Payment
+	switch {
+	case payment == nil || payment.Status != model.PaymentStatusSuccess:
+		refund.Status = model.RefundStatusFailed
+		refund.FailureReason = "no captured payment for booking"
+	case refund.Amount <= 0 || refund.Amount > payment.Amount:
+		refund.Status = model.RefundStatusFailed
+		refund.FailureReason = fmt.Sprintf("refund amount %.2f outside captured amount %.2f", refund.Amount, payment.Amount)
+	default:
+		gwRefund, err := s.gateway.Refund(ctx, gateway.RefundRequest{
+			TransactionID:  payment.TransactionID,
+			Amount:         refund.Amount,
+			IdempotencyKey: refund.RequestID,
+		})
+		if err != nil {
+			log.Printf("Refund for booking %s failed: %v", refund.BookingID, err)
+			refund.Status = model.RefundStatusFailed
+			refund.FailureReason = err.Error()
+			break
+		}
+		refund.Status = model.RefundStatusSuccess
+		refund.GatewayRefundID = gwRefund.ID
+
+		updated = payment
+		updated.Status = model.PaymentStatusRefunded
+		if refund.Amount < payment.Amount {
+			updated.Status = model.PaymentStatusPartiallyRefunded
+		}
+	}
+
+	if err := s.repo.CompleteRefund(ctx, refund, updated); err != nil {
+		return err
+	}
+
+	return s.publishRefundOutcome(ctx, refund)
+}
+
+// replayRefund republishes the recorded outcome of an already processed
+// refund, or resumes it when its claim has gone stale.
+func (s *paymentService) replayRefund(ctx context.Context, rf *model.Refund) error {
+	if rf.Status == model.RefundStatusPending {
+		if time.Since(rf.UpdatedAt) < s.lease {
+			log.Printf("Refund for request %s is still in progress, skipping duplicate event", rf.RequestID)
+			return nil
+		}
+		claimed, err := s.repo.ClaimStaleRefund(ctx, rf, time.Now().Add(-s.lease))
+		if err != nil {
+			return err
+		}
+		if !claimed {
+			log.Printf("Refund for request %s was finished or resumed elsewhere, skipping duplicate event", rf.RequestID)
+			return nil
+		}
+		payment, err := s.repo.GetByBookingID(ctx, rf.BookingID)
+		if err != nil {
+			return err
+		}
+		log.Printf("Resuming refund for request %s left pending since %s", rf.RequestID, rf.CreatedAt.Format(time.RFC3339))
+		return s.completeRefund(ctx, rf, payment)
+	}
+	log.Printf("Duplicate cancellation for booking %s, replaying %s refund outcome", rf.BookingID, rf.Status)
+	return s.publishRefundOutcome(ctx, rf)
+}
+
+func (s *paymentService) publishRefundOutcome(ctx context.Context, rf *model.Refund) error {
+	resultEvent := model.RefundEvent{
+		RefundID:  rf.ID,
+		RequestID: rf.RequestID,
+		BookingID: rf.BookingID,
+		UserID:    rf.UserID,
+		Amount:    rf.Amount,
+		Status:    rf.Status,
+		Reason:    rf.FailureReason,
+	}
+
+	topic := "payment.refunded"
+	if rf.Status == model.RefundStatusFailed {
+		topic = "payment.refund_failed"
+	}
+
+	if err := s.producer.Publish(ctx, topic, rf.BookingID, resultEvent); err != nil {
+		log.Printf("Failed to publish %s event: %v", topic, err)
+		return err
+	}
+
+	return nil
+}
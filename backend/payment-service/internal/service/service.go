@@ -2,9 +2,12 @@ package service
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log"
+	"time"
 
+	"github.com/gavinadlan/tripnest/backend/payment-service/internal/gateway"
 	"github.com/gavinadlan/tripnest/backend/payment-service/internal/model"
 	"github.com/gavinadlan/tripnest/backend/payment-service/internal/msgbroker"
 	"github.com/gavinadlan/tripnest/backend/payment-service/internal/repository"
@@ -12,54 +15,244 @@ import (
 
 type PaymentService interface {
 	ProcessPayment(ctx context.Context, bookingEvent model.BookingEvent) error
+	ProcessRefund(ctx context.Context, event model.CancelRequestedEvent) error
+	ProcessExpiry(ctx context.Context, event model.BookingExpiredEvent) error
 }
 
 type paymentService struct {
 	repo     repository.PaymentRepository
-	producer *msgbroker.Producer
+	producer msgbroker.Publisher
+	gateway  gateway.PaymentGateway
+	currency string
+	// lease is how long a PENDING payment is left to the consumer that
+	// claimed it before a redelivery may resume it.
+	lease time.Duration
 }
 
-func NewPaymentService(repo repository.PaymentRepository, producer *msgbroker.Producer) PaymentService {
-	return &paymentService{repo: repo, producer: producer}
+func NewPaymentService(repo repository.PaymentRepository, producer msgbroker.Publisher, gw gateway.PaymentGateway, currency string, lease time.Duration) PaymentService {
+	return &paymentService{repo: repo, producer: producer, gateway: gw, currency: currency, lease: lease}
 }
 
+// ProcessPayment charges a booking at most once. The payment row is claimed
+// (inserted as PENDING) before charging, so a redelivered or concurrently
+// delivered booking.created finds the existing row and replays its outcome
+// instead of charging again. A claim left PENDING for longer than the lease
+// (the process died mid-charge, or the result could not be stored) is
+// resumed by the next delivery.
 func (s *paymentService) ProcessPayment(ctx context.Context, event model.BookingEvent) error {
+	existing, err := s.repo.GetByBookingID(ctx, event.BookingID)
+	if err != nil {
+		return err
+	}
+	if existing != nil {
+		return s.replay(ctx, existing, event.PaymentToken)
+	}
+
+	payment := &model.Payment{
+		BookingID: event.BookingID,
+		UserID:    event.UserID,
+		Amount:    event.TotalAmount,
+		Status:    model.PaymentStatusPending,
+	}
+
+	if err := s.repo.Create(ctx, payment); err != nil {
+		if errors.Is(err, repository.ErrDuplicatePayment) {
+			// Lost the race against a concurrent delivery of the same event
+			existing, err := s.repo.GetByBookingID(ctx, event.BookingID)
+			if err != nil {
+				return err
+			}
+			if existing == nil {
+				return fmt.Errorf("payment for booking %s vanished after duplicate insert", event.BookingID)
+			}
+			return s.replay(ctx, existing, event.PaymentToken)
+		}
+		// A genuine DB failure says nothing about the payment itself, so
+		// no outcome is published.
+		return fmt.Errorf("failed to claim payment for booking %s: %w", event.BookingID, err)
+	}
+
 	log.Printf("Processing payment for booking %s amount %.2f", event.BookingID, event.TotalAmount)
 
-	// Simulate payment logic (Success mostly)
-	status := "SUCCESS"
-	transactionID := fmt.Sprintf("txn_%s", event.BookingID) // Dummy txn ID
+	return s.complete(ctx, payment, event.PaymentToken)
+}
 
-	// Create payment record
-	payment := &model.Payment{
+// complete charges a claimed payment, stores the outcome and publishes it.
+func (s *paymentService) complete(ctx context.Context, p *model.Payment, paymentToken string) error {
+	s.charge(ctx, p, paymentToken)
+
+	if err := s.repo.UpdateResult(ctx, p); err != nil {
+		return err
+	}
+	if p.BookingExpiredAt != nil {
+		return s.settleExpired(ctx, p)
+	}
+
+	return s.publishOutcome(ctx, p)
+}
+
+// charge authorizes and captures the payment through the gateway and records
+// the outcome on p. A capture failure voids the authorization so the
+// traveller is not left with a hold on their card. Both calls are keyed by
+// the booking, so charging a resumed payment again returns the original
+// authorization and capture instead of charging twice.
+func (s *paymentService) charge(ctx context.Context, p *model.Payment, paymentToken string) {
+	auth, err := s.gateway.Authorize(ctx, gateway.AuthorizeRequest{
+		BookingID:      p.BookingID,
+		Amount:         p.Amount,
+		Currency:       s.currency,
+		PaymentToken:   paymentToken,
+		IdempotencyKey: p.BookingID,
+	})
+	if err != nil {
+		s.fail(p, "authorize", err)
+		return
+	}
+	p.AuthorizationID = auth.ID
+
+	if s.bookingExpired(ctx, p.BookingID) {
+		log.Printf("Booking %s expired before capture, voiding authorization %s", p.BookingID, auth.ID)
+		p.Status = model.PaymentStatusFailed
+		p.FailureReason = "booking expired before capture"
+		if err := s.gateway.Void(ctx, auth.ID); err != nil {
+			log.Printf("Failed to void authorization %s for booking %s: %v", auth.ID, p.BookingID, err)
+		}
+		return
+	}
+
+	txn, err := s.gateway.Capture(ctx, auth.ID, p.Amount)
+	if err != nil {
+		s.fail(p, "capture", err)
+		if verr := s.gateway.Void(ctx, auth.ID); verr != nil {
+			log.Printf("Failed to void authorization %s for booking %s: %v", auth.ID, p.BookingID, verr)
+		}
+		return
+	}
+
+	p.Status = model.PaymentStatusSuccess
+	p.TransactionID = txn.ID
+}
+
+func (s *paymentService) fail(p *model.Payment, step string, err error) {
+	if gateway.IsDecline(err) {
+		log.Printf("Payment for booking %s declined at %s: %v", p.BookingID, step, err)
+	} else {
+		log.Printf("Payment for booking %s failed at %s: %v", p.BookingID, step, err)
+	}
+	p.Status = model.PaymentStatusFailed
+	p.FailureReason = err.Error()
+}
+
+// bookingExpired reports whether the booking has been expired meanwhile. A
+// failed lookup counts as not expired: complete settles the charge anyway
+// once the outcome is stored.
+func (s *paymentService) bookingExpired(ctx context.Context, bookingID string) bool {
+	p, err := s.repo.GetByBookingID(ctx, bookingID)
+	if err != nil {
+		log.Printf("Failed to check expiry of booking %s before capture: %v", bookingID, err)
+		return false
+	}
+	return p != nil && p.BookingExpiredAt != nil
+}
+
+// replay republishes the recorded outcome of an already processed payment,
+// or resumes it when its claim has gone stale.
+func (s *paymentService) replay(ctx context.Context, p *model.Payment, paymentToken string) error {
+	if p.Status == model.PaymentStatusPending {
+		if time.Since(p.UpdatedAt) < s.lease {
+			log.Printf("Payment for booking %s is still in progress, skipping duplicate event", p.BookingID)
+			return nil
+		}
+		claimed, err := s.repo.ClaimStale(ctx, p, time.Now().Add(-s.lease))
+		if err != nil {
+			return err
+		}
+		if !claimed {
+			log.Printf("Payment for booking %s was finished or resumed elsewhere, skipping duplicate event", p.BookingID)
+			return nil
+		}
+		log.Printf("Resuming payment for booking %s left pending since %s", p.BookingID, p.CreatedAt.Format(time.RFC3339))
+		return s.complete(ctx, p, paymentToken)
+	}
+	if p.BookingExpiredAt != nil {
+		log.Printf("Duplicate event for expired booking %s, nothing to replay", p.BookingID)
+		return nil
+	}
+	if p.Status != model.PaymentStatusSuccess && p.Status != model.PaymentStatusFailed {
+		// Refunded since: the refund outcome went out on payment.refunded
+		// and announcing the payment again would confirm the booking.
+		log.Printf("Duplicate event for booking %s with payment %s, nothing to replay", p.BookingID, p.Status)
+		return nil
+	}
+	log.Printf("Duplicate event for booking %s, replaying %s outcome", p.BookingID, p.Status)
+	return s.publishOutcome(ctx, p)
+}
+
+// ProcessExpiry reverses the charge of a booking that booking-service
+// expired before its payment outcome arrived. A captured payment is refunded
+// in full; a charge still in progress is settled by complete once it stores
+// its outcome, and a booking not charged yet is recorded as failed so it
+// never is.
+func (s *paymentService) ProcessExpiry(ctx context.Context, event model.BookingExpiredEvent) error {
+	p := &model.Payment{
 		BookingID:     event.BookingID,
+		UserID:        event.UserID,
 		Amount:        event.TotalAmount,
-		Status:        status,
-		TransactionID: transactionID,
+		Status:        model.PaymentStatusFailed,
+		FailureReason: "booking expired before payment",
+	}
+	if err := s.repo.MarkBookingExpired(ctx, p); err != nil {
+		return err
 	}
 
-	if err := s.repo.Create(ctx, payment); err != nil {
-		log.Printf("Failed to create payment record: %v", err)
-		// If duplicate entry error -> Idempotency handled by DB Unique Constraint?
-		// We should publish failed event if it's a real failure
-		status = "FAILED"
+	switch p.Status {
+	case model.PaymentStatusPending:
+		log.Printf("Booking %s expired while its payment is in progress, settling it when the charge completes", p.BookingID)
+		return nil
+	case model.PaymentStatusSuccess:
+		return s.settleExpired(ctx, p)
+	default:
+		log.Printf("Booking %s expired with payment %s, nothing to reverse", p.BookingID, p.Status)
+		return nil
 	}
+}
 
-	// Publish result event
+// settleExpired refunds a payment captured for an expired booking. The
+// refund is keyed by the booking ID, so settling twice refunds once. No
+// payment outcome is published since booking-service no longer waits for it.
+func (s *paymentService) settleExpired(ctx context.Context, p *model.Payment) error {
+	if p.Status != model.PaymentStatusSuccess {
+		log.Printf("Booking %s expired, payment %s was not captured", p.BookingID, p.Status)
+		return nil
+	}
+	log.Printf("Booking %s expired after its payment was captured, refunding %.2f", p.BookingID, p.Amount)
+	return s.ProcessRefund(ctx, model.CancelRequestedEvent{
+		RequestID:    p.BookingID,
+		BookingID:    p.BookingID,
+		UserID:       p.UserID,
+		TotalAmount:  p.Amount,
+		RefundAmount: p.Amount,
+		Reason:       "booking expired",
+	})
+}
+
+func (s *paymentService) publishOutcome(ctx context.Context, p *model.Payment) error {
 	resultEvent := model.PaymentEvent{
-		PaymentID:     payment.ID,
-		BookingID:     event.BookingID,
-		Amount:        event.TotalAmount,
-		Status:        status,
-		TransactionID: transactionID,
+		PaymentID:     p.ID,
+		BookingID:     p.BookingID,
+		UserID:        p.UserID,
+		Amount:        p.Amount,
+		Status:        p.Status,
+		TransactionID: p.TransactionID,
+		Reason:        p.FailureReason,
 	}
 
 	topic := "payment.success"
-	if status == "FAILED" {
+	if p.Status == model.PaymentStatusFailed {
 		topic = "payment.failed"
 	}
 
-	if err := s.producer.Publish(ctx, topic, event.BookingID, resultEvent); err != nil {
+	if err := s.producer.Publish(ctx, topic, p.BookingID, resultEvent); err != nil {
 		log.Printf("Failed to publish %s event: %v", topic, err)
 		return err
 	}
@@ -0,0 +1,561 @@
+package service
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gavinadlan/tripnest/backend/payment-service/internal/gateway"
+	"github.com/gavinadlan/tripnest/backend/payment-service/internal/model"
+	"github.com/gavinadlan/tripnest/backend/payment-service/internal/repository"
+)
+
+const testLease = time.Minute
+
+type fakeRepo struct {
+	mu       sync.Mutex
+	payments map[string]*model.Payment // By booking ID
+	refunds  map[string]*model.Refund  // By request ID
+}
+
+func newFakeRepo() *fakeRepo {
+	return &fakeRepo{payments: make(map[string]*model.Payment), refunds: make(map[string]*model.Refund)}
+}
+
+func (r *fakeRepo) Create(ctx context.Context, p *model.Payment) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.payments[p.BookingID]; ok {
+		return repository.ErrDuplicatePayment
+	}
+	p.ID = "pay-" + p.BookingID
+	p.CreatedAt = time.Now()
+	p.UpdatedAt = p.CreatedAt
+	stored := *p
+	r.payments[p.BookingID] = &stored
+	return nil
+}
+
+func (r *fakeRepo) GetByBookingID(ctx context.Context, bookingID string) (*model.Payment, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	p, ok := r.payments[bookingID]
+	if !ok {
+		return nil, nil
+	}
+	found := *p
+	return &found, nil
+}
+
+func (r *fakeRepo) UpdateResult(ctx context.Context, p *model.Payment) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	p.UpdatedAt = time.Now()
+	p.BookingExpiredAt = r.payments[p.BookingID].BookingExpiredAt
+	stored := *p
+	r.payments[p.BookingID] = &stored
+	return nil
+}
+
+func (r *fakeRepo) ClaimStale(ctx context.Context, p *model.Payment, staleBefore time.Time) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	stored := r.payments[p.BookingID]
+	if stored.Status != model.PaymentStatusPending || !stored.UpdatedAt.Before(staleBefore) {
+		return false, nil
+	}
+	stored.UpdatedAt = time.Now()
+	p.UpdatedAt = stored.UpdatedAt
+	return true, nil
+}
+
+func (r *fakeRepo) MarkBookingExpired(ctx context.Context, p *model.Payment) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	now := time.Now()
+	stored, ok := r.payments[p.BookingID]
+	if !ok {
+		p.ID = "pay-" + p.BookingID
+		p.CreatedAt, p.UpdatedAt = now, now
+		copied := *p
+		stored = &copied
+		r.payments[p.BookingID] = stored
+	}
+	if stored.BookingExpiredAt == nil {
+		stored.BookingExpiredAt = &now
+	}
+	*p = *stored
+	return nil
+}
+
+func (r *fakeRepo) CreateRefund(ctx context.Context, rf *model.Refund) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.refunds[rf.RequestID]; ok {
+		return repository.ErrDuplicateRefund
+	}
+	rf.ID = "re-" + rf.RequestID
+	rf.CreatedAt = time.Now()
+	rf.UpdatedAt = rf.CreatedAt
+	stored := *rf
+	r.refunds[rf.RequestID] = &stored
+	return nil
+}
+
+func (r *fakeRepo) GetRefundByRequestID(ctx context.Context, requestID string) (*model.Refund, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	rf, ok := r.refunds[requestID]
+	if !ok {
+		return nil, nil
+	}
+	found := *rf
+	return &found, nil
+}
+
+func (r *fakeRepo) ClaimStaleRefund(ctx context.Context, rf *model.Refund, staleBefore time.Time) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	stored := r.refunds[rf.RequestID]
+	if stored.Status != model.RefundStatusPending || !stored.UpdatedAt.Before(staleBefore) {
+		return false, nil
+	}
+	stored.UpdatedAt = time.Now()
+	rf.UpdatedAt = stored.UpdatedAt
+	return true, nil
+}
+
+func (r *fakeRepo) CompleteRefund(ctx context.Context, rf *model.Refund, p *model.Payment) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	stored := *rf
+	r.refunds[rf.RequestID] = &stored
+	if p != nil {
+		r.payments[p.BookingID].Status = p.Status
+	}
+	return nil
+}
+
+func (r *fakeRepo) Close() {}
+
+// countingGateway records the calls made to the simulator underneath. When
+// hold is set, Authorize signals entered and waits for hold to close;
+// beforeCapture runs ahead of every capture.
+type countingGateway struct {
+	*gateway.Simulator
+
+	mu            sync.Mutex
+	authorizes    int
+	captures      int
+	voids         int
+	refunds       int
+	entered       chan struct{}
+	hold          chan struct{}
+	beforeCapture func()
+}
+
+func newCountingGateway() *countingGateway {
+	return &countingGateway{Simulator: gateway.NewSimulator(gateway.SimulatorConfig{})}
+}
+
+func (g *countingGateway) Authorize(ctx context.Context, req gateway.AuthorizeRequest) (*gateway.Authorization, error) {
+	g.mu.Lock()
+	g.authorizes++
+	g.mu.Unlock()
+	if g.hold != nil {
+		g.entered <- struct{}{}
+		<-g.hold
+	}
+	return g.Simulator.Authorize(ctx, req)
+}
+
+func (g *countingGateway) Capture(ctx context.Context, authorizationID string, amount float64) (*gateway.Transaction, error) {
+	g.mu.Lock()
+	g.captures++
+	g.mu.Unlock()
+	if g.beforeCapture != nil {
+		g.beforeCapture()
+	}
+	return g.Simulator.Capture(ctx, authorizationID, amount)
+}
+
+func (g *countingGateway) Void(ctx context.Context, authorizationID string) error {
+	g.mu.Lock()
+	g.voids++
+	g.mu.Unlock()
+	return g.Simulator.Void(ctx, authorizationID)
+}
+
+func (g *countingGateway) Refund(ctx context.Context, req gateway.RefundRequest) (*gateway.Refund, error) {
+	g.mu.Lock()
+	g.refunds++
+	g.mu.Unlock()
+	return g.Simulator.Refund(ctx, req)
+}
+
+type published struct {
+	topic string
+	event model.PaymentEvent
+}
+
+type fakePublisher struct {
+	mu     sync.Mutex
+	events []published
+}
+
+func (p *fakePublisher) Publish(ctx context.Context, topic string, key string, payload interface{}) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	event, _ := payload.(model.PaymentEvent)
+	p.events = append(p.events, published{topic: topic, event: event})
+	return nil
+}
+
+func (p *fakePublisher) topics() []string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	topics := make([]string, len(p.events))
+	for i, e := range p.events {
+		topics[i] = e.topic
+	}
+	return topics
+}
+
+func newTestService(repo *fakeRepo, gw *countingGateway, pub *fakePublisher) PaymentService {
+	return NewPaymentService(repo, pub, gw, "USD", testLease)
+}
+
+func TestProcessPaymentConcurrentDeliveriesChargeOnce(t *testing.T) {
+	repo, gw, pub := newFakeRepo(), newCountingGateway(), &fakePublisher{}
+	gw.entered, gw.hold = make(chan struct{}, 1), make(chan struct{})
+	svc := newTestService(repo, gw, pub)
+	event := model.BookingEvent{BookingID: "b1", UserID: "u1", TotalAmount: 120}
+
+	errs := make(chan error, 2)
+	for i := 0; i < 2; i++ {
+		go func() { errs <- svc.ProcessPayment(context.Background(), event) }()
+	}
+
+	// One delivery claims the payment and blocks in the gateway; the other
+	// must see the claim and return without charging.
+	<-gw.entered
+	if err := <-errs; err != nil {
+		t.Fatalf("duplicate delivery: %v", err)
+	}
+	if got := pub.topics(); len(got) != 0 {
+		t.Fatalf("duplicate delivery published %v while the charge was in progress", got)
+	}
+
+	close(gw.hold)
+	if err := <-errs; err != nil {
+		t.Fatalf("claiming delivery: %v", err)
+	}
+
+	if gw.authorizes != 1 || gw.captures != 1 {
+		t.Fatalf("gateway called %d/%d times (authorize/capture), want 1/1", gw.authorizes, gw.captures)
+	}
+	if got := pub.topics(); len(got) != 1 || got[0] != "payment.success" {
+		t.Fatalf("published %v, want [payment.success]", got)
+	}
+
+	// A later redelivery replays the outcome without charging.
+	if err := svc.ProcessPayment(context.Background(), event); err != nil {
+		t.Fatalf("redelivery: %v", err)
+	}
+	if gw.authorizes != 1 {
+		t.Fatalf("redelivery authorized again")
+	}
+	if got := pub.topics(); len(got) != 2 || got[1] != "payment.success" {
+		t.Fatalf("published %v, want the success replayed", got)
+	}
+}
+
+func TestProcessPaymentReplaysDecline(t *testing.T) {
+	repo, pub := newFakeRepo(), &fakePublisher{}
+	gw := &countingGateway{Simulator: gateway.NewSimulator(gateway.SimulatorConfig{FailTokens: []string{"tok_declined"}})}
+	svc := newTestService(repo, gw, pub)
+	event := model.BookingEvent{BookingID: "b1", TotalAmount: 50, PaymentToken: "tok_declined"}
+
+	for i := 0; i < 2; i++ {
+		if err := svc.ProcessPayment(context.Background(), event); err != nil {
+			t.Fatalf("delivery %d: %v", i, err)
+		}
+	}
+
+	if gw.authorizes != 1 {
+		t.Fatalf("authorized %d times, want 1", gw.authorizes)
+	}
+	if got := pub.topics(); len(got) != 2 || got[0] != "payment.failed" || got[1] != "payment.failed" {
+		t.Fatalf("published %v, want payment.failed twice", got)
+	}
+}
+
+func TestProcessPaymentDoesNotReplayRefundedPayments(t *testing.T) {
+	for _, amount := range []float64{100, 40} {
+		repo, gw, pub := newFakeRepo(), newCountingGateway(), &fakePublisher{}
+		svc := newTestService(repo, gw, pub)
+		ctx := context.Background()
+		event := model.BookingEvent{BookingID: "b1", TotalAmount: 100}
+
+		if err := svc.ProcessPayment(ctx, event); err != nil {
+			t.Fatal(err)
+		}
+		cancel := model.CancelRequestedEvent{RequestID: "r1", BookingID: "b1", TotalAmount: 100, RefundAmount: amount}
+		if err := svc.ProcessRefund(ctx, cancel); err != nil {
+			t.Fatal(err)
+		}
+		if err := svc.ProcessPayment(ctx, event); err != nil {
+			t.Fatal(err)
+		}
+
+		if got := pub.topics(); len(got) != 2 || got[0] != "payment.success" || got[1] != "payment.refunded" {
+			t.Errorf("refund of %v: published %v, want payment.success then payment.refunded only", amount, got)
+		}
+	}
+}
+
+func TestProcessPaymentSkipsPendingWithinLease(t *testing.T) {
+	repo, gw, pub := newFakeRepo(), newCountingGateway(), &fakePublisher{}
+	repo.payments["b1"] = &model.Payment{
+		ID: "pay-b1", BookingID: "b1", Amount: 80, Status: model.PaymentStatusPending,
+		CreatedAt: time.Now(), UpdatedAt: time.Now(),
+	}
+	svc := newTestService(repo, gw, pub)
+
+	if err := svc.ProcessPayment(context.Background(), model.BookingEvent{BookingID: "b1", TotalAmount: 80}); err != nil {
+		t.Fatal(err)
+	}
+	if gw.authorizes != 0 || len(pub.topics()) != 0 {
+		t.Fatalf("payment within its lease was touched: %d authorizations, published %v", gw.authorizes, pub.topics())
+	}
+}
+
+func TestProcessPaymentResumesStalePending(t *testing.T) {
+	repo, gw, pub := newFakeRepo(), newCountingGateway(), &fakePublisher{}
+	svc := newTestService(repo, gw, pub)
+	ctx := context.Background()
+
+	// The previous attempt captured the charge and then died before
+	// storing the result.
+	auth, err := gw.Simulator.Authorize(ctx, gateway.AuthorizeRequest{BookingID: "b1", Amount: 80, IdempotencyKey: "b1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	txn, err := gw.Simulator.Capture(ctx, auth.ID, 80)
+	if err != nil {
+		t.Fatal(err)
+	}
+	stale := time.Now().Add(-2 * testLease)
+	repo.payments["b1"] = &model.Payment{
+		ID: "pay-b1", BookingID: "b1", Amount: 80, Status: model.PaymentStatusPending,
+		CreatedAt: stale, UpdatedAt: stale,
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 2; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := svc.ProcessPayment(ctx, model.BookingEvent{BookingID: "b1", TotalAmount: 80}); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if gw.authorizes != 1 {
+		t.Fatalf("stale payment resumed %d times, want 1", gw.authorizes)
+	}
+	stored, _ := repo.GetByBookingID(ctx, "b1")
+	if stored.Status != model.PaymentStatusSuccess || stored.TransactionID != txn.ID || stored.AuthorizationID != auth.ID {
+		t.Fatalf("stored %+v, want SUCCESS with the original authorization %s and transaction %s", stored, auth.ID, txn.ID)
+	}
+	// The other delivery either lost the claim or replayed the outcome.
+	got := pub.topics()
+	if len(got) == 0 {
+		t.Fatal("resumed payment published nothing")
+	}
+	for _, topic := range got {
+		if topic != "payment.success" {
+			t.Fatalf("published %v, want only payment.success", got)
+		}
+	}
+}
+
+func TestProcessExpiryRefundsCapturedPayment(t *testing.T) {
+	repo, gw, pub := newFakeRepo(), newCountingGateway(), &fakePublisher{}
+	svc := newTestService(repo, gw, pub)
+	ctx := context.Background()
+
+	if err := svc.ProcessPayment(ctx, model.BookingEvent{BookingID: "b1", TotalAmount: 90}); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 2; i++ {
+		if err := svc.ProcessExpiry(ctx, model.BookingExpiredEvent{BookingID: "b1", TotalAmount: 90}); err != nil {
+			t.Fatalf("expiry %d: %v", i, err)
+		}
+	}
+
+	if gw.refunds != 1 {
+		t.Fatalf("refunded %d times, want 1", gw.refunds)
+	}
+	stored, _ := repo.GetByBookingID(ctx, "b1")
+	if stored.Status != model.PaymentStatusRefunded {
+		t.Fatalf("payment status %s, want %s", stored.Status, model.PaymentStatusRefunded)
+	}
+	if got := pub.topics(); len(got) != 2 || got[0] != "payment.success" || got[1] != "payment.refunded" {
+		t.Fatalf("published %v, want [payment.success payment.refunded]", got)
+	}
+}
+
+func TestProcessExpiryBeforePaymentPreventsCharge(t *testing.T) {
+	repo, gw, pub := newFakeRepo(), newCountingGateway(), &fakePublisher{}
+	svc := newTestService(repo, gw, pub)
+	ctx := context.Background()
+
+	if err := svc.ProcessExpiry(ctx, model.BookingExpiredEvent{BookingID: "b1", TotalAmount: 90}); err != nil {
+		t.Fatal(err)
+	}
+	if err := svc.ProcessPayment(ctx, model.BookingEvent{BookingID: "b1", TotalAmount: 90}); err != nil {
+		t.Fatal(err)
+	}
+
+	if gw.authorizes != 0 {
+		t.Fatalf("expired booking was charged")
+	}
+	if got := pub.topics(); len(got) != 0 {
+		t.Fatalf("published %v for an expired booking", got)
+	}
+}
+
+func TestProcessExpiryDuringAuthorizationVoids(t *testing.T) {
+	repo, gw, pub := newFakeRepo(), newCountingGateway(), &fakePublisher{}
+	gw.entered, gw.hold = make(chan struct{}, 1), make(chan struct{})
+	svc := newTestService(repo, gw, pub)
+	ctx := context.Background()
+
+	done := make(chan error, 1)
+	go func() { done <- svc.ProcessPayment(ctx, model.BookingEvent{BookingID: "b1", TotalAmount: 90}) }()
+
+	<-gw.entered
+	if err := svc.ProcessExpiry(ctx, model.BookingExpiredEvent{BookingID: "b1", TotalAmount: 90}); err != nil {
+		t.Fatal(err)
+	}
+	close(gw.hold)
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+
+	if gw.captures != 0 || gw.voids != 1 {
+		t.Fatalf("gateway captured %d and voided %d times, want 0 and 1", gw.captures, gw.voids)
+	}
+	stored, _ := repo.GetByBookingID(ctx, "b1")
+	if stored.Status != model.PaymentStatusFailed {
+		t.Fatalf("payment status %s, want %s", stored.Status, model.PaymentStatusFailed)
+	}
+	if got := pub.topics(); len(got) != 0 {
+		t.Fatalf("published %v for an expired booking", got)
+	}
+}
+
+func TestProcessExpiryDuringCaptureRefunds(t *testing.T) {
+	repo, gw, pub := newFakeRepo(), newCountingGateway(), &fakePublisher{}
+	svc := newTestService(repo, gw, pub)
+	ctx := context.Background()
+
+	// The booking expires after the pre-capture check has passed
+	gw.beforeCapture = func() {
+		if err := svc.ProcessExpiry(ctx, model.BookingExpiredEvent{BookingID: "b1", TotalAmount: 90}); err != nil {
+			t.Error(err)
+		}
+	}
+	if err := svc.ProcessPayment(ctx, model.BookingEvent{BookingID: "b1", TotalAmount: 90}); err != nil {
+		t.Fatal(err)
+	}
+
+	if gw.captures != 1 || gw.refunds != 1 {
+		t.Fatalf("gateway captured %d and refunded %d times, want 1 and 1", gw.captures, gw.refunds)
+	}
+	stored, _ := repo.GetByBookingID(ctx, "b1")
+	if stored.Status != model.PaymentStatusRefunded {
+		t.Fatalf("payment status %s, want %s", stored.Status, model.PaymentStatusRefunded)
+	}
+	if got := pub.topics(); len(got) != 1 || got[0] != "payment.refunded" {
+		t.Fatalf("published %v, want [payment.refunded]", got)
+	}
+}
+
+// pendingRefund stores a captured payment for b1 and a refund of it claimed
+// for request r1 at claimedAt but never completed.
+func pendingRefund(repo *fakeRepo, claimedAt time.Time) model.CancelRequestedEvent {
+	repo.payments["b1"] = &model.Payment{
+		ID: "pay-b1", BookingID: "b1", Amount: 100, Status: model.PaymentStatusSuccess, TransactionID: "txn_b1",
+	}
+	repo.refunds["r1"] = &model.Refund{
+		ID: "re-r1", RequestID: "r1", PaymentID: "pay-b1", BookingID: "b1", Amount: 40,
+		Status: model.RefundStatusPending, CreatedAt: claimedAt, UpdatedAt: claimedAt,
+	}
+	return model.CancelRequestedEvent{RequestID: "r1", BookingID: "b1", TotalAmount: 100, RefundAmount: 40}
+}
+
+func TestProcessRefundSkipsPendingWithinLease(t *testing.T) {
+	repo, gw, pub := newFakeRepo(), newCountingGateway(), &fakePublisher{}
+	event := pendingRefund(repo, time.Now())
+	svc := newTestService(repo, gw, pub)
+
+	if err := svc.ProcessRefund(context.Background(), event); err != nil {
+		t.Fatal(err)
+	}
+	if gw.refunds != 0 || len(pub.topics()) != 0 {
+		t.Fatalf("refund within its lease was touched: %d gateway refunds, published %v", gw.refunds, pub.topics())
+	}
+}
+
+func TestProcessRefundResumesStalePending(t *testing.T) {
+	repo, gw, pub := newFakeRepo(), newCountingGateway(), &fakePublisher{}
+	event := pendingRefund(repo, time.Now().Add(-2*testLease))
+	svc := newTestService(repo, gw, pub)
+	ctx := context.Background()
+
+	// The previous attempt reached the gateway and then died before storing
+	// the result.
+	paid, err := gw.Simulator.Refund(ctx, gateway.RefundRequest{TransactionID: "txn_b1", Amount: 40, IdempotencyKey: "r1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 2; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := svc.ProcessRefund(ctx, event); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if gw.refunds != 1 {
+		t.Fatalf("stale refund resumed %d times, want 1", gw.refunds)
+	}
+	stored, _ := repo.GetRefundByRequestID(ctx, "r1")
+	if stored.Status != model.RefundStatusSuccess || stored.GatewayRefundID != paid.ID {
+		t.Fatalf("stored %+v, want SUCCESS with the original gateway refund %s", stored, paid.ID)
+	}
+	payment, _ := repo.GetByBookingID(ctx, "b1")
+	if payment.Status != model.PaymentStatusPartiallyRefunded {
+		t.Fatalf("payment status %s, want %s", payment.Status, model.PaymentStatusPartiallyRefunded)
+	}
+	// The other delivery either lost the claim or replayed the outcome.
+	got := pub.topics()
+	if len(got) == 0 {
+		t.Fatal("resumed refund published nothing")
+	}
+	for _, topic := range got {
+		if topic != "payment.refunded" {
+			t.Fatalf("published %v, want only payment.refunded", got)
+		}
+	}
+}
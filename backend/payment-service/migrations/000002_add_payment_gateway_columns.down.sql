@@ -0,0 +1,2 @@
+ALTER TABLE payments DROP COLUMN IF EXISTS failure_reason;
+ALTER TABLE payments DROP COLUMN IF EXISTS authorization_id;
@@ -0,0 +1,2 @@
+ALTER TABLE refunds DROP COLUMN IF EXISTS user_id;
+ALTER TABLE payments DROP COLUMN IF EXISTS user_id;
@@ -0,0 +1,2 @@
+ALTER TABLE payments ADD COLUMN IF NOT EXISTS user_id UUID;
+ALTER TABLE refunds ADD COLUMN IF NOT EXISTS user_id UUID;
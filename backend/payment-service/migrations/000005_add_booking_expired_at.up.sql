@@ -0,0 +1 @@
+ALTER TABLE payments ADD COLUMN IF NOT EXISTS booking_expired_at TIMESTAMP WITH TIME ZONE;
@@ -0,0 +1,106 @@
+package main
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/gavinadlan/tripnest/backend/common/auth"
+	"github.com/gavinadlan/tripnest/backend/recommendation-service/internal/catalog"
+	"github.com/gavinadlan/tripnest/backend/recommendation-service/internal/config"
+	"github.com/gavinadlan/tripnest/backend/recommendation-service/internal/db"
+	"github.com/gavinadlan/tripnest/backend/recommendation-service/internal/handler"
+	"github.com/gavinadlan/tripnest/backend/recommendation-service/internal/msgbroker"
+	"github.com/gavinadlan/tripnest/backend/recommendation-service/internal/repository"
+	"github.com/gavinadlan/tripnest/backend/recommendation-service/internal/scheduler"
+	"github.com/gavinadlan/tripnest/backend/recommendation-service/internal/service"
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/joho/godotenv"
+)
+
+func main() {
+	godotenv.Load()
+	cfg := config.Load()
+
+	db.RunMigrations(cfg.DatabaseURL)
+
+	repo, err := repository.NewPostgresRepository(cfg.DatabaseURL, repository.Decay{
+		UserHalfLife:     cfg.InterestHalfLife,
+		TrendingHalfLife: cfg.TrendingHalfLife,
+	})
+	if err != nil {
+		log.Fatalf("Database connection failed: %v", err)
+	}
+	defer repo.Close()
+
+	listings := catalog.NewHTTPCatalog(cfg.SearchServiceURL, cfg.SearchTimeout)
+	svc := service.NewRecommendationService(repo, listings)
+
+	identify := auth.Identify(auth.NewJWKSVerifier(cfg.JWKSURL, cfg.JWTIssuer, cfg.JWKSRefreshTime))
+	if cfg.TrustGatewayHeaders {
+		identify = auth.IdentifyGatewayHeaders()
+	}
+	h := handler.NewHandler(svc, identify)
+
+	r := chi.NewRouter()
+
+	r.Use(middleware.Logger)
+	r.Use(middleware.Recoverer)
+
+	h.RegisterRoutes(r)
+
+	server := &http.Server{
+		Addr:    ":" + cfg.Port,
+		Handler: r,
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	sweeper := scheduler.NewRetentionSweeper(svc, cfg.ProcessedEventRetention, cfg.RetentionSweepInterval, cfg.RetentionSweepBatchSize)
+	go sweeper.Run(ctx)
+
+	for _, topic := range service.Topics {
+		consumer := msgbroker.NewConsumer(cfg.KafkaBrokers, topic, "recommendation-service-group")
+		defer consumer.Close()
+
+		go func() {
+			log.Printf("Listening for %s events...", topic)
+			for {
+				msg, err := consumer.ReadMessage(ctx)
+				if err != nil {
+					log.Printf("%s consumer error: %v", topic, err)
+					break
+				}
+
+				if err := svc.HandleEvent(ctx, msg.Topic, msgbroker.MessageID(msg), msg.Value, msg.Time); err != nil {
+					log.Printf("Failed to handle %s event: %v", msg.Topic, err)
+				}
+			}
+		}()
+	}
+
+	go func() {
+		log.Printf("Recommendation Service starting on port %s", cfg.Port)
+		if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			log.Fatalf("Server error: %v", err)
+		}
+	}()
+
+	quit := make(chan os.Signal, 1)
+	signal.Notify(quit, os.Interrupt, syscall.SIGTERM)
+	<-quit
+	log.Println("Shutting down Recommendation Service")
+
+	shutdownCtx, shutdownCancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer shutdownCancel()
+
+	if err := server.Shutdown(shutdownCtx); err != nil {
+		log.Fatalf("Server forced to shutdown: %v", err)
+	}
+}
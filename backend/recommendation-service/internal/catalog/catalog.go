@@ -0,0 +1,96 @@
+package catalog
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/gavinadlan/tripnest/backend/recommendation-service/internal/model"
+)
+
+// Catalog reads listings from search-service, which owns them.
+type Catalog interface {
+	// GetListing returns nil if the listing does not exist.
+	GetListing(ctx context.Context, id string) (*model.Listing, error)
+	// ListByDestination returns up to limit listings whose destination
+	// matches destination.
+	ListByDestination(ctx context.Context, destination string, limit int) ([]model.Listing, error)
+}
+
+// HTTPCatalog calls search-service's HTTP API.
+type HTTPCatalog struct {
+	baseURL string
+	client  *http.Client
+}
+
+func NewHTTPCatalog(baseURL string, timeout time.Duration) *HTTPCatalog {
+	return &HTTPCatalog{
+		baseURL: baseURL,
+		client:  &http.Client{Timeout: timeout},
+	}
+}
+
+func (c *HTTPCatalog) GetListing(ctx context.Context, id string) (*model.Listing, error) {
+	endpoint, err := url.JoinPath(c.baseURL, "listings", id)
+	if err != nil {
+		return nil, fmt.Errorf("invalid search service URL: %w", err)
+	}
+
+	var listing model.Listing
+	found, err := c.get(ctx, endpoint, &listing)
+	if err != nil || !found {
+		return nil, err
+	}
+	return &listing, nil
+}
+
+func (c *HTTPCatalog) ListByDestination(ctx context.Context, destination string, limit int) ([]model.Listing, error) {
+	endpoint, err := url.JoinPath(c.baseURL, "search")
+	if err != nil {
+		return nil, fmt.Errorf("invalid search service URL: %w", err)
+	}
+	// track=false keeps these lookups out of the search statistics
+	query := url.Values{"limit": {strconv.Itoa(limit)}, "track": {"false"}}
+	if destination != "" {
+		query.Set("destination", destination)
+	}
+
+	var page struct {
+		Data []model.Listing `json:"data"`
+	}
+	if _, err := c.get(ctx, endpoint+"?"+query.Encode(), &page); err != nil {
+		return nil, err
+	}
+	return page.Data, nil
+}
+
+// get decodes the JSON response of endpoint into v. It returns false on
+// 404.
+func (c *HTTPCatalog) get(ctx context.Context, endpoint string, v interface{}) (bool, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return false, err
+	}
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return false, fmt.Errorf("failed to reach search service: %w", err)
+	}
+	defer resp.Body.Close()
+
+	switch {
+	case resp.StatusCode == http.StatusNotFound:
+		return false, nil
+	case resp.StatusCode != http.StatusOK:
+		return false, fmt.Errorf("search service returned %s", resp.Status)
+	}
+
+	if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+		return false, fmt.Errorf("failed to decode search service response: %w", err)
+	}
+	return true, nil
+}
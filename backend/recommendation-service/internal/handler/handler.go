@@ -0,0 +1,77 @@
+package handler
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/gavinadlan/tripnest/backend/common/auth"
+	"github.com/gavinadlan/tripnest/backend/common/utils"
+	"github.com/gavinadlan/tripnest/backend/recommendation-service/internal/model"
+	"github.com/gavinadlan/tripnest/backend/recommendation-service/internal/service"
+	"github.com/go-chi/chi/v5"
+)
+
+type Handler struct {
+	svc      service.RecommendationService
+	identify func(http.Handler) http.Handler
+}
+
+// NewHandler serves recommendations, identifying callers when they are
+// signed in with identify (auth.Identify or, behind the api-gateway,
+// auth.IdentifyGatewayHeaders).
+func NewHandler(svc service.RecommendationService, identify func(http.Handler) http.Handler) *Handler {
+	return &Handler{svc: svc, identify: identify}
+}
+
+func (h *Handler) RegisterRoutes(r chi.Router) {
+	r.Get("/health", h.Health)
+
+	r.With(h.identify).Get("/recommendations", h.GetRecommendations)
+}
+
+// GetRecommendations ranks listings for user_id (default: the caller).
+// Users may only ask for themselves unless they are admins; anonymous
+// callers get trending listings. limit caps the result.
+func (h *Handler) GetRecommendations(w http.ResponseWriter, r *http.Request) {
+	principal, _ := auth.PrincipalFromContext(r.Context())
+	query := r.URL.Query()
+
+	userID := query.Get("user_id")
+	switch {
+	case userID == "":
+		userID = principal.UserID
+	case principal.UserID == "":
+		utils.WriteError(w, http.StatusUnauthorized, errors.New("authentication required for user_id"))
+		return
+	case userID != principal.UserID && !principal.IsAdmin():
+		utils.WriteError(w, http.StatusForbidden, errors.New("cannot read another user's recommendations"))
+		return
+	}
+
+	limit := model.DefaultRecommendationLimit
+	if raw := query.Get("limit"); raw != "" {
+		var err error
+		limit, err = strconv.Atoi(raw)
+		if err != nil || limit < 1 || limit > model.MaxRecommendationLimit {
+			utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("invalid limit: must be between 1 and %d", model.MaxRecommendationLimit))
+			return
+		}
+	}
+
+	resp, err := h.svc.Recommend(r.Context(), userID, limit)
+	if err != nil {
+		log.Printf("Recommend failed: %v", err)
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, resp)
+}
+
+func (h *Handler) Health(w http.ResponseWriter, r *http.Request) {
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte("OK"))
+}
@@ -0,0 +1,23 @@
+package model
+
+import "time"
+
+// Events consumed by the service. Only the fields used for statistics are
+// decoded.
+
+type BookingCreatedEvent struct {
+	BookingID   string  `json:"booking_id"`
+	UserID      string  `json:"user_id"`
+	ResourceID  string  `json:"resource_id"`
+	TotalAmount float64 `json:"total_amount"`
+}
+
+type SearchPerformedEvent struct {
+	UserID      string    `json:"user_id,omitempty"`
+	Destination string    `json:"destination,omitempty"`
+	MinPrice    float64   `json:"min_price,omitempty"`
+	MaxPrice    float64   `json:"max_price,omitempty"`
+	Date        string    `json:"date,omitempty"`
+	ResultCount int64     `json:"result_count"`
+	SearchedAt  time.Time `json:"searched_at"`
+}
@@ -0,0 +1,11 @@
+package model
+
+// Listing is a listing as served by search-service.
+type Listing struct {
+	ID             string  `json:"id"`
+	Title          string  `json:"title"`
+	Destination    string  `json:"destination"`
+	Price          float64 `json:"price"`
+	Date           string  `json:"date"`
+	AvailableSlots int     `json:"available_slots"`
+}
@@ -0,0 +1,28 @@
+package model
+
+const (
+	DefaultRecommendationLimit = 10
+	MaxRecommendationLimit     = 50
+)
+
+const (
+	// StrategyPersonalized ranks by the user's own history
+	StrategyPersonalized = "personalized"
+	// StrategyTrending is the cold-start fallback for unknown or anonymous
+	// users: what everyone is booking and searching for right now.
+	StrategyTrending = "trending"
+)
+
+// Recommendation is one ranked listing with why it was picked.
+type Recommendation struct {
+	ListingID string   `json:"listing_id"`
+	Score     float64  `json:"score"`
+	Reason    string   `json:"reason"`
+	Listing   *Listing `json:"listing"`
+}
+
+type RecommendationResponse struct {
+	UserID   string           `json:"user_id,omitempty"`
+	Strategy string           `json:"strategy"`
+	Data     []Recommendation `json:"data"`
+}
@@ -0,0 +1,91 @@
+package model
+
+import (
+	"strings"
+	"time"
+)
+
+// Price bands group listings by price so interest in one listing carries
+// over to similarly priced ones.
+const (
+	BandBudget  = "budget"  // below 100
+	BandMid     = "mid"     // 100 to 250
+	BandPremium = "premium" // 250 to 500
+	BandLuxury  = "luxury"  // 500 and above
+)
+
+var bandFloors = []struct {
+	band  string
+	floor float64
+}{
+	{BandLuxury, 500},
+	{BandPremium, 250},
+	{BandMid, 100},
+	{BandBudget, 0},
+}
+
+// BandFor returns the price band of price.
+func BandFor(price float64) string {
+	for _, b := range bandFloors {
+		if price >= b.floor {
+			return b.band
+		}
+	}
+	return BandBudget
+}
+
+// BandsBetween returns the bands a searched price range overlaps. Zero
+// bounds are open, as in search-service; with both open it returns nil.
+func BandsBetween(min, max float64) []string {
+	if min <= 0 && max <= 0 {
+		return nil
+	}
+	low, high := BandFor(min), BandLuxury
+	if max > 0 {
+		high = BandFor(max)
+	}
+
+	var bands []string
+	inRange := false
+	for i := len(bandFloors) - 1; i >= 0; i-- {
+		band := bandFloors[i].band
+		if band == low {
+			inRange = true
+		}
+		if inRange {
+			bands = append(bands, band)
+		}
+		if band == high {
+			break
+		}
+	}
+	return bands
+}
+
+// NormalizeDestination returns the key destinations are counted under, so
+// "Paris" and " paris" are the same destination.
+func NormalizeDestination(destination string) string {
+	return strings.ToLower(strings.Join(strings.Fields(destination), " "))
+}
+
+// Interaction is one signal of interest: a booking or a search.
+type Interaction struct {
+	// Key identifies the source event so it is only counted once
+	Key    string
+	UserID string
+	// Destination is normalized; empty when unknown (e.g. a search by price)
+	Destination string
+	// Bands the interaction points at, weighted equally
+	Bands []string
+	// ListingID is set for bookings of a known listing
+	ListingID  string
+	Booking    bool
+	Weight     float64
+	OccurredAt time.Time
+}
+
+// ScoredDestination is a destination with its decayed score.
+type ScoredDestination struct {
+	Destination string
+	Score       float64
+}
@@ -0,0 +1,326 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/gavinadlan/tripnest/backend/recommendation-service/internal/model"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// coOccurrenceFanOut caps how many of the user's other destinations a new
+// interaction is paired with.
+const coOccurrenceFanOut = 10
+
+type StatsRepository interface {
+	// Record adds in to the statistics in one transaction. It returns false
+	// without changing anything if in.Key was already recorded.
+	Record(ctx context.Context, in *model.Interaction) (bool, error)
+	// TopUserDestinations returns the user's destinations by decayed score.
+	TopUserDestinations(ctx context.Context, userID string, limit int) ([]model.ScoredDestination, error)
+	// UserBandScores returns the user's decayed score per price band.
+	UserBandScores(ctx context.Context, userID string) (map[string]float64, error)
+	// TrendingDestinations returns destinations by recent interest from all
+	// users.
+	TrendingDestinations(ctx context.Context, limit int) ([]model.ScoredDestination, error)
+	// RelatedDestinations returns destinations that co-occur with any of
+	// destinations, excluding those themselves.
+	RelatedDestinations(ctx context.Context, destinations []string, limit int) ([]model.ScoredDestination, error)
+	// ListingScores returns the decayed booking popularity of listings.
+	ListingScores(ctx context.Context, listingIDs []string) (map[string]float64, error)
+	// BookedListings returns the IDs of listings the user has booked.
+	BookedListings(ctx context.Context, userID string) (map[string]bool, error)
+	// PruneProcessedEvents forgets up to limit event keys recorded before
+	// cutoff and returns how many it removed.
+	PruneProcessedEvents(ctx context.Context, cutoff time.Time, limit int) (int, error)
+	Close()
+}
+
+// Decay sets the half-lives of the scores: personal interest, co-occurrence
+// and listing popularity fade with UserHalfLife, trending destinations with
+// the (shorter) TrendingHalfLife.
+type Decay struct {
+	UserHalfLife     time.Duration
+	TrendingHalfLife time.Duration
+}
+
+type postgresRepository struct {
+	db    *pgxpool.Pool
+	decay Decay
+}
+
+func NewPostgresRepository(connString string, decay Decay) (StatsRepository, error) {
+	config, err := pgxpool.ParseConfig(connString)
+	if err != nil {
+		return nil, err
+	}
+	pool, err := pgxpool.NewWithConfig(context.Background(), config)
+	if err != nil {
+		return nil, err
+	}
+	return &postgresRepository{db: pool, decay: decay}, nil
+}
+
+func (r *postgresRepository) Close() {
+	r.db.Close()
+}
+
+// decayedScore is the SQL for the score of table as of now, given the
+// half-life in seconds as parameter halfLife.
+func decayedScore(table, halfLife string) string {
+	return fmt.Sprintf("%[1]s.score * power(0.5, GREATEST(EXTRACT(EPOCH FROM (NOW() - %[1]s.updated_at)), 0) / %[2]s)", table, halfLife)
+}
+
+// accumulate is the ON CONFLICT clause that decays the stored score of
+// table up to the new event and adds the event's weight. Events older than
+// the stored score are added undecayed, which slightly favours them but
+// keeps the update commutative enough for at-least-once delivery.
+func accumulate(table, halfLife string) string {
+	return fmt.Sprintf(`score = %[1]s.score * power(0.5, GREATEST(EXTRACT(EPOCH FROM (EXCLUDED.updated_at - %[1]s.updated_at)), 0) / %[2]s) + EXCLUDED.score,
+            updated_at = GREATEST(%[1]s.updated_at, EXCLUDED.updated_at)`, table, halfLife)
+}
+
+func (r *postgresRepository) Record(ctx context.Context, in *model.Interaction) (bool, error) {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return false, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	tag, err := tx.Exec(ctx, `INSERT INTO processed_events (event_key) VALUES ($1) ON CONFLICT DO NOTHING`, in.Key)
+	if err != nil {
+		return false, fmt.Errorf("failed to record processed event: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return false, nil
+	}
+
+	userHalfLife := r.decay.UserHalfLife.Seconds()
+	trendingHalfLife := r.decay.TrendingHalfLife.Seconds()
+	bookings, searches := 0, 1
+	if in.Booking {
+		bookings, searches = 1, 0
+	}
+
+	if in.Destination != "" {
+		_, err := tx.Exec(ctx, `
+            INSERT INTO destination_stats (destination, score, bookings, searches, updated_at)
+            VALUES ($1, $2, $3, $4, $5)
+            ON CONFLICT (destination) DO UPDATE SET
+            `+accumulate("destination_stats", "$6")+`,
+            bookings = destination_stats.bookings + EXCLUDED.bookings,
+            searches = destination_stats.searches + EXCLUDED.searches`,
+			in.Destination, in.Weight, bookings, searches, in.OccurredAt, trendingHalfLife)
+		if err != nil {
+			return false, fmt.Errorf("failed to update destination stats: %w", err)
+		}
+	}
+
+	if in.Destination != "" && in.UserID != "" {
+		if err := r.recordCoOccurrence(ctx, tx, in, userHalfLife); err != nil {
+			return false, err
+		}
+
+		_, err := tx.Exec(ctx, `
+            INSERT INTO user_destination_stats (user_id, destination, score, bookings, searches, updated_at)
+            VALUES ($1, $2, $3, $4, $5, $6)
+            ON CONFLICT (user_id, destination) DO UPDATE SET
+            `+accumulate("user_destination_stats", "$7")+`,
+            bookings = user_destination_stats.bookings + EXCLUDED.bookings,
+            searches = user_destination_stats.searches + EXCLUDED.searches`,
+			in.UserID, in.Destination, in.Weight, bookings, searches, in.OccurredAt, userHalfLife)
+		if err != nil {
+			return false, fmt.Errorf("failed to update user destination stats: %w", err)
+		}
+	}
+
+	if in.UserID != "" {
+		for _, band := range in.Bands {
+			_, err := tx.Exec(ctx, `
+                INSERT INTO user_price_band_stats (user_id, band, score, updated_at)
+                VALUES ($1, $2, $3, $4)
+                ON CONFLICT (user_id, band) DO UPDATE SET
+                `+accumulate("user_price_band_stats", "$5"),
+				in.UserID, band, in.Weight/float64(len(in.Bands)), in.OccurredAt, userHalfLife)
+			if err != nil {
+				return false, fmt.Errorf("failed to update user price band stats: %w", err)
+			}
+		}
+	}
+
+	if in.ListingID != "" && len(in.Bands) > 0 {
+		_, err := tx.Exec(ctx, `
+            INSERT INTO listing_stats (listing_id, destination, price_band, score, bookings, updated_at)
+            VALUES ($1, $2, $3, $4, 1, $5)
+            ON CONFLICT (listing_id) DO UPDATE SET
+            `+accumulate("listing_stats", "$6")+`,
+            destination = EXCLUDED.destination,
+            price_band = EXCLUDED.price_band,
+            bookings = listing_stats.bookings + 1`,
+			in.ListingID, in.Destination, in.Bands[0], in.Weight, in.OccurredAt, userHalfLife)
+		if err != nil {
+			return false, fmt.Errorf("failed to update listing stats: %w", err)
+		}
+
+		if in.UserID != "" {
+			_, err := tx.Exec(ctx, `
+                INSERT INTO user_bookings (user_id, listing_id, booked_at) VALUES ($1, $2, $3)
+                ON CONFLICT DO NOTHING`, in.UserID, in.ListingID, in.OccurredAt)
+			if err != nil {
+				return false, fmt.Errorf("failed to record user booking: %w", err)
+			}
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return false, fmt.Errorf("failed to commit stats: %w", err)
+	}
+	return true, nil
+}
+
+// recordCoOccurrence pairs in.Destination with the user's other top
+// destinations, in both directions.
+func (r *postgresRepository) recordCoOccurrence(ctx context.Context, tx pgx.Tx, in *model.Interaction, halfLife float64) error {
+	rows, err := tx.Query(ctx, `
+        SELECT destination FROM user_destination_stats
+        WHERE user_id = $1 AND destination <> $2
+        ORDER BY `+decayedScore("user_destination_stats", "$3")+` DESC
+        LIMIT $4`, in.UserID, in.Destination, halfLife, coOccurrenceFanOut)
+	if err != nil {
+		return fmt.Errorf("failed to load user destinations: %w", err)
+	}
+	related, err := pgx.CollectRows(rows, pgx.RowTo[string])
+	if err != nil {
+		return fmt.Errorf("failed to scan user destinations: %w", err)
+	}
+
+	for _, other := range related {
+		for _, pair := range [][2]string{{in.Destination, other}, {other, in.Destination}} {
+			_, err := tx.Exec(ctx, `
+                INSERT INTO destination_cooccurrence (destination, related, score, updated_at)
+                VALUES ($1, $2, $3, $4)
+                ON CONFLICT (destination, related) DO UPDATE SET
+                `+accumulate("destination_cooccurrence", "$5"),
+				pair[0], pair[1], in.Weight, in.OccurredAt, halfLife)
+			if err != nil {
+				return fmt.Errorf("failed to update destination co-occurrence: %w", err)
+			}
+		}
+	}
+	return nil
+}
+
+func (r *postgresRepository) TopUserDestinations(ctx context.Context, userID string, limit int) ([]model.ScoredDestination, error) {
+	return r.scoredDestinations(ctx, `
+        SELECT destination, `+decayedScore("user_destination_stats", "$2")+` AS s
+        FROM user_destination_stats
+        WHERE user_id = $1
+        ORDER BY s DESC
+        LIMIT $3`, userID, r.decay.UserHalfLife.Seconds(), limit)
+}
+
+func (r *postgresRepository) TrendingDestinations(ctx context.Context, limit int) ([]model.ScoredDestination, error) {
+	return r.scoredDestinations(ctx, `
+        SELECT destination, `+decayedScore("destination_stats", "$1")+` AS s
+        FROM destination_stats
+        ORDER BY s DESC
+        LIMIT $2`, r.decay.TrendingHalfLife.Seconds(), limit)
+}
+
+func (r *postgresRepository) RelatedDestinations(ctx context.Context, destinations []string, limit int) ([]model.ScoredDestination, error) {
+	return r.scoredDestinations(ctx, `
+        SELECT related, SUM(`+decayedScore("destination_cooccurrence", "$2")+`) AS s
+        FROM destination_cooccurrence
+        WHERE destination = ANY($1) AND NOT related = ANY($1)
+        GROUP BY related
+        ORDER BY s DESC
+        LIMIT $3`, destinations, r.decay.UserHalfLife.Seconds(), limit)
+}
+
+func (r *postgresRepository) scoredDestinations(ctx context.Context, query string, args ...interface{}) ([]model.ScoredDestination, error) {
+	rows, err := r.db.Query(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to rank destinations: %w", err)
+	}
+	destinations, err := pgx.CollectRows(rows, func(row pgx.CollectableRow) (model.ScoredDestination, error) {
+		var d model.ScoredDestination
+		err := row.Scan(&d.Destination, &d.Score)
+		return d, err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to scan destinations: %w", err)
+	}
+	return destinations, nil
+}
+
+func (r *postgresRepository) UserBandScores(ctx context.Context, userID string) (map[string]float64, error) {
+	rows, err := r.db.Query(ctx, `
+        SELECT band, `+decayedScore("user_price_band_stats", "$2")+`
+        FROM user_price_band_stats
+        WHERE user_id = $1`, userID, r.decay.UserHalfLife.Seconds())
+	if err != nil {
+		return nil, fmt.Errorf("failed to load price band stats: %w", err)
+	}
+	return collectScores(rows)
+}
+
+func (r *postgresRepository) ListingScores(ctx context.Context, listingIDs []string) (map[string]float64, error) {
+	rows, err := r.db.Query(ctx, `
+        SELECT listing_id, `+decayedScore("listing_stats", "$2")+`
+        FROM listing_stats
+        WHERE listing_id = ANY($1)`, listingIDs, r.decay.UserHalfLife.Seconds())
+	if err != nil {
+		return nil, fmt.Errorf("failed to load listing stats: %w", err)
+	}
+	return collectScores(rows)
+}
+
+func collectScores(rows pgx.Rows) (map[string]float64, error) {
+	defer rows.Close()
+	scores := make(map[string]float64)
+	for rows.Next() {
+		var key string
+		var score float64
+		if err := rows.Scan(&key, &score); err != nil {
+			return nil, fmt.Errorf("failed to scan scores: %w", err)
+		}
+		scores[key] = score
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to scan scores: %w", err)
+	}
+	return scores, nil
+}
+
+func (r *postgresRepository) BookedListings(ctx context.Context, userID string) (map[string]bool, error) {
+	rows, err := r.db.Query(ctx, `SELECT listing_id FROM user_bookings WHERE user_id = $1`, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load user bookings: %w", err)
+	}
+	ids, err := pgx.CollectRows(rows, pgx.RowTo[string])
+	if err != nil {
+		return nil, fmt.Errorf("failed to scan user bookings: %w", err)
+	}
+	booked := make(map[string]bool, len(ids))
+	for _, id := range ids {
+		booked[id] = true
+	}
+	return booked, nil
+}
+
+func (r *postgresRepository) PruneProcessedEvents(ctx context.Context, cutoff time.Time, limit int) (int, error) {
+	tag, err := r.db.Exec(ctx, `
+        DELETE FROM processed_events
+        WHERE event_key IN (
+            SELECT event_key FROM processed_events
+            WHERE processed_at < $1
+            ORDER BY processed_at
+            LIMIT $2
+        )`, cutoff, limit)
+	if err != nil {
+		return 0, fmt.Errorf("failed to prune processed events: %w", err)
+	}
+	return int(tag.RowsAffected()), nil
+}
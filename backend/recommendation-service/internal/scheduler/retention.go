@@ -0,0 +1,58 @@
+package scheduler
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/gavinadlan/tripnest/backend/recommendation-service/internal/service"
+)
+
+// RetentionSweeper periodically forgets the keys of events handled longer
+// ago than the retention. Kafka cannot redeliver a message after it has
+// left the log, so a retention longer than the topics' keeps deduplication
+// intact while the table stays bounded. Every replica may run one.
+type RetentionSweeper struct {
+	svc       service.RecommendationService
+	retention time.Duration
+	interval  time.Duration
+	batchSize int
+}
+
+func NewRetentionSweeper(svc service.RecommendationService, retention, interval time.Duration, batchSize int) *RetentionSweeper {
+	return &RetentionSweeper{
+		svc:       svc,
+		retention: retention,
+		interval:  interval,
+		batchSize: batchSize,
+	}
+}
+
+func (s *RetentionSweeper) Run(ctx context.Context) {
+	log.Printf("Processed event sweeper started (retention %s, interval %s)", s.retention, s.interval)
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			log.Println("Processed event sweeper stopped")
+			return
+		case <-ticker.C:
+		}
+
+		// Drain in batches so a backlog doesn't wait for further ticks
+		for {
+			n, err := s.svc.PruneProcessedEvents(ctx, s.retention, s.batchSize)
+			if err != nil {
+				if ctx.Err() == nil {
+					log.Printf("Processed event sweep failed: %v", err)
+				}
+				break
+			}
+			if n < s.batchSize {
+				break
+			}
+		}
+	}
+}
@@ -0,0 +1,227 @@
+package service
+
+import (
+	"context"
+	"log"
+	"math"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/gavinadlan/tripnest/backend/recommendation-service/internal/model"
+)
+
+const (
+	// Destinations whose listings are considered, and listings per
+	// destination fetched from search-service (its maximum page size).
+	candidateDestinations     = 5
+	listingsPerDestination    = 50
+	relatedDestinationWeight  = 0.5
+	trendingDestinationWeight = 0.2
+	listingPopularityWeight   = 0.3
+)
+
+// Reasons a destination, and so its listings, was recommended.
+const (
+	reasonInterest = "interest" // the user booked or searched it
+	reasonRelated  = "related"  // travellers with the user's interests went there
+	reasonTrending = "trending" // popular with everyone right now
+	reasonNew      = "new"      // no statistics yet; newest listings
+)
+
+type scoredDestination struct {
+	score  float64
+	reason string
+}
+
+func (s *recommendationService) Recommend(ctx context.Context, userID string, limit int) (*model.RecommendationResponse, error) {
+	resp := &model.RecommendationResponse{UserID: userID, Strategy: model.StrategyTrending}
+
+	destinations := make(map[string]*scoredDestination)
+	bands := map[string]float64{}
+	booked := map[string]bool{}
+
+	if userID != "" {
+		interests, err := s.repo.TopUserDestinations(ctx, userID, candidateDestinations)
+		if err != nil {
+			return nil, err
+		}
+		if len(interests) > 0 {
+			resp.Strategy = model.StrategyPersonalized
+			addDestinations(destinations, interests, 1, reasonInterest)
+
+			names := make([]string, len(interests))
+			for i, d := range interests {
+				names[i] = d.Destination
+			}
+			related, err := s.repo.RelatedDestinations(ctx, names, candidateDestinations)
+			if err != nil {
+				return nil, err
+			}
+			addDestinations(destinations, related, relatedDestinationWeight, reasonRelated)
+		}
+
+		// Price preferences and past bookings apply even before the user
+		// has destination history, e.g. after price-only searches
+		if bands, err = s.repo.UserBandScores(ctx, userID); err != nil {
+			return nil, err
+		}
+		if booked, err = s.repo.BookedListings(ctx, userID); err != nil {
+			return nil, err
+		}
+	}
+
+	trending, err := s.repo.TrendingDestinations(ctx, candidateDestinations)
+	if err != nil {
+		return nil, err
+	}
+	weight := 1.0
+	if resp.Strategy == model.StrategyPersonalized {
+		weight = trendingDestinationWeight
+	}
+	addDestinations(destinations, trending, weight, reasonTrending)
+
+	if len(destinations) == 0 {
+		// Nothing recorded yet: fall back to the newest listings
+		destinations[""] = &scoredDestination{score: 1, reason: reasonNew}
+	}
+
+	recs, err := s.rankListings(ctx, topDestinations(destinations, candidateDestinations), destinations, bandShares(bands), booked)
+	if err != nil {
+		return nil, err
+	}
+	if len(recs) > limit {
+		recs = recs[:limit]
+	}
+	resp.Data = recs
+	return resp, nil
+}
+
+// rankListings fetches the listings of the candidate destinations from
+// search-service and scores them.
+func (s *recommendationService) rankListings(ctx context.Context, candidates []string, destinations map[string]*scoredDestination, bands map[string]float64, booked map[string]bool) ([]model.Recommendation, error) {
+	results := make([][]model.Listing, len(candidates))
+	errs := make([]error, len(candidates))
+	var wg sync.WaitGroup
+	for i, destination := range candidates {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			results[i], errs[i] = s.catalog.ListByDestination(ctx, destination, listingsPerDestination)
+		}()
+	}
+	wg.Wait()
+
+	today := time.Now().UTC().Format("2006-01-02")
+	byID := make(map[string]model.Recommendation)
+	var failed error
+	for i, listings := range results {
+		if errs[i] != nil {
+			// A partial list beats none
+			log.Printf("Failed to fetch listings for %q: %v", candidates[i], errs[i])
+			failed = errs[i]
+			continue
+		}
+		for _, l := range listings {
+			// Skip what the user already booked and what can't be booked
+			if booked[l.ID] || l.AvailableSlots <= 0 || (l.Date != "" && l.Date < today) {
+				continue
+			}
+			// search-service matches destinations by substring, so score the
+			// listing by its own destination when it has one
+			dest, ok := destinations[model.NormalizeDestination(l.Destination)]
+			if !ok {
+				dest = destinations[candidates[i]]
+			}
+			score := dest.score * (1 + bands[model.BandFor(l.Price)])
+			if existing, ok := byID[l.ID]; ok && existing.Score >= score {
+				continue
+			}
+			listing := l
+			byID[l.ID] = model.Recommendation{ListingID: l.ID, Score: score, Reason: dest.reason, Listing: &listing}
+		}
+	}
+	if len(byID) == 0 && failed != nil {
+		return nil, failed
+	}
+
+	ids := make([]string, 0, len(byID))
+	for id := range byID {
+		ids = append(ids, id)
+	}
+	popularity, err := s.repo.ListingScores(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+	maxPopularity := 0.0
+	for _, p := range popularity {
+		maxPopularity = math.Max(maxPopularity, p)
+	}
+
+	recs := make([]model.Recommendation, 0, len(byID))
+	for id, rec := range byID {
+		if maxPopularity > 0 {
+			rec.Score += listingPopularityWeight * popularity[id] / maxPopularity
+		}
+		rec.Score = math.Round(rec.Score*1000) / 1000
+		recs = append(recs, rec)
+	}
+	sort.Slice(recs, func(i, j int) bool {
+		if recs[i].Score != recs[j].Score {
+			return recs[i].Score > recs[j].Score
+		}
+		return recs[i].ListingID < recs[j].ListingID
+	})
+	return recs, nil
+}
+
+// addDestinations adds scored, normalized to the best of them and scaled by
+// weight, to destinations. A destination already present keeps its reason.
+func addDestinations(destinations map[string]*scoredDestination, scored []model.ScoredDestination, weight float64, reason string) {
+	if len(scored) == 0 || scored[0].Score <= 0 {
+		return
+	}
+	best := scored[0].Score
+	for _, d := range scored {
+		score := weight * d.Score / best
+		if existing, ok := destinations[d.Destination]; ok {
+			existing.score += score
+			continue
+		}
+		destinations[d.Destination] = &scoredDestination{score: score, reason: reason}
+	}
+}
+
+func topDestinations(destinations map[string]*scoredDestination, n int) []string {
+	names := make([]string, 0, len(destinations))
+	for name := range destinations {
+		names = append(names, name)
+	}
+	sort.Slice(names, func(i, j int) bool {
+		a, b := destinations[names[i]], destinations[names[j]]
+		if a.score != b.score {
+			return a.score > b.score
+		}
+		return names[i] < names[j]
+	})
+	if len(names) > n {
+		names = names[:n]
+	}
+	return names
+}
+
+// bandShares turns band scores into each band's share of the total.
+func bandShares(scores map[string]float64) map[string]float64 {
+	total := 0.0
+	for _, s := range scores {
+		total += s
+	}
+	shares := make(map[string]float64, len(scores))
+	if total <= 0 {
+		return shares
+	}
+	for band, s := range scores {
+		shares[band] = s / total
+	}
+	return shares
+}
@@ -0,0 +1,314 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"math"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gavinadlan/tripnest/backend/recommendation-service/internal/model"
+)
+
+// fakeStats serves fixed statistics and records which users were looked up.
+type fakeStats struct {
+	interests  map[string][]model.ScoredDestination
+	related    []model.ScoredDestination
+	relatedTo  []string
+	bands      map[string]map[string]float64
+	booked     map[string]map[string]bool
+	trending   []model.ScoredDestination
+	popularity map[string]float64
+	lookedUp   []string
+}
+
+func (f *fakeStats) Record(ctx context.Context, in *model.Interaction) (bool, error) {
+	return true, nil
+}
+
+func (f *fakeStats) TopUserDestinations(ctx context.Context, userID string, limit int) ([]model.ScoredDestination, error) {
+	f.lookedUp = append(f.lookedUp, userID)
+	return f.interests[userID], nil
+}
+
+func (f *fakeStats) UserBandScores(ctx context.Context, userID string) (map[string]float64, error) {
+	return f.bands[userID], nil
+}
+
+func (f *fakeStats) TrendingDestinations(ctx context.Context, limit int) ([]model.ScoredDestination, error) {
+	return f.trending, nil
+}
+
+func (f *fakeStats) RelatedDestinations(ctx context.Context, destinations []string, limit int) ([]model.ScoredDestination, error) {
+	f.relatedTo = destinations
+	return f.related, nil
+}
+
+func (f *fakeStats) ListingScores(ctx context.Context, listingIDs []string) (map[string]float64, error) {
+	return f.popularity, nil
+}
+
+func (f *fakeStats) BookedListings(ctx context.Context, userID string) (map[string]bool, error) {
+	return f.booked[userID], nil
+}
+
+func (f *fakeStats) PruneProcessedEvents(ctx context.Context, cutoff time.Time, limit int) (int, error) {
+	return 0, nil
+}
+
+func (f *fakeStats) Close() {}
+
+// fakeCatalog serves listings by destination query, failing the queries in
+// failing.
+type fakeCatalog struct {
+	mu       sync.Mutex
+	listings map[string][]model.Listing
+	failing  map[string]bool
+	queried  []string
+}
+
+func (c *fakeCatalog) GetListing(ctx context.Context, id string) (*model.Listing, error) {
+	return nil, nil
+}
+
+func (c *fakeCatalog) ListByDestination(ctx context.Context, destination string, limit int) ([]model.Listing, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.queried = append(c.queried, destination)
+	if c.failing[destination] {
+		return nil, errors.New("search service returned 503 Service Unavailable")
+	}
+	return c.listings[destination], nil
+}
+
+type ranked struct {
+	id     string
+	score  float64
+	reason string
+}
+
+func assertRanking(t *testing.T, resp *model.RecommendationResponse, want []ranked) {
+	t.Helper()
+	got := make([]ranked, len(resp.Data))
+	for i, rec := range resp.Data {
+		got[i] = ranked{rec.ListingID, rec.Score, rec.Reason}
+		if rec.Listing == nil || rec.Listing.ID != rec.ListingID {
+			t.Errorf("recommendation %s carries listing %+v", rec.ListingID, rec.Listing)
+		}
+	}
+	if !slices.Equal(got, want) {
+		t.Fatalf("ranked %v, want %v", got, want)
+	}
+}
+
+func dest(name string, score float64) model.ScoredDestination {
+	return model.ScoredDestination{Destination: name, Score: score}
+}
+
+const future = "2099-01-01"
+
+func TestRecommendColdStart(t *testing.T) {
+	catalog := &fakeCatalog{listings: map[string][]model.Listing{
+		"": {
+			{ID: "b", Destination: "Bali", Price: 80, Date: future, AvailableSlots: 2},
+			{ID: "a", Destination: "Paris", Price: 300, Date: future, AvailableSlots: 1},
+		},
+	}}
+	svc := NewRecommendationService(&fakeStats{}, catalog)
+
+	resp, err := svc.Recommend(context.Background(), "u1", 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.Strategy != model.StrategyTrending || resp.UserID != "u1" {
+		t.Fatalf("strategy %s for %s", resp.Strategy, resp.UserID)
+	}
+	if !slices.Equal(catalog.queried, []string{""}) {
+		t.Fatalf("queried %q, want only the newest listings", catalog.queried)
+	}
+	assertRanking(t, resp, []ranked{{"a", 1, reasonNew}, {"b", 1, reasonNew}})
+}
+
+func TestRecommendTrending(t *testing.T) {
+	stats := &fakeStats{
+		trending:   []model.ScoredDestination{dest("paris", 10), dest("bali", 5)},
+		popularity: map[string]float64{"b1": 4},
+	}
+	catalog := &fakeCatalog{listings: map[string][]model.Listing{
+		"paris": {{ID: "p1", Destination: "Paris", Price: 150, AvailableSlots: 1}},
+		"bali":  {{ID: "b1", Destination: "Bali", Price: 80, AvailableSlots: 1}},
+	}}
+	svc := NewRecommendationService(stats, catalog)
+
+	resp, err := svc.Recommend(context.Background(), "", 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.Strategy != model.StrategyTrending || len(stats.lookedUp) != 0 {
+		t.Fatalf("strategy %s, looked up users %v", resp.Strategy, stats.lookedUp)
+	}
+	// Bali: half of Paris's interest plus the full popularity bonus
+	assertRanking(t, resp, []ranked{{"p1", 1, reasonTrending}, {"b1", 0.8, reasonTrending}})
+}
+
+func TestRecommendPersonalized(t *testing.T) {
+	stats := &fakeStats{
+		interests: map[string][]model.ScoredDestination{"u1": {dest("paris", 4), dest("rome", 2)}},
+		related:   []model.ScoredDestination{dest("bali", 3)},
+		trending:  []model.ScoredDestination{dest("paris", 10), dest("tokyo", 5)},
+		bands:     map[string]map[string]float64{"u1": {model.BandMid: 3, model.BandBudget: 1}},
+		popularity: map[string]float64{
+			"p-lux": 2,
+			"r1":    1,
+		},
+	}
+	catalog := &fakeCatalog{listings: map[string][]model.Listing{
+		"paris": {
+			{ID: "p-mid", Destination: "Paris", Price: 150, AvailableSlots: 1},
+			{ID: "p-lux", Destination: "Paris", Price: 600, AvailableSlots: 1},
+			// Matched by substring; scored as the Rome listing it is
+			{ID: "r2", Destination: "Rome", Price: 60, AvailableSlots: 1},
+		},
+		"rome":  {{ID: "r1", Destination: "Rome", Price: 50, AvailableSlots: 1}},
+		"bali":  {{ID: "b1", Destination: "Bali", Price: 120, AvailableSlots: 1}},
+		"tokyo": {{ID: "t1", Destination: "Tokyo", Price: 90, AvailableSlots: 1}},
+	}}
+	svc := NewRecommendationService(stats, catalog)
+
+	resp, err := svc.Recommend(context.Background(), "u1", 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.Strategy != model.StrategyPersonalized {
+		t.Fatalf("strategy %s", resp.Strategy)
+	}
+	if !slices.Equal(stats.relatedTo, []string{"paris", "rome"}) {
+		t.Fatalf("related to %v, want the user's destinations", stats.relatedTo)
+	}
+	// Destinations: paris 1 + 0.2 trending, rome 0.5, bali 0.5 related, tokyo
+	// 0.2 * 0.5 trending. Bands: mid 75%, budget 25%. Popularity adds up to
+	// 0.3.
+	assertRanking(t, resp, []ranked{
+		{"p-mid", 2.1, reasonInterest}, // 1.2 * 1.75
+		{"p-lux", 1.5, reasonInterest}, // 1.2 + 0.3
+		{"b1", 0.875, reasonRelated},   // 0.5 * 1.75
+		{"r1", 0.775, reasonInterest},  // 0.5 * 1.25 + 0.15
+		{"r2", 0.625, reasonInterest},  // 0.5 * 1.25
+		{"t1", 0.125, reasonTrending},  // 0.1 * 1.25
+	})
+
+	resp, err = svc.Recommend(context.Background(), "u1", 2)
+	if err != nil || len(resp.Data) != 2 || resp.Data[0].ListingID != "p-mid" {
+		t.Fatalf("limited to 2: %+v, %v", resp, err)
+	}
+}
+
+func TestRecommendExcludesUnbookableListings(t *testing.T) {
+	today := time.Now().UTC().Format("2006-01-02")
+	stats := &fakeStats{
+		trending: []model.ScoredDestination{dest("paris", 1)},
+		booked:   map[string]map[string]bool{"u1": {"booked": true}},
+	}
+	catalog := &fakeCatalog{listings: map[string][]model.Listing{
+		"paris": {
+			{ID: "booked", Destination: "Paris", AvailableSlots: 3, Date: future},
+			{ID: "sold-out", Destination: "Paris", AvailableSlots: 0, Date: future},
+			{ID: "past", Destination: "Paris", AvailableSlots: 3, Date: "2020-01-01"},
+			{ID: "today", Destination: "Paris", AvailableSlots: 1, Date: today},
+			{ID: "undated", Destination: "Paris", AvailableSlots: 1},
+		},
+	}}
+	svc := NewRecommendationService(stats, catalog)
+
+	resp, err := svc.Recommend(context.Background(), "u1", 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertRanking(t, resp, []ranked{{"today", 1, reasonTrending}, {"undated", 1, reasonTrending}})
+
+	// Someone else may still book it
+	resp, err = svc.Recommend(context.Background(), "u2", 10)
+	if err != nil || len(resp.Data) != 3 {
+		t.Fatalf("recommended %+v to another user, %v", resp, err)
+	}
+}
+
+func TestRecommendPartialCatalogFailure(t *testing.T) {
+	stats := &fakeStats{trending: []model.ScoredDestination{dest("paris", 2), dest("rome", 1)}}
+	catalog := &fakeCatalog{
+		listings: map[string][]model.Listing{
+			"rome": {{ID: "r1", Destination: "Rome", AvailableSlots: 1}},
+		},
+		failing: map[string]bool{"paris": true},
+	}
+	svc := NewRecommendationService(stats, catalog)
+
+	resp, err := svc.Recommend(context.Background(), "", 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertRanking(t, resp, []ranked{{"r1", 0.5, reasonTrending}})
+
+	catalog.failing["rome"] = true
+	if _, err := svc.Recommend(context.Background(), "", 10); err == nil {
+		t.Fatal("no listings and every lookup failed, yet no error")
+	}
+}
+
+func TestBandShares(t *testing.T) {
+	cases := []struct {
+		scores map[string]float64
+		want   map[string]float64
+	}{
+		{nil, map[string]float64{}},
+		{map[string]float64{model.BandMid: 0}, map[string]float64{}},
+		{map[string]float64{model.BandMid: 2}, map[string]float64{model.BandMid: 1}},
+		{
+			map[string]float64{model.BandBudget: 1, model.BandMid: 3},
+			map[string]float64{model.BandBudget: 0.25, model.BandMid: 0.75},
+		},
+	}
+	for _, tc := range cases {
+		got := bandShares(tc.scores)
+		if len(got) != len(tc.want) {
+			t.Errorf("bandShares(%v) = %v, want %v", tc.scores, got, tc.want)
+			continue
+		}
+		for band, share := range tc.want {
+			if math.Abs(got[band]-share) > 1e-9 {
+				t.Errorf("bandShares(%v) = %v, want %v", tc.scores, got, tc.want)
+			}
+		}
+	}
+}
+
+func TestAddDestinations(t *testing.T) {
+	destinations := map[string]*scoredDestination{}
+
+	addDestinations(destinations, []model.ScoredDestination{dest("paris", 8), dest("rome", 2)}, 1, reasonInterest)
+	addDestinations(destinations, []model.ScoredDestination{dest("rome", 4), dest("bali", 1)}, 0.5, reasonRelated)
+	// Ignored: nothing to normalize against
+	addDestinations(destinations, nil, 1, reasonTrending)
+	addDestinations(destinations, []model.ScoredDestination{dest("tokyo", 0)}, 1, reasonTrending)
+
+	want := map[string]scoredDestination{
+		"paris": {1, reasonInterest},
+		"rome":  {0.75, reasonInterest}, // 0.25 + 0.5, keeping its first reason
+		"bali":  {0.125, reasonRelated},
+	}
+	if len(destinations) != len(want) {
+		t.Fatalf("destinations %v, want %v", destinations, want)
+	}
+	for name, w := range want {
+		got := destinations[name]
+		if got == nil || math.Abs(got.score-w.score) > 1e-9 || got.reason != w.reason {
+			t.Errorf("%s: %+v, want %+v", name, got, w)
+		}
+	}
+
+	if top := topDestinations(destinations, 2); !slices.Equal(top, []string{"paris", "rome"}) {
+		t.Fatalf("top destinations %v", top)
+	}
+}
@@ -0,0 +1,140 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/gavinadlan/tripnest/backend/recommendation-service/internal/catalog"
+	"github.com/gavinadlan/tripnest/backend/recommendation-service/internal/model"
+	"github.com/gavinadlan/tripnest/backend/recommendation-service/internal/repository"
+)
+
+// Topics the service learns from.
+const (
+	TopicBookingCreated  = "booking.created"
+	TopicSearchPerformed = "search.performed"
+)
+
+var Topics = []string{TopicBookingCreated, TopicSearchPerformed}
+
+// A booking says far more about a traveller's interest than a search.
+const (
+	bookingWeight = 5.0
+	searchWeight  = 1.0
+)
+
+type RecommendationService interface {
+	// HandleEvent adds a consumed event to the statistics. eventID
+	// identifies the message so redeliveries are not counted twice.
+	HandleEvent(ctx context.Context, topic, eventID string, payload []byte, occurredAt time.Time) error
+	// Recommend returns up to limit listings ranked for userID, or trending
+	// listings when the user is anonymous ("") or has no history yet.
+	Recommend(ctx context.Context, userID string, limit int) (*model.RecommendationResponse, error)
+	// PruneProcessedEvents forgets up to limit deduplication keys of events
+	// handled more than retention ago and returns how many it removed.
+	PruneProcessedEvents(ctx context.Context, retention time.Duration, limit int) (int, error)
+}
+
+type recommendationService struct {
+	repo    repository.StatsRepository
+	catalog catalog.Catalog
+}
+
+func NewRecommendationService(repo repository.StatsRepository, catalog catalog.Catalog) RecommendationService {
+	return &recommendationService{repo: repo, catalog: catalog}
+}
+
+func (s *recommendationService) HandleEvent(ctx context.Context, topic, eventID string, payload []byte, occurredAt time.Time) error {
+	if occurredAt.IsZero() {
+		occurredAt = time.Now()
+	}
+
+	var in *model.Interaction
+	var err error
+	switch topic {
+	case TopicBookingCreated:
+		in, err = s.bookingInteraction(ctx, payload)
+	case TopicSearchPerformed:
+		in, err = searchInteraction(eventID, payload)
+	default:
+		return fmt.Errorf("unexpected topic %q", topic)
+	}
+	if err != nil {
+		return err
+	}
+	if in == nil {
+		return nil
+	}
+	in.OccurredAt = occurredAt
+
+	recorded, err := s.repo.Record(ctx, in)
+	if err != nil {
+		return err
+	}
+	if !recorded {
+		log.Printf("Event %s already counted, skipping duplicate", in.Key)
+	}
+	return nil
+}
+
+func (s *recommendationService) PruneProcessedEvents(ctx context.Context, retention time.Duration, limit int) (int, error) {
+	n, err := s.repo.PruneProcessedEvents(ctx, time.Now().Add(-retention), limit)
+	if n > 0 {
+		log.Printf("Pruned %d processed event(s) older than %s", n, retention)
+	}
+	return n, err
+}
+
+func (s *recommendationService) bookingInteraction(ctx context.Context, payload []byte) (*model.Interaction, error) {
+	var event model.BookingCreatedEvent
+	if err := json.Unmarshal(payload, &event); err != nil {
+		return nil, fmt.Errorf("failed to decode booking.created event: %w", err)
+	}
+
+	// Keyed by booking so a booking republished by the outbox relay counts
+	// once
+	in := &model.Interaction{
+		Key:     TopicBookingCreated + ":" + event.BookingID,
+		UserID:  event.UserID,
+		Booking: true,
+		Weight:  bookingWeight,
+		Bands:   []string{model.BandFor(event.TotalAmount)},
+	}
+
+	listing, err := s.catalog.GetListing(ctx, event.ResourceID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to look up listing %s: %w", event.ResourceID, err)
+	}
+	if listing == nil {
+		// Still worth the price band the traveller paid
+		log.Printf("Booked resource %s is not a known listing", event.ResourceID)
+		return in, nil
+	}
+
+	in.ListingID = listing.ID
+	in.Destination = model.NormalizeDestination(listing.Destination)
+	in.Bands = []string{model.BandFor(listing.Price)}
+	return in, nil
+}
+
+func searchInteraction(eventID string, payload []byte) (*model.Interaction, error) {
+	var event model.SearchPerformedEvent
+	if err := json.Unmarshal(payload, &event); err != nil {
+		return nil, fmt.Errorf("failed to decode search.performed event: %w", err)
+	}
+
+	in := &model.Interaction{
+		Key:         TopicSearchPerformed + ":" + eventID,
+		UserID:      event.UserID,
+		Destination: model.NormalizeDestination(event.Destination),
+		Bands:       model.BandsBetween(event.MinPrice, event.MaxPrice),
+		Weight:      searchWeight,
+	}
+	if in.Destination == "" && len(in.Bands) == 0 {
+		return nil, nil // Nothing to learn from an unfiltered search
+	}
+	return in, nil
+}
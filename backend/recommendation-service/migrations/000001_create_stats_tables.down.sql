@@ -0,0 +1,7 @@
+DROP TABLE IF EXISTS user_bookings;
+DROP TABLE IF EXISTS listing_stats;
+DROP TABLE IF EXISTS destination_cooccurrence;
+DROP TABLE IF EXISTS destination_stats;
+DROP TABLE IF EXISTS user_price_band_stats;
+DROP TABLE IF EXISTS user_destination_stats;
+DROP TABLE IF EXISTS processed_events;
@@ -0,0 +1,61 @@
+-- Events already counted, so redelivered events are not counted twice
+CREATE TABLE IF NOT EXISTS processed_events (
+    event_key VARCHAR(255) PRIMARY KEY,
+    processed_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Scores decay exponentially; each is stored as of its updated_at and
+-- decayed further when read or incremented.
+CREATE TABLE IF NOT EXISTS user_destination_stats (
+    user_id UUID NOT NULL,
+    destination VARCHAR(255) NOT NULL,
+    score DOUBLE PRECISION NOT NULL DEFAULT 0,
+    bookings INT NOT NULL DEFAULT 0,
+    searches INT NOT NULL DEFAULT 0,
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    PRIMARY KEY (user_id, destination)
+);
+
+CREATE TABLE IF NOT EXISTS user_price_band_stats (
+    user_id UUID NOT NULL,
+    band VARCHAR(20) NOT NULL,
+    score DOUBLE PRECISION NOT NULL DEFAULT 0,
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    PRIMARY KEY (user_id, band)
+);
+
+CREATE TABLE IF NOT EXISTS destination_stats (
+    destination VARCHAR(255) PRIMARY KEY,
+    score DOUBLE PRECISION NOT NULL DEFAULT 0,
+    bookings INT NOT NULL DEFAULT 0,
+    searches INT NOT NULL DEFAULT 0,
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL
+);
+
+-- How often travellers interested in destination were also interested in
+-- related; stored in both directions.
+CREATE TABLE IF NOT EXISTS destination_cooccurrence (
+    destination VARCHAR(255) NOT NULL,
+    related VARCHAR(255) NOT NULL,
+    score DOUBLE PRECISION NOT NULL DEFAULT 0,
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    PRIMARY KEY (destination, related)
+);
+
+CREATE TABLE IF NOT EXISTS listing_stats (
+    listing_id VARCHAR(64) PRIMARY KEY,
+    destination VARCHAR(255) NOT NULL,
+    price_band VARCHAR(20) NOT NULL,
+    score DOUBLE PRECISION NOT NULL DEFAULT 0,
+    bookings INT NOT NULL DEFAULT 0,
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS user_bookings (
+    user_id UUID NOT NULL,
+    listing_id VARCHAR(64) NOT NULL,
+    booked_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    PRIMARY KEY (user_id, listing_id)
+);
+
+CREATE INDEX idx_listing_stats_destination ON listing_stats(destination);
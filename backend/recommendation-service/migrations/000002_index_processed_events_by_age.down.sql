@@ -0,0 +1 @@
+DROP INDEX IF EXISTS idx_processed_events_processed_at;
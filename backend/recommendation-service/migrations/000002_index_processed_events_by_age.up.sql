@@ -0,0 +1,2 @@
+-- The retention sweep deletes the oldest processed events first
+CREATE INDEX IF NOT EXISTS idx_processed_events_processed_at ON processed_events(processed_at);
@@ -2,6 +2,9 @@ package main
 
 import (
 	"context"
+	"crypto/rand"
+	"encoding/json"
+	"expvar"
 	"log"
 	"net/http"
 	"os"
@@ -9,13 +12,16 @@ import (
 	"syscall"
 	"time"
 
+	"github.com/gavinadlan/tripnest/backend/common/auth"
 	"github.com/gavinadlan/tripnest/backend/search-service/internal/config"
+	"github.com/gavinadlan/tripnest/backend/search-service/internal/cursor"
+	"github.com/gavinadlan/tripnest/backend/search-service/internal/events"
 	"github.com/gavinadlan/tripnest/backend/search-service/internal/handler"
+	"github.com/gavinadlan/tripnest/backend/search-service/internal/model"
 	"github.com/gavinadlan/tripnest/backend/search-service/internal/repository"
 	"github.com/gavinadlan/tripnest/backend/search-service/internal/service"
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/chi/v5/middleware"
-	"github.com/go-chi/cors"
 	"github.com/joho/godotenv"
 )
 
@@ -26,32 +32,39 @@ func main() {
 	log.Printf("Starting Search Service on port %s", cfg.Port)
 
 	// Initialize Mongo Repository
-	mongoRepo, err := repository.NewMongoRepository(cfg.MongoURI, "tripnest_search")
+	mongoRepo, err := repository.NewMongoRepository(cfg.MongoURI, "tripnest_search", cfg.TextLanguage)
 	if err != nil {
 		log.Fatalf("Failed to connect to MongoDB: %v", err)
 	}
 
 	// Wrap with Redis Cache
-	cachedRepo := repository.NewCachedListingRepository(mongoRepo, cfg.RedisAddr, 60*time.Second)
+	cachedRepo := repository.NewCachedListingRepository(mongoRepo, cfg.RedisAddr, repository.CacheOptions{
+		TTL:              cfg.CacheTTL,
+		StaleTTL:         cfg.CacheStaleTTL,
+		BreakerThreshold: cfg.RedisBreakerThreshold,
+		BreakerCooldown:  cfg.RedisBreakerCooldown,
+	})
+
+	producer := events.NewKafkaProducer(cfg.KafkaBrokers)
+	defer producer.Close()
 
 	// Initialize Service
-	svc := service.NewSearchService(cachedRepo) // cachedRepo implements ListingRepository
+	svc := service.NewSearchService(cachedRepo, producer) // cachedRepo implements ListingRepository
+
+	cursorSecret := []byte(cfg.CursorSecret)
+	if len(cursorSecret) == 0 {
+		log.Println("SEARCH_CURSOR_SECRET not set, search cursors will not survive a restart or work across replicas")
+		cursorSecret = make([]byte, 32)
+		if _, err := rand.Read(cursorSecret); err != nil {
+			log.Fatalf("Failed to generate cursor secret: %v", err)
+		}
+	}
 
 	// Initialize Handler
-	h := handler.NewHandler(svc)
+	h := handler.NewHandler(svc, cursor.NewCodec(cursorSecret))
 
 	r := chi.NewRouter()
 
-	// CORS Setup
-	r.Use(cors.Handler(cors.Options{
-		AllowedOrigins:   []string{"http://localhost:3000"}, // Frontend URL
-		AllowedMethods:   []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"},
-		AllowedHeaders:   []string{"Accept", "Authorization", "Content-Type", "X-CSRF-Token"},
-		ExposedHeaders:   []string{"Link"},
-		AllowCredentials: true,
-		MaxAge:           300,
-	}))
-
 	r.Use(middleware.Logger)
 	r.Use(middleware.Recoverer)
 
@@ -60,10 +73,139 @@ func main() {
 		w.Write([]byte("OK"))
 	})
 
+	// Cache counters (search_cache) and runtime stats, which include the
+	// command line, so only for operators holding the service token
+	r.With(auth.RequireServiceToken(cfg.ServiceToken)).Handle("/debug/vars", expvar.Handler())
+
 	// Seed endpoint for testing
 	r.Post("/seed", h.Seed)
 
-	r.Get("/search", h.Search)
+	verifier := auth.NewJWKSVerifier(cfg.JWKSURL, cfg.JWTIssuer, cfg.JWKSRefreshTime)
+	identify, authenticate := auth.Identify(verifier), auth.Authenticate(verifier)
+	if cfg.TrustGatewayHeaders {
+		identify, authenticate = auth.IdentifyGatewayHeaders(), auth.TrustGatewayHeaders()
+	}
+	r.With(identify).Get("/search", h.Search)
+	r.Get("/search/suggest", h.Suggest)
+	r.Get("/listings/{id}", h.GetListing)
+
+	// Catalog management
+	r.Group(func(r chi.Router) {
+		r.Use(authenticate)
+		r.Use(auth.RequireRole(auth.RoleAdmin))
+
+		r.Post("/listings", h.CreateListing)
+		r.Put("/listings/{id}", h.ReplaceListing)
+		r.Patch("/listings/{id}", h.PatchListing)
+		r.Delete("/listings/{id}", h.DeleteListing)
+	})
+
+	// Inventory holds for booking-service
+	r.Route("/internal/listings/{id}/holds", func(r chi.Router) {
+		r.Use(auth.RequireServiceToken(cfg.ServiceToken))
+		r.Post("/", h.HoldSlot)
+		r.Delete("/{holdId}", h.ReleaseSlot)
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	releaseConsumer := events.NewConsumer(cfg.KafkaBrokers, "inventory.release", "search-service-group")
+	defer releaseConsumer.Close()
+
+	// Catalog consumers keep the index and cache in step with listing changes
+	upsertedConsumer := events.NewConsumer(cfg.KafkaBrokers, "listing.upserted", "search-service-group")
+	defer upsertedConsumer.Close()
+
+	deletedConsumer := events.NewConsumer(cfg.KafkaBrokers, "listing.deleted", "search-service-group")
+	defer deletedConsumer.Close()
+
+	inventoryConsumer := events.NewConsumer(cfg.KafkaBrokers, "inventory.changed", "search-service-group")
+	defer inventoryConsumer.Close()
+
+	go func() {
+		log.Println("Listening for inventory.release events...")
+		for {
+			msg, err := releaseConsumer.ReadMessage(ctx)
+			if err != nil {
+				log.Printf("Inventory Release Consumer error: %v", err)
+				break
+			}
+
+			var event model.InventoryReleaseEvent
+			if err := json.Unmarshal(msg.Value, &event); err != nil {
+				log.Printf("Failed to unmarshal inventory release event: %v", err)
+				continue
+			}
+
+			if err := svc.ReleaseSlot(ctx, event.ListingID, event.HoldID, event.Reason); err != nil {
+				log.Printf("Failed to release hold %s: %v", event.HoldID, err)
+			}
+		}
+	}()
+
+	go func() {
+		log.Println("Listening for listing.upserted events...")
+		for {
+			msg, err := upsertedConsumer.ReadMessage(ctx)
+			if err != nil {
+				log.Printf("Listing Upserted Consumer error: %v", err)
+				break
+			}
+
+			var event model.ListingUpsertedEvent
+			if err := json.Unmarshal(msg.Value, &event); err != nil {
+				log.Printf("Failed to unmarshal listing upserted event: %v", err)
+				continue
+			}
+
+			if err := svc.ApplyListingUpserted(ctx, &event); err != nil {
+				log.Printf("Failed to index listing %s: %v", event.ID, err)
+			}
+		}
+	}()
+
+	go func() {
+		log.Println("Listening for listing.deleted events...")
+		for {
+			msg, err := deletedConsumer.ReadMessage(ctx)
+			if err != nil {
+				log.Printf("Listing Deleted Consumer error: %v", err)
+				break
+			}
+
+			var event model.ListingDeletedEvent
+			if err := json.Unmarshal(msg.Value, &event); err != nil {
+				log.Printf("Failed to unmarshal listing deleted event: %v", err)
+				continue
+			}
+
+			if err := svc.ApplyListingDeleted(ctx, &event); err != nil {
+				log.Printf("Failed to remove listing %s: %v", event.ID, err)
+			}
+		}
+	}()
+
+	go func() {
+		log.Println("Listening for inventory.changed events...")
+		for {
+			msg, err := inventoryConsumer.ReadMessage(ctx)
+			if err != nil {
+				log.Printf("Inventory Changed Consumer error: %v", err)
+				break
+			}
+
+			var event model.InventoryChangedEvent
+			if err := json.Unmarshal(msg.Value, &event); err != nil {
+				log.Printf("Failed to unmarshal inventory changed event: %v", err)
+				continue
+			}
+
+			if err := svc.ApplyInventoryChanged(ctx, &event); err != nil {
+				log.Printf("Failed to update inventory of listing %s: %v", event.ListingID, err)
+			}
+		}
+	}()
 
 	server := &http.Server{
 		Addr:    ":" + cfg.Port,
@@ -79,8 +221,9 @@ func main() {
 	quit := make(chan os.Signal, 1)
 	signal.Notify(quit, os.Interrupt, syscall.SIGTERM)
 	<-quit
+	cancel()
 
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-	defer cancel()
-	server.Shutdown(ctx)
+	shutdownCtx, shutdownCancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer shutdownCancel()
+	server.Shutdown(shutdownCtx)
 }
@@ -1,19 +1,69 @@
 package config
 
 import (
+	"strings"
+	"time"
+
 	"github.com/gavinadlan/tripnest/backend/common/env"
 )
 
 type Config struct {
-	Port      string
-	MongoURI  string
-	RedisAddr string
+	Port         string
+	MongoURI     string
+	RedisAddr    string
+	KafkaBrokers []string
+	// TextLanguage is the default stemming language of the full-text index
+	TextLanguage string
+
+	// Searches are attributed to the caller when a token is present
+	JWTIssuer       string
+	JWKSURL         string
+	JWKSRefreshTime time.Duration
+	// TrustGatewayHeaders takes the caller from the api-gateway's principal
+	// headers instead of verifying tokens. Only enable it when the service is
+	// not reachable except through the gateway.
+	TrustGatewayHeaders bool
+
+	// Searches are fresh in the cache for CacheTTL, then served stale for
+	// up to CacheStaleTTL while they are reloaded in the background
+	CacheTTL      time.Duration
+	CacheStaleTTL time.Duration
+	// After RedisBreakerThreshold consecutive Redis errors the cache is
+	// bypassed for RedisBreakerCooldown
+	RedisBreakerThreshold int
+	RedisBreakerCooldown  time.Duration
+
+	// CursorSecret signs search cursors. Replicas must share it; empty
+	// picks a random one, so cursors only work on the instance that made
+	// them and until it restarts.
+	CursorSecret string
+
+	// ServiceToken guards the /internal inventory endpoints booking-service
+	// calls; empty disables them.
+	ServiceToken string
 }
 
 func Load() *Config {
+	brokers := env.GetString("KAFKA_BROKERS", "localhost:9092")
 	return &Config{
-		Port:      env.GetString("PORT", "8083"),
-		MongoURI:  env.GetString("MONGO_URI", "mongodb://localhost:27017/tripnest_search"),
-		RedisAddr: env.GetString("REDIS_ADDR", "localhost:6379"),
+		Port:         env.GetString("PORT", "8083"),
+		MongoURI:     env.GetString("MONGO_URI", "mongodb://localhost:27017/tripnest_search"),
+		RedisAddr:    env.GetString("REDIS_ADDR", "localhost:6379"),
+		KafkaBrokers: strings.Split(brokers, ","),
+		TextLanguage: env.GetString("SEARCH_TEXT_LANGUAGE", "english"),
+
+		JWTIssuer:           env.GetString("JWT_ISSUER", "user-service"),
+		JWKSURL:             env.GetString("JWKS_URL", "http://localhost:8080/.well-known/jwks.json"),
+		JWKSRefreshTime:     env.GetDuration("JWKS_REFRESH_INTERVAL", 10*time.Minute),
+		TrustGatewayHeaders: env.GetBool("TRUST_GATEWAY_HEADERS", false),
+
+		CacheTTL:              env.GetDuration("SEARCH_CACHE_TTL", 60*time.Second),
+		CacheStaleTTL:         env.GetDuration("SEARCH_CACHE_STALE_TTL", 5*time.Minute),
+		RedisBreakerThreshold: env.GetInt("REDIS_BREAKER_THRESHOLD", 5),
+		RedisBreakerCooldown:  env.GetDuration("REDIS_BREAKER_COOLDOWN", 10*time.Second),
+
+		CursorSecret: env.GetString("SEARCH_CURSOR_SECRET", ""),
+
+		ServiceToken: env.GetString("INTERNAL_SERVICE_TOKEN", ""),
 	}
 }
@@ -0,0 +1,58 @@
+package cursor
+
+import (
+	"encoding/base64"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gavinadlan/tripnest/backend/search-service/internal/model"
+)
+
+func TestCodecRoundTrip(t *testing.T) {
+	codec := NewCodec([]byte("secret"))
+	start := time.Date(2026, 7, 1, 0, 0, 0, 0, time.UTC)
+
+	for _, cur := range []*model.Cursor{
+		{Sort: model.SortPrice, Number: 149.5, ID: "64b7f0c2a1b2c3d4e5f60718", Search: "abc"},
+		{Sort: model.SortDate, Time: &start, ID: "64b7f0c2a1b2c3d4e5f60718", Backward: true, Search: "def"},
+	} {
+		token := codec.Encode(cur)
+		if strings.ContainsAny(token, "+/=") {
+			t.Fatalf("token %q is not URL-safe", token)
+		}
+		got, err := codec.Decode(token)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got.Sort != cur.Sort || got.Number != cur.Number || got.ID != cur.ID ||
+			got.Backward != cur.Backward || got.Search != cur.Search {
+			t.Fatalf("decoded %+v, want %+v", got, cur)
+		}
+		if (got.Time == nil) != (cur.Time == nil) || (got.Time != nil && !got.Time.Equal(*cur.Time)) {
+			t.Fatalf("decoded time %v, want %v", got.Time, cur.Time)
+		}
+	}
+}
+
+func TestCodecRejectsTamperedTokens(t *testing.T) {
+	codec := NewCodec([]byte("secret"))
+	token := codec.Encode(&model.Cursor{Sort: model.SortPrice, Number: 10, ID: "64b7f0c2a1b2c3d4e5f60718"})
+	payload, sig, _ := strings.Cut(token, ".")
+
+	forged := base64.RawURLEncoding.EncodeToString([]byte(`{"s":"price","n":0,"id":"64b7f0c2a1b2c3d4e5f60718"}`))
+	for name, bad := range map[string]string{
+		"empty":           "",
+		"no signature":    payload,
+		"forged payload":  forged + "." + sig,
+		"bad signature":   payload + "." + base64.RawURLEncoding.EncodeToString([]byte("nope")),
+		"not base64":      payload + ".!!!",
+		"other secret":    NewCodec([]byte("other")).Encode(&model.Cursor{ID: "64b7f0c2a1b2c3d4e5f60718"}),
+		"signed non-JSON": "bm90IGpzb24." + base64.RawURLEncoding.EncodeToString(codec.sign("bm90IGpzb24")),
+	} {
+		if _, err := codec.Decode(bad); !errors.Is(err, ErrInvalidCursor) {
+			t.Errorf("%s: Decode returned %v, want ErrInvalidCursor", name, err)
+		}
+	}
+}
@@ -0,0 +1,58 @@
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"time"
+
+	"github.com/segmentio/kafka-go"
+)
+
+type Producer interface {
+	Publish(ctx context.Context, topic string, key string, payload interface{}) error
+	Close()
+}
+
+// KafkaProducer publishes asynchronously: search events are analytics, so
+// a slow or unavailable broker must not hold up search responses. Failed
+// writes are logged and dropped.
+type KafkaProducer struct {
+	writer *kafka.Writer
+}
+
+func NewKafkaProducer(brokers []string) *KafkaProducer {
+	log.Printf("Connecting to Kafka brokers: %v", brokers)
+	return &KafkaProducer{
+		writer: &kafka.Writer{
+			Addr:         kafka.TCP(brokers...),
+			Balancer:     &kafka.Hash{},
+			BatchTimeout: 50 * time.Millisecond,
+			Async:        true,
+			Completion: func(messages []kafka.Message, err error) {
+				if err != nil {
+					log.Printf("Failed to publish %d event(s): %v", len(messages), err)
+				}
+			},
+		},
+	}
+}
+
+func (p *KafkaProducer) Publish(ctx context.Context, topic string, key string, payload interface{}) error {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+
+	return p.writer.WriteMessages(ctx, kafka.Message{
+		Topic: topic,
+		Key:   []byte(key),
+		Value: data,
+	})
+}
+
+func (p *KafkaProducer) Close() {
+	if err := p.writer.Close(); err != nil {
+		log.Printf("Failed to close kafka writer: %v", err)
+	}
+}
@@ -2,62 +2,544 @@ package handler
 
 import (
 	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"math"
 	"net/http"
+	"net/url"
 	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
 
+	"github.com/gavinadlan/tripnest/backend/common/auth"
+	"github.com/gavinadlan/tripnest/backend/common/utils"
+	"github.com/gavinadlan/tripnest/backend/search-service/internal/cursor"
 	"github.com/gavinadlan/tripnest/backend/search-service/internal/model"
 	"github.com/gavinadlan/tripnest/backend/search-service/internal/service"
+	"github.com/go-chi/chi/v5"
+	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
 type Handler struct {
-	svc service.SearchService
+	svc     service.SearchService
+	cursors *cursor.Codec
 }
 
-func NewHandler(svc service.SearchService) *Handler {
-	return &Handler{svc: svc}
+func NewHandler(svc service.SearchService, cursors *cursor.Codec) *Handler {
+	return &Handler{svc: svc, cursors: cursors}
 }
 
+const (
+	maxQueryLength = 200
+	maxLimit       = 50
+)
+
+// Search lists bookable listings. Query parameters: q (full text over
+// title, destination and description), lang (stemming language for q),
+// destination, min_price, max_price, date (exact start date) or from/to
+// (the trip lies between them), flex_days (widens the dates),
+// min_duration/max_duration (trip length in days), lat/lng/radius_km
+// (listings around a point, with their distance), bbox (min_lng,min_lat,
+// max_lng,max_lat), sort (relevance|price|date|newest|distance), page or
+// cursor (a next_cursor or prev_cursor of the same search), limit, facets
+// (true to add counts over all matches) and track. Malformed values are a
+// 400.
 func (h *Handler) Search(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
 
 	// Parse Query Params
 	query := r.URL.Query()
-	page, _ := strconv.Atoi(query.Get("page"))
-	if page < 1 {
-		page = 1
+	page, err := intParam(query, "page", 1, 1, math.MaxInt32)
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+	limit, err := intParam(query, "limit", 10, 1, maxLimit)
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+	minPrice, err := floatParam(query, "min_price")
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+	maxPrice, err := floatParam(query, "max_price")
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+	if maxPrice > 0 && minPrice > maxPrice {
+		utils.WriteError(w, http.StatusBadRequest, errors.New("min_price must not exceed max_price"))
+		return
+	}
+	dates, err := parseDates(query)
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	q := strings.TrimSpace(query.Get("q"))
+	if len(q) > maxQueryLength {
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("invalid q: at most %d characters", maxQueryLength))
+		return
+	}
+	near, err := parseNear(query)
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err)
+		return
 	}
-	limit, _ := strconv.Atoi(query.Get("limit"))
-	if limit < 1 || limit > 50 {
-		limit = 10
+	bbox, err := parseBBox(query.Get("bbox"))
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+	sort := model.SortOrder(strings.ToLower(query.Get("sort")))
+	switch {
+	case sort == "" && q != "":
+		sort = model.SortRelevance
+	case sort == "" && near != nil:
+		sort = model.SortDistance
+	case sort == "":
+		sort = model.SortNewest
+	case !sort.Valid():
+		utils.WriteError(w, http.StatusBadRequest, errors.New("invalid sort: must be relevance, price, date, newest or distance"))
+		return
+	case sort == model.SortRelevance && q == "":
+		utils.WriteError(w, http.StatusBadRequest, errors.New("sort=relevance requires q"))
+		return
+	case sort == model.SortDistance && near == nil:
+		utils.WriteError(w, http.StatusBadRequest, errors.New("sort=distance requires lat and lng"))
+		return
+	}
+	lang := strings.ToLower(query.Get("lang"))
+	if lang != "" && !model.TextLanguages[lang] {
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("unsupported lang %q", lang))
+		return
 	}
-	minPrice, _ := strconv.ParseFloat(query.Get("min_price"), 64)
-	maxPrice, _ := strconv.ParseFloat(query.Get("max_price"), 64)
 
+	principal, _ := auth.PrincipalFromContext(r.Context())
 	params := &model.SearchParams{
+		Query:       q,
+		Language:    lang,
+		Sort:        sort,
 		Destination: query.Get("destination"),
-		Date:        query.Get("date"),
+		Date:        dates.Date,
+		From:        dates.From,
+		To:          dates.To,
+		FlexDays:    dates.FlexDays,
+		MinDuration: dates.MinDuration,
+		MaxDuration: dates.MaxDuration,
+		Near:        near,
+		BBox:        bbox,
 		MinPrice:    minPrice,
 		MaxPrice:    maxPrice,
 		Page:        page,
 		Limit:       limit,
+		Facets:      query.Get("facets") == "true",
+		UserID:      principal.UserID,
+		Untracked:   query.Get("track") == "false",
+	}
+
+	if token := query.Get("cursor"); token != "" {
+		if query.Get("page") != "" {
+			utils.WriteError(w, http.StatusBadRequest, errors.New("cursor cannot be combined with page"))
+			return
+		}
+		c, err := h.cursors.Decode(token)
+		if err != nil {
+			utils.WriteError(w, http.StatusBadRequest, err)
+			return
+		}
+		if _, err := primitive.ObjectIDFromHex(c.ID); err != nil || c.Search != params.Fingerprint() {
+			utils.WriteError(w, http.StatusBadRequest, errors.New("cursor does not belong to this search"))
+			return
+		}
+		params.Cursor = c
 	}
 
-	listings, total, err := h.svc.SearchListings(r.Context(), params)
+	result, err := h.svc.SearchListings(r.Context(), params)
 	if err != nil {
 		http.Error(w, `{"error": "search failed"}`, http.StatusInternalServerError)
 		return
 	}
 
 	response := map[string]interface{}{
-		"data":  listings,
-		"page":  page,
+		"data":  result.Listings,
 		"limit": limit,
-		"total": total,
+		"total": result.Total,
+	}
+	if params.Cursor == nil {
+		response["page"] = page
+	}
+	if result.Next != nil {
+		response["next_cursor"] = h.cursors.Encode(result.Next)
+	}
+	if result.Prev != nil {
+		response["prev_cursor"] = h.cursors.Encode(result.Prev)
+	}
+	if result.Facets != nil {
+		response["facets"] = result.Facets
 	}
 
 	json.NewEncoder(w).Encode(response)
 }
 
+// intParam reads an integer between min and max, def when absent.
+func intParam(query url.Values, name string, def, min, max int) (int, error) {
+	raw := query.Get(name)
+	if raw == "" {
+		return def, nil
+	}
+	v, err := strconv.Atoi(raw)
+	if err != nil || v < min || v > max {
+		return 0, fmt.Errorf("invalid %s: must be an integer from %d to %d", name, min, max)
+	}
+	return v, nil
+}
+
+// floatParam reads a non-negative number, 0 when absent.
+func floatParam(query url.Values, name string) (float64, error) {
+	raw := query.Get(name)
+	if raw == "" {
+		return 0, nil
+	}
+	v, err := strconv.ParseFloat(raw, 64)
+	if err != nil || !(v >= 0 && v <= math.MaxFloat64) {
+		return 0, fmt.Errorf("invalid %s: must be a non-negative number", name)
+	}
+	return v, nil
+}
+
+// dateParam reads a YYYY-MM-DD date, nil when absent.
+func dateParam(query url.Values, name string) (*time.Time, error) {
+	raw := query.Get(name)
+	if raw == "" {
+		return nil, nil
+	}
+	t, err := model.ParseDate(raw)
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s: must be YYYY-MM-DD", name)
+	}
+	return &t, nil
+}
+
+// parseDates reads the date and duration filters into params. date pins
+// the start date, so it cannot be combined with from and to.
+func parseDates(query url.Values) (*model.SearchParams, error) {
+	var params model.SearchParams
+	var err error
+	if params.Date, err = dateParam(query, "date"); err != nil {
+		return nil, err
+	}
+	if params.From, err = dateParam(query, "from"); err != nil {
+		return nil, err
+	}
+	if params.To, err = dateParam(query, "to"); err != nil {
+		return nil, err
+	}
+	if params.Date != nil && (params.From != nil || params.To != nil) {
+		return nil, errors.New("date cannot be combined with from or to")
+	}
+	if params.From != nil && params.To != nil && params.To.Before(*params.From) {
+		return nil, errors.New("to must not be before from")
+	}
+
+	if params.FlexDays, err = intParam(query, "flex_days", 0, 0, model.MaxFlexDays); err != nil {
+		return nil, err
+	}
+	if params.FlexDays > 0 && params.Date == nil && params.From == nil && params.To == nil {
+		return nil, errors.New("flex_days requires date, from or to")
+	}
+
+	if params.MinDuration, err = intParam(query, "min_duration", 0, 0, model.MaxDurationDays); err != nil {
+		return nil, err
+	}
+	if params.MaxDuration, err = intParam(query, "max_duration", 0, 0, model.MaxDurationDays); err != nil {
+		return nil, err
+	}
+	if params.MaxDuration > 0 && params.MinDuration > params.MaxDuration {
+		return nil, errors.New("min_duration must not exceed max_duration")
+	}
+	return &params, nil
+}
+
+// parseNear reads lat, lng and radius_km. lat and lng go together; without
+// radius_km every listing with a location matches.
+func parseNear(query url.Values) (*model.GeoNear, error) {
+	latParam, lngParam, radiusParam := query.Get("lat"), query.Get("lng"), query.Get("radius_km")
+	if latParam == "" && lngParam == "" {
+		if radiusParam != "" {
+			return nil, errors.New("radius_km requires lat and lng")
+		}
+		return nil, nil
+	}
+	if latParam == "" || lngParam == "" {
+		return nil, errors.New("lat and lng must be given together")
+	}
+
+	lat, err := strconv.ParseFloat(latParam, 64)
+	if err != nil {
+		return nil, errors.New("invalid lat: must be a number")
+	}
+	lng, err := strconv.ParseFloat(lngParam, 64)
+	if err != nil {
+		return nil, errors.New("invalid lng: must be a number")
+	}
+	if err := model.ValidateCoordinates(lat, lng); err != nil {
+		return nil, err
+	}
+
+	near := &model.GeoNear{Lat: lat, Lng: lng}
+	if radiusParam != "" {
+		near.RadiusKm, err = strconv.ParseFloat(radiusParam, 64)
+		if err != nil || !(near.RadiusKm > 0 && near.RadiusKm <= model.MaxRadiusKm) {
+			return nil, fmt.Errorf("invalid radius_km: must be a number between 0 and %g", model.MaxRadiusKm)
+		}
+	}
+	return near, nil
+}
+
+// parseBBox reads a min_lng,min_lat,max_lng,max_lat box, the GeoJSON
+// order. Boxes crossing the antimeridian are not supported.
+func parseBBox(param string) (*model.BoundingBox, error) {
+	if param == "" {
+		return nil, nil
+	}
+	invalid := errors.New("invalid bbox: must be min_lng,min_lat,max_lng,max_lat")
+
+	parts := strings.Split(param, ",")
+	if len(parts) != 4 {
+		return nil, invalid
+	}
+	var v [4]float64
+	for i, part := range parts {
+		f, err := strconv.ParseFloat(strings.TrimSpace(part), 64)
+		if err != nil {
+			return nil, invalid
+		}
+		v[i] = f
+	}
+
+	box := &model.BoundingBox{MinLng: v[0], MinLat: v[1], MaxLng: v[2], MaxLat: v[3]}
+	if err := model.ValidateCoordinates(box.MinLat, box.MinLng); err != nil {
+		return nil, fmt.Errorf("invalid bbox: %w", err)
+	}
+	if err := model.ValidateCoordinates(box.MaxLat, box.MaxLng); err != nil {
+		return nil, fmt.Errorf("invalid bbox: %w", err)
+	}
+	if box.MinLng > box.MaxLng || box.MinLat > box.MaxLat {
+		return nil, errors.New("invalid bbox: min must not exceed max")
+	}
+	return box, nil
+}
+
+// Suggest completes what is typed into the search box with destinations
+// and listing titles. Query parameters: prefix (required) and limit (per
+// kind, default 5).
+func (h *Handler) Suggest(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	prefix := strings.TrimSpace(query.Get("prefix"))
+	if prefix == "" || utf8.RuneCountInString(prefix) > model.MaxSuggestPrefix {
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("invalid prefix: must be 1 to %d characters", model.MaxSuggestPrefix))
+		return
+	}
+	limit, err := intParam(query, "limit", 5, 1, model.MaxSuggestions)
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	suggestions, err := h.svc.Suggest(r.Context(), prefix, limit)
+	if err != nil {
+		log.Printf("Failed to suggest for %q: %v", prefix, err)
+		utils.WriteError(w, http.StatusInternalServerError, errors.New("suggest failed"))
+		return
+	}
+	utils.WriteJSON(w, http.StatusOK, suggestions)
+}
+
+func (h *Handler) GetListing(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	listing, err := h.svc.GetListing(r.Context(), chi.URLParam(r, "id"))
+	if err != nil {
+		http.Error(w, `{"error": "failed to get listing"}`, http.StatusInternalServerError)
+		return
+	}
+	if listing == nil {
+		http.Error(w, `{"error": "listing not found"}`, http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("ETag", etag(listing.Version))
+	json.NewEncoder(w).Encode(listing)
+}
+
+// CreateListing, ReplaceListing, PatchListing and DeleteListing are
+// admin-only. Writes to an existing listing must send the ETag of the
+// version they are based on in If-Match (optional for DELETE); a listing
+// changed since then is answered with 412 Precondition Failed.
+
+func (h *Handler) CreateListing(w http.ResponseWriter, r *http.Request) {
+	var in model.ListingInput
+	if err := utils.ReadJSON(r, &in); err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	listing, err := h.svc.CreateListing(r.Context(), &in)
+	if err != nil {
+		writeListingError(w, "CreateListing", err)
+		return
+	}
+
+	w.Header().Set("ETag", etag(listing.Version))
+	utils.WriteJSON(w, http.StatusCreated, listing)
+}
+
+func (h *Handler) ReplaceListing(w http.ResponseWriter, r *http.Request) {
+	version, ok := requireIfMatch(w, r)
+	if !ok {
+		return
+	}
+	var in model.ListingInput
+	if err := utils.ReadJSON(r, &in); err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	listing, err := h.svc.ReplaceListing(r.Context(), chi.URLParam(r, "id"), version, &in)
+	if err != nil {
+		writeListingError(w, "ReplaceListing", err)
+		return
+	}
+
+	w.Header().Set("ETag", etag(listing.Version))
+	utils.WriteJSON(w, http.StatusOK, listing)
+}
+
+func (h *Handler) PatchListing(w http.ResponseWriter, r *http.Request) {
+	version, ok := requireIfMatch(w, r)
+	if !ok {
+		return
+	}
+	var patch model.ListingPatch
+	if err := utils.ReadJSON(r, &patch); err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	listing, err := h.svc.PatchListing(r.Context(), chi.URLParam(r, "id"), version, &patch)
+	if err != nil {
+		writeListingError(w, "PatchListing", err)
+		return
+	}
+
+	w.Header().Set("ETag", etag(listing.Version))
+	utils.WriteJSON(w, http.StatusOK, listing)
+}
+
+func (h *Handler) DeleteListing(w http.ResponseWriter, r *http.Request) {
+	var version int64
+	if r.Header.Get("If-Match") != "" {
+		var ok bool
+		if version, ok = requireIfMatch(w, r); !ok {
+			return
+		}
+	}
+
+	if err := h.svc.DeleteListing(r.Context(), chi.URLParam(r, "id"), version); err != nil {
+		writeListingError(w, "DeleteListing", err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func writeListingError(w http.ResponseWriter, op string, err error) {
+	switch {
+	case errors.Is(err, model.ErrInvalidListing):
+		utils.WriteError(w, http.StatusBadRequest, err)
+	case errors.Is(err, model.ErrListingNotFound):
+		utils.WriteError(w, http.StatusNotFound, err)
+	case errors.Is(err, model.ErrVersionConflict):
+		utils.WriteErrorCode(w, http.StatusPreconditionFailed, "VERSION_CONFLICT", err)
+	default:
+		log.Printf("%s failed: %v", op, err)
+		utils.WriteError(w, http.StatusInternalServerError, errors.New("failed to save listing"))
+	}
+}
+
+func etag(version int64) string {
+	return `"` + strconv.FormatInt(version, 10) + `"`
+}
+
+// requireIfMatch reads the listing version from the If-Match header. On
+// failure the response has already been written.
+func requireIfMatch(w http.ResponseWriter, r *http.Request) (int64, bool) {
+	raw := r.Header.Get("If-Match")
+	if raw == "" {
+		utils.WriteError(w, http.StatusPreconditionRequired, errors.New("If-Match header with the listing's ETag is required"))
+		return 0, false
+	}
+	raw = strings.TrimPrefix(strings.TrimSpace(raw), "W/")
+	version, err := strconv.ParseInt(strings.Trim(raw, `"`), 10, 64)
+	if err != nil || version < 0 {
+		utils.WriteError(w, http.StatusBadRequest, errors.New("invalid If-Match header: expected the listing's ETag"))
+		return 0, false
+	}
+	return version, true
+}
+
+// HoldSlot is called by booking-service before it stores a booking.
+func (h *Handler) HoldSlot(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	var req model.HoldRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.HoldID == "" {
+		http.Error(w, `{"error": "hold_id is required"}`, http.StatusBadRequest)
+		return
+	}
+
+	listingID := chi.URLParam(r, "id")
+	listing, err := h.svc.HoldSlot(r.Context(), listingID, req.HoldID)
+	switch {
+	case errors.Is(err, model.ErrListingNotFound):
+		http.Error(w, `{"error": "listing not found", "code": "LISTING_NOT_FOUND"}`, http.StatusNotFound)
+		return
+	case errors.Is(err, model.ErrSoldOut):
+		http.Error(w, `{"error": "listing is sold out", "code": "SOLD_OUT"}`, http.StatusConflict)
+		return
+	case err != nil:
+		log.Printf("HoldSlot failed: %v", err)
+		http.Error(w, `{"error": "failed to hold slot"}`, http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(model.HoldResponse{
+		HoldID:         req.HoldID,
+		ListingID:      listingID,
+		AvailableSlots: listing.AvailableSlots,
+		StartDate:      listing.Date,
+	})
+}
+
+// ReleaseSlot lets booking-service undo a hold for a booking it failed to
+// store. Holds of stored bookings are released through inventory.release.
+func (h *Handler) ReleaseSlot(w http.ResponseWriter, r *http.Request) {
+	err := h.svc.ReleaseSlot(r.Context(), chi.URLParam(r, "id"), chi.URLParam(r, "holdId"), "booking not created")
+	if err != nil {
+		log.Printf("ReleaseSlot failed: %v", err)
+		w.Header().Set("Content-Type", "application/json")
+		http.Error(w, `{"error": "failed to release slot"}`, http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
 func (h *Handler) Seed(w http.ResponseWriter, r *http.Request) {
 	if err := h.svc.SeedListings(r.Context()); err != nil {
 		http.Error(w, `{"error": "seeding failed"}`, http.StatusInternalServerError)
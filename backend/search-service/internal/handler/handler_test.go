@@ -0,0 +1,154 @@
+package handler
+
+import (
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/gavinadlan/tripnest/backend/search-service/internal/model"
+)
+
+func TestParseNear(t *testing.T) {
+	cases := []struct {
+		query string
+		want  *model.GeoNear
+	}{
+		{"", nil},
+		{"lat=48.85&lng=2.35", &model.GeoNear{Lat: 48.85, Lng: 2.35}},
+		{"lat=-33.9&lng=151.2&radius_km=25", &model.GeoNear{Lat: -33.9, Lng: 151.2, RadiusKm: 25}},
+		{"lat=90&lng=-180&radius_km=20015", &model.GeoNear{Lat: 90, Lng: -180, RadiusKm: model.MaxRadiusKm}},
+	}
+	for _, tc := range cases {
+		query, _ := url.ParseQuery(tc.query)
+		got, err := parseNear(query)
+		if err != nil {
+			t.Errorf("parseNear(%q): %v", tc.query, err)
+			continue
+		}
+		if (got == nil) != (tc.want == nil) || (got != nil && *got != *tc.want) {
+			t.Errorf("parseNear(%q) = %+v, want %+v", tc.query, got, tc.want)
+		}
+	}
+}
+
+func TestParseNearRejects(t *testing.T) {
+	for _, q := range []string{
+		"radius_km=10",
+		"lat=48.85",
+		"lng=2.35",
+		"lat=north&lng=2.35",
+		"lat=48.85&lng=east",
+		"lat=91&lng=0",
+		"lat=0&lng=180.5",
+		"lat=NaN&lng=0",
+		"lat=0&lng=Inf",
+		"lat=0&lng=0&radius_km=0",
+		"lat=0&lng=0&radius_km=-5",
+		"lat=0&lng=0&radius_km=20016",
+		"lat=0&lng=0&radius_km=NaN",
+		"lat=0&lng=0&radius_km=far",
+	} {
+		query, _ := url.ParseQuery(q)
+		if near, err := parseNear(query); err == nil {
+			t.Errorf("parseNear(%q) accepted %+v", q, near)
+		}
+	}
+}
+
+func TestParseBBox(t *testing.T) {
+	got, err := parseBBox(" 2.2, 48.8 ,2.5,49")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := model.BoundingBox{MinLng: 2.2, MinLat: 48.8, MaxLng: 2.5, MaxLat: 49}
+	if *got != want {
+		t.Fatalf("parseBBox = %+v, want %+v", *got, want)
+	}
+	if got, err := parseBBox(""); got != nil || err != nil {
+		t.Fatalf("empty bbox gave %+v, %v", got, err)
+	}
+	if _, err := parseBBox("-180,-90,180,90"); err != nil {
+		t.Fatalf("whole world: %v", err)
+	}
+}
+
+func TestParseBBoxRejects(t *testing.T) {
+	for _, param := range []string{
+		"2.2,48.8,2.5",
+		"2.2,48.8,2.5,49,1",
+		"a,48.8,2.5,49",
+		"2.2,-91,2.5,49",
+		"2.2,48.8,181,49",
+		"2.5,48.8,2.2,49", // min_lng past max_lng, e.g. across the antimeridian
+		"2.2,49,2.5,48.8", // min_lat past max_lat
+		"NaN,48.8,2.5,49",
+		"2.2,48.8,2.5,Inf",
+	} {
+		if box, err := parseBBox(param); err == nil {
+			t.Errorf("parseBBox(%q) accepted %+v", param, box)
+		}
+	}
+}
+
+func TestParseDates(t *testing.T) {
+	day := func(s string) *time.Time {
+		d, err := model.ParseDate(s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return &d
+	}
+	sameDay := func(a, b *time.Time) bool {
+		return (a == nil) == (b == nil) && (a == nil || a.Equal(*b))
+	}
+
+	cases := []struct {
+		query string
+		want  model.SearchParams
+	}{
+		{"", model.SearchParams{}},
+		{"date=2026-07-01&flex_days=3", model.SearchParams{Date: day("2026-07-01"), FlexDays: 3}},
+		{"from=2026-07-01&to=2026-07-01", model.SearchParams{From: day("2026-07-01"), To: day("2026-07-01")}},
+		{"from=2026-07-01&flex_days=30", model.SearchParams{From: day("2026-07-01"), FlexDays: model.MaxFlexDays}},
+		{"to=2026-08-31", model.SearchParams{To: day("2026-08-31")}},
+		{"min_duration=3&max_duration=7", model.SearchParams{MinDuration: 3, MaxDuration: 7}},
+		{"min_duration=5", model.SearchParams{MinDuration: 5}},
+		{"max_duration=0", model.SearchParams{}},
+	}
+	for _, tc := range cases {
+		query, _ := url.ParseQuery(tc.query)
+		got, err := parseDates(query)
+		if err != nil {
+			t.Errorf("parseDates(%q): %v", tc.query, err)
+			continue
+		}
+		if !sameDay(got.Date, tc.want.Date) || !sameDay(got.From, tc.want.From) || !sameDay(got.To, tc.want.To) ||
+			got.FlexDays != tc.want.FlexDays || got.MinDuration != tc.want.MinDuration || got.MaxDuration != tc.want.MaxDuration {
+			t.Errorf("parseDates(%q) = %+v, want %+v", tc.query, got, tc.want)
+		}
+	}
+}
+
+func TestParseDatesRejects(t *testing.T) {
+	for _, q := range []string{
+		"date=01/07/2026",
+		"date=2026-02-30",
+		"from=tomorrow",
+		"to=2026-13-01",
+		"date=2026-07-01&from=2026-07-01",
+		"date=2026-07-01&to=2026-07-10",
+		"from=2026-07-10&to=2026-07-01",
+		"flex_days=2",
+		"date=2026-07-01&flex_days=-1",
+		"date=2026-07-01&flex_days=31",
+		"date=2026-07-01&flex_days=two",
+		"min_duration=-1",
+		"max_duration=366",
+		"min_duration=8&max_duration=7",
+	} {
+		query, _ := url.ParseQuery(q)
+		if params, err := parseDates(query); err == nil {
+			t.Errorf("parseDates(%q) accepted %+v", q, params)
+		}
+	}
+}
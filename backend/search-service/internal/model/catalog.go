@@ -0,0 +1,120 @@
+package model
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+var (
+	ErrInvalidListing = errors.New("invalid listing")
+	// ErrVersionConflict means the listing changed since the caller read it
+	ErrVersionConflict = errors.New("listing was modified by someone else")
+)
+
+const (
+	maxTitleLength       = 200
+	maxDestinationLength = 100
+	maxDescriptionLength = 5000
+)
+
+// ListingInput is the body of POST /listings and PUT /listings/{id}; every
+// field is required except location, which a PUT keeps when omitted.
+type ListingInput struct {
+	Title       string    `json:"title"`
+	Destination string    `json:"destination"`
+	Description string    `json:"description"`
+	Price       float64   `json:"price"`
+	Date        string    `json:"date"`
+	EndDate     string    `json:"end_date"` // Defaults to date, a day trip
+	Location    *GeoPoint `json:"location"`
+	Capacity    *int      `json:"capacity"`
+}
+
+// ListingPatch is the body of PATCH /listings/{id}; omitted fields are kept.
+type ListingPatch struct {
+	Title       *string   `json:"title"`
+	Destination *string   `json:"destination"`
+	Description *string   `json:"description"`
+	Price       *float64  `json:"price"`
+	Date        *string   `json:"date"` // Without end_date, moves the trip keeping its length
+	EndDate     *string   `json:"end_date"`
+	Location    *GeoPoint `json:"location"`
+	Capacity    *int      `json:"capacity"`
+}
+
+// Patch returns the input as a patch setting every field.
+func (in *ListingInput) Patch() *ListingPatch {
+	endDate := &in.EndDate
+	if in.EndDate == "" {
+		endDate = &in.Date
+	}
+	return &ListingPatch{
+		Title:       &in.Title,
+		Destination: &in.Destination,
+		Description: &in.Description,
+		Price:       &in.Price,
+		Date:        &in.Date,
+		EndDate:     endDate,
+		Location:    in.Location,
+		Capacity:    in.Capacity,
+	}
+}
+
+func (in *ListingInput) Validate() error {
+	if in.Capacity == nil {
+		return fmt.Errorf("%w: capacity is required", ErrInvalidListing)
+	}
+	return in.Patch().Validate()
+}
+
+// Validate checks the fields that are set and trims surrounding spaces.
+func (p *ListingPatch) Validate() error {
+	if p.Title == nil && p.Destination == nil && p.Description == nil && p.Price == nil && p.Date == nil && p.EndDate == nil && p.Location == nil && p.Capacity == nil {
+		return fmt.Errorf("%w: no fields to update", ErrInvalidListing)
+	}
+	if p.Title != nil {
+		*p.Title = strings.TrimSpace(*p.Title)
+		if *p.Title == "" || len(*p.Title) > maxTitleLength {
+			return fmt.Errorf("%w: title must be 1 to %d characters", ErrInvalidListing, maxTitleLength)
+		}
+	}
+	if p.Destination != nil {
+		*p.Destination = strings.TrimSpace(*p.Destination)
+		if *p.Destination == "" || len(*p.Destination) > maxDestinationLength {
+			return fmt.Errorf("%w: destination must be 1 to %d characters", ErrInvalidListing, maxDestinationLength)
+		}
+	}
+	if p.Description != nil {
+		*p.Description = strings.TrimSpace(*p.Description)
+		if len(*p.Description) > maxDescriptionLength {
+			return fmt.Errorf("%w: description must be at most %d characters", ErrInvalidListing, maxDescriptionLength)
+		}
+	}
+	if p.Price != nil && *p.Price <= 0 {
+		return fmt.Errorf("%w: price must be positive", ErrInvalidListing)
+	}
+	if p.Date != nil {
+		endDate := ""
+		if p.EndDate != nil {
+			endDate = *p.EndDate
+		}
+		if _, _, err := TripDates(*p.Date, endDate); err != nil {
+			return err
+		}
+	} else if p.EndDate != nil {
+		// Checked against the stored date when the patch is applied
+		if _, err := ParseDate(*p.EndDate); err != nil {
+			return fmt.Errorf("%w: end_date must be YYYY-MM-DD", ErrInvalidListing)
+		}
+	}
+	if p.Location != nil {
+		if err := p.Location.Validate(); err != nil {
+			return fmt.Errorf("%w: %v", ErrInvalidListing, err)
+		}
+	}
+	if p.Capacity != nil && *p.Capacity < 0 {
+		return fmt.Errorf("%w: capacity must not be negative", ErrInvalidListing)
+	}
+	return nil
+}
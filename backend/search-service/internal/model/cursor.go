@@ -0,0 +1,71 @@
+package model
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"time"
+)
+
+// Cursor points at a listing in the results of one search: the value of
+// the sort key and the ID that breaks ties. Listings are fetched after it,
+// or before it when Backward.
+type Cursor struct {
+	Sort     SortOrder  `json:"s"`
+	Number   float64    `json:"n,omitempty"` // Price, score or distance
+	Time     *time.Time `json:"t,omitempty"` // Creation or start date
+	ID       string     `json:"id"`
+	Backward bool       `json:"b,omitempty"`
+	// Search fingerprints the filters, so a cursor is not replayed on
+	// another search
+	Search string `json:"f"`
+}
+
+// NewCursor points at listing in the results of params.
+func NewCursor(params *SearchParams, listing *Listing, backward bool) *Cursor {
+	c := &Cursor{
+		Sort:     params.Sort,
+		ID:       listing.ID.Hex(),
+		Backward: backward,
+		Search:   params.Fingerprint(),
+	}
+	switch params.Sort {
+	case SortRelevance:
+		c.Number = listing.Score
+	case SortPrice:
+		c.Number = listing.Price
+	case SortDistance:
+		if listing.DistanceKm != nil {
+			c.Number = *listing.DistanceKm
+		}
+	case SortDate:
+		t := listing.StartDate
+		c.Time = &t
+	default:
+		t := listing.CreatedAt
+		c.Time = &t
+	}
+	return c
+}
+
+// Fingerprint identifies the listings a search matches and their order,
+// leaving out paging and the caller.
+func (p *SearchParams) Fingerprint() string {
+	date := func(t *time.Time) string {
+		if t == nil {
+			return ""
+		}
+		return t.Format(DateLayout)
+	}
+	key := fmt.Sprintf("%q|%s|%q|%g|%g|%s|%s|%s|%d|%d|%d|%s",
+		p.Query, p.Language, p.Destination, p.MinPrice, p.MaxPrice,
+		date(p.Date), date(p.From), date(p.To), p.FlexDays, p.MinDuration, p.MaxDuration, p.Sort)
+	if p.Near != nil {
+		key += fmt.Sprintf("|near:%g,%g,%g", p.Near.Lat, p.Near.Lng, p.Near.RadiusKm)
+	}
+	if p.BBox != nil {
+		key += fmt.Sprintf("|bbox:%g,%g,%g,%g", p.BBox.MinLng, p.BBox.MinLat, p.BBox.MaxLng, p.BBox.MaxLat)
+	}
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:8])
+}
@@ -0,0 +1,64 @@
+package model
+
+import (
+	"fmt"
+	"time"
+)
+
+// DateLayout is the format of dates in requests and events
+const DateLayout = "2006-01-02"
+
+// MaxDurationDays caps how long a trip can be.
+const MaxDurationDays = 365
+
+// MaxFlexDays caps how far a flexible-date search may widen its dates.
+const MaxFlexDays = 30
+
+// ParseDate reads a YYYY-MM-DD date as midnight UTC.
+func ParseDate(s string) (time.Time, error) {
+	return time.Parse(DateLayout, s)
+}
+
+// DaysBetween counts the days from start to end.
+func DaysBetween(start, end time.Time) int {
+	return int(end.Sub(start).Hours() / 24)
+}
+
+// SetDates sets the trip dates of the listing. Date keeps the start date as
+// a string for clients that read it.
+func (l *Listing) SetDates(start, end time.Time) {
+	l.Date = start.Format(DateLayout)
+	l.StartDate = start
+	l.EndDate = end
+	l.DurationDays = DaysBetween(start, end)
+}
+
+// TripDates parses a start and optional end date, as sent in listing input
+// and catalog events. Without an end date the trip is a day trip.
+func TripDates(date, endDate string) (start, end time.Time, err error) {
+	start, err = ParseDate(date)
+	if err != nil {
+		return start, end, fmt.Errorf("%w: date must be YYYY-MM-DD", ErrInvalidListing)
+	}
+	if endDate == "" {
+		return start, start, nil
+	}
+	end, err = ParseDate(endDate)
+	if err != nil {
+		return start, end, fmt.Errorf("%w: end_date must be YYYY-MM-DD", ErrInvalidListing)
+	}
+	if err := validateDuration(start, end); err != nil {
+		return start, end, err
+	}
+	return start, end, nil
+}
+
+func validateDuration(start, end time.Time) error {
+	if end.Before(start) {
+		return fmt.Errorf("%w: end_date must not be before date", ErrInvalidListing)
+	}
+	if DaysBetween(start, end) > MaxDurationDays {
+		return fmt.Errorf("%w: trips can last at most %d days", ErrInvalidListing, MaxDurationDays)
+	}
+	return nil
+}
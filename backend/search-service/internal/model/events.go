@@ -0,0 +1,72 @@
+package model
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Catalog events are keyed by listing ID, so the events of one listing are
+// applied in the order they were published.
+
+// ListingUpsertedEvent creates a listing or replaces its details. Slots
+// already held by bookings are kept: available_slots is derived from
+// Capacity.
+type ListingUpsertedEvent struct {
+	ID          string    `json:"id"`
+	Title       string    `json:"title"`
+	Destination string    `json:"destination"`
+	Description string    `json:"description"`
+	Price       float64   `json:"price"`
+	Date        string    `json:"date"`
+	EndDate     string    `json:"end_date,omitempty"` // Defaults to date
+	Location    *GeoPoint `json:"location,omitempty"`
+	Capacity    int       `json:"capacity"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+// Listing converts the event into the listing it describes.
+func (e *ListingUpsertedEvent) Listing() (*Listing, error) {
+	oid, err := primitive.ObjectIDFromHex(e.ID)
+	if err != nil {
+		return nil, ErrListingNotFound
+	}
+	start, end, err := TripDates(e.Date, e.EndDate)
+	if err != nil {
+		return nil, err
+	}
+	updatedAt := e.UpdatedAt
+	if updatedAt.IsZero() {
+		updatedAt = time.Now()
+	}
+	listing := &Listing{
+		ID:          oid,
+		Title:       e.Title,
+		Destination: e.Destination,
+		Description: e.Description,
+		Price:       e.Price,
+		Location:    e.Location,
+		Capacity:    e.Capacity,
+		UpdatedAt:   updatedAt,
+	}
+	listing.SetDates(start, end)
+	return listing, nil
+}
+
+type ListingDeletedEvent struct {
+	ID string `json:"id"`
+}
+
+// InventoryChangedEvent sets the total number of slots a listing offers.
+type InventoryChangedEvent struct {
+	ListingID string `json:"listing_id"`
+	Capacity  int    `json:"capacity"`
+}
+
+// InventoryReleaseEvent is published by booking-service when a booking
+// that held a slot ends without being used (cancelled, expired, refunded).
+type InventoryReleaseEvent struct {
+	HoldID    string `json:"hold_id"`
+	ListingID string `json:"listing_id"`
+	Reason    string `json:"reason"`
+}
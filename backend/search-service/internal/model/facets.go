@@ -0,0 +1,44 @@
+package model
+
+// SearchResult is one page of search results.
+type SearchResult struct {
+	Listings []*Listing `json:"data"`
+	Total    int64      `json:"total"`
+	// Facets summarise every listing matching the search, not just the
+	// page; only set when SearchParams.Facets is
+	Facets *Facets `json:"facets,omitempty"`
+	// Next and Prev point at the pages around this one, when there are any
+	Next *Cursor `json:"next,omitempty"`
+	Prev *Cursor `json:"prev,omitempty"`
+}
+
+type Facets struct {
+	// Destinations are the most common destinations, most listings first
+	Destinations []FacetCount `json:"destinations"`
+	Prices       []RangeCount `json:"prices"`
+	// Months are trip months (YYYY-MM) in calendar order
+	Months []FacetCount `json:"months"`
+	// Availability buckets listings by slots left
+	Availability []RangeCount `json:"availability"`
+}
+
+type FacetCount struct {
+	Value string `json:"value" bson:"_id"`
+	Count int64  `json:"count" bson:"count"`
+}
+
+// RangeCount counts values in [Min, Max); the last range has no Max.
+type RangeCount struct {
+	Min   float64  `json:"min"`
+	Max   *float64 `json:"max,omitempty"`
+	Count int64    `json:"count"`
+}
+
+const MaxDestinationFacets = 20
+
+var (
+	// PriceBuckets and AvailabilityBuckets are the lower bounds of the
+	// ranges reported; every range is listed, even when empty
+	PriceBuckets        = []float64{0, 100, 250, 500, 1000}
+	AvailabilityBuckets = []float64{1, 3, 10}
+)
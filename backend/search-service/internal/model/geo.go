@@ -0,0 +1,57 @@
+package model
+
+import (
+	"errors"
+	"fmt"
+)
+
+// EarthRadiusKm is the mean Earth radius used for distances
+const EarthRadiusKm = 6371.0
+
+// MaxRadiusKm is half the Earth's circumference: every point is closer.
+const MaxRadiusKm = 20015.0
+
+// GeoPoint is a GeoJSON point. Coordinates are [longitude, latitude].
+type GeoPoint struct {
+	Type        string    `json:"type" bson:"type"`
+	Coordinates []float64 `json:"coordinates" bson:"coordinates"`
+}
+
+func NewGeoPoint(lat, lng float64) *GeoPoint {
+	return &GeoPoint{Type: "Point", Coordinates: []float64{lng, lat}}
+}
+
+func (p *GeoPoint) Validate() error {
+	if p.Type != "Point" || len(p.Coordinates) != 2 {
+		return errors.New(`location must be a GeoJSON point: {"type": "Point", "coordinates": [lng, lat]}`)
+	}
+	return ValidateCoordinates(p.Coordinates[1], p.Coordinates[0])
+}
+
+func ValidateCoordinates(lat, lng float64) error {
+	// Negated so NaN is rejected too
+	if !(lat >= -90 && lat <= 90) {
+		return fmt.Errorf("latitude %g out of range [-90, 90]", lat)
+	}
+	if !(lng >= -180 && lng <= 180) {
+		return fmt.Errorf("longitude %g out of range [-180, 180]", lng)
+	}
+	return nil
+}
+
+// GeoNear restricts a search to listings around a point. RadiusKm 0 keeps
+// every listing with a location; results get their distance either way.
+type GeoNear struct {
+	Lat      float64
+	Lng      float64
+	RadiusKm float64
+}
+
+// BoundingBox restricts a search to listings whose coordinates fall in the
+// latitude and longitude ranges.
+type BoundingBox struct {
+	MinLng float64
+	MinLat float64
+	MaxLng float64
+	MaxLat float64
+}
@@ -0,0 +1,23 @@
+package model
+
+import "errors"
+
+var (
+	ErrListingNotFound = errors.New("listing not found")
+	ErrSoldOut         = errors.New("listing is sold out")
+)
+
+// HoldRequest asks for one slot of a listing on behalf of a booking. HoldID
+// is the booking ID, which makes retried requests idempotent.
+type HoldRequest struct {
+	HoldID string `json:"hold_id"`
+}
+
+type HoldResponse struct {
+	HoldID         string `json:"hold_id"`
+	ListingID      string `json:"listing_id"`
+	AvailableSlots int    `json:"available_slots"`
+	// StartDate (YYYY-MM-DD) lets booking-service time its cancellation
+	// policy from the trip start
+	StartDate string `json:"start_date"`
+}
@@ -10,18 +10,112 @@ type Listing struct {
 	ID             primitive.ObjectID `json:"id" bson:"_id,omitempty"`
 	Title          string             `json:"title" bson:"title"`
 	Destination    string             `json:"destination" bson:"destination"`
+	Description    string             `json:"description" bson:"description"`
 	Price          float64            `json:"price" bson:"price"`
-	Date           string             `json:"date" bson:"date"` // Using simplified YYYY-MM-DD
+	Date           string             `json:"date" bson:"date"` // Start date as YYYY-MM-DD
+	StartDate      time.Time          `json:"start_date" bson:"start_date"`
+	EndDate        time.Time          `json:"end_date" bson:"end_date"`
+	DurationDays   int                `json:"duration_days" bson:"duration_days"` // EndDate minus StartDate; 0 for a day trip
+	Location       *GeoPoint          `json:"location,omitempty" bson:"location,omitempty"`
 	AvailableSlots int                `json:"available_slots" bson:"available_slots"`
+	Capacity       int                `json:"capacity" bson:"capacity"` // Total slots; available is capacity minus holds
 	CreatedAt      time.Time          `json:"created_at" bson:"created_at"`
 	UpdatedAt      time.Time          `json:"updated_at" bson:"updated_at"`
+	Version        int64              `json:"version" bson:"version"` // Bumped by every change except holds
+	DeletedAt      *time.Time         `json:"deleted_at,omitempty" bson:"deleted_at,omitempty"`
+	Score          float64            `json:"score,omitempty" bson:"score,omitempty"`             // Text relevance, on full-text search results only
+	DistanceKm     *float64           `json:"distance_km,omitempty" bson:"distance_km,omitempty"` // From the search point, on geo search results only
+
+	// Holds lists the bookings currently holding a slot. Keeping them on the
+	// listing lets a reservation check, decrement and record in one update.
+	Holds []string `json:"-" bson:"holds,omitempty"`
+
+	// Folded copies of the title and destination for suggestions, see
+	// SuggestKey
+	TitleKey       string `json:"-" bson:"title_key"`
+	DestinationKey string `json:"-" bson:"destination_key"`
 }
 
 type SearchParams struct {
+	// Query is matched against title, destination and description with the
+	// text index; Language picks the stemming rules for it (default: the
+	// index's language)
+	Query       string
+	Language    string
 	Destination string
 	MinPrice    float64
 	MaxPrice    float64
-	Date        string
-	Page        int
-	Limit       int
+	// Date is the exact start date. From and To bound the whole trip: it
+	// starts on or after From and ends on or before To. FlexDays widens
+	// each of them by that many days.
+	Date     *time.Time
+	From     *time.Time
+	To       *time.Time
+	FlexDays int
+	// MinDuration and MaxDuration bound the trip length in days; 0 is no
+	// bound
+	MinDuration int
+	MaxDuration int
+	// Near and BBox restrict results to listings with a location
+	Near  *GeoNear
+	BBox  *BoundingBox
+	Sort  SortOrder
+	Page  int
+	Limit int
+	// Cursor continues from a listing of an earlier page instead of Page
+	Cursor *Cursor
+	// Facets asks for counts over all matching listings along with the page
+	Facets bool
+
+	// UserID is the caller, if known. It is not part of the query.
+	UserID string
+	// Untracked searches are not published as search.performed, so
+	// service-to-service lookups are not mistaken for traveller interest.
+	Untracked bool
+}
+
+// SearchPerformedEvent is published for every first-page search so other
+// services (recommendation-service) can learn what travellers look for.
+type SearchPerformedEvent struct {
+	UserID      string    `json:"user_id,omitempty"`
+	Query       string    `json:"query,omitempty"`
+	Destination string    `json:"destination,omitempty"`
+	MinPrice    float64   `json:"min_price,omitempty"`
+	MaxPrice    float64   `json:"max_price,omitempty"`
+	Date        string    `json:"date,omitempty"`
+	From        string    `json:"from,omitempty"`
+	To          string    `json:"to,omitempty"`
+	ResultCount int64     `json:"result_count"`
+	SearchedAt  time.Time `json:"searched_at"`
+}
+
+type SortOrder string
+
+const (
+	// SortRelevance ranks by text score; only valid with a query, where it
+	// is the default
+	SortRelevance SortOrder = "relevance"
+	SortPrice     SortOrder = "price"  // Cheapest first
+	SortDate      SortOrder = "date"   // Earliest trip first
+	SortNewest    SortOrder = "newest" // Most recently listed first; the default without a query
+	// SortDistance ranks by distance from the search point; only valid with
+	// one, where it is the default without a query
+	SortDistance SortOrder = "distance"
+)
+
+func (s SortOrder) Valid() bool {
+	switch s {
+	case SortRelevance, SortPrice, SortDate, SortNewest, SortDistance:
+		return true
+	}
+	return false
+}
+
+// TextLanguages are the stemming languages accepted for full-text search
+// ("none" matches words exactly).
+var TextLanguages = map[string]bool{
+	"none": true, "danish": true, "dutch": true, "english": true, "finnish": true,
+	"french": true, "german": true, "hungarian": true, "italian": true, "norwegian": true,
+	"portuguese": true, "romanian": true, "russian": true, "spanish": true, "swedish": true,
+	"turkish": true,
 }
@@ -0,0 +1,57 @@
+package model
+
+import (
+	"strings"
+	"unicode"
+
+	"golang.org/x/text/runes"
+	"golang.org/x/text/transform"
+	"golang.org/x/text/unicode/norm"
+)
+
+const (
+	// MaxSuggestPrefix caps the length of a type-ahead prefix
+	MaxSuggestPrefix = 100
+	// MaxSuggestions caps each kind of suggestion
+	MaxSuggestions = 10
+)
+
+// Suggestions complete what a traveller is typing into the search box, most
+// popular first. Popularity counts the slots currently held by bookings.
+type Suggestions struct {
+	Destinations []DestinationSuggestion `json:"destinations"`
+	Listings     []ListingSuggestion     `json:"listings"`
+}
+
+type DestinationSuggestion struct {
+	Destination string `json:"destination" bson:"destination"`
+	Listings    int64  `json:"listings" bson:"listings"` // Bookable listings there
+	Popularity  int64  `json:"popularity" bson:"popularity"`
+}
+
+type ListingSuggestion struct {
+	ID          string `json:"id" bson:"_id"`
+	Title       string `json:"title" bson:"title"`
+	Destination string `json:"destination" bson:"destination"`
+	Popularity  int64  `json:"popularity" bson:"popularity"`
+}
+
+// SuggestKey folds s for prefix matching: accents are stripped, letters
+// lower-cased and runs of spaces collapsed, so "São Paulo" and "sao  paulo"
+// share a key.
+func SuggestKey(s string) string {
+	// Transformers keep state, so each call gets its own
+	fold := transform.Chain(norm.NFD, runes.Remove(runes.In(unicode.Mn)), norm.NFC)
+	folded, _, err := transform.String(fold, s)
+	if err != nil {
+		folded = s
+	}
+	return strings.ToLower(strings.Join(strings.Fields(folded), " "))
+}
+
+// SetSuggestKeys fills the folded title and destination suggestions are
+// matched on.
+func (l *Listing) SetSuggestKeys() {
+	l.TitleKey = SuggestKey(l.Title)
+	l.DestinationKey = SuggestKey(l.Destination)
+}
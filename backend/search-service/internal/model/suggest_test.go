@@ -0,0 +1,46 @@
+package model
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestSuggestKey(t *testing.T) {
+	cases := map[string]string{
+		"São Paulo":          "sao paulo",
+		"  sao   PAULO ":     "sao paulo",
+		"Zürich":             "zurich",
+		"Zu\u0308rich":       "zurich", // Already decomposed
+		"Côte d'Azur":        "cote d'azur",
+		"Kraków\tOld Town":   "krakow old town",
+		"Ærøskøbing":         "ærøskøbing", // Letters, not accented ones
+		"東京":                 "東京",
+		"":                   "",
+		"   ":                "",
+		"İstanbul":           "istanbul",
+		"Reykjavík – Harbor": "reykjavik – harbor",
+	}
+	for in, want := range cases {
+		if got := SuggestKey(in); got != want {
+			t.Errorf("SuggestKey(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestSuggestKeyPrefixesMatch(t *testing.T) {
+	// A folded prefix of the name is a prefix of the folded name
+	name := "São Tomé and Príncipe"
+	for _, typed := range []string{"sao", "SÃO T", "são  tome", "Sao Tome and Princ"} {
+		if !strings.HasPrefix(SuggestKey(name), SuggestKey(typed)) {
+			t.Errorf("%q does not complete to %q", typed, name)
+		}
+	}
+}
+
+func TestSetSuggestKeys(t *testing.T) {
+	l := &Listing{Title: "Côte  Walk", Destination: "Nice, Côte d'Azur"}
+	l.SetSuggestKeys()
+	if l.TitleKey != "cote walk" || l.DestinationKey != "nice, cote d'azur" {
+		t.Fatalf("keys %q and %q", l.TitleKey, l.DestinationKey)
+	}
+}
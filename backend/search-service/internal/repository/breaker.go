@@ -0,0 +1,100 @@
+package repository
+
+import (
+	"sync"
+	"time"
+)
+
+// breaker stops calls to an unhealthy dependency. After threshold
+// consecutive failures it opens for cooldown; then a single probe is let
+// through, which closes it again or reopens it.
+type breaker struct {
+	threshold int
+	cooldown  time.Duration
+
+	mu        sync.Mutex
+	failures  int
+	openUntil time.Time // Zero while closed
+	probing   bool
+}
+
+func newBreaker(threshold int, cooldown time.Duration) *breaker {
+	if threshold < 1 {
+		threshold = 1
+	}
+	return &breaker{threshold: threshold, cooldown: cooldown}
+}
+
+type breakerDecision int
+
+const (
+	breakerAllow breakerDecision = iota
+	breakerProbe                 // The caller must probe, then call close or failure
+	breakerDeny
+)
+
+func (b *breaker) allow() breakerDecision {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	switch {
+	case b.openUntil.IsZero():
+		return breakerAllow
+	case b.probing || time.Now().Before(b.openUntil):
+		return breakerDeny
+	default:
+		b.probing = true
+		return breakerProbe
+	}
+}
+
+// success resets the failure count of a closed breaker.
+func (b *breaker) success() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.openUntil.IsZero() {
+		b.failures = 0
+	}
+}
+
+// failure counts a failed call or probe and reports whether it opened the
+// breaker.
+func (b *breaker) failure() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.probing {
+		b.probing = false
+		b.openUntil = time.Now().Add(b.cooldown)
+		return false
+	}
+	if !b.openUntil.IsZero() {
+		return false
+	}
+	b.failures++
+	if b.failures < b.threshold {
+		return false
+	}
+	b.openUntil = time.Now().Add(b.cooldown)
+	return true
+}
+
+// close ends a successful probe.
+func (b *breaker) close() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.failures = 0
+	b.openUntil = time.Time{}
+	b.probing = false
+}
+
+func (b *breaker) state() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	switch {
+	case b.openUntil.IsZero():
+		return "closed"
+	case b.probing:
+		return "half-open"
+	default:
+		return "open"
+	}
+}
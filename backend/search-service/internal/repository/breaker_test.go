@@ -0,0 +1,80 @@
+package repository
+
+import (
+	"testing"
+	"time"
+)
+
+func TestBreakerOpensAfterThreshold(t *testing.T) {
+	b := newBreaker(3, time.Hour)
+
+	for i := 0; i < 2; i++ {
+		if b.failure() {
+			t.Fatalf("opened after %d failures, threshold is 3", i+1)
+		}
+	}
+	b.success()
+	for i := 0; i < 2; i++ {
+		b.failure()
+	}
+	if b.state() != "closed" {
+		t.Fatalf("success did not reset the failure count: %s", b.state())
+	}
+	if !b.failure() {
+		t.Fatal("third consecutive failure did not open the breaker")
+	}
+	if b.state() != "open" || b.allow() != breakerDeny {
+		t.Fatalf("open breaker is %s and allows calls", b.state())
+	}
+	if b.failure() {
+		t.Fatal("failure of an open breaker reported opening it again")
+	}
+}
+
+func TestBreakerProbesAfterCooldown(t *testing.T) {
+	b := newBreaker(1, 10*time.Millisecond)
+	b.failure()
+	if b.allow() != breakerDeny {
+		t.Fatal("breaker allowed a call during its cooldown")
+	}
+
+	time.Sleep(20 * time.Millisecond)
+	if got := b.allow(); got != breakerProbe {
+		t.Fatalf("first call after the cooldown got %v, want a probe", got)
+	}
+	if b.state() != "half-open" || b.allow() != breakerDeny {
+		t.Fatal("a second probe was allowed while the first was running")
+	}
+
+	// A failed probe reopens the breaker for another cooldown
+	b.failure()
+	if b.state() != "open" || b.allow() != breakerDeny {
+		t.Fatalf("failed probe left the breaker %s", b.state())
+	}
+
+	time.Sleep(20 * time.Millisecond)
+	if b.allow() != breakerProbe {
+		t.Fatal("no probe after the second cooldown")
+	}
+	b.close()
+	if b.state() != "closed" || b.allow() != breakerAllow {
+		t.Fatalf("successful probe left the breaker %s", b.state())
+	}
+}
+
+func TestBreakerThresholdAtLeastOne(t *testing.T) {
+	b := newBreaker(0, time.Hour)
+	if !b.failure() {
+		t.Fatal("a threshold below one should open on the first failure")
+	}
+}
+
+func TestJitter(t *testing.T) {
+	const d = time.Minute
+	for i := 0; i < 1000; i++ {
+		got := jitter(d)
+		if got < d-d/10 || got > d+d/10 {
+			t.Fatalf("jitter(%s) = %s, outside ±10%%", d, got)
+		}
+	}
+}
@@ -3,69 +3,627 @@ package repository
 import (
 	"context"
 	"encoding/json"
+	"errors"
+	"expvar"
 	"fmt"
 	"log"
+	"math/rand"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/gavinadlan/tripnest/backend/search-service/internal/model"
 	"github.com/go-redis/redis/v8"
+	"golang.org/x/sync/singleflight"
 )
 
+// cacheStats counts cache outcomes, published on /debug/vars as
+// "search_cache": hit, stale (served while refreshing), miss, coalesced
+// (waited for another request's load), refresh, refresh_error, discarded
+// (a load overtaken by an invalidation), redis_error, bypass (Redis skipped
+// while the breaker is open or a flush is pending) and the suggest_
+// counterparts.
+var cacheStats = expvar.NewMap("search_cache")
+
+const (
+	// jitterFraction spreads expirations by up to ±10% so entries cached
+	// together do not all expire together
+	jitterFraction = 0.1
+	// loadTimeout bounds a database load shared by coalesced requests,
+	// which outlives the request that started it
+	loadTimeout = 10 * time.Second
+	// redisTimeout bounds the probe and flush after an outage
+	redisTimeout = 5 * time.Second
+	// searchGenKey counts invalidations. A load only keeps its cached
+	// result if the count did not move while it ran, since its database
+	// read may predate the change.
+	searchGenKey = "search-gen"
+)
+
+// CacheOptions tune CachedListingRepository.
+type CacheOptions struct {
+	// TTL is how long a cached search is fresh. For StaleTTL after that it
+	// is still served while a background load replaces it.
+	TTL      time.Duration
+	StaleTTL time.Duration
+	// After BreakerThreshold consecutive Redis errors the cache is
+	// bypassed for BreakerCooldown, then one request probes Redis again.
+	BreakerThreshold int
+	BreakerCooldown  time.Duration
+}
+
+// CachedListingRepository caches searches and suggestions in Redis.
+// Concurrent misses for one key share a single database load, and Redis
+// is bypassed while it is failing.
 type CachedListingRepository struct {
-	next ListingRepository
-	rdb  *redis.Client
-	ttl  time.Duration
+	next    ListingRepository
+	rdb     *redis.Client
+	opts    CacheOptions
+	loads   singleflight.Group
+	breaker *breaker
+	// missedInvalidations counts invalidations that could not be applied
+	// since the last flush; cached searches are flushed before Redis is
+	// used again
+	missedInvalidations atomic.Int64
+	flushing            sync.Mutex
 }
 
-func NewCachedListingRepository(next ListingRepository, redisAddr string, ttl time.Duration) *CachedListingRepository {
+func NewCachedListingRepository(next ListingRepository, redisAddr string, opts CacheOptions) *CachedListingRepository {
 	rdb := redis.NewClient(&redis.Options{
 		Addr: redisAddr,
 	})
-	return &CachedListingRepository{
-		next: next,
-		rdb:  rdb,
-		ttl:  ttl,
+	r := &CachedListingRepository{
+		next:    next,
+		rdb:     rdb,
+		opts:    opts,
+		breaker: newBreaker(opts.BreakerThreshold, opts.BreakerCooldown),
 	}
+	cacheStats.Set("breaker", expvar.Func(func() interface{} { return r.breaker.state() }))
+	return r
 }
 
-func (r *CachedListingRepository) Search(ctx context.Context, params *model.SearchParams) ([]*model.Listing, int64, error) {
-	cacheKey := fmt.Sprintf("search:%s:%f:%f:%s:%d:%d",
-		params.Destination, params.MinPrice, params.MaxPrice, params.Date, params.Page, params.Limit)
+// cachedSearch is the cache entry of a search.
+type cachedSearch struct {
+	FreshUntil time.Time           `json:"fresh_until"`
+	Result     *model.SearchResult `json:"result"`
+}
 
-	val, err := r.rdb.Get(ctx, cacheKey).Result()
-	if err == nil {
-		log.Println("CACHE HIT")
-		var result struct {
-			Listings []*model.Listing `json:"data"`
-			Total    int64            `json:"total"`
+func (r *CachedListingRepository) Search(ctx context.Context, params *model.SearchParams) (*model.SearchResult, error) {
+	cacheKey := fmt.Sprintf("search:%s:%f:%f:%s:%d:%d:%q:%s:%s:%t:%s:%s",
+		params.Destination, params.MinPrice, params.MaxPrice, dateKey(params), params.Page, params.Limit,
+		params.Query, params.Language, params.Sort, params.Facets, geoKey(params), cursorKey(params))
+
+	if r.cacheAvailable(ctx) {
+		var entry cachedSearch
+		if r.get(ctx, cacheKey, &entry) && entry.Result != nil {
+			if time.Now().Before(entry.FreshUntil) {
+				cacheStats.Add("hit", 1)
+				return entry.Result, nil
+			}
+			cacheStats.Add("stale", 1)
+			r.refresh(cacheKey, params)
+			return entry.Result, nil
+		}
+		cacheStats.Add("miss", 1)
+	}
+
+	leader := false
+	v, err, _ := r.loads.Do(cacheKey, func() (interface{}, error) {
+		leader = true
+		ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), loadTimeout)
+		defer cancel()
+		return r.loadSearch(ctx, cacheKey, params)
+	})
+	if !leader {
+		cacheStats.Add("coalesced", 1)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return v.(*model.SearchResult), nil
+}
+
+// refresh reloads a stale search in the background, unless a load of it is
+// already running.
+func (r *CachedListingRepository) refresh(cacheKey string, params *model.SearchParams) {
+	cacheStats.Add("refresh", 1)
+	// The channel is buffered, so nothing has to receive from it
+	r.loads.DoChan(cacheKey, func() (interface{}, error) {
+		ctx, cancel := context.WithTimeout(context.Background(), loadTimeout)
+		defer cancel()
+		result, err := r.loadSearch(ctx, cacheKey, params)
+		if err != nil {
+			cacheStats.Add("refresh_error", 1)
+			log.Printf("Failed to refresh cached search: %v", err)
 		}
-		if err := json.Unmarshal([]byte(val), &result); err == nil {
-			return result.Listings, result.Total, nil
+		return result, err
+	})
+}
+
+// loadSearch runs the search on the database and caches the result, unless
+// an invalidation ran meanwhile.
+func (r *CachedListingRepository) loadSearch(ctx context.Context, cacheKey string, params *model.SearchParams) (*model.SearchResult, error) {
+	cacheable := r.cacheAvailable(ctx)
+	var gen int64
+	if cacheable {
+		var err error
+		gen, err = r.generation(ctx)
+		cacheable = err == nil
+	}
+
+	result, err := r.next.Search(ctx, params)
+	if err != nil {
+		return nil, err
+	}
+	if !cacheable {
+		return result, nil
+	}
+
+	fresh := jitter(r.opts.TTL)
+	data, err := json.Marshal(cachedSearch{FreshUntil: time.Now().Add(fresh), Result: result})
+	if err != nil {
+		return result, nil
+	}
+	if err := r.rdb.Set(ctx, cacheKey, data, fresh+r.opts.StaleTTL).Err(); err != nil {
+		r.observe(err)
+		return result, nil
+	}
+	r.tag(ctx, cacheKey, searchTags(params, result))
+
+	// An invalidation that bumped the count after this check finds the
+	// entry in its tag sets; one that bumped it earlier is caught here.
+	if now, err := r.generation(ctx); err != nil || now != gen {
+		cacheStats.Add("discarded", 1)
+		r.observe(r.rdb.Del(ctx, cacheKey).Err())
+	}
+	return result, nil
+}
+
+// generation returns the invalidation count.
+func (r *CachedListingRepository) generation(ctx context.Context) (int64, error) {
+	gen, err := r.rdb.Get(ctx, searchGenKey).Int64()
+	r.observe(err)
+	if err == redis.Nil {
+		return 0, nil
+	}
+	return gen, err
+}
+
+// get decodes the entry at key into v, reporting whether there was one.
+func (r *CachedListingRepository) get(ctx context.Context, key string, v interface{}) bool {
+	data, err := r.rdb.Get(ctx, key).Bytes()
+	r.observe(err)
+	if err != nil {
+		return false
+	}
+	return json.Unmarshal(data, v) == nil
+}
+
+// cacheAvailable reports whether Redis should be used. While the breaker is
+// open it is skipped; once the cooldown is over this request probes it.
+// After a missed invalidation the cached searches are flushed before any is
+// served again.
+func (r *CachedListingRepository) cacheAvailable(ctx context.Context) bool {
+	switch r.breaker.allow() {
+	case breakerAllow:
+		if r.missedInvalidations.Load() == 0 {
+			return true
 		}
-	} else if err != redis.Nil {
+		if err := r.flushMissed(ctx); err != nil {
+			r.observe(err)
+			cacheStats.Add("bypass", 1)
+			return false
+		}
+		return true
+	case breakerDeny:
+		cacheStats.Add("bypass", 1)
+		return false
+	}
+
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), redisTimeout)
+	defer cancel()
+	err := r.rdb.Ping(ctx).Err()
+	if err == nil {
+		err = r.flushMissed(ctx)
+	}
+	if err != nil {
+		cacheStats.Add("redis_error", 1)
+		r.breaker.failure()
+		cacheStats.Add("bypass", 1)
+		return false
+	}
+	r.breaker.close()
+	log.Println("Redis is reachable again, search cache re-enabled")
+	return true
+}
+
+// errFlushPending is returned to callers that find another one flushing.
+var errFlushPending = errors.New("flush of cached searches in progress")
+
+// flushMissed flushes the cached searches if an invalidation was missed.
+// Only one caller flushes; the others get errFlushPending and bypass the
+// cache meanwhile. Invalidations missed during the flush trigger another.
+func (r *CachedListingRepository) flushMissed(ctx context.Context) error {
+	missed := r.missedInvalidations.Load()
+	if missed == 0 {
+		return nil
+	}
+	if !r.flushing.TryLock() {
+		return errFlushPending
+	}
+	defer r.flushing.Unlock()
+
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), redisTimeout)
+	defer cancel()
+	if err := r.flushSearches(ctx); err != nil {
+		return err
+	}
+	r.missedInvalidations.CompareAndSwap(missed, 0)
+	return nil
+}
+
+// observe records the outcome of a Redis call with the breaker; redis.Nil
+// is a miss, not a failure.
+func (r *CachedListingRepository) observe(err error) {
+	if err == errFlushPending {
+		return
+	}
+	if err == nil || err == redis.Nil {
+		r.breaker.success()
+		return
+	}
+	cacheStats.Add("redis_error", 1)
+	if r.breaker.failure() {
+		log.Printf("Redis error: %v; bypassing the search cache for %s", err, r.opts.BreakerCooldown)
+	} else {
 		log.Printf("Redis error: %v", err)
 	}
+}
+
+// flushSearches deletes every cached search and tag set, for when some
+// invalidations were lost.
+func (r *CachedListingRepository) flushSearches(ctx context.Context) error {
+	deleted := 0
+	for _, pattern := range []string{"search:*", "search-tag:*"} {
+		iter := r.rdb.Scan(ctx, 0, pattern, 500).Iterator()
+		var batch []string
+		for iter.Next(ctx) {
+			batch = append(batch, iter.Val())
+			if len(batch) == 500 {
+				if err := r.rdb.Del(ctx, batch...).Err(); err != nil {
+					return err
+				}
+				deleted += len(batch)
+				batch = batch[:0]
+			}
+		}
+		if err := iter.Err(); err != nil {
+			return err
+		}
+		if len(batch) > 0 {
+			if err := r.rdb.Del(ctx, batch...).Err(); err != nil {
+				return err
+			}
+			deleted += len(batch)
+		}
+	}
+	log.Printf("Flushed %d cached search key(s) after missed invalidations", deleted)
+	return nil
+}
+
+// jitter spreads d by up to jitterFraction either way.
+func jitter(d time.Duration) time.Duration {
+	return d + time.Duration((rand.Float64()*2-1)*jitterFraction*float64(d))
+}
+
+// dateKey identifies the date and duration filters of a search in its
+// cache key.
+func dateKey(params *model.SearchParams) string {
+	format := func(t *time.Time) string {
+		if t == nil {
+			return "-"
+		}
+		return t.Format(model.DateLayout)
+	}
+	return fmt.Sprintf("%s,%s,%s,%d,%d,%d", format(params.Date), format(params.From), format(params.To),
+		params.FlexDays, params.MinDuration, params.MaxDuration)
+}
+
+// cursorKey identifies the cursor a search continues from in its cache key.
+func cursorKey(params *model.SearchParams) string {
+	c := params.Cursor
+	if c == nil {
+		return "-"
+	}
+	value := strconv.FormatFloat(c.Number, 'g', -1, 64)
+	if c.Time != nil {
+		value = c.Time.UTC().Format(time.RFC3339Nano)
+	}
+	return fmt.Sprintf("%s,%s,%t", value, c.ID, c.Backward)
+}
+
+// geoKey identifies the geo filters of a search in its cache key.
+func geoKey(params *model.SearchParams) string {
+	near, box := "-", "-"
+	if n := params.Near; n != nil {
+		near = fmt.Sprintf("%f,%f,%f", n.Lat, n.Lng, n.RadiusKm)
+	}
+	if b := params.BBox; b != nil {
+		box = fmt.Sprintf("%f,%f,%f,%f", b.MinLng, b.MinLat, b.MaxLng, b.MaxLat)
+	}
+	return near + ":" + box
+}
 
-	log.Println("CACHE MISS")
-	listings, total, err := r.next.Search(ctx, params)
+// searchTags names the tag sets a cached search is filed under: the
+// destination it filtered on ("*" when it did not), the months its trips
+// can start in and the destinations of the listings it returned or counted
+// in its facets. The latter matter because destination filters match
+// substrings ("par" finds Paris).
+func searchTags(params *model.SearchParams, result *model.SearchResult) []string {
+	tags := []string{tagKey("dest", params.Destination)}
+	for _, month := range startMonths(params) {
+		tags = append(tags, tagKey("month", month))
+	}
+	for _, l := range result.Listings {
+		tags = append(tags, tagKey("dest", l.Destination))
+	}
+	if result.Facets != nil {
+		for _, d := range result.Facets.Destinations {
+			tags = append(tags, tagKey("dest", d.Value))
+		}
+	}
+	return tags
+}
+
+// listingTags names the tag sets holding searches that a change to listing
+// can affect: those on its destination or start month, and those on any
+// destination. Searches on another destination with no date filter, or an
+// open-ended or very wide one, are left alone; searches with a substring
+// filter that did not return the listing yet only see it once they expire.
+func listingTags(listing *model.Listing) []string {
+	tags := []string{
+		tagKey("dest", listing.Destination),
+		tagKey("dest", ""),
+	}
+	if !listing.StartDate.IsZero() {
+		tags = append(tags, tagKey("month", listing.StartDate.Format("2006-01")))
+	}
+	return tags
+}
+
+// maxTaggedMonths bounds the month tags of one search.
+const maxTaggedMonths = 12
+
+// startMonths lists the months (YYYY-MM) in which the trips a search can
+// match start. It is empty without date filters, or when the start dates
+// are not bounded on both sides or span more than maxTaggedMonths.
+func startMonths(params *model.SearchParams) []string {
+	flex := time.Duration(params.FlexDays) * 24 * time.Hour
+	var first, last time.Time
+	switch {
+	case params.Date != nil:
+		first, last = params.Date.Add(-flex), params.Date.Add(flex)
+	case params.From != nil && params.To != nil:
+		// A trip ends on or before To, so it starts by then too
+		first, last = params.From.Add(-flex), params.To.Add(flex)
+	default:
+		return nil
+	}
+
+	var months []string
+	for m := time.Date(first.Year(), first.Month(), 1, 0, 0, 0, 0, time.UTC); !m.After(last); m = m.AddDate(0, 1, 0) {
+		if len(months) == maxTaggedMonths {
+			return nil
+		}
+		months = append(months, m.Format("2006-01"))
+	}
+	return months
+}
+
+func tagKey(kind, value string) string {
+	value = strings.ToLower(strings.TrimSpace(value))
+	if value == "" {
+		value = "*"
+	}
+	return "search-tag:" + kind + ":" + value
+}
+
+// tag adds cacheKey to each tag set. Sets live as long as the longest
+// lived entry could, so they do not outgrow the cache.
+func (r *CachedListingRepository) tag(ctx context.Context, cacheKey string, tags []string) {
+	ttl := time.Duration(float64(r.opts.TTL)*(1+jitterFraction)) + r.opts.StaleTTL
+	pipe := r.rdb.Pipeline()
+	for _, tag := range tags {
+		pipe.SAdd(ctx, tag, cacheKey)
+		pipe.Expire(ctx, tag, ttl)
+	}
+	_, err := pipe.Exec(ctx)
+	r.observe(err)
+	if err != nil {
+		// An entry missing from a tag set would escape invalidation
+		r.rdb.Del(ctx, cacheKey)
+	}
+}
+
+// invalidate drops every cached search that may show any of listings (nil
+// entries are skipped), so changes are visible on the next search instead
+// of after the TTL. If Redis is unavailable, every cached search is
+// flushed before it is used again.
+func (r *CachedListingRepository) invalidate(ctx context.Context, listings ...*model.Listing) {
+	seen := make(map[string]bool)
+	var tags []string
+	for _, l := range listings {
+		if l == nil {
+			continue
+		}
+		for _, tag := range listingTags(l) {
+			if !seen[tag] {
+				seen[tag] = true
+				tags = append(tags, tag)
+			}
+		}
+	}
+	if len(tags) == 0 {
+		return
+	}
+	if !r.cacheAvailable(ctx) {
+		r.missedInvalidations.Add(1)
+		return
+	}
+
+	// The count moves first, so loads already running do not cache what
+	// they read before the change
+	pipe := r.rdb.Pipeline()
+	pipe.Incr(ctx, searchGenKey)
+	union := pipe.SUnion(ctx, tags...)
+	_, err := pipe.Exec(ctx)
+	r.observe(err)
+	if err != nil {
+		r.missedInvalidations.Add(1)
+		return
+	}
+	keys := union.Val()
+	if len(keys) == 0 {
+		return
+	}
+
+	// Dropped keys may still be members of other tag sets; that is harmless
+	// since deleting a missing key is a no-op, and those sets expire.
+	members := make([]interface{}, len(keys))
+	for i, key := range keys {
+		members[i] = key
+	}
+	pipe = r.rdb.Pipeline()
+	pipe.Del(ctx, keys...)
+	for _, tag := range tags {
+		pipe.SRem(ctx, tag, members...)
+	}
+	_, err = pipe.Exec(ctx)
+	r.observe(err)
+	if err != nil {
+		r.missedInvalidations.Add(1)
+		return
+	}
+	// Searches arriving now must not join a load that may predate the change
+	for _, key := range keys {
+		r.loads.Forget(key)
+	}
+	log.Printf("Invalidated %d cached search(es)", len(keys))
+}
+
+// Suggest results are cached per folded prefix and left to expire rather
+// than invalidated: listing writes would otherwise clear every prefix of
+// their title and destination, and slightly stale popularity is harmless.
+func (r *CachedListingRepository) Suggest(ctx context.Context, prefix string, limit int) (*model.Suggestions, error) {
+	cacheKey := fmt.Sprintf("suggest:%q:%d", prefix, limit)
+
+	if r.cacheAvailable(ctx) {
+		var suggestions model.Suggestions
+		if r.get(ctx, cacheKey, &suggestions) {
+			cacheStats.Add("suggest_hit", 1)
+			return &suggestions, nil
+		}
+		cacheStats.Add("suggest_miss", 1)
+	}
+
+	// Every keystroke of every traveller lands here, so identical prefixes
+	// share a load
+	leader := false
+	v, err, _ := r.loads.Do(cacheKey, func() (interface{}, error) {
+		leader = true
+		ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), loadTimeout)
+		defer cancel()
+		suggestions, err := r.next.Suggest(ctx, prefix, limit)
+		if err != nil {
+			return nil, err
+		}
+		if r.cacheAvailable(ctx) {
+			if data, err := json.Marshal(suggestions); err == nil {
+				r.observe(r.rdb.Set(ctx, cacheKey, data, jitter(r.opts.TTL)).Err())
+			}
+		}
+		return suggestions, nil
+	})
+	if !leader {
+		cacheStats.Add("suggest_coalesced", 1)
+	}
 	if err != nil {
-		return nil, 0, err
+		return nil, err
 	}
+	return v.(*model.Suggestions), nil
+}
+
+// GetByID is not cached: single listings are cheap to read and callers
+// such as booking flows want current availability.
+func (r *CachedListingRepository) GetByID(ctx context.Context, id string) (*model.Listing, error) {
+	return r.next.GetByID(ctx, id)
+}
+
+// Writes go to the database and then invalidate the cached searches that
+// may show the listing, before and after the change.
 
-	// Cache result
-	cacheData := struct {
-		Listings []*model.Listing `json:"data"`
-		Total    int64            `json:"total"`
-	}{
-		Listings: listings,
-		Total:    total,
+func (r *CachedListingRepository) Reserve(ctx context.Context, listingID, holdID string) (*model.Listing, error) {
+	listing, err := r.next.Reserve(ctx, listingID, holdID)
+	if err == nil {
+		r.invalidate(ctx, listing)
 	}
+	return listing, err
+}
 
-	if data, err := json.Marshal(cacheData); err == nil {
-		r.rdb.Set(ctx, cacheKey, data, r.ttl)
+func (r *CachedListingRepository) Release(ctx context.Context, listingID, holdID string) (*model.Listing, error) {
+	listing, err := r.next.Release(ctx, listingID, holdID)
+	if err == nil {
+		r.invalidate(ctx, listing)
 	}
+	return listing, err
+}
 
-	return listings, total, nil
+func (r *CachedListingRepository) Upsert(ctx context.Context, listing *model.Listing) (*model.Listing, error) {
+	previous, err := r.next.Upsert(ctx, listing)
+	if err == nil {
+		r.invalidate(ctx, previous, listing)
+	}
+	return previous, err
+}
+
+func (r *CachedListingRepository) Create(ctx context.Context, listing *model.Listing) error {
+	err := r.next.Create(ctx, listing)
+	if err == nil {
+		r.invalidate(ctx, listing)
+	}
+	return err
+}
+
+func (r *CachedListingRepository) Update(ctx context.Context, id string, version int64, patch *model.ListingPatch) (*model.Listing, error) {
+	// The update only applies at this version, so if it succeeds previous
+	// is exactly what it replaced
+	previous, err := r.next.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	listing, err := r.next.Update(ctx, id, version, patch)
+	if err == nil {
+		r.invalidate(ctx, previous, listing)
+	}
+	return listing, err
+}
+
+func (r *CachedListingRepository) Delete(ctx context.Context, id string, version int64) (*model.Listing, error) {
+	previous, err := r.next.Delete(ctx, id, version)
+	if err == nil {
+		r.invalidate(ctx, previous)
+	}
+	return previous, err
+}
+
+func (r *CachedListingRepository) SetCapacity(ctx context.Context, id string, capacity int) (*model.Listing, error) {
+	listing, err := r.next.SetCapacity(ctx, id, capacity)
+	if err == nil {
+		r.invalidate(ctx, listing)
+	}
+	return listing, err
 }
 
 func (r *CachedListingRepository) Seed(ctx context.Context) error {
@@ -0,0 +1,417 @@
+package repository
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"path"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gavinadlan/tripnest/backend/search-service/internal/model"
+)
+
+// fakeRedis speaks enough RESP for CachedListingRepository: strings, sets,
+// INCR and SCAN, without expiry. Commands fail while down is set, and the
+// next failNext[command] calls of a command fail.
+type fakeRedis struct {
+	ln   net.Listener
+	down atomic.Bool
+
+	mu       sync.Mutex
+	strings  map[string]string
+	sets     map[string]map[string]bool
+	failNext map[string]int
+}
+
+func newFakeRedis(t *testing.T) *fakeRedis {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	f := &fakeRedis{
+		ln:       ln,
+		strings:  make(map[string]string),
+		sets:     make(map[string]map[string]bool),
+		failNext: make(map[string]int),
+	}
+	go f.serve()
+	t.Cleanup(func() { ln.Close() })
+	return f
+}
+
+func (f *fakeRedis) serve() {
+	for {
+		conn, err := f.ln.Accept()
+		if err != nil {
+			return
+		}
+		go f.handle(conn)
+	}
+}
+
+func (f *fakeRedis) handle(conn net.Conn) {
+	defer conn.Close()
+	rd := bufio.NewReader(conn)
+	for {
+		args, err := readCommand(rd)
+		if err != nil {
+			return
+		}
+		if _, err := io.WriteString(conn, f.exec(args)); err != nil {
+			return
+		}
+	}
+}
+
+func readCommand(rd *bufio.Reader) ([]string, error) {
+	line, err := rd.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+	n, err := strconv.Atoi(strings.TrimSpace(line[1:]))
+	if err != nil {
+		return nil, err
+	}
+	args := make([]string, n)
+	for i := range args {
+		header, err := rd.ReadString('\n')
+		if err != nil {
+			return nil, err
+		}
+		size, err := strconv.Atoi(strings.TrimSpace(header[1:]))
+		if err != nil {
+			return nil, err
+		}
+		buf := make([]byte, size+2)
+		if _, err := io.ReadFull(rd, buf); err != nil {
+			return nil, err
+		}
+		args[i] = string(buf[:size])
+	}
+	return args, nil
+}
+
+func bulk(s string) string { return fmt.Sprintf("$%d\r\n%s\r\n", len(s), s) }
+
+func array(items []string) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "*%d\r\n", len(items))
+	for _, item := range items {
+		b.WriteString(bulk(item))
+	}
+	return b.String()
+}
+
+func (f *fakeRedis) exec(args []string) string {
+	cmd := strings.ToUpper(args[0])
+	if f.down.Load() {
+		return "-ERR server down\r\n"
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.failNext[cmd] > 0 {
+		f.failNext[cmd]--
+		return "-ERR injected failure\r\n"
+	}
+
+	switch cmd {
+	case "PING":
+		return "+PONG\r\n"
+	case "GET":
+		v, ok := f.strings[args[1]]
+		if !ok {
+			return "$-1\r\n"
+		}
+		return bulk(v)
+	case "SET":
+		f.strings[args[1]] = args[2]
+		return "+OK\r\n"
+	case "INCR":
+		n, _ := strconv.Atoi(f.strings[args[1]])
+		f.strings[args[1]] = strconv.Itoa(n + 1)
+		return fmt.Sprintf(":%d\r\n", n+1)
+	case "DEL":
+		deleted := 0
+		for _, key := range args[1:] {
+			_, isString := f.strings[key]
+			_, isSet := f.sets[key]
+			if isString || isSet {
+				deleted++
+			}
+			delete(f.strings, key)
+			delete(f.sets, key)
+		}
+		return fmt.Sprintf(":%d\r\n", deleted)
+	case "EXPIRE":
+		return ":1\r\n"
+	case "SADD":
+		if f.sets[args[1]] == nil {
+			f.sets[args[1]] = make(map[string]bool)
+		}
+		for _, m := range args[2:] {
+			f.sets[args[1]][m] = true
+		}
+		return fmt.Sprintf(":%d\r\n", len(args)-2)
+	case "SREM":
+		for _, m := range args[2:] {
+			delete(f.sets[args[1]], m)
+		}
+		return fmt.Sprintf(":%d\r\n", len(args)-2)
+	case "SUNION":
+		seen := make(map[string]bool)
+		var members []string
+		for _, key := range args[1:] {
+			for m := range f.sets[key] {
+				if !seen[m] {
+					seen[m] = true
+					members = append(members, m)
+				}
+			}
+		}
+		return array(members)
+	case "SCAN":
+		pattern := "*"
+		for i := 2; i+1 < len(args); i++ {
+			if strings.ToUpper(args[i]) == "MATCH" {
+				pattern = args[i+1]
+			}
+		}
+		var keys []string
+		for key := range f.strings {
+			if ok, _ := path.Match(pattern, key); ok {
+				keys = append(keys, key)
+			}
+		}
+		for key := range f.sets {
+			if ok, _ := path.Match(pattern, key); ok {
+				keys = append(keys, key)
+			}
+		}
+		return "*2\r\n" + bulk("0") + array(keys)
+	default:
+		return "-ERR unknown command " + cmd + "\r\n"
+	}
+}
+
+// searchKeys returns the cached searches.
+func (f *fakeRedis) searchKeys() []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	var keys []string
+	for key := range f.strings {
+		if strings.HasPrefix(key, "search:") {
+			keys = append(keys, key)
+		}
+	}
+	return keys
+}
+
+// fakeListings returns one Paris listing priced at price from Search.
+// onSearch, when set, runs inside every Search.
+type fakeListings struct {
+	ListingRepository
+
+	searches atomic.Int64
+	price    atomic.Int64
+	delay    time.Duration
+	onSearch func()
+}
+
+func (f *fakeListings) Search(ctx context.Context, params *model.SearchParams) (*model.SearchResult, error) {
+	f.searches.Add(1)
+	price := f.price.Load()
+	if f.onSearch != nil {
+		f.onSearch()
+	}
+	time.Sleep(f.delay)
+	return &model.SearchResult{
+		Listings: []*model.Listing{{Destination: "Paris", Price: float64(price)}},
+		Total:    1,
+	}, nil
+}
+
+func (f *fakeListings) SetCapacity(ctx context.Context, id string, capacity int) (*model.Listing, error) {
+	return &model.Listing{Destination: "Paris", Capacity: capacity}, nil
+}
+
+func newTestCache(t *testing.T, next ListingRepository, opts CacheOptions) (*CachedListingRepository, *fakeRedis) {
+	t.Helper()
+	redis := newFakeRedis(t)
+	if opts.TTL == 0 {
+		opts.TTL = time.Minute
+	}
+	if opts.StaleTTL == 0 {
+		opts.StaleTTL = time.Minute
+	}
+	if opts.BreakerThreshold == 0 {
+		opts.BreakerThreshold = 5
+	}
+	if opts.BreakerCooldown == 0 {
+		opts.BreakerCooldown = time.Hour
+	}
+	r := NewCachedListingRepository(next, redis.ln.Addr().String(), opts)
+	t.Cleanup(func() { r.rdb.Close() })
+	return r, redis
+}
+
+func searchPrice(t *testing.T, r *CachedListingRepository) float64 {
+	t.Helper()
+	result, err := r.Search(context.Background(), &model.SearchParams{Page: 1, Limit: 10, Sort: model.SortNewest})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return result.Listings[0].Price
+}
+
+func stat(name string) int64 {
+	if v := cacheStats.Get(name); v != nil {
+		n, _ := strconv.ParseInt(v.String(), 10, 64)
+		return n
+	}
+	return 0
+}
+
+func TestCachedSearchCoalescesMisses(t *testing.T) {
+	next := &fakeListings{delay: 50 * time.Millisecond}
+	r, _ := newTestCache(t, next, CacheOptions{})
+
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			searchPrice(t, r)
+		}()
+	}
+	wg.Wait()
+	if n := next.searches.Load(); n != 1 {
+		t.Fatalf("20 concurrent misses ran %d database searches, want 1", n)
+	}
+
+	searchPrice(t, r)
+	if n := next.searches.Load(); n != 1 {
+		t.Fatalf("cached search went to the database")
+	}
+}
+
+func TestCachedSearchServesStaleWhileRefreshing(t *testing.T) {
+	next := &fakeListings{}
+	next.price.Store(1)
+	r, _ := newTestCache(t, next, CacheOptions{TTL: 50 * time.Millisecond})
+
+	searchPrice(t, r)
+	time.Sleep(80 * time.Millisecond)
+	next.price.Store(2)
+
+	if got := searchPrice(t, r); got != 1 {
+		t.Fatalf("stale search returned price %v, want the cached 1", got)
+	}
+	deadline := time.Now().Add(time.Second)
+	for searchPrice(t, r) != 2 {
+		if time.Now().After(deadline) {
+			t.Fatal("stale search was not refreshed")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestMissedInvalidationFlushedBeforeServing(t *testing.T) {
+	next := &fakeListings{}
+	next.price.Store(1)
+	r, redis := newTestCache(t, next, CacheOptions{BreakerThreshold: 5})
+
+	searchPrice(t, r)
+
+	// One Redis error is below the threshold, so the breaker stays closed
+	next.price.Store(2)
+	redis.mu.Lock()
+	redis.failNext["SUNION"] = 1
+	redis.mu.Unlock()
+	if _, err := r.SetCapacity(context.Background(), "l1", 0); err != nil {
+		t.Fatal(err)
+	}
+	if r.breaker.state() != "closed" || r.missedInvalidations.Load() == 0 {
+		t.Fatalf("breaker %s, missed invalidations %d", r.breaker.state(), r.missedInvalidations.Load())
+	}
+
+	if got := searchPrice(t, r); got != 2 {
+		t.Fatalf("search after a missed invalidation returned price %v, want 2", got)
+	}
+	if r.missedInvalidations.Load() != 0 {
+		t.Fatal("missed invalidation not cleared by the flush")
+	}
+}
+
+func TestLoadOvertakenByInvalidationIsNotCached(t *testing.T) {
+	next := &fakeListings{}
+	next.price.Store(1)
+	r, redis := newTestCache(t, next, CacheOptions{})
+
+	// The listing changes after the load has read it
+	var once sync.Once
+	next.onSearch = func() {
+		once.Do(func() {
+			next.price.Store(2)
+			r.invalidate(context.Background(), &model.Listing{Destination: "Paris"})
+		})
+	}
+	discarded := stat("discarded")
+
+	if got := searchPrice(t, r); got != 1 {
+		t.Fatalf("first search returned price %v, want 1", got)
+	}
+	if keys := redis.searchKeys(); len(keys) != 0 {
+		t.Fatalf("result read before the invalidation was cached: %v", keys)
+	}
+	if stat("discarded") != discarded+1 {
+		t.Fatal("discarded load not counted")
+	}
+	if got := searchPrice(t, r); got != 2 {
+		t.Fatalf("second search returned price %v, want 2", got)
+	}
+	if keys := redis.searchKeys(); len(keys) != 1 {
+		t.Fatalf("undisturbed load was not cached: %v", keys)
+	}
+}
+
+func TestCacheBypassedWhileRedisIsDown(t *testing.T) {
+	next := &fakeListings{}
+	next.price.Store(1)
+	r, redis := newTestCache(t, next, CacheOptions{BreakerThreshold: 2, BreakerCooldown: 50 * time.Millisecond})
+
+	searchPrice(t, r)
+	redis.down.Store(true)
+	for i := 0; i < 3; i++ {
+		searchPrice(t, r)
+	}
+	if r.breaker.state() != "open" {
+		t.Fatalf("breaker %s after Redis went down, want open", r.breaker.state())
+	}
+
+	// Invalidations are missed while the breaker is open
+	next.price.Store(2)
+	if _, err := r.SetCapacity(context.Background(), "l1", 0); err != nil {
+		t.Fatal(err)
+	}
+	redis.down.Store(false)
+	if got := searchPrice(t, r); got != 2 {
+		t.Fatalf("search during the cooldown returned price %v, want 2 from the database", got)
+	}
+
+	time.Sleep(60 * time.Millisecond)
+	if got := searchPrice(t, r); got != 2 {
+		t.Fatalf("search after the probe returned price %v, want 2", got)
+	}
+	if r.breaker.state() != "closed" || r.missedInvalidations.Load() != 0 {
+		t.Fatalf("after the probe: breaker %s, missed invalidations %d", r.breaker.state(), r.missedInvalidations.Load())
+	}
+}
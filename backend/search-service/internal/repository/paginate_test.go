@@ -0,0 +1,120 @@
+package repository
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/gavinadlan/tripnest/backend/search-service/internal/model"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func testListings(n int) []*model.Listing {
+	listings := make([]*model.Listing, n)
+	for i := range listings {
+		listings[i] = &model.Listing{ID: primitive.NewObjectID(), Price: float64(100 + i)}
+	}
+	return listings
+}
+
+func TestPaginateFirstPage(t *testing.T) {
+	params := &model.SearchParams{Sort: model.SortPrice, Page: 1, Limit: 2}
+	listings := testListings(3) // One past the page
+
+	result := paginate(params, listings, 10)
+	if len(result.Listings) != 2 || result.Total != 10 {
+		t.Fatalf("got %d listings of %d", len(result.Listings), result.Total)
+	}
+	if result.Prev != nil {
+		t.Fatal("first page has a previous cursor")
+	}
+	next := result.Next
+	if next == nil || next.ID != listings[1].ID.Hex() || next.Number != 101 || next.Backward {
+		t.Fatalf("next cursor %+v, want forward from the last listing on the page", next)
+	}
+	if next.Search != params.Fingerprint() {
+		t.Fatal("cursor does not carry the search fingerprint")
+	}
+}
+
+func TestPaginateLastPage(t *testing.T) {
+	params := &model.SearchParams{Sort: model.SortPrice, Page: 3, Limit: 2}
+	result := paginate(params, testListings(1), 5)
+	if result.Next != nil || result.Prev == nil {
+		t.Fatalf("last numbered page: next %v, prev %v", result.Next, result.Prev)
+	}
+
+	// A page past the end has no listings to point from
+	if result := paginate(params, nil, 5); result.Next != nil || result.Prev != nil {
+		t.Fatal("empty page has cursors")
+	}
+}
+
+func TestPaginateForwardCursor(t *testing.T) {
+	params := &model.SearchParams{Sort: model.SortPrice, Limit: 2, Cursor: &model.Cursor{Sort: model.SortPrice}}
+
+	result := paginate(params, testListings(2), 10)
+	if result.Prev == nil || !result.Prev.Backward || result.Prev.ID != result.Listings[0].ID.Hex() {
+		t.Fatalf("prev cursor %+v, want backward from the first listing", result.Prev)
+	}
+	if result.Next != nil {
+		t.Fatal("next cursor without a listing past the page")
+	}
+}
+
+func TestPaginateBackwardCursor(t *testing.T) {
+	params := &model.SearchParams{Sort: model.SortPrice, Limit: 2, Cursor: &model.Cursor{Sort: model.SortPrice, Backward: true}}
+	// Fetched walking back from the cursor: nearest first, one extra
+	fetched := testListings(3)
+	nearest, second := fetched[0], fetched[1]
+
+	result := paginate(params, fetched, 10)
+	if len(result.Listings) != 2 || result.Listings[0] != second || result.Listings[1] != nearest {
+		t.Fatal("backward page is not restored to sort order")
+	}
+	if result.Next == nil || result.Next.ID != nearest.ID.Hex() || result.Next.Backward {
+		t.Fatalf("next cursor %+v, want forward from the last listing", result.Next)
+	}
+	if result.Prev == nil || result.Prev.ID != second.ID.Hex() {
+		t.Fatalf("prev cursor %+v, want backward from the first listing", result.Prev)
+	}
+
+	// At the start of the results there is nothing before
+	params.Cursor = &model.Cursor{Sort: model.SortPrice, Backward: true}
+	if result := paginate(params, testListings(1), 10); result.Prev != nil || result.Next == nil {
+		t.Fatalf("first page reached backwards: prev %v, next %v", result.Prev, result.Next)
+	}
+}
+
+func TestPastCursor(t *testing.T) {
+	id := primitive.NewObjectID()
+	start := time.Date(2026, 7, 1, 0, 0, 0, 0, time.UTC)
+
+	cases := []struct {
+		name  string
+		c     *model.Cursor
+		field string
+		op    string
+		value interface{}
+	}{
+		{"cheapest first", &model.Cursor{Sort: model.SortPrice, Number: 99}, "price", "$gt", 99.0},
+		{"cheapest first, backward", &model.Cursor{Sort: model.SortPrice, Number: 99, Backward: true}, "price", "$lt", 99.0},
+		{"newest first", &model.Cursor{Sort: model.SortNewest, Time: &start}, "created_at", "$lt", start},
+		{"newest first, backward", &model.Cursor{Sort: model.SortNewest, Time: &start, Backward: true}, "created_at", "$gt", start},
+		{"earliest trip first", &model.Cursor{Sort: model.SortDate, Time: &start}, "start_date", "$gt", start},
+		{"most relevant first", &model.Cursor{Sort: model.SortRelevance, Number: 1.5}, "score", "$lt", 1.5},
+		{"nearest first", &model.Cursor{Sort: model.SortDistance, Number: 3}, "distance_km", "$gt", 3.0},
+	}
+	for _, tc := range cases {
+		tc.c.ID = id.Hex()
+		got := pastCursor(tc.c)
+		want := bson.A{
+			bson.M{tc.field: bson.M{tc.op: tc.value}},
+			bson.D{{Key: tc.field, Value: tc.value}, {Key: "_id", Value: bson.M{tc.op: id}}},
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("%s: pastCursor = %v, want %v", tc.name, got, want)
+		}
+	}
+}
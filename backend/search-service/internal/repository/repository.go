@@ -2,17 +2,56 @@ package repository
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"log"
+	"regexp"
 	"time"
 
 	"github.com/gavinadlan/tripnest/backend/search-service/internal/model"
 	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
 type ListingRepository interface {
-	Search(ctx context.Context, params *model.SearchParams) ([]*model.Listing, int64, error)
+	Search(ctx context.Context, params *model.SearchParams) (*model.SearchResult, error)
+	// Suggest returns up to limit destinations and listing titles whose
+	// folded form (model.SuggestKey) starts with prefix, which must already
+	// be folded. Only bookable listings count.
+	Suggest(ctx context.Context, prefix string, limit int) (*model.Suggestions, error)
+	// GetByID returns nil if no listing has the ID, including when it is not
+	// a valid ObjectID or the listing is deleted.
+	GetByID(ctx context.Context, id string) (*model.Listing, error)
+	// Reserve takes one slot of the listing for holdID, returning the updated
+	// listing. The slot count never goes below zero: model.ErrSoldOut is
+	// returned instead. Reserving an already held slot succeeds unchanged.
+	Reserve(ctx context.Context, listingID, holdID string) (*model.Listing, error)
+	// Release gives the slot held by holdID back and returns the updated
+	// listing, or nil if the hold did not exist (never taken or already
+	// released).
+	Release(ctx context.Context, listingID, holdID string) (*model.Listing, error)
+	// Upsert creates the listing or replaces its details, keeping its holds:
+	// available_slots becomes the capacity minus the slots held. It returns
+	// the listing as it was before, or nil if it is new.
+	Upsert(ctx context.Context, listing *model.Listing) (*model.Listing, error)
+	// Create inserts a new listing at version 1 with all its capacity
+	// available, filling in its ID and timestamps.
+	Create(ctx context.Context, listing *model.Listing) error
+	// Update applies patch if the listing is still at version and returns
+	// the result, or model.ErrListingNotFound / model.ErrVersionConflict.
+	Update(ctx context.Context, id string, version int64, patch *model.ListingPatch) (*model.Listing, error)
+	// Delete soft-deletes the listing, hiding it from reads and searches but
+	// keeping its holds so they can still be released. A version of 0
+	// deletes whatever the current version is. It returns the listing as it
+	// was, nil if it does not exist or is already deleted, or
+	// model.ErrVersionConflict.
+	Delete(ctx context.Context, id string, version int64) (*model.Listing, error)
+	// SetCapacity changes the listing's total slots, recomputing
+	// available_slots the same way as Upsert. It returns the updated
+	// listing, or nil if it does not exist.
+	SetCapacity(ctx context.Context, id string, capacity int) (*model.Listing, error)
 	Seed(ctx context.Context) error
 }
 
@@ -20,7 +59,9 @@ type mongoRepository struct {
 	coll *mongo.Collection
 }
 
-func NewMongoRepository(uri string, dbName string) (ListingRepository, error) {
+// NewMongoRepository connects to dbName. textLanguage is the default
+// stemming language of the full-text index (see model.TextLanguages).
+func NewMongoRepository(uri string, dbName string, textLanguage string) (ListingRepository, error) {
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 
@@ -35,20 +76,180 @@ func NewMongoRepository(uri string, dbName string) (ListingRepository, error) {
 	_, err = coll.Indexes().CreateMany(ctx, []mongo.IndexModel{
 		{Keys: bson.D{{Key: "destination", Value: 1}}},
 		{Keys: bson.D{{Key: "price", Value: 1}}},
-		{Keys: bson.D{{Key: "date", Value: 1}}},
+		{Keys: bson.D{{Key: "start_date", Value: 1}}},
+		{Keys: bson.D{{Key: "end_date", Value: 1}}},
+		{
+			// Destination matches count most, then the title
+			Keys: bson.D{
+				{Key: "title", Value: "text"},
+				{Key: "destination", Value: "text"},
+				{Key: "description", Value: "text"},
+			},
+			Options: options.Index().
+				SetName("listing_text").
+				SetWeights(bson.M{"destination": 10, "title": 5, "description": 1}).
+				SetDefaultLanguage(textLanguage),
+		},
+		{Keys: bson.D{{Key: "location", Value: "2dsphere"}}},
+		// Anchored prefix regexes on the folded keys are index range scans
+		{Keys: bson.D{{Key: "destination_key", Value: 1}}},
+		{Keys: bson.D{{Key: "title_key", Value: 1}}},
 	})
 	if err != nil {
 		log.Printf("Failed to create indexes: %v", err)
 	}
+	if err := backfillTripDates(ctx, coll); err != nil {
+		log.Printf("Failed to backfill trip dates: %v", err)
+	}
+	if err := backfillSuggestKeys(ctx, coll); err != nil {
+		log.Printf("Failed to backfill suggestion keys: %v", err)
+	}
 
 	return &mongoRepository{coll: coll}, nil
 }
 
-func (r *mongoRepository) Search(ctx context.Context, params *model.SearchParams) ([]*model.Listing, int64, error) {
+// backfillTripDates turns listings stored with only a date string into day
+// trips on that date.
+func backfillTripDates(ctx context.Context, coll *mongo.Collection) error {
+	filter := bson.M{"start_date": bson.M{"$exists": false}, "date": bson.M{"$type": "string"}}
+	update := mongo.Pipeline{
+		{{Key: "$set", Value: bson.M{"start_date": bson.M{"$dateFromString": bson.M{
+			"dateString": "$date",
+			"format":     "%Y-%m-%d",
+			"timezone":   "UTC",
+			"onError":    nil,
+		}}}}},
+		{{Key: "$set", Value: bson.M{"end_date": "$start_date", "duration_days": 0}}},
+	}
+	res, err := coll.UpdateMany(ctx, filter, update)
+	if err != nil {
+		return err
+	}
+	if res.ModifiedCount > 0 {
+		log.Printf("Backfilled trip dates of %d listing(s)", res.ModifiedCount)
+	}
+	return nil
+}
+
+// backfillSuggestKeys folds the titles and destinations of listings stored
+// before suggestions. Folding needs Go, so they are updated one by one.
+func backfillSuggestKeys(ctx context.Context, coll *mongo.Collection) error {
+	filter := bson.M{"title_key": bson.M{"$exists": false}}
+	cursor, err := coll.Find(ctx, filter, options.Find().SetProjection(bson.M{"title": 1, "destination": 1}))
+	if err != nil {
+		return err
+	}
+	defer cursor.Close(ctx)
+
+	count := 0
+	for cursor.Next(ctx) {
+		var listing model.Listing
+		if err := cursor.Decode(&listing); err != nil {
+			return err
+		}
+		listing.SetSuggestKeys()
+		update := bson.M{"$set": bson.M{"title_key": listing.TitleKey, "destination_key": listing.DestinationKey}}
+		if _, err := coll.UpdateByID(ctx, listing.ID, update); err != nil {
+			return err
+		}
+		count++
+	}
+	if count > 0 {
+		log.Printf("Backfilled suggestion keys of %d listing(s)", count)
+	}
+	return cursor.Err()
+}
+
+func (r *mongoRepository) Search(ctx context.Context, params *model.SearchParams) (*model.SearchResult, error) {
+	filter := searchFilter(params)
+	// Relevance and distance sort on computed fields
+	if params.Facets || params.Near != nil || params.Sort == model.SortRelevance {
+		return r.aggregateSearch(ctx, filter, params)
+	}
+
+	count, err := r.coll.CountDocuments(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	pageFilter := filter
+	findOptions := options.Find()
+	if params.Query != "" {
+		findOptions.SetProjection(bson.M{"score": bson.M{"$meta": "textScore"}})
+	}
+	findOptions.SetSort(searchSort(params.Sort, params.Cursor != nil && params.Cursor.Backward))
+	if params.Cursor != nil {
+		pageFilter = bson.M{"$or": pastCursor(params.Cursor)}
+		for k, v := range filter {
+			pageFilter[k] = v
+		}
+	} else {
+		findOptions.SetSkip(int64((params.Page - 1) * params.Limit))
+	}
+	// One more than the page, to tell whether another follows
+	findOptions.SetLimit(int64(params.Limit + 1))
+
+	cursor, err := r.coll.Find(ctx, pageFilter, findOptions)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var listings []*model.Listing
+	if err := cursor.All(ctx, &listings); err != nil {
+		return nil, err
+	}
+
+	return paginate(params, listings, count), nil
+}
+
+// paginate trims the extra listing fetched past the page, restores the
+// order of backward pages and sets the cursors to the pages around it.
+func paginate(params *model.SearchParams, listings []*model.Listing, total int64) *model.SearchResult {
+	more := len(listings) > params.Limit
+	if more {
+		listings = listings[:params.Limit]
+	}
+
+	// Whether there are listings before and after the page
+	var before, after bool
+	switch c := params.Cursor; {
+	case c != nil && c.Backward:
+		for i, j := 0, len(listings)-1; i < j; i, j = i+1, j-1 {
+			listings[i], listings[j] = listings[j], listings[i]
+		}
+		before, after = more, true
+	case c != nil:
+		before, after = true, more
+	default:
+		before, after = params.Page > 1, more
+	}
+
+	result := &model.SearchResult{Listings: listings, Total: total}
+	if len(listings) > 0 {
+		if before {
+			result.Prev = model.NewCursor(params, listings[0], true)
+		}
+		if after {
+			result.Next = model.NewCursor(params, listings[len(listings)-1], false)
+		}
+	}
+	return result
+}
+
+func searchFilter(params *model.SearchParams) bson.M {
 	filter := bson.M{}
 
+	if params.Query != "" {
+		text := bson.M{"$search": params.Query}
+		if params.Language != "" {
+			text["$language"] = params.Language
+		}
+		filter["$text"] = text
+	}
 	if params.Destination != "" {
-		filter["destination"] = bson.M{"$regex": params.Destination, "$options": "i"} // Case-insensitive search
+		// Case-insensitive substring match; quoted so the value is not a pattern
+		filter["destination"] = bson.M{"$regex": regexp.QuoteMeta(params.Destination), "$options": "i"}
 	}
 	if params.MinPrice > 0 || params.MaxPrice > 0 {
 		priceFilter := bson.M{}
@@ -60,32 +261,649 @@ func (r *mongoRepository) Search(ctx context.Context, params *model.SearchParams
 		}
 		filter["price"] = priceFilter
 	}
-	if params.Date != "" {
-		filter["date"] = params.Date
+	flex := time.Duration(params.FlexDays) * day
+	start := bson.M{}
+	if params.Date != nil {
+		start["$gte"] = params.Date.Add(-flex)
+		start["$lte"] = params.Date.Add(flex)
+	}
+	if params.From != nil {
+		start["$gte"] = params.From.Add(-flex)
+	}
+	if len(start) > 0 {
+		filter["start_date"] = start
+	}
+	if params.To != nil {
+		filter["end_date"] = bson.M{"$lte": params.To.Add(flex)}
 	}
+	if params.MinDuration > 0 || params.MaxDuration > 0 {
+		duration := bson.M{}
+		if params.MinDuration > 0 {
+			duration["$gte"] = params.MinDuration
+		}
+		if params.MaxDuration > 0 {
+			duration["$lte"] = params.MaxDuration
+		}
+		filter["duration_days"] = duration
+	}
+	if near := params.Near; near != nil {
+		if near.RadiusKm > 0 {
+			// $centerSphere takes the radius in radians
+			center := bson.A{near.Lng, near.Lat}
+			filter["location"] = bson.M{"$geoWithin": bson.M{"$centerSphere": bson.A{center, near.RadiusKm / model.EarthRadiusKm}}}
+		} else {
+			filter["location"] = bson.M{"$exists": true}
+		}
+	}
+	if box := params.BBox; box != nil {
+		// Plain coordinate ranges rather than a $geometry polygon, whose
+		// edges would follow great circles instead of the parallels
+		filter["location.coordinates.0"] = bson.M{"$gte": box.MinLng, "$lte": box.MaxLng}
+		filter["location.coordinates.1"] = bson.M{"$gte": box.MinLat, "$lte": box.MaxLat}
+	}
+	// Sold-out listings cannot be booked, so they are not shown
+	filter["available_slots"] = bson.M{"$gt": 0}
+	filter["deleted_at"] = nil
+	return filter
+}
 
-	count, err := r.coll.CountDocuments(ctx, filter)
+type bucketCount struct {
+	Min   float64 `bson:"_id"`
+	Count int64   `bson:"count"`
+}
+
+// aggregateSearch fetches the page, and the facets if asked for, in one
+// aggregation, so the matching listings are only selected once. Geo
+// searches use it to compute distances.
+func (r *mongoRepository) aggregateSearch(ctx context.Context, filter bson.M, params *model.SearchParams) (*model.SearchResult, error) {
+	pipeline := mongo.Pipeline{{{Key: "$match", Value: filter}}}
+	if params.Query != "" {
+		// The text score is kept as a field so the sub-pipelines can use it
+		pipeline = append(pipeline, bson.D{{Key: "$addFields", Value: bson.M{"score": bson.M{"$meta": "textScore"}}}})
+	}
+	if params.Near != nil {
+		// Computed rather than taken from $geoNear, which must be the first
+		// stage and cannot be combined with $text
+		pipeline = append(pipeline, bson.D{{Key: "$addFields", Value: bson.M{"distance_km": distanceKm(params.Near)}}})
+	}
+
+	// The cursor only narrows the page; the total and facets count every
+	// match
+	var results bson.A
+	if params.Cursor != nil {
+		results = bson.A{
+			bson.M{"$match": bson.M{"$or": pastCursor(params.Cursor)}},
+			bson.M{"$sort": searchSort(params.Sort, params.Cursor.Backward)},
+		}
+	} else {
+		results = bson.A{
+			bson.M{"$sort": searchSort(params.Sort, false)},
+			bson.M{"$skip": (params.Page - 1) * params.Limit},
+		}
+	}
+	stages := bson.M{
+		"results": append(results, bson.M{"$limit": params.Limit + 1}),
+		"total":   bson.A{bson.M{"$count": "count"}},
+	}
+	if params.Facets {
+		for name, stage := range facetStages() {
+			stages[name] = stage
+		}
+	}
+	pipeline = append(pipeline, bson.D{{Key: "$facet", Value: stages}})
+
+	cursor, err := r.coll.Aggregate(ctx, pipeline)
 	if err != nil {
-		return nil, 0, err
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var out []struct {
+		Results []*model.Listing `bson:"results"`
+		Total   []struct {
+			Count int64 `bson:"count"`
+		} `bson:"total"`
+		Destinations []model.FacetCount `bson:"destinations"`
+		Prices       []bucketCount      `bson:"prices"`
+		Months       []model.FacetCount `bson:"months"`
+		Availability []bucketCount      `bson:"availability"`
+	}
+	if err := cursor.All(ctx, &out); err != nil {
+		return nil, err
+	}
+	if len(out) == 0 {
+		return nil, errors.New("search aggregation returned no document")
 	}
 
-	findOptions := options.Find()
-	findOptions.SetSort(bson.D{{Key: "created_at", Value: -1}})
-	findOptions.SetSkip(int64((params.Page - 1) * params.Limit))
-	findOptions.SetLimit(int64(params.Limit))
+	res := out[0]
+	var total int64
+	if len(res.Total) > 0 {
+		total = res.Total[0].Count
+	}
+	result := paginate(params, res.Results, total)
+	if !params.Facets {
+		return result, nil
+	}
+
+	result.Facets = &model.Facets{
+		Destinations: res.Destinations,
+		Prices:       ranges(model.PriceBuckets, res.Prices),
+		Months:       res.Months,
+		Availability: ranges(model.AvailabilityBuckets, res.Availability),
+	}
+	if result.Facets.Destinations == nil {
+		result.Facets.Destinations = []model.FacetCount{}
+	}
+	if result.Facets.Months == nil {
+		result.Facets.Months = []model.FacetCount{}
+	}
+	return result, nil
+}
+
+// facetStages are the $facet sub-pipelines counting the matching listings.
+func facetStages() bson.M {
+	count := bson.M{"count": bson.M{"$sum": 1}}
+	return bson.M{
+		"destinations": bson.A{
+			bson.M{"$group": bson.M{"_id": "$destination", "count": bson.M{"$sum": 1}}},
+			bson.M{"$sort": bson.D{{Key: "count", Value: -1}, {Key: "_id", Value: 1}}},
+			bson.M{"$limit": model.MaxDestinationFacets},
+		},
+		"prices": bson.A{bson.M{"$bucket": bson.M{
+			"groupBy":    "$price",
+			"boundaries": model.PriceBuckets,
+			"default":    model.PriceBuckets[len(model.PriceBuckets)-1],
+			"output":     count,
+		}}},
+		// By start_date, the month the cache tags listings with
+		"months": bson.A{
+			bson.M{"$match": bson.M{"start_date": bson.M{"$type": "date"}}},
+			bson.M{"$group": bson.M{
+				"_id":   bson.M{"$dateToString": bson.M{"format": "%Y-%m", "date": "$start_date"}},
+				"count": bson.M{"$sum": 1},
+			}},
+			bson.M{"$sort": bson.M{"_id": 1}},
+		},
+		"availability": bson.A{bson.M{"$bucket": bson.M{
+			"groupBy":    "$available_slots",
+			"boundaries": model.AvailabilityBuckets,
+			"default":    model.AvailabilityBuckets[len(model.AvailabilityBuckets)-1],
+			"output":     count,
+		}}},
+	}
+}
+
+// distanceKm is an aggregation expression for the haversine distance from
+// near to the listing's location, rounded to metres.
+func distanceKm(near *model.GeoNear) bson.M {
+	rad := func(v interface{}) bson.M { return bson.M{"$degreesToRadians": v} }
+	sq := func(v interface{}) bson.M { return bson.M{"$pow": bson.A{v, 2}} }
+	lat1 := rad(near.Lat)
+	lat2 := rad(bson.M{"$arrayElemAt": bson.A{"$location.coordinates", 1}})
+	dLat := bson.M{"$subtract": bson.A{lat2, lat1}}
+	dLng := bson.M{"$subtract": bson.A{
+		rad(bson.M{"$arrayElemAt": bson.A{"$location.coordinates", 0}}),
+		rad(near.Lng),
+	}}
+	// a = sin²(Δlat/2) + cos(lat1)·cos(lat2)·sin²(Δlng/2)
+	a := bson.M{"$add": bson.A{
+		sq(bson.M{"$sin": bson.M{"$divide": bson.A{dLat, 2}}}),
+		bson.M{"$multiply": bson.A{
+			bson.M{"$cos": lat1},
+			bson.M{"$cos": lat2},
+			sq(bson.M{"$sin": bson.M{"$divide": bson.A{dLng, 2}}}),
+		}},
+	}}
+	// Clamped, as rounding can push a just past 1 for antipodal points
+	c := bson.M{"$asin": bson.M{"$min": bson.A{1, bson.M{"$sqrt": a}}}}
+	return bson.M{"$round": bson.A{bson.M{"$multiply": bson.A{2 * model.EarthRadiusKm, c}}, 3}}
+}
+
+// ranges lists a range per lower bound, including the empty ones $bucket
+// leaves out.
+func ranges(bounds []float64, counts []bucketCount) []model.RangeCount {
+	out := make([]model.RangeCount, len(bounds))
+	for i, min := range bounds {
+		out[i].Min = min
+		if i+1 < len(bounds) {
+			max := bounds[i+1]
+			out[i].Max = &max
+		}
+	}
+	for _, c := range counts {
+		for i := len(bounds) - 1; i >= 0; i-- {
+			if c.Min >= bounds[i] {
+				out[i].Count += c.Count
+				break
+			}
+		}
+	}
+	return out
+}
+
+// sortKey is the field a sort order ranks by and whether it descends. Ties
+// are broken by _id in the same direction, so every listing has its own
+// position for a cursor to point at.
+func sortKey(sort model.SortOrder) (string, bool) {
+	switch sort {
+	case model.SortRelevance:
+		return "score", true // Only set by aggregateSearch
+	case model.SortPrice:
+		return "price", false
+	case model.SortDate:
+		return "start_date", false
+	case model.SortDistance:
+		return "distance_km", false // Only set by aggregateSearch
+	default:
+		return "created_at", true
+	}
+}
+
+// searchSort orders results by sort, or the other way round to walk back
+// from a cursor.
+func searchSort(sort model.SortOrder, backward bool) bson.D {
+	field, desc := sortKey(sort)
+	dir := 1
+	if desc != backward {
+		dir = -1
+	}
+	return bson.D{{Key: field, Value: dir}, {Key: "_id", Value: dir}}
+}
+
+// pastCursor lists the conditions ($or) for listings past c in its
+// direction: a later sort key, or the same one and a later ID.
+func pastCursor(c *model.Cursor) bson.A {
+	field, desc := sortKey(c.Sort)
+	op := "$gt"
+	if desc != c.Backward {
+		op = "$lt"
+	}
+	var value interface{} = c.Number
+	if c.Time != nil {
+		value = *c.Time
+	}
+	// Checked when the cursor was decoded
+	id, _ := primitive.ObjectIDFromHex(c.ID)
+	return bson.A{
+		bson.M{field: bson.M{op: value}},
+		bson.D{{Key: field, Value: value}, {Key: "_id", Value: bson.M{op: id}}},
+	}
+}
+
+func (r *mongoRepository) Suggest(ctx context.Context, prefix string, limit int) (*model.Suggestions, error) {
+	anchored := bson.M{"$regex": "^" + regexp.QuoteMeta(prefix)}
+	bookable := bson.M{"deleted_at": nil, "available_slots": bson.M{"$gt": 0}}
+	held := bson.M{"$size": bson.M{"$ifNull": bson.A{"$holds", bson.A{}}}}
 
-	cursor, err := r.coll.Find(ctx, filter, findOptions)
+	// Spellings of a destination that fold alike are one suggestion, shown
+	// as its most recent spelling
+	destinations := mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{"destination_key": anchored}}},
+		{{Key: "$match", Value: bookable}},
+		{{Key: "$sort", Value: bson.M{"updated_at": -1}}},
+		{{Key: "$group", Value: bson.M{
+			"_id":         "$destination_key",
+			"destination": bson.M{"$first": "$destination"},
+			"listings":    bson.M{"$sum": 1},
+			"popularity":  bson.M{"$sum": held},
+		}}},
+		{{Key: "$sort", Value: bson.D{{Key: "popularity", Value: -1}, {Key: "listings", Value: -1}, {Key: "_id", Value: 1}}}},
+		{{Key: "$limit", Value: limit}},
+	}
+	listings := mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{"title_key": anchored}}},
+		{{Key: "$match", Value: bookable}},
+		{{Key: "$project", Value: bson.M{
+			"_id":         bson.M{"$toString": "$_id"},
+			"title":       1,
+			"destination": 1,
+			"title_key":   1,
+			"popularity":  held,
+		}}},
+		{{Key: "$sort", Value: bson.D{{Key: "popularity", Value: -1}, {Key: "title_key", Value: 1}, {Key: "_id", Value: 1}}}},
+		{{Key: "$limit", Value: limit}},
+	}
+
+	suggestions := &model.Suggestions{
+		Destinations: []model.DestinationSuggestion{},
+		Listings:     []model.ListingSuggestion{},
+	}
+	if err := r.aggregateAll(ctx, destinations, &suggestions.Destinations); err != nil {
+		return nil, fmt.Errorf("failed to suggest destinations: %w", err)
+	}
+	if err := r.aggregateAll(ctx, listings, &suggestions.Listings); err != nil {
+		return nil, fmt.Errorf("failed to suggest listings: %w", err)
+	}
+	return suggestions, nil
+}
+
+func (r *mongoRepository) aggregateAll(ctx context.Context, pipeline mongo.Pipeline, out interface{}) error {
+	cursor, err := r.coll.Aggregate(ctx, pipeline)
 	if err != nil {
-		return nil, 0, err
+		return err
 	}
 	defer cursor.Close(ctx)
+	return cursor.All(ctx, out)
+}
 
-	var listings []*model.Listing
-	if err := cursor.All(ctx, &listings); err != nil {
-		return nil, 0, err
+func (r *mongoRepository) GetByID(ctx context.Context, id string) (*model.Listing, error) {
+	oid, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return nil, nil // Not found
+	}
+
+	var listing model.Listing
+	err = r.coll.FindOne(ctx, bson.M{"_id": oid, "deleted_at": nil}).Decode(&listing)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, nil // Not found
+		}
+		return nil, err
+	}
+	return &listing, nil
+}
+
+func (r *mongoRepository) Reserve(ctx context.Context, listingID, holdID string) (*model.Listing, error) {
+	oid, err := primitive.ObjectIDFromHex(listingID)
+	if err != nil {
+		return nil, model.ErrListingNotFound
+	}
+
+	// A single conditional update: the filter only matches while a slot is
+	// left and the hold is not recorded yet, so concurrent bookings can
+	// never take the count negative or hold twice.
+	filter := bson.M{
+		"_id":             oid,
+		"deleted_at":      nil,
+		"available_slots": bson.M{"$gte": 1},
+		"holds":           bson.M{"$ne": holdID},
+	}
+	update := bson.M{
+		"$inc":  bson.M{"available_slots": -1},
+		"$push": bson.M{"holds": holdID},
+		"$set":  bson.M{"updated_at": time.Now()},
+	}
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+
+	var listing model.Listing
+	err = r.coll.FindOneAndUpdate(ctx, filter, update, opts).Decode(&listing)
+	if err == nil {
+		return &listing, nil
+	}
+	if !errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, fmt.Errorf("failed to reserve listing %s: %w", listingID, err)
+	}
+
+	// Nothing matched: find out why
+	current, err := r.GetByID(ctx, listingID)
+	if err != nil {
+		return nil, err
+	}
+	if current == nil {
+		return nil, model.ErrListingNotFound
+	}
+	for _, id := range current.Holds {
+		if id == holdID {
+			return current, nil
+		}
 	}
+	return nil, model.ErrSoldOut
+}
 
-	return listings, count, nil
+func (r *mongoRepository) Release(ctx context.Context, listingID, holdID string) (*model.Listing, error) {
+	oid, err := primitive.ObjectIDFromHex(listingID)
+	if err != nil {
+		return nil, nil // Not found
+	}
+
+	filter := bson.M{"_id": oid, "holds": holdID}
+	update := bson.M{
+		"$inc":  bson.M{"available_slots": 1},
+		"$pull": bson.M{"holds": holdID},
+		"$set":  bson.M{"updated_at": time.Now()},
+	}
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+
+	var listing model.Listing
+	err = r.coll.FindOneAndUpdate(ctx, filter, update, opts).Decode(&listing)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, nil // No such hold
+		}
+		return nil, fmt.Errorf("failed to release hold %s on listing %s: %w", holdID, listingID, err)
+	}
+	return &listing, nil
+}
+
+// nextVersion is an aggregation expression bumping the listing's version;
+// listings stored before versioning count as version 0.
+var nextVersion = bson.M{"$add": bson.A{bson.M{"$ifNull": bson.A{"$version", 0}}, 1}}
+
+const day = 24 * time.Hour
+
+// availableSlots is an aggregation expression for capacity minus the slots
+// currently held, never below zero.
+func availableSlots(capacity int) bson.M {
+	held := bson.M{"$size": bson.M{"$ifNull": bson.A{"$holds", bson.A{}}}}
+	return bson.M{"$max": bson.A{0, bson.M{"$subtract": bson.A{capacity, held}}}}
+}
+
+func (r *mongoRepository) Upsert(ctx context.Context, listing *model.Listing) (*model.Listing, error) {
+	now := time.Now()
+	// An update pipeline, so available_slots can be computed from the holds
+	// stored on the document. Values are wrapped in $literal so a title
+	// starting with "$" is not read as a field path.
+	update := mongo.Pipeline{{{Key: "$set", Value: bson.M{
+		"title":           bson.M{"$literal": listing.Title},
+		"destination":     bson.M{"$literal": listing.Destination},
+		"description":     bson.M{"$literal": listing.Description},
+		"price":           bson.M{"$literal": listing.Price},
+		"title_key":       bson.M{"$literal": model.SuggestKey(listing.Title)},
+		"destination_key": bson.M{"$literal": model.SuggestKey(listing.Destination)},
+		"date":            bson.M{"$literal": listing.Date},
+		"start_date":      listing.StartDate,
+		"end_date":        listing.EndDate,
+		"duration_days":   listing.DurationDays,
+		"location":        locationValue(listing.Location),
+		"capacity":        bson.M{"$literal": listing.Capacity},
+		"available_slots": availableSlots(listing.Capacity),
+		"created_at":      bson.M{"$ifNull": bson.A{"$created_at", now}},
+		"updated_at":      listing.UpdatedAt,
+		"version":         nextVersion,
+		"deleted_at":      "$$REMOVE",
+	}}}}
+	opts := options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.Before)
+
+	var previous model.Listing
+	err := r.coll.FindOneAndUpdate(ctx, bson.M{"_id": listing.ID}, update, opts).Decode(&previous)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, nil // Inserted
+		}
+		return nil, fmt.Errorf("failed to upsert listing %s: %w", listing.ID.Hex(), err)
+	}
+	return &previous, nil
+}
+
+// locationValue sets the location in an update pipeline, or unsets it for
+// listings without one.
+func locationValue(location *model.GeoPoint) interface{} {
+	if location == nil {
+		return "$$REMOVE"
+	}
+	return bson.M{"$literal": location}
+}
+
+func (r *mongoRepository) Create(ctx context.Context, listing *model.Listing) error {
+	now := time.Now()
+	listing.ID = primitive.NewObjectID()
+	listing.AvailableSlots = listing.Capacity
+	listing.Version = 1
+	listing.CreatedAt = now
+	listing.UpdatedAt = now
+	listing.DeletedAt = nil
+	listing.Holds = nil
+	listing.SetSuggestKeys()
+
+	if _, err := r.coll.InsertOne(ctx, listing); err != nil {
+		return fmt.Errorf("failed to create listing: %w", err)
+	}
+	return nil
+}
+
+// versionFilter matches listings at version; 0 also matches listings
+// stored before versioning.
+func versionFilter(version int64) interface{} {
+	if version == 0 {
+		return bson.M{"$in": bson.A{0, nil}}
+	}
+	return version
+}
+
+func (r *mongoRepository) Update(ctx context.Context, id string, version int64, patch *model.ListingPatch) (*model.Listing, error) {
+	oid, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return nil, model.ErrListingNotFound
+	}
+
+	// Values are wrapped in $literal, see Upsert
+	set := bson.M{
+		"updated_at": time.Now(),
+		"version":    nextVersion,
+	}
+	if patch.Title != nil {
+		set["title"] = bson.M{"$literal": *patch.Title}
+		set["title_key"] = bson.M{"$literal": model.SuggestKey(*patch.Title)}
+	}
+	if patch.Destination != nil {
+		set["destination"] = bson.M{"$literal": *patch.Destination}
+		set["destination_key"] = bson.M{"$literal": model.SuggestKey(*patch.Destination)}
+	}
+	if patch.Description != nil {
+		set["description"] = bson.M{"$literal": *patch.Description}
+	}
+	if patch.Price != nil {
+		set["price"] = bson.M{"$literal": *patch.Price}
+	}
+	filter := bson.M{"_id": oid, "deleted_at": nil, "version": versionFilter(version)}
+	// Dates were validated with the patch
+	var start, end time.Time
+	if patch.Date != nil {
+		start, _ = model.ParseDate(*patch.Date)
+		set["date"] = bson.M{"$literal": *patch.Date}
+		set["start_date"] = start
+	}
+	if patch.EndDate != nil {
+		end, _ = model.ParseDate(*patch.EndDate)
+		set["end_date"] = end
+	}
+	switch {
+	case patch.Date != nil && patch.EndDate != nil:
+		set["duration_days"] = model.DaysBetween(start, end)
+	case patch.Date != nil:
+		// Moving the start moves the whole trip
+		set["end_date"] = bson.M{"$add": bson.A{start, bson.M{"$multiply": bson.A{bson.M{"$ifNull": bson.A{"$duration_days", 0}}, day.Milliseconds()}}}}
+	case patch.EndDate != nil:
+		set["duration_days"] = bson.M{"$toInt": bson.M{"$divide": bson.A{bson.M{"$subtract": bson.A{end, "$start_date"}}, day.Milliseconds()}}}
+		// The trip must still fit the stored start; checked again below to
+		// tell a bad end date from a version conflict
+		filter["start_date"] = bson.M{"$lte": end, "$gte": end.Add(-model.MaxDurationDays * day)}
+	}
+	if patch.Location != nil {
+		set["location"] = bson.M{"$literal": patch.Location}
+	}
+	if patch.Capacity != nil {
+		set["capacity"] = *patch.Capacity
+		set["available_slots"] = availableSlots(*patch.Capacity)
+	}
+
+	update := mongo.Pipeline{{{Key: "$set", Value: set}}}
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+
+	var listing model.Listing
+	err = r.coll.FindOneAndUpdate(ctx, filter, update, opts).Decode(&listing)
+	if err == nil {
+		return &listing, nil
+	}
+	if !errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, fmt.Errorf("failed to update listing %s: %w", id, err)
+	}
+
+	current, err := r.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if current == nil {
+		return nil, model.ErrListingNotFound
+	}
+	if patch.Date == nil && patch.EndDate != nil {
+		if _, _, err := model.TripDates(current.Date, *patch.EndDate); err != nil {
+			return nil, err
+		}
+	}
+	return nil, model.ErrVersionConflict
+}
+
+func (r *mongoRepository) Delete(ctx context.Context, id string, version int64) (*model.Listing, error) {
+	oid, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return nil, nil // Not found
+	}
+
+	filter := bson.M{"_id": oid, "deleted_at": nil}
+	if version > 0 {
+		filter["version"] = version
+	}
+	now := time.Now()
+	update := bson.M{
+		"$set": bson.M{"deleted_at": now, "updated_at": now},
+		"$inc": bson.M{"version": 1},
+	}
+
+	var previous model.Listing
+	err = r.coll.FindOneAndUpdate(ctx, filter, update).Decode(&previous)
+	if err == nil {
+		return &previous, nil
+	}
+	if !errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, fmt.Errorf("failed to delete listing %s: %w", id, err)
+	}
+
+	if version > 0 {
+		current, err := r.GetByID(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+		if current != nil {
+			return nil, model.ErrVersionConflict
+		}
+	}
+	return nil, nil // Not found or already deleted
+}
+
+func (r *mongoRepository) SetCapacity(ctx context.Context, id string, capacity int) (*model.Listing, error) {
+	oid, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return nil, nil // Not found
+	}
+
+	update := mongo.Pipeline{{{Key: "$set", Value: bson.M{
+		"capacity":        capacity,
+		"available_slots": availableSlots(capacity),
+		"updated_at":      time.Now(),
+		"version":         nextVersion,
+	}}}}
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+
+	var listing model.Listing
+	err = r.coll.FindOneAndUpdate(ctx, bson.M{"_id": oid}, update, opts).Decode(&listing)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, nil // Not found
+		}
+		return nil, fmt.Errorf("failed to set capacity of listing %s: %w", id, err)
+	}
+	return &listing, nil
 }
 
 func (r *mongoRepository) Seed(ctx context.Context) error {
@@ -94,14 +912,24 @@ func (r *mongoRepository) Seed(ctx context.Context) error {
 		return nil // Already seeded
 	}
 
-	listings := []interface{}{
-		model.Listing{Title: "Paris Gateway", Destination: "Paris", Price: 200, Date: "2026-06-01", AvailableSlots: 10, CreatedAt: time.Now(), UpdatedAt: time.Now()},
-		model.Listing{Title: "Tokyo Adventure", Destination: "Tokyo", Price: 300, Date: "2026-07-15", AvailableSlots: 5, CreatedAt: time.Now(), UpdatedAt: time.Now()},
-		model.Listing{Title: "New York City Break", Destination: "New York", Price: 250, Date: "2026-08-20", AvailableSlots: 8, CreatedAt: time.Now(), UpdatedAt: time.Now()},
-		model.Listing{Title: "Bali Retreat", Destination: "Bali", Price: 150, Date: "2026-09-05", AvailableSlots: 12, CreatedAt: time.Now(), UpdatedAt: time.Now()},
-		model.Listing{Title: "London Historical Tour", Destination: "London", Price: 220, Date: "2026-06-10", AvailableSlots: 15, CreatedAt: time.Now(), UpdatedAt: time.Now()},
+	listings := []*model.Listing{
+		{Version: 1, Title: "Paris Gateway", Destination: "Paris", Description: "Three nights in the City of Light with a Seine river cruise and a guided Louvre visit.", Price: 200, Date: "2026-06-01", StartDate: seedDate("2026-06-01"), EndDate: seedDate("2026-06-04"), DurationDays: 3, Location: model.NewGeoPoint(48.8566, 2.3522), AvailableSlots: 10, Capacity: 10, CreatedAt: time.Now(), UpdatedAt: time.Now()},
+		{Version: 1, Title: "Tokyo Adventure", Destination: "Tokyo", Description: "Explore Shibuya and Asakusa, ride the bullet train and join a sushi-making class.", Price: 300, Date: "2026-07-15", StartDate: seedDate("2026-07-15"), EndDate: seedDate("2026-07-22"), DurationDays: 7, Location: model.NewGeoPoint(35.6762, 139.6503), AvailableSlots: 5, Capacity: 5, CreatedAt: time.Now(), UpdatedAt: time.Now()},
+		{Version: 1, Title: "New York City Break", Destination: "New York", Description: "Broadway show tickets, a Central Park bike tour and skyline views from the Top of the Rock.", Price: 250, Date: "2026-08-20", StartDate: seedDate("2026-08-20"), EndDate: seedDate("2026-08-24"), DurationDays: 4, Location: model.NewGeoPoint(40.7128, -74.006), AvailableSlots: 8, Capacity: 8, CreatedAt: time.Now(), UpdatedAt: time.Now()},
+		{Version: 1, Title: "Bali Retreat", Destination: "Bali", Description: "Beachfront villa stay with daily yoga, rice terrace walks and a traditional spa treatment.", Price: 150, Date: "2026-09-05", StartDate: seedDate("2026-09-05"), EndDate: seedDate("2026-09-11"), DurationDays: 6, Location: model.NewGeoPoint(-8.4095, 115.1889), AvailableSlots: 12, Capacity: 12, CreatedAt: time.Now(), UpdatedAt: time.Now()},
+		{Version: 1, Title: "London Historical Tour", Destination: "London", Description: "Walk through centuries of history at the Tower of London, Westminster Abbey and the British Museum.", Price: 220, Date: "2026-06-10", StartDate: seedDate("2026-06-10"), EndDate: seedDate("2026-06-12"), DurationDays: 2, Location: model.NewGeoPoint(51.5074, -0.1278), AvailableSlots: 15, Capacity: 15, CreatedAt: time.Now(), UpdatedAt: time.Now()},
 	}
 
-	_, err := r.coll.InsertMany(ctx, listings)
+	docs := make([]interface{}, len(listings))
+	for i, l := range listings {
+		l.SetSuggestKeys()
+		docs[i] = l
+	}
+	_, err := r.coll.InsertMany(ctx, docs)
 	return err
 }
+
+func seedDate(s string) time.Time {
+	t, _ := model.ParseDate(s)
+	return t
+}
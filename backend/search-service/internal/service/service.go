@@ -2,26 +2,224 @@ package service
 
 import (
 	"context"
+	"fmt"
+	"log"
+	"time"
 
+	"github.com/gavinadlan/tripnest/backend/search-service/internal/events"
 	"github.com/gavinadlan/tripnest/backend/search-service/internal/model"
 	"github.com/gavinadlan/tripnest/backend/search-service/internal/repository"
 )
 
 type SearchService interface {
-	SearchListings(ctx context.Context, params *model.SearchParams) ([]*model.Listing, int64, error)
+	SearchListings(ctx context.Context, params *model.SearchParams) (*model.SearchResult, error)
+	GetListing(ctx context.Context, id string) (*model.Listing, error)
+	// Suggest completes a prefix typed into the search box, ignoring case
+	// and accents.
+	Suggest(ctx context.Context, prefix string, limit int) (*model.Suggestions, error)
+	// CreateListing, ReplaceListing, PatchListing and DeleteListing manage
+	// the catalog. Writes to an existing listing take the version the caller
+	// last read and fail with model.ErrVersionConflict if it has changed.
+	CreateListing(ctx context.Context, in *model.ListingInput) (*model.Listing, error)
+	ReplaceListing(ctx context.Context, id string, version int64, in *model.ListingInput) (*model.Listing, error)
+	PatchListing(ctx context.Context, id string, version int64, patch *model.ListingPatch) (*model.Listing, error)
+	DeleteListing(ctx context.Context, id string, version int64) error
+	// HoldSlot reserves one slot of the listing for a booking.
+	HoldSlot(ctx context.Context, listingID, holdID string) (*model.Listing, error)
+	// ReleaseSlot returns the slot held for a booking; releasing twice is a
+	// no-op.
+	ReleaseSlot(ctx context.Context, listingID, holdID, reason string) error
+	// ApplyListingUpserted, ApplyListingDeleted and ApplyInventoryChanged
+	// keep the index in step with the catalog events of the same names.
+	// Each is idempotent, so redelivered events are harmless.
+	ApplyListingUpserted(ctx context.Context, event *model.ListingUpsertedEvent) error
+	ApplyListingDeleted(ctx context.Context, event *model.ListingDeletedEvent) error
+	ApplyInventoryChanged(ctx context.Context, event *model.InventoryChangedEvent) error
 	SeedListings(ctx context.Context) error
 }
 
 type searchService struct {
-	repo repository.ListingRepository
+	repo     repository.ListingRepository
+	producer events.Producer
 }
 
-func NewSearchService(repo repository.ListingRepository) SearchService {
-	return &searchService{repo: repo}
+func NewSearchService(repo repository.ListingRepository, producer events.Producer) SearchService {
+	return &searchService{repo: repo, producer: producer}
 }
 
-func (s *searchService) SearchListings(ctx context.Context, params *model.SearchParams) ([]*model.Listing, int64, error) {
-	return s.repo.Search(ctx, params)
+func (s *searchService) SearchListings(ctx context.Context, params *model.SearchParams) (*model.SearchResult, error) {
+	result, err := s.repo.Search(ctx, params)
+	if err != nil {
+		return nil, err
+	}
+
+	// Paging through results is not a new search
+	if params.Page == 1 && params.Cursor == nil && !params.Untracked {
+		s.publishSearch(ctx, params, result.Total)
+	}
+	return result, nil
+}
+
+func (s *searchService) publishSearch(ctx context.Context, params *model.SearchParams, total int64) {
+	event := model.SearchPerformedEvent{
+		UserID:      params.UserID,
+		Query:       params.Query,
+		Destination: params.Destination,
+		MinPrice:    params.MinPrice,
+		MaxPrice:    params.MaxPrice,
+		Date:        formatDate(params.Date),
+		From:        formatDate(params.From),
+		To:          formatDate(params.To),
+		ResultCount: total,
+		SearchedAt:  time.Now().UTC(),
+	}
+	if err := s.producer.Publish(ctx, "search.performed", params.UserID, event); err != nil {
+		log.Printf("Failed to publish search.performed: %v", err)
+	}
+}
+
+func formatDate(t *time.Time) string {
+	if t == nil {
+		return ""
+	}
+	return t.Format(model.DateLayout)
+}
+
+func (s *searchService) Suggest(ctx context.Context, prefix string, limit int) (*model.Suggestions, error) {
+	key := model.SuggestKey(prefix)
+	if key == "" {
+		return &model.Suggestions{Destinations: []model.DestinationSuggestion{}, Listings: []model.ListingSuggestion{}}, nil
+	}
+	return s.repo.Suggest(ctx, key, limit)
+}
+
+func (s *searchService) GetListing(ctx context.Context, id string) (*model.Listing, error) {
+	return s.repo.GetByID(ctx, id)
+}
+
+func (s *searchService) CreateListing(ctx context.Context, in *model.ListingInput) (*model.Listing, error) {
+	if err := in.Validate(); err != nil {
+		return nil, err
+	}
+	start, end, err := model.TripDates(in.Date, in.EndDate)
+	if err != nil {
+		return nil, err
+	}
+	listing := &model.Listing{
+		Title:       in.Title,
+		Destination: in.Destination,
+		Description: in.Description,
+		Price:       in.Price,
+		Location:    in.Location,
+		Capacity:    *in.Capacity,
+	}
+	listing.SetDates(start, end)
+	if err := s.repo.Create(ctx, listing); err != nil {
+		return nil, err
+	}
+	log.Printf("Created listing %s", listing.ID.Hex())
+	return listing, nil
+}
+
+func (s *searchService) ReplaceListing(ctx context.Context, id string, version int64, in *model.ListingInput) (*model.Listing, error) {
+	if err := in.Validate(); err != nil {
+		return nil, err
+	}
+	return s.repo.Update(ctx, id, version, in.Patch())
+}
+
+func (s *searchService) PatchListing(ctx context.Context, id string, version int64, patch *model.ListingPatch) (*model.Listing, error) {
+	if err := patch.Validate(); err != nil {
+		return nil, err
+	}
+	return s.repo.Update(ctx, id, version, patch)
+}
+
+func (s *searchService) DeleteListing(ctx context.Context, id string, version int64) error {
+	previous, err := s.repo.Delete(ctx, id, version)
+	if err != nil {
+		return err
+	}
+	if previous == nil {
+		return model.ErrListingNotFound
+	}
+	log.Printf("Deleted listing %s", id)
+	return nil
+}
+
+func (s *searchService) HoldSlot(ctx context.Context, listingID, holdID string) (*model.Listing, error) {
+	listing, err := s.repo.Reserve(ctx, listingID, holdID)
+	if err != nil {
+		return nil, err
+	}
+	log.Printf("Hold %s on listing %s, %d slot(s) left", holdID, listingID, listing.AvailableSlots)
+	return listing, nil
+}
+
+func (s *searchService) ReleaseSlot(ctx context.Context, listingID, holdID, reason string) error {
+	listing, err := s.repo.Release(ctx, listingID, holdID)
+	if err != nil {
+		return err
+	}
+	if listing != nil {
+		log.Printf("Released hold %s on listing %s (%s)", holdID, listingID, reason)
+	} else {
+		log.Printf("No hold %s on listing %s to release, ignoring", holdID, listingID)
+	}
+	return nil
+}
+
+func (s *searchService) ApplyListingUpserted(ctx context.Context, event *model.ListingUpsertedEvent) error {
+	listing, err := event.Listing()
+	if err != nil {
+		return fmt.Errorf("invalid listing %q: %w", event.ID, err)
+	}
+	if listing.Capacity < 0 {
+		return fmt.Errorf("invalid capacity %d for listing %s", listing.Capacity, event.ID)
+	}
+
+	previous, err := s.repo.Upsert(ctx, listing)
+	if err != nil {
+		return err
+	}
+	if previous == nil {
+		log.Printf("Indexed new listing %s", event.ID)
+	} else {
+		log.Printf("Updated listing %s", event.ID)
+	}
+	return nil
+}
+
+func (s *searchService) ApplyListingDeleted(ctx context.Context, event *model.ListingDeletedEvent) error {
+	previous, err := s.repo.Delete(ctx, event.ID, 0)
+	if err != nil {
+		return err
+	}
+	if previous == nil {
+		log.Printf("Listing %s already removed, ignoring", event.ID)
+		return nil
+	}
+	if len(previous.Holds) > 0 {
+		log.Printf("Removed listing %s with %d slot(s) still held", event.ID, len(previous.Holds))
+	} else {
+		log.Printf("Removed listing %s", event.ID)
+	}
+	return nil
+}
+
+func (s *searchService) ApplyInventoryChanged(ctx context.Context, event *model.InventoryChangedEvent) error {
+	if event.Capacity < 0 {
+		return fmt.Errorf("invalid capacity %d for listing %s", event.Capacity, event.ListingID)
+	}
+	listing, err := s.repo.SetCapacity(ctx, event.ListingID, event.Capacity)
+	if err != nil {
+		return err
+	}
+	if listing == nil {
+		return model.ErrListingNotFound
+	}
+	log.Printf("Capacity of listing %s set to %d, %d slot(s) available", event.ListingID, event.Capacity, listing.AvailableSlots)
+	return nil
 }
 
 func (s *searchService) SeedListings(ctx context.Context) error {
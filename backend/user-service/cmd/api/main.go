@@ -13,13 +13,13 @@ import (
 
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/chi/v5/middleware"
-	"github.com/go-chi/cors"
 
 	"github.com/gavinadlan/tripnest/backend/user-service/internal/config"
 	"github.com/gavinadlan/tripnest/backend/user-service/internal/db"
 	"github.com/gavinadlan/tripnest/backend/user-service/internal/handler"
 	"github.com/gavinadlan/tripnest/backend/user-service/internal/repository"
 	"github.com/gavinadlan/tripnest/backend/user-service/internal/service"
+	"github.com/gavinadlan/tripnest/backend/user-service/internal/signing"
 )
 
 func main() {
@@ -34,29 +34,20 @@ func main() {
 	}
 	defer repo.Close()
 
-	svc := service.NewUserService(repo, cfg)
-	h := handler.NewHandler(svc)
+	keys, err := signing.LoadKeyRing(cfg.JWTKeyFiles, cfg.JWTKeysPEM, cfg.JWTActiveKID)
+	if err != nil {
+		log.Fatalf("Failed to load JWT signing keys: %v", err)
+	}
+	log.Printf("Signing access tokens with key %s", keys.ActiveKeyID())
 
-	r := chi.NewRouter()
-	// CORS Setup
-	r.Use(cors.Handler(cors.Options{
-		AllowedOrigins:   []string{"http://localhost:3000"}, // Frontend URL
-		AllowedMethods:   []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"},
-		AllowedHeaders:   []string{"Accept", "Authorization", "Content-Type", "X-CSRF-Token"},
-		ExposedHeaders:   []string{"Link"},
-		AllowCredentials: true,
-		MaxAge:           300,
-	}))
+	svc := service.NewUserService(repo, cfg, keys)
+	h := handler.NewHandler(svc, keys, cfg.ServiceToken)
 
+	r := chi.NewRouter()
 	r.Use(middleware.Logger)
 	r.Use(middleware.Recoverer)
 
-	// Route Grouping
-	r.Group(func(r chi.Router) {
-		r.Post("/register", h.Register)
-		r.Post("/login", h.Login)
-		r.Get("/health", h.Health)
-	})
+	h.RegisterRoutes(r)
 
 	server := &http.Server{
 		Addr:    ":" + cfg.Port,
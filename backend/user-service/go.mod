@@ -5,7 +5,6 @@ go 1.23.4
 require (
 	github.com/gavinadlan/tripnest/backend/common v0.0.0
 	github.com/go-chi/chi/v5 v5.2.5
-	github.com/go-chi/cors v1.2.2
 	github.com/golang-jwt/jwt/v5 v5.3.1
 	github.com/golang-migrate/migrate/v4 v4.18.1
 	github.com/jackc/pgx/v5 v5.7.2
@@ -1,26 +1,45 @@
 package handler
 
 import (
+	"errors"
 	"net/http"
 
+	"github.com/gavinadlan/tripnest/backend/common/auth"
 	"github.com/gavinadlan/tripnest/backend/common/utils"
 	"github.com/gavinadlan/tripnest/backend/user-service/internal/model"
 	"github.com/gavinadlan/tripnest/backend/user-service/internal/service"
+	"github.com/gavinadlan/tripnest/backend/user-service/internal/signing"
 	"github.com/go-chi/chi/v5"
 )
 
 type Handler struct {
-	svc service.UserService
+	svc          service.UserService
+	keys         *signing.KeyRing
+	verifier     auth.Verifier
+	serviceToken string
 }
 
-func NewHandler(svc service.UserService) *Handler {
-	return &Handler{svc: svc}
+func NewHandler(svc service.UserService, keys *signing.KeyRing, serviceToken string) *Handler {
+	return &Handler{
+		svc:          svc,
+		keys:         keys,
+		verifier:     auth.NewStaticVerifier(keys.PublicKeys(), "user-service"),
+		serviceToken: serviceToken,
+	}
 }
 
 func (h *Handler) RegisterRoutes(r chi.Router) {
 	r.Post("/register", h.Register)
 	r.Post("/login", h.Login)
+	r.Post("/token/refresh", h.Refresh)
+	r.Post("/logout", h.Logout)
 	r.Get("/health", h.Health)
+	r.Get("/.well-known/jwks.json", h.JWKS)
+
+	r.With(auth.Authenticate(h.verifier)).Post("/logout/all", h.LogoutAll)
+
+	// Service-to-service lookups, e.g. notification-service resolving contacts
+	r.With(auth.RequireServiceToken(h.serviceToken)).Get("/internal/users/{id}", h.GetUser)
 }
 
 func (h *Handler) Register(w http.ResponseWriter, r *http.Request) {
@@ -55,6 +74,85 @@ func (h *Handler) Login(w http.ResponseWriter, r *http.Request) {
 	utils.WriteJSON(w, http.StatusOK, resp)
 }
 
+func (h *Handler) Refresh(w http.ResponseWriter, r *http.Request) {
+	var req model.RefreshTokenRequest
+	if err := utils.ReadJSON(r, &req); err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+	if req.RefreshToken == "" {
+		utils.WriteError(w, http.StatusBadRequest, errors.New("missing refresh_token"))
+		return
+	}
+
+	resp, err := h.svc.Refresh(r.Context(), req.RefreshToken)
+	if err != nil {
+		writeTokenError(w, err)
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, resp)
+}
+
+func (h *Handler) Logout(w http.ResponseWriter, r *http.Request) {
+	var req model.RefreshTokenRequest
+	if err := utils.ReadJSON(r, &req); err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+	if req.RefreshToken == "" {
+		utils.WriteError(w, http.StatusBadRequest, errors.New("missing refresh_token"))
+		return
+	}
+
+	if err := h.svc.Logout(r.Context(), req.RefreshToken); err != nil {
+		writeTokenError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// LogoutAll revokes every refresh token of the authenticated user, logging
+// them out on all devices.
+func (h *Handler) LogoutAll(w http.ResponseWriter, r *http.Request) {
+	principal, _ := auth.PrincipalFromContext(r.Context())
+	if err := h.svc.LogoutAll(r.Context(), principal.UserID); err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *Handler) GetUser(w http.ResponseWriter, r *http.Request) {
+	user, err := h.svc.GetUser(r.Context(), chi.URLParam(r, "id"))
+	if err != nil {
+		if errors.Is(err, service.ErrUserNotFound) {
+			utils.WriteError(w, http.StatusNotFound, err)
+			return
+		}
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, user)
+}
+
+func writeTokenError(w http.ResponseWriter, err error) {
+	if errors.Is(err, service.ErrInvalidRefreshToken) {
+		utils.WriteError(w, http.StatusUnauthorized, err)
+		return
+	}
+	utils.WriteError(w, http.StatusInternalServerError, err)
+}
+
+// JWKS publishes the public keys other services use to verify access tokens.
+func (h *Handler) JWKS(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Cache-Control", "public, max-age=300")
+	utils.WriteJSON(w, http.StatusOK, h.keys.JWKS())
+}
+
 func (h *Handler) Health(w http.ResponseWriter, r *http.Request) {
 	w.WriteHeader(http.StatusOK)
 	w.Write([]byte("OK"))
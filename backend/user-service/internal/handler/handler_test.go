@@ -0,0 +1,117 @@
+package handler
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gavinadlan/tripnest/backend/user-service/internal/model"
+	"github.com/gavinadlan/tripnest/backend/user-service/internal/service"
+	"github.com/gavinadlan/tripnest/backend/user-service/internal/signing"
+	"github.com/go-chi/chi/v5"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// stubService records the sessions logged out. Only "valid" is a known
+// refresh token.
+type stubService struct {
+	service.UserService
+	loggedOut    []string
+	loggedOutAll []string
+}
+
+func (s *stubService) Logout(ctx context.Context, refreshToken string) error {
+	if refreshToken != "valid" {
+		return service.ErrInvalidRefreshToken
+	}
+	s.loggedOut = append(s.loggedOut, refreshToken)
+	return nil
+}
+
+func (s *stubService) LogoutAll(ctx context.Context, userID string) error {
+	s.loggedOutAll = append(s.loggedOutAll, userID)
+	return nil
+}
+
+func newTestRouter(t *testing.T) (http.Handler, *stubService, *signing.KeyRing) {
+	t.Helper()
+	keys, err := signing.LoadKeyRing(nil, "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	svc := &stubService{}
+	r := chi.NewRouter()
+	NewHandler(svc, keys, "").RegisterRoutes(r)
+	return r, svc, keys
+}
+
+func accessToken(t *testing.T, keys *signing.KeyRing, userID string, expiresIn time.Duration) string {
+	t.Helper()
+	token, err := keys.Sign(model.Claims{
+		UserID: userID,
+		Role:   "user",
+		RegisteredClaims: jwt.RegisteredClaims{
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(expiresIn)),
+			Issuer:    "user-service",
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return token
+}
+
+func TestLogout(t *testing.T) {
+	router, svc, _ := newTestRouter(t)
+	cases := []struct {
+		body string
+		want int
+	}{
+		{`{"refresh_token":"valid"}`, http.StatusNoContent},
+		{`{"refresh_token":"unknown"}`, http.StatusUnauthorized},
+		{`{"refresh_token":""}`, http.StatusBadRequest},
+		{`{}`, http.StatusBadRequest},
+		{`not json`, http.StatusBadRequest},
+	}
+	for _, tc := range cases {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/logout", strings.NewReader(tc.body)))
+		if w.Code != tc.want {
+			t.Errorf("POST /logout %s: status %d, want %d", tc.body, w.Code, tc.want)
+		}
+	}
+	if len(svc.loggedOut) != 1 {
+		t.Fatalf("logged out %v, want only the valid token", svc.loggedOut)
+	}
+}
+
+func TestLogoutAll(t *testing.T) {
+	router, svc, keys := newTestRouter(t)
+	cases := []struct {
+		name   string
+		header string
+		want   int
+	}{
+		{"signed in", "Bearer " + accessToken(t, keys, "u1", time.Minute), http.StatusNoContent},
+		{"no token", "", http.StatusUnauthorized},
+		{"expired token", "Bearer " + accessToken(t, keys, "u1", -time.Minute), http.StatusUnauthorized},
+		{"malformed token", "Bearer not.a.jwt", http.StatusUnauthorized},
+	}
+	for _, tc := range cases {
+		req := httptest.NewRequest(http.MethodPost, "/logout/all", nil)
+		if tc.header != "" {
+			req.Header.Set("Authorization", tc.header)
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		if w.Code != tc.want {
+			t.Errorf("%s: status %d, want %d", tc.name, w.Code, tc.want)
+		}
+	}
+	if len(svc.loggedOutAll) != 1 || svc.loggedOutAll[0] != "u1" {
+		t.Fatalf("logged out everywhere %v, want only u1", svc.loggedOutAll)
+	}
+}
@@ -1,9 +1,6 @@
 package model
 
-import "github.com/golang-jwt/jwt/v5"
+import "github.com/gavinadlan/tripnest/backend/common/auth"
 
-type Claims struct {
-	UserID string `json:"user_id"`
-	Role   string `json:"role"`
-	jwt.RegisteredClaims
-}
+// Claims is shared with the services that verify user-service tokens.
+type Claims = auth.Claims
@@ -0,0 +1,107 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/gavinadlan/tripnest/backend/user-service/internal/model"
+	"github.com/jackc/pgx/v5"
+)
+
+// ErrTokenAlreadyRotated is returned by RotateRefreshToken when the token was
+// revoked or rotated concurrently, i.e. it is being reused.
+var ErrTokenAlreadyRotated = errors.New("refresh token already rotated")
+
+type TokenRepository interface {
+	CreateRefreshToken(ctx context.Context, t *model.RefreshToken) error
+	GetRefreshTokenByHash(ctx context.Context, hash string) (*model.RefreshToken, error)
+	// RotateRefreshToken revokes current and stores next in its place in one
+	// transaction. It fails with ErrTokenAlreadyRotated if current is no
+	// longer active.
+	RotateRefreshToken(ctx context.Context, current, next *model.RefreshToken) error
+	RevokeTokenFamily(ctx context.Context, familyID string) error
+	RevokeUserTokens(ctx context.Context, userID string) error
+}
+
+func (r *postgresRepository) CreateRefreshToken(ctx context.Context, t *model.RefreshToken) error {
+	return insertRefreshToken(ctx, r.db, t)
+}
+
+type queryRower interface {
+	QueryRow(ctx context.Context, sql string, args ...any) pgx.Row
+}
+
+func insertRefreshToken(ctx context.Context, db queryRower, t *model.RefreshToken) error {
+	query := `
+        INSERT INTO refresh_tokens (user_id, family_id, token_hash, expires_at)
+        VALUES ($1, $2, $3, $4)
+        RETURNING id, created_at`
+
+	err := db.QueryRow(ctx, query, t.UserID, t.FamilyID, t.TokenHash, t.ExpiresAt).Scan(&t.ID, &t.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to create refresh token: %w", err)
+	}
+	return nil
+}
+
+func (r *postgresRepository) GetRefreshTokenByHash(ctx context.Context, hash string) (*model.RefreshToken, error) {
+	query := `
+        SELECT id, user_id, family_id, token_hash, expires_at, revoked_at, replaced_by, created_at
+        FROM refresh_tokens WHERE token_hash = $1`
+
+	var t model.RefreshToken
+	err := r.db.QueryRow(ctx, query, hash).Scan(
+		&t.ID, &t.UserID, &t.FamilyID, &t.TokenHash, &t.ExpiresAt, &t.RevokedAt, &t.ReplacedBy, &t.CreatedAt,
+	)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get refresh token: %w", err)
+	}
+	return &t, nil
+}
+
+func (r *postgresRepository) RotateRefreshToken(ctx context.Context, current, next *model.RefreshToken) error {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	if err := insertRefreshToken(ctx, tx, next); err != nil {
+		return err
+	}
+
+	tag, err := tx.Exec(ctx, `
+        UPDATE refresh_tokens SET revoked_at = NOW(), replaced_by = $1
+        WHERE id = $2 AND revoked_at IS NULL`, next.ID, current.ID)
+	if err != nil {
+		return fmt.Errorf("failed to revoke refresh token: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrTokenAlreadyRotated
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit token rotation: %w", err)
+	}
+	return nil
+}
+
+func (r *postgresRepository) RevokeTokenFamily(ctx context.Context, familyID string) error {
+	_, err := r.db.Exec(ctx, `UPDATE refresh_tokens SET revoked_at = NOW() WHERE family_id = $1 AND revoked_at IS NULL`, familyID)
+	if err != nil {
+		return fmt.Errorf("failed to revoke token family: %w", err)
+	}
+	return nil
+}
+
+func (r *postgresRepository) RevokeUserTokens(ctx context.Context, userID string) error {
+	_, err := r.db.Exec(ctx, `UPDATE refresh_tokens SET revoked_at = NOW() WHERE user_id = $1 AND revoked_at IS NULL`, userID)
+	if err != nil {
+		return fmt.Errorf("failed to revoke user tokens: %w", err)
+	}
+	return nil
+}
@@ -2,7 +2,13 @@ package service
 
 import (
 	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
 	"errors"
+	"fmt"
+	"log"
 	"time"
 
 	"github.com/golang-jwt/jwt/v5"
@@ -11,20 +17,31 @@ import (
 	"github.com/gavinadlan/tripnest/backend/user-service/internal/config"
 	"github.com/gavinadlan/tripnest/backend/user-service/internal/model"
 	"github.com/gavinadlan/tripnest/backend/user-service/internal/repository"
+	"github.com/gavinadlan/tripnest/backend/user-service/internal/signing"
+)
+
+var (
+	ErrInvalidRefreshToken = errors.New("invalid refresh token")
+	ErrUserNotFound        = errors.New("user not found")
 )
 
 type UserService interface {
 	Register(ctx context.Context, req *model.RegisterUserRequest) (*model.User, error)
 	Login(ctx context.Context, req *model.LoginUserRequest) (*model.AuthResponse, error)
+	Refresh(ctx context.Context, refreshToken string) (*model.TokenResponse, error)
+	Logout(ctx context.Context, refreshToken string) error
+	LogoutAll(ctx context.Context, userID string) error
+	GetUser(ctx context.Context, id string) (*model.User, error)
 }
 
 type userService struct {
 	repo repository.UserRepository
 	cfg  *config.Config
+	keys *signing.KeyRing
 }
 
-func NewUserService(repo repository.UserRepository, cfg *config.Config) UserService {
-	return &userService{repo: repo, cfg: cfg}
+func NewUserService(repo repository.UserRepository, cfg *config.Config, keys *signing.KeyRing) UserService {
+	return &userService{repo: repo, cfg: cfg, keys: keys}
 }
 
 func (s *userService) Register(ctx context.Context, req *model.RegisterUserRequest) (*model.User, error) {
@@ -72,24 +89,168 @@ func (s *userService) Login(ctx context.Context, req *model.LoginUserRequest) (*
 		return nil, errors.New("invalid credentials")
 	}
 
+	accessToken, err := s.issueAccessToken(user)
+	if err != nil {
+		return nil, err
+	}
+
+	familyID, err := newTokenID()
+	if err != nil {
+		return nil, err
+	}
+	refreshToken, err := s.issueRefreshToken(ctx, user.ID, familyID)
+	if err != nil {
+		return nil, err
+	}
+
+	user.Password = ""
+	return &model.AuthResponse{
+		Token:        accessToken,
+		RefreshToken: refreshToken,
+		ExpiresIn:    int64(s.cfg.JWTExpiresIn.Seconds()),
+		User:         *user,
+	}, nil
+}
+
+// Refresh exchanges a refresh token for a new access token and a new refresh
+// token in the same family. Presenting a token that was already rotated means
+// it was stolen or replayed, so the whole family is revoked.
+func (s *userService) Refresh(ctx context.Context, refreshToken string) (*model.TokenResponse, error) {
+	current, err := s.repo.GetRefreshTokenByHash(ctx, hashToken(refreshToken))
+	if err != nil {
+		return nil, err
+	}
+	if current == nil {
+		return nil, ErrInvalidRefreshToken
+	}
+	if current.RevokedAt != nil {
+		return nil, s.revokeReusedFamily(ctx, current)
+	}
+	if time.Now().After(current.ExpiresAt) {
+		return nil, ErrInvalidRefreshToken
+	}
+
+	user, err := s.repo.GetByID(ctx, current.UserID)
+	if err != nil {
+		return nil, err
+	}
+	if user == nil {
+		return nil, ErrInvalidRefreshToken
+	}
+
+	raw, next, err := s.newRefreshToken(user.ID, current.FamilyID)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.repo.RotateRefreshToken(ctx, current, next); err != nil {
+		if errors.Is(err, repository.ErrTokenAlreadyRotated) {
+			return nil, s.revokeReusedFamily(ctx, current)
+		}
+		return nil, err
+	}
+
+	accessToken, err := s.issueAccessToken(user)
+	if err != nil {
+		return nil, err
+	}
+
+	return &model.TokenResponse{
+		Token:        accessToken,
+		RefreshToken: raw,
+		ExpiresIn:    int64(s.cfg.JWTExpiresIn.Seconds()),
+	}, nil
+}
+
+func (s *userService) revokeReusedFamily(ctx context.Context, t *model.RefreshToken) error {
+	log.Printf("Refresh token reuse detected for user %s, revoking token family %s", t.UserID, t.FamilyID)
+	if err := s.repo.RevokeTokenFamily(ctx, t.FamilyID); err != nil {
+		return err
+	}
+	return ErrInvalidRefreshToken
+}
+
+// Logout revokes the session (token family) the refresh token belongs to.
+func (s *userService) Logout(ctx context.Context, refreshToken string) error {
+	current, err := s.repo.GetRefreshTokenByHash(ctx, hashToken(refreshToken))
+	if err != nil {
+		return err
+	}
+	if current == nil {
+		return ErrInvalidRefreshToken
+	}
+	return s.repo.RevokeTokenFamily(ctx, current.FamilyID)
+}
+
+// LogoutAll revokes every session of the user. Access tokens already issued
+// stay valid until they expire, which is why they are short-lived.
+func (s *userService) LogoutAll(ctx context.Context, userID string) error {
+	return s.repo.RevokeUserTokens(ctx, userID)
+}
+
+func (s *userService) GetUser(ctx context.Context, id string) (*model.User, error) {
+	user, err := s.repo.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if user == nil {
+		return nil, ErrUserNotFound
+	}
+	return user, nil
+}
+
+func (s *userService) issueAccessToken(user *model.User) (string, error) {
 	claims := model.Claims{
 		UserID: user.ID,
 		Role:   user.Role,
 		RegisteredClaims: jwt.RegisteredClaims{
 			ExpiresAt: jwt.NewNumericDate(time.Now().Add(s.cfg.JWTExpiresIn)),
+			IssuedAt:  jwt.NewNumericDate(time.Now()),
 			Issuer:    "user-service",
 		},
 	}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	tokenString, err := token.SignedString([]byte(s.cfg.JWTSecret))
+	return s.keys.Sign(claims)
+}
+
+func (s *userService) issueRefreshToken(ctx context.Context, userID, familyID string) (string, error) {
+	raw, t, err := s.newRefreshToken(userID, familyID)
 	if err != nil {
-		return nil, err
+		return "", err
+	}
+	if err := s.repo.CreateRefreshToken(ctx, t); err != nil {
+		return "", err
 	}
+	return raw, nil
+}
 
-	user.Password = ""
-	return &model.AuthResponse{
-		Token: tokenString,
-		User:  *user,
+// newRefreshToken generates a random refresh token, returning the raw value
+// for the client and the record (holding only its hash) to store.
+func (s *userService) newRefreshToken(userID, familyID string) (string, *model.RefreshToken, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", nil, err
+	}
+	raw := base64.RawURLEncoding.EncodeToString(buf)
+	return raw, &model.RefreshToken{
+		UserID:    userID,
+		FamilyID:  familyID,
+		TokenHash: hashToken(raw),
+		ExpiresAt: time.Now().Add(s.cfg.RefreshTokenTTL),
 	}, nil
 }
+
+func hashToken(raw string) string {
+	sum := sha256.Sum256([]byte(raw))
+	return hex.EncodeToString(sum[:])
+}
+
+// newTokenID returns a random UUID used to group a login's refresh tokens.
+func newTokenID() (string, error) {
+	var b [16]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		return "", err
+	}
+	b[6] = (b[6] & 0x0f) | 0x40
+	b[8] = (b[8] & 0x3f) | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:16]), nil
+}
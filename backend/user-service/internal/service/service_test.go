@@ -0,0 +1,327 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+
+	"github.com/gavinadlan/tripnest/backend/common/auth"
+	"github.com/gavinadlan/tripnest/backend/user-service/internal/config"
+	"github.com/gavinadlan/tripnest/backend/user-service/internal/model"
+	"github.com/gavinadlan/tripnest/backend/user-service/internal/repository"
+	"github.com/gavinadlan/tripnest/backend/user-service/internal/signing"
+)
+
+// fakeRepo keeps users and refresh tokens in memory with the semantics of the
+// postgres repository.
+type fakeRepo struct {
+	mu     sync.Mutex
+	users  map[string]*model.User
+	tokens map[string]*model.RefreshToken // By hash
+	nextID int
+
+	// beforeRotate runs inside RotateRefreshToken, e.g. to lose a race.
+	beforeRotate func(current *model.RefreshToken)
+}
+
+func newFakeRepo() *fakeRepo {
+	return &fakeRepo{users: make(map[string]*model.User), tokens: make(map[string]*model.RefreshToken)}
+}
+
+func (r *fakeRepo) Create(ctx context.Context, user *model.User) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.nextID++
+	user.ID = fmt.Sprintf("user-%d", r.nextID)
+	stored := *user
+	r.users[user.ID] = &stored
+	return nil
+}
+
+func (r *fakeRepo) GetByEmail(ctx context.Context, email string) (*model.User, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, u := range r.users {
+		if u.Email == email {
+			found := *u
+			return &found, nil
+		}
+	}
+	return nil, nil
+}
+
+func (r *fakeRepo) GetByID(ctx context.Context, id string) (*model.User, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	u, ok := r.users[id]
+	if !ok {
+		return nil, nil
+	}
+	found := *u
+	found.Password = ""
+	return &found, nil
+}
+
+func (r *fakeRepo) CreateRefreshToken(ctx context.Context, t *model.RefreshToken) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.insert(t)
+	return nil
+}
+
+func (r *fakeRepo) insert(t *model.RefreshToken) {
+	r.nextID++
+	t.ID = fmt.Sprintf("rt-%d", r.nextID)
+	t.CreatedAt = time.Now()
+	stored := *t
+	r.tokens[t.TokenHash] = &stored
+}
+
+func (r *fakeRepo) GetRefreshTokenByHash(ctx context.Context, hash string) (*model.RefreshToken, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	t, ok := r.tokens[hash]
+	if !ok {
+		return nil, nil
+	}
+	found := *t
+	return &found, nil
+}
+
+func (r *fakeRepo) RotateRefreshToken(ctx context.Context, current, next *model.RefreshToken) error {
+	if r.beforeRotate != nil {
+		r.beforeRotate(current)
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	stored := r.tokens[current.TokenHash]
+	if stored.RevokedAt != nil {
+		return repository.ErrTokenAlreadyRotated
+	}
+	r.insert(next)
+	now := time.Now()
+	stored.RevokedAt = &now
+	stored.ReplacedBy = &next.ID
+	return nil
+}
+
+func (r *fakeRepo) RevokeTokenFamily(ctx context.Context, familyID string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.revoke(func(t *model.RefreshToken) bool { return t.FamilyID == familyID })
+	return nil
+}
+
+func (r *fakeRepo) RevokeUserTokens(ctx context.Context, userID string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.revoke(func(t *model.RefreshToken) bool { return t.UserID == userID })
+	return nil
+}
+
+func (r *fakeRepo) revoke(match func(*model.RefreshToken) bool) {
+	now := time.Now()
+	for _, t := range r.tokens {
+		if match(t) && t.RevokedAt == nil {
+			t.RevokedAt = &now
+		}
+	}
+}
+
+func (r *fakeRepo) Close() {}
+
+// token returns the stored record of a raw refresh token.
+func (r *fakeRepo) token(t *testing.T, raw string) model.RefreshToken {
+	t.Helper()
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	stored, ok := r.tokens[hashToken(raw)]
+	if !ok {
+		t.Fatalf("refresh token %q not stored", raw)
+	}
+	return *stored
+}
+
+const password = "correct horse"
+
+// newTestService returns a service with one registered user, ada@example.com.
+func newTestService(t *testing.T) (*userService, *fakeRepo) {
+	t.Helper()
+	keys, err := signing.LoadKeyRing(nil, "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	repo := newFakeRepo()
+	hashed, _ := bcrypt.GenerateFromPassword([]byte(password), bcrypt.MinCost)
+	repo.Create(context.Background(), &model.User{Email: "ada@example.com", Password: string(hashed), Role: "user"})
+
+	cfg := &config.Config{JWTExpiresIn: time.Minute, RefreshTokenTTL: time.Hour}
+	return NewUserService(repo, cfg, keys).(*userService), repo
+}
+
+func login(t *testing.T, svc *userService) *model.AuthResponse {
+	t.Helper()
+	resp, err := svc.Login(context.Background(), &model.LoginUserRequest{Email: "ada@example.com", Password: password})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return resp
+}
+
+func TestRefreshRotatesToken(t *testing.T) {
+	svc, repo := newTestService(t)
+	ctx := context.Background()
+	session := login(t, svc)
+
+	resp, err := svc.Refresh(ctx, session.RefreshToken)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.RefreshToken == session.RefreshToken {
+		t.Fatal("refresh token was not rotated")
+	}
+
+	old, next := repo.token(t, session.RefreshToken), repo.token(t, resp.RefreshToken)
+	if old.RevokedAt == nil || old.ReplacedBy == nil || *old.ReplacedBy != next.ID {
+		t.Fatalf("rotated token %+v, want revoked and replaced by %s", old, next.ID)
+	}
+	if next.FamilyID != old.FamilyID || next.RevokedAt != nil {
+		t.Fatalf("new token %+v, want active in family %s", next, old.FamilyID)
+	}
+
+	claims, err := auth.NewStaticVerifier(svc.keys.PublicKeys(), "user-service").Verify(resp.Token)
+	if err != nil || claims.UserID != session.User.ID {
+		t.Fatalf("access token: %+v, %v", claims, err)
+	}
+
+	// The new token rotates in turn
+	if _, err := svc.Refresh(ctx, resp.RefreshToken); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestRefreshReuseRevokesFamily(t *testing.T) {
+	svc, repo := newTestService(t)
+	ctx := context.Background()
+	stolen, other := login(t, svc), login(t, svc)
+
+	resp, err := svc.Refresh(ctx, stolen.RefreshToken)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := svc.Refresh(ctx, stolen.RefreshToken); !errors.Is(err, ErrInvalidRefreshToken) {
+		t.Fatalf("reused token: %v, want ErrInvalidRefreshToken", err)
+	}
+
+	if repo.token(t, resp.RefreshToken).RevokedAt == nil {
+		t.Fatal("token rotated from the reused one is still active")
+	}
+	if _, err := svc.Refresh(ctx, resp.RefreshToken); !errors.Is(err, ErrInvalidRefreshToken) {
+		t.Fatalf("refresh after reuse: %v, want ErrInvalidRefreshToken", err)
+	}
+	// Other sessions of the user are untouched
+	if _, err := svc.Refresh(ctx, other.RefreshToken); err != nil {
+		t.Fatalf("other session: %v", err)
+	}
+}
+
+func TestRefreshLosingRotationRaceRevokesFamily(t *testing.T) {
+	svc, repo := newTestService(t)
+	ctx := context.Background()
+	session := login(t, svc)
+
+	// Another request rotates the same token between the lookup and the
+	// rotation.
+	var winner *model.TokenResponse
+	repo.beforeRotate = func(*model.RefreshToken) {
+		repo.beforeRotate = nil
+		var err error
+		if winner, err = svc.Refresh(ctx, session.RefreshToken); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if _, err := svc.Refresh(ctx, session.RefreshToken); !errors.Is(err, ErrInvalidRefreshToken) {
+		t.Fatalf("losing rotation: %v, want ErrInvalidRefreshToken", err)
+	}
+	if repo.token(t, winner.RefreshToken).RevokedAt == nil {
+		t.Fatal("family survived a concurrent reuse")
+	}
+}
+
+func TestRefreshRejectsInvalidTokens(t *testing.T) {
+	cases := []struct {
+		name   string
+		expire bool
+		revoke bool
+	}{
+		{name: "expired", expire: true},
+		{name: "revoked", revoke: true},
+	}
+	for _, tc := range cases {
+		svc, repo := newTestService(t)
+		session := login(t, svc)
+		stored := repo.tokens[hashToken(session.RefreshToken)]
+		if tc.expire {
+			stored.ExpiresAt = time.Now().Add(-time.Second)
+		}
+		if tc.revoke {
+			now := time.Now()
+			stored.RevokedAt = &now
+		}
+
+		if _, err := svc.Refresh(context.Background(), session.RefreshToken); !errors.Is(err, ErrInvalidRefreshToken) {
+			t.Errorf("%s: %v, want ErrInvalidRefreshToken", tc.name, err)
+		}
+	}
+
+	svc, _ := newTestService(t)
+	if _, err := svc.Refresh(context.Background(), "never-issued"); !errors.Is(err, ErrInvalidRefreshToken) {
+		t.Errorf("unknown: %v, want ErrInvalidRefreshToken", err)
+	}
+}
+
+func TestLogoutRevokesSession(t *testing.T) {
+	svc, _ := newTestService(t)
+	ctx := context.Background()
+	session, other := login(t, svc), login(t, svc)
+
+	// Logging out with an already rotated token still ends the session
+	resp, err := svc.Refresh(ctx, session.RefreshToken)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := svc.Logout(ctx, session.RefreshToken); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := svc.Refresh(ctx, resp.RefreshToken); !errors.Is(err, ErrInvalidRefreshToken) {
+		t.Fatalf("refresh after logout: %v, want ErrInvalidRefreshToken", err)
+	}
+	if _, err := svc.Refresh(ctx, other.RefreshToken); err != nil {
+		t.Fatalf("other session: %v", err)
+	}
+
+	if err := svc.Logout(ctx, "never-issued"); !errors.Is(err, ErrInvalidRefreshToken) {
+		t.Fatalf("unknown token: %v, want ErrInvalidRefreshToken", err)
+	}
+}
+
+func TestLogoutAllRevokesEverySession(t *testing.T) {
+	svc, _ := newTestService(t)
+	ctx := context.Background()
+	first, second := login(t, svc), login(t, svc)
+
+	if err := svc.LogoutAll(ctx, first.User.ID); err != nil {
+		t.Fatal(err)
+	}
+	for _, session := range []*model.AuthResponse{first, second} {
+		if _, err := svc.Refresh(ctx, session.RefreshToken); !errors.Is(err, ErrInvalidRefreshToken) {
+			t.Errorf("refresh after logout everywhere: %v, want ErrInvalidRefreshToken", err)
+		}
+	}
+}
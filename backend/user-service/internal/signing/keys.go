@@ -0,0 +1,204 @@
+package signing
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+
+	"github.com/gavinadlan/tripnest/backend/common/auth"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// Key is a key pair identified by its RFC 7638 thumbprint. Keys loaded from
+// a public PEM can only verify; they stay published so tokens signed before a
+// rotation remain valid until they expire.
+type Key struct {
+	ID      string
+	Method  jwt.SigningMethod
+	Public  crypto.PublicKey
+	Private crypto.Signer
+}
+
+// KeyRing holds every published key and the one currently used to sign.
+type KeyRing struct {
+	active *Key
+	keys   []*Key
+}
+
+// LoadKeyRing reads PEM keys from the given files and from pemData (one or
+// more concatenated PEM blocks, e.g. from an environment variable). The key
+// with ID activeKID signs new tokens; an empty activeKID selects the first
+// private key. Without any key an ephemeral Ed25519 key is generated, which
+// is only suitable for a single local instance.
+func LoadKeyRing(files []string, pemData string, activeKID string) (*KeyRing, error) {
+	ring := &KeyRing{}
+
+	for _, file := range files {
+		file = strings.TrimSpace(file)
+		if file == "" {
+			continue
+		}
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read key file %s: %w", file, err)
+		}
+		if err := ring.addPEM(data); err != nil {
+			return nil, fmt.Errorf("invalid key file %s: %w", file, err)
+		}
+	}
+	if pemData != "" {
+		if err := ring.addPEM([]byte(pemData)); err != nil {
+			return nil, fmt.Errorf("invalid PEM keys: %w", err)
+		}
+	}
+
+	if len(ring.keys) == 0 {
+		log.Println("No JWT signing keys configured, generating an ephemeral Ed25519 key")
+		_, priv, err := ed25519.GenerateKey(rand.Reader)
+		if err != nil {
+			return nil, err
+		}
+		if err := ring.add(priv.Public(), priv); err != nil {
+			return nil, err
+		}
+	}
+
+	for _, k := range ring.keys {
+		if k.Private == nil {
+			continue
+		}
+		if (activeKID == "" && ring.active == nil) || k.ID == activeKID {
+			ring.active = k
+		}
+	}
+	if ring.active == nil {
+		if activeKID != "" {
+			return nil, fmt.Errorf("active signing key %q not found among private keys", activeKID)
+		}
+		return nil, errors.New("no private key available for signing")
+	}
+
+	return ring, nil
+}
+
+func (r *KeyRing) addPEM(data []byte) error {
+	found := false
+	for {
+		var block *pem.Block
+		block, data = pem.Decode(data)
+		if block == nil {
+			break
+		}
+		found = true
+
+		switch block.Type {
+		case "PRIVATE KEY":
+			key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+			if err != nil {
+				return err
+			}
+			signer, ok := key.(crypto.Signer)
+			if !ok {
+				return auth.ErrUnsupportedKey
+			}
+			if err := r.add(signer.Public(), signer); err != nil {
+				return err
+			}
+		case "RSA PRIVATE KEY":
+			key, err := x509.ParsePKCS1PrivateKey(block.Bytes)
+			if err != nil {
+				return err
+			}
+			if err := r.add(key.Public(), key); err != nil {
+				return err
+			}
+		case "PUBLIC KEY":
+			key, err := x509.ParsePKIXPublicKey(block.Bytes)
+			if err != nil {
+				return err
+			}
+			if err := r.add(key, nil); err != nil {
+				return err
+			}
+		default:
+			return fmt.Errorf("unsupported PEM block %q", block.Type)
+		}
+	}
+	if !found {
+		return errors.New("no PEM blocks found")
+	}
+	return nil
+}
+
+func (r *KeyRing) add(pub crypto.PublicKey, priv crypto.Signer) error {
+	var method jwt.SigningMethod
+	switch k := pub.(type) {
+	case *rsa.PublicKey:
+		if k.N.BitLen() < 2048 {
+			return errors.New("RSA keys must be at least 2048 bits")
+		}
+		method = jwt.SigningMethodRS256
+	case ed25519.PublicKey:
+		method = jwt.SigningMethodEdDSA
+	default:
+		return auth.ErrUnsupportedKey
+	}
+
+	kid, err := auth.Thumbprint(pub)
+	if err != nil {
+		return err
+	}
+	for _, existing := range r.keys {
+		if existing.ID == kid {
+			// The same key given as both private and public: keep the signer
+			if existing.Private == nil {
+				existing.Private = priv
+			}
+			return nil
+		}
+	}
+	r.keys = append(r.keys, &Key{ID: kid, Method: method, Public: pub, Private: priv})
+	return nil
+}
+
+// Sign signs claims with the active key, naming it in the "kid" header.
+func (r *KeyRing) Sign(claims jwt.Claims) (string, error) {
+	token := jwt.NewWithClaims(r.active.Method, claims)
+	token.Header["kid"] = r.active.ID
+	return token.SignedString(r.active.Private)
+}
+
+// ActiveKeyID returns the ID of the key used for signing.
+func (r *KeyRing) ActiveKeyID() string {
+	return r.active.ID
+}
+
+// JWKS returns the public key set to publish.
+func (r *KeyRing) JWKS() auth.JWKS {
+	set := auth.JWKS{Keys: make([]auth.JWK, 0, len(r.keys))}
+	for _, k := range r.keys {
+		jwk, err := auth.NewJWK(k.ID, k.Public)
+		if err != nil {
+			continue // Rejected when loaded
+		}
+		set.Keys = append(set.Keys, jwk)
+	}
+	return set
+}
+
+// PublicKeys returns the published keys by ID, for local verification.
+func (r *KeyRing) PublicKeys() map[string]crypto.PublicKey {
+	keys := make(map[string]crypto.PublicKey, len(r.keys))
+	for _, k := range r.keys {
+		keys[k.ID] = k.Public
+	}
+	return keys
+}
@@ -1,19 +1,18 @@
 import axios from 'axios';
 
-export const USER_SERVICE_URL = process.env.NEXT_PUBLIC_USER_SERVICE_URL || 'http://localhost:8080';
-export const BOOKING_SERVICE_URL = process.env.NEXT_PUBLIC_BOOKING_SERVICE_URL || 'http://localhost:8081';
-export const SEARCH_SERVICE_URL = process.env.NEXT_PUBLIC_SEARCH_SERVICE_URL || 'http://localhost:8083';
+// All traffic goes through the api-gateway
+export const API_GATEWAY_URL = process.env.NEXT_PUBLIC_API_GATEWAY_URL || 'http://localhost:8000';
 
 export const userApi = axios.create({
-  baseURL: USER_SERVICE_URL,
+  baseURL: `${API_GATEWAY_URL}/api/users`,
 });
 
 export const bookingApi = axios.create({
-  baseURL: BOOKING_SERVICE_URL,
+  baseURL: `${API_GATEWAY_URL}/api`,
 });
 
 export const searchApi = axios.create({
-  baseURL: SEARCH_SERVICE_URL,
+  baseURL: `${API_GATEWAY_URL}/api`,
 });
 
 // Adding an interceptor to inject JWT into bookingApi